@@ -1,18 +1,64 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"os"
+	"path/filepath"
 
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/unbindapp/unbind-installer/internal/cli"
+	"github.com/unbindapp/unbind-installer/internal/installer"
+	"github.com/unbindapp/unbind-installer/internal/pkgmanager"
 	"github.com/unbindapp/unbind-installer/internal/tui"
 )
 
 var Version = "dev"
 
 func main() {
+	// The installer doubles as the management CLI when installed as unbind
+	if filepath.Base(os.Args[0]) == cli.BinaryName {
+		os.Exit(cli.Execute(Version, os.Args[1:]))
+	}
+
+	// A fresh server is rebuilt from a backup or an old server's export instead of running
+	// the install wizard, packages for offline servers are downloaded before installing anything
+	if len(os.Args) > 1 && (os.Args[1] == "restore-server" || os.Args[1] == "migrate" || os.Args[1] == "download-packages") {
+		os.Exit(cli.Execute(Version, os.Args[1:]))
+	}
+
+	registryAuthFrom := flag.String("registry-auth-from", "",
+		"import external registry credentials from this docker config.json (and the credential helpers it uses)")
+	packageLockTimeout := flag.Duration("package-lock-timeout", pkgmanager.DefaultLockTimeout,
+		"how long to wait for another package manager, such as unattended upgrades, to release its lock")
+	packageSource := flag.String("package-source", "",
+		"install the required packages from a directory of package files or a file:// repository instead of the internet, see download-packages")
+	upgradeCLI := flag.Bool("upgrade-cli", false,
+		"install or upgrade the unbind management CLI at "+installer.ManagementCLIPath+" and exit")
+	flag.Parse()
+
+	if *upgradeCLI {
+		if err := installer.InstallManagementCLI(nil); err != nil {
+			fmt.Printf("Error upgrading the management CLI: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Installed unbind %s at %s\n", Version, installer.ManagementCLIPath)
+		return
+	}
+
 	// Initialize the Bubble Tea model
-	model := tui.NewModel(Version)
+	model := tui.NewModel(Version).WithPackageLockTimeout(*packageLockTimeout)
+	if *registryAuthFrom != "" {
+		model = model.WithRegistryAuthSource(*registryAuthFrom)
+	}
+	if *packageSource != "" {
+		source, err := pkgmanager.ParseLocalSource(*packageSource)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		model = model.WithPackageSource(source)
+	}
 
 	// Run the TUI
 	p := tea.NewProgram(model, tea.WithAltScreen())
@@ -0,0 +1,173 @@
+package awsauth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	algorithm      = "AWS4-HMAC-SHA256"
+	amzDateFormat  = "20060102T150405Z"
+	dateOnlyFormat = "20060102"
+
+	// UnsignedPayload may be passed as the payload hash when the body is streamed
+	UnsignedPayload = "UNSIGNED-PAYLOAD"
+)
+
+// Credentials are a static AWS access key pair
+type Credentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string // Optional, only for temporary credentials
+}
+
+// Signer signs requests with AWS Signature Version 4
+type Signer struct {
+	Credentials Credentials
+	Region      string
+	Service     string
+
+	// now is mockable for tests
+	now func() time.Time
+}
+
+// NewSigner creates a signer for a service in a region
+func NewSigner(creds Credentials, region, service string) *Signer {
+	return &Signer{
+		Credentials: creds,
+		Region:      region,
+		Service:     service,
+		now:         time.Now,
+	}
+}
+
+// Sign adds the SigV4 authorization headers to req, body is the full request payload
+func (self *Signer) Sign(req *http.Request, body []byte) {
+	sum := sha256.Sum256(body)
+	self.SignWithPayloadHash(req, hex.EncodeToString(sum[:]))
+}
+
+// SignWithPayloadHash signs req using a precomputed hex payload hash (or UnsignedPayload)
+func (self *Signer) SignWithPayloadHash(req *http.Request, payloadHash string) {
+	now := self.now().UTC()
+	amzDate := now.Format(amzDateFormat)
+	dateStamp := now.Format(dateOnlyFormat)
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	if self.Service == "s3" {
+		req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	}
+	if self.Credentials.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", self.Credentials.SessionToken)
+	}
+
+	canonicalHeaders, signedHeaders := canonicalHeaders(req)
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		canonicalURI(req.URL),
+		canonicalQuery(req.URL),
+		canonicalHeaders,
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := strings.Join([]string{dateStamp, self.Region, self.Service, "aws4_request"}, "/")
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{
+		algorithm,
+		amzDate,
+		scope,
+		hex.EncodeToString(requestHash[:]),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+self.Credentials.SecretAccessKey), dateStamp)
+	key = hmacSHA256(key, self.Region)
+	key = hmacSHA256(key, self.Service)
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		algorithm, self.Credentials.AccessKeyID, scope, signedHeaders, signature))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// canonicalURI returns the URI-encoded path, S3 style (each segment encoded once)
+func canonicalURI(u *url.URL) string {
+	path := u.EscapedPath()
+	if path == "" {
+		return "/"
+	}
+	return path
+}
+
+// canonicalQuery sorts and encodes the query string
+func canonicalQuery(u *url.URL) string {
+	query := u.Query()
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var parts []string
+	for _, key := range keys {
+		values := query[key]
+		sort.Strings(values)
+		for _, value := range values {
+			parts = append(parts, awsEscape(key)+"="+awsEscape(value))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// awsEscape percent-encodes everything except the RFC 3986 unreserved characters
+func awsEscape(s string) string {
+	return strings.ReplaceAll(url.QueryEscape(s), "+", "%20")
+}
+
+// canonicalHeaders returns the canonical header block and the signed header list
+func canonicalHeaders(req *http.Request) (string, string) {
+	headers := map[string]string{"host": req.URL.Host}
+	if req.Host != "" {
+		headers["host"] = req.Host
+	}
+	for name, values := range req.Header {
+		lower := strings.ToLower(name)
+		if lower == "authorization" || lower == "user-agent" {
+			continue
+		}
+		trimmed := make([]string, len(values))
+		for i, value := range values {
+			trimmed[i] = strings.Join(strings.Fields(value), " ")
+		}
+		headers[lower] = strings.Join(trimmed, ",")
+	}
+
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var block strings.Builder
+	for _, name := range names {
+		block.WriteString(name)
+		block.WriteString(":")
+		block.WriteString(headers[name])
+		block.WriteString("\n")
+	}
+
+	return block.String(), strings.Join(names, ";")
+}
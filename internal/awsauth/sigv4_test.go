@@ -0,0 +1,50 @@
+package awsauth
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestSign_AWSExample checks the signer against the example from the AWS SigV4 documentation
+func TestSign_AWSExample(t *testing.T) {
+	req, err := http.NewRequest(http.MethodGet, "https://iam.amazonaws.com/?Action=ListUsers&Version=2010-05-08", nil)
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded; charset=utf-8")
+
+	signer := NewSigner(Credentials{
+		AccessKeyID:     "AKIDEXAMPLE",
+		SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY",
+	}, "us-east-1", "iam")
+	signer.now = func() time.Time { return time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC) }
+
+	signer.Sign(req, nil)
+
+	assert.Equal(t, "20150830T123600Z", req.Header.Get("X-Amz-Date"))
+	assert.Equal(t,
+		"AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/iam/aws4_request, "+
+			"SignedHeaders=content-type;host;x-amz-date, "+
+			"Signature=5d672d79c15b13162d9279b0855cfba6789a8edb4c82c400e06b5924a6f2b5d7",
+		req.Header.Get("Authorization"))
+}
+
+func TestSign_S3ContentHash(t *testing.T) {
+	req, err := http.NewRequest(http.MethodPut, "https://bucket.s3.us-west-2.amazonaws.com/backups/a%20b.tar.gz", nil)
+	require.NoError(t, err)
+
+	signer := NewSigner(Credentials{AccessKeyID: "AKID", SecretAccessKey: "secret", SessionToken: "session"}, "us-west-2", "s3")
+	signer.SignWithPayloadHash(req, UnsignedPayload)
+
+	assert.Equal(t, UnsignedPayload, req.Header.Get("X-Amz-Content-Sha256"))
+	assert.Equal(t, "session", req.Header.Get("X-Amz-Security-Token"))
+	assert.Contains(t, req.Header.Get("Authorization"), "SignedHeaders=host;x-amz-content-sha256;x-amz-date;x-amz-security-token")
+}
+
+func TestCanonicalQuery(t *testing.T) {
+	req, err := http.NewRequest(http.MethodGet, "https://example.com/?b=2&a=1&a=0&c=x+y", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "a=0&a=1&b=2&c=x%20y", canonicalQuery(req.URL))
+}
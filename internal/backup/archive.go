@@ -0,0 +1,287 @@
+package backup
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	archivePrefix = "unbind-backup-"
+	archiveSuffix = ".tar.gz"
+	manifestName  = "manifest.json"
+
+	// Directories inside the archive
+	datastoreDir = "datastore"
+	configDir    = "etc-unbind"
+	tokenName    = "token"
+
+	// formatVersion is bumped when the archive layout changes incompatibly
+	formatVersion = 1
+)
+
+// Datastore is the K3s datastore a backup was taken from
+type Datastore string
+
+const (
+	DatastoreSQLite Datastore = "sqlite"
+	DatastoreEtcd   Datastore = "etcd"
+)
+
+// Manifest describes the contents of a backup archive
+type Manifest struct {
+	FormatVersion    int       `json:"formatVersion"`
+	CreatedAt        time.Time `json:"createdAt"`
+	Hostname         string    `json:"hostname"`
+	Datastore        Datastore `json:"datastore"`
+	Snapshot         string    `json:"snapshot"` // Datastore file within the archive
+	K3sVersion       string    `json:"k3sVersion,omitempty"`
+	InstallerVersion string    `json:"installerVersion,omitempty"`
+}
+
+// Paths are the files a backup covers
+type Paths struct {
+	ServerDir string // K3s server data, holding db/ and token
+	ConfigDir string // Unbind configuration
+}
+
+// DefaultPaths returns the locations used by the installer
+func DefaultPaths() Paths {
+	return Paths{
+		ServerDir: "/var/lib/rancher/k3s/server",
+		ConfigDir: "/etc/unbind",
+	}
+}
+
+func (self Paths) dbDir() string      { return filepath.Join(self.ServerDir, "db") }
+func (self Paths) sqlitePath() string { return filepath.Join(self.dbDir(), "state.db") }
+func (self Paths) etcdDir() string    { return filepath.Join(self.dbDir(), "etcd") }
+func (self Paths) tokenPath() string  { return filepath.Join(self.ServerDir, "token") }
+func (self Paths) tlsDir() string     { return filepath.Join(self.ServerDir, "tls") }
+func (self Paths) credDir() string    { return filepath.Join(self.ServerDir, "cred") }
+
+// ExtractedConfigDir returns where Extract unpacks the Unbind configuration
+func ExtractedConfigDir(extractedDir string) string {
+	return filepath.Join(extractedDir, configDir)
+}
+
+// ArchiveName returns the name of a backup taken at t, names sort by time
+func ArchiveName(t time.Time) string {
+	return archivePrefix + t.UTC().Format("20060102T150405Z") + archiveSuffix
+}
+
+// CreateOptions configures a backup
+type CreateOptions struct {
+	Paths            Paths
+	K3sVersion       string
+	InstallerVersion string
+	LogFn            func(string)
+}
+
+// Create snapshots the datastore and writes an archive with the snapshot, the K3s token and
+// the Unbind configuration to a temporary file. The caller uploads and removes the file.
+func Create(ctx context.Context, opts CreateOptions) (string, *Manifest, error) {
+	logFn := opts.LogFn
+	if logFn == nil {
+		logFn = func(string) {}
+	}
+
+	workDir, err := os.MkdirTemp("", "unbind-backup-")
+	if err != nil {
+		return "", nil, err
+	}
+	defer os.RemoveAll(workDir)
+
+	hostname, _ := os.Hostname()
+	manifest := &Manifest{
+		FormatVersion:    formatVersion,
+		CreatedAt:        time.Now().UTC(),
+		Hostname:         hostname,
+		Datastore:        DetectDatastore(opts.Paths),
+		K3sVersion:       opts.K3sVersion,
+		InstallerVersion: opts.InstallerVersion,
+	}
+
+	snapshotDir := filepath.Join(workDir, datastoreDir)
+	if err := os.MkdirAll(snapshotDir, 0700); err != nil {
+		return "", nil, err
+	}
+	var snapshot string
+	switch manifest.Datastore {
+	case DatastoreEtcd:
+		logFn("Taking an etcd snapshot...")
+		snapshot, err = snapshotEtcd(ctx, snapshotDir, strings.TrimSuffix(ArchiveName(manifest.CreatedAt), archiveSuffix))
+	default:
+		logFn("Taking a snapshot of the sqlite datastore...")
+		snapshot = filepath.Join(snapshotDir, "state.db")
+		err = snapshotSQLite(ctx, opts.Paths, snapshot, logFn)
+	}
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to snapshot the datastore: %w", err)
+	}
+	manifest.Snapshot = filepath.ToSlash(filepath.Join(datastoreDir, filepath.Base(snapshot)))
+
+	archive, err := os.CreateTemp("", "unbind-backup-*"+archiveSuffix)
+	if err != nil {
+		return "", nil, err
+	}
+	if err := writeArchive(archive, manifest, workDir, opts.Paths); err != nil {
+		archive.Close()
+		os.Remove(archive.Name())
+		return "", nil, err
+	}
+	if err := archive.Close(); err != nil {
+		os.Remove(archive.Name())
+		return "", nil, err
+	}
+	return archive.Name(), manifest, nil
+}
+
+func writeArchive(w io.Writer, manifest *Manifest, workDir string, paths Paths) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	manifestData, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := tw.WriteHeader(&tar.Header{Name: manifestName, Mode: 0600, Size: int64(len(manifestData)), ModTime: manifest.CreatedAt}); err != nil {
+		return err
+	}
+	if _, err := tw.Write(manifestData); err != nil {
+		return err
+	}
+
+	if err := addFile(tw, filepath.Join(workDir, manifest.Snapshot), manifest.Snapshot); err != nil {
+		return fmt.Errorf("failed to archive the datastore snapshot: %w", err)
+	}
+	if err := addFile(tw, paths.tokenPath(), tokenName); err != nil {
+		return fmt.Errorf("failed to archive the K3s token: %w", err)
+	}
+	if err := addDir(tw, paths.ConfigDir, configDir); err != nil {
+		return fmt.Errorf("failed to archive %s: %w", paths.ConfigDir, err)
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+func addFile(tw *tar.Writer, path, name string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	header, err := tar.FileInfoHeader(info, "")
+	if err != nil {
+		return err
+	}
+	header.Name = name
+	if err := tw.WriteHeader(header); err != nil {
+		return err
+	}
+	_, err = io.Copy(tw, file)
+	return err
+}
+
+// addDir archives the regular files of a directory tree, a missing directory is skipped
+func addDir(tw *tar.Writer, dir, name string) error {
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		return nil
+	}
+	return filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || !info.Mode().IsRegular() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		// Keep the backup credentials out of the backups themselves
+		if rel == EnvFileName {
+			return nil
+		}
+		return addFile(tw, path, filepath.ToSlash(filepath.Join(name, rel)))
+	})
+}
+
+// Extract unpacks an archive into dir and returns its manifest
+func Extract(r io.Reader, dir string) (*Manifest, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("not a backup archive: %w", err)
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read backup archive: %w", err)
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		// Never write outside dir, whatever the archive says
+		name := filepath.Clean(filepath.FromSlash(header.Name))
+		if filepath.IsAbs(name) || name == ".." || strings.HasPrefix(name, ".."+string(filepath.Separator)) {
+			return nil, fmt.Errorf("backup archive contains an unsafe path: %s", header.Name)
+		}
+		target := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(target), 0700); err != nil {
+			return nil, err
+		}
+		file, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.FileMode(header.Mode).Perm())
+		if err != nil {
+			return nil, err
+		}
+		if _, err := io.Copy(file, tr); err != nil {
+			file.Close()
+			return nil, err
+		}
+		if err := file.Close(); err != nil {
+			return nil, err
+		}
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, manifestName))
+	if err != nil {
+		return nil, fmt.Errorf("backup archive has no manifest")
+	}
+	var manifest Manifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse the backup manifest: %w", err)
+	}
+	if manifest.FormatVersion > formatVersion {
+		return nil, fmt.Errorf("backup format %d is newer than this version of unbind supports, upgrade the CLI first", manifest.FormatVersion)
+	}
+	// The snapshot path comes from the archive too, it has to stay in the datastore directory
+	snapshot := filepath.Clean(filepath.FromSlash(manifest.Snapshot))
+	if filepath.IsAbs(manifest.Snapshot) || slices.Contains(strings.Split(filepath.ToSlash(manifest.Snapshot), "/"), "..") ||
+		!strings.HasPrefix(snapshot, datastoreDir+string(filepath.Separator)) {
+		return nil, fmt.Errorf("backup manifest has an unsafe snapshot path: %s", manifest.Snapshot)
+	}
+	if _, err := os.Stat(filepath.Join(dir, snapshot)); err != nil {
+		return nil, fmt.Errorf("backup archive is missing the datastore snapshot %s", manifest.Snapshot)
+	}
+	return &manifest, nil
+}
@@ -0,0 +1,331 @@
+package backup
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeCommands records commands and stands in for sqlite3, k3s and systemctl
+func fakeCommands(t *testing.T, sqlite3 bool) *[]string {
+	commands := []string{}
+	originalRun, originalLookPath := runCommand, lookPath
+	t.Cleanup(func() { runCommand, lookPath = originalRun, originalLookPath })
+
+	lookPath = func(file string) (string, error) {
+		if file == "sqlite3" && sqlite3 {
+			return "/usr/bin/sqlite3", nil
+		}
+		return "", fmt.Errorf("%s not found", file)
+	}
+	runCommand = func(ctx context.Context, name string, args ...string) (string, error) {
+		commands = append(commands, name+" "+strings.Join(args, " "))
+		switch {
+		case name == "sqlite3":
+			// sqlite3 <db> ".backup '<dest>'"
+			data, err := os.ReadFile(args[0])
+			if err != nil {
+				return "", err
+			}
+			dest := strings.Trim(strings.TrimPrefix(args[1], ".backup "), "'")
+			return "", os.WriteFile(dest, data, 0600)
+		case name == "k3s" && args[0] == "etcd-snapshot":
+			dir := args[len(args)-1]
+			return "", os.WriteFile(filepath.Join(dir, args[3]+"-server-1-1735700000"), []byte("etcd snapshot"), 0600)
+		}
+		return "", nil
+	}
+	return &commands
+}
+
+func testPaths(t *testing.T) Paths {
+	root := t.TempDir()
+	paths := Paths{ServerDir: filepath.Join(root, "server"), ConfigDir: filepath.Join(root, "unbind")}
+	require.NoError(t, os.MkdirAll(paths.dbDir(), 0700))
+	require.NoError(t, os.MkdirAll(paths.ConfigDir, 0755))
+	require.NoError(t, os.WriteFile(paths.sqlitePath(), []byte("sqlite state"), 0600))
+	require.NoError(t, os.WriteFile(paths.tokenPath(), []byte("K10abc::server:secret\n"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(paths.ConfigDir, "config"), []byte("DOMAIN=example.com\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(paths.ConfigDir, EnvFileName), []byte("AWS_SECRET_ACCESS_KEY=secret\n"), 0600))
+	return paths
+}
+
+func createArchive(t *testing.T, paths Paths) (string, *Manifest) {
+	archive, manifest, err := Create(context.Background(), CreateOptions{Paths: paths, K3sVersion: "v1.33.1+k3s1", InstallerVersion: "1.2.3"})
+	require.NoError(t, err)
+	t.Cleanup(func() { os.Remove(archive) })
+	return archive, manifest
+}
+
+func extractArchive(t *testing.T, archive string) (string, *Manifest) {
+	file, err := os.Open(archive)
+	require.NoError(t, err)
+	defer file.Close()
+
+	dir := t.TempDir()
+	manifest, err := Extract(file, dir)
+	require.NoError(t, err)
+	return dir, manifest
+}
+
+func TestCreateAndRestore_SQLite(t *testing.T) {
+	commands := fakeCommands(t, true)
+	paths := testPaths(t)
+
+	archive, manifest := createArchive(t, paths)
+	assert.Equal(t, DatastoreSQLite, manifest.Datastore)
+	assert.Equal(t, "datastore/state.db", manifest.Snapshot)
+	require.Len(t, *commands, 1)
+	assert.True(t, strings.HasPrefix((*commands)[0], "sqlite3 "+paths.sqlitePath()+" .backup '"), (*commands)[0])
+
+	dir, extracted := extractArchive(t, archive)
+	assert.Equal(t, manifest.CreatedAt, extracted.CreatedAt)
+	assert.Equal(t, "v1.33.1+k3s1", extracted.K3sVersion)
+	assert.Equal(t, "1.2.3", extracted.InstallerVersion)
+	assert.NoFileExists(t, filepath.Join(dir, configDir, EnvFileName), "backup credentials must not be archived")
+
+	// Change the live state, then restore
+	require.NoError(t, os.WriteFile(paths.sqlitePath(), []byte("newer state"), 0600))
+	require.NoError(t, os.WriteFile(paths.tokenPath(), []byte("other token"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(paths.ConfigDir, "config"), []byte("DOMAIN=other.com\n"), 0644))
+	*commands = nil
+
+	require.NoError(t, Restore(context.Background(), dir, extracted, paths, nil))
+	assert.Equal(t, []string{"systemctl stop k3s", "systemctl start k3s"}, *commands)
+
+	data, err := os.ReadFile(paths.sqlitePath())
+	require.NoError(t, err)
+	assert.Equal(t, "sqlite state", string(data))
+	data, err = os.ReadFile(paths.tokenPath())
+	require.NoError(t, err)
+	assert.Equal(t, "K10abc::server:secret\n", string(data))
+	data, err = os.ReadFile(filepath.Join(paths.ConfigDir, "config"))
+	require.NoError(t, err)
+	assert.Equal(t, "DOMAIN=example.com\n", string(data))
+
+	// The replaced datastore is kept
+	previous, err := filepath.Glob(paths.dbDir() + ".before-restore-*")
+	require.NoError(t, err)
+	require.Len(t, previous, 1)
+	data, err = os.ReadFile(filepath.Join(previous[0], "state.db"))
+	require.NoError(t, err)
+	assert.Equal(t, "newer state", string(data))
+}
+
+func TestRestore_RollsBackOnFailure(t *testing.T) {
+	commands := fakeCommands(t, true)
+	paths := testPaths(t)
+	archive, _ := createArchive(t, paths)
+	dir, manifest := extractArchive(t, archive)
+
+	require.NoError(t, os.WriteFile(paths.sqlitePath(), []byte("newer state"), 0600))
+	require.NoError(t, os.WriteFile(paths.tokenPath(), []byte("other token"), 0600))
+	// The datastore copy fails
+	require.NoError(t, os.Remove(filepath.Join(dir, filepath.FromSlash(manifest.Snapshot))))
+	*commands = nil
+
+	err := Restore(context.Background(), dir, manifest, paths, nil)
+	assert.ErrorContains(t, err, "failed to restore the datastore")
+	assert.Equal(t, []string{"systemctl stop k3s", "systemctl start k3s"}, *commands, "K3s is started again on the previous datastore")
+	assert.Equal(t, "newer state", readFile(t, paths.sqlitePath()))
+	assert.Equal(t, "other token", readFile(t, paths.tokenPath()), "the previous datastore needs its own token")
+	previous, err := filepath.Glob(paths.dbDir() + ".before-restore-*")
+	require.NoError(t, err)
+	assert.Empty(t, previous)
+}
+
+func TestCreate_SQLiteWithoutCLI(t *testing.T) {
+	commands := fakeCommands(t, false)
+	paths := testPaths(t)
+
+	archive, _ := createArchive(t, paths)
+	assert.Equal(t, []string{"systemctl stop k3s", "systemctl start k3s"}, *commands)
+
+	dir, manifest := extractArchive(t, archive)
+	data, err := os.ReadFile(filepath.Join(dir, manifest.Snapshot))
+	require.NoError(t, err)
+	assert.Equal(t, "sqlite state", string(data))
+}
+
+func TestCreateAndRestore_Etcd(t *testing.T) {
+	commands := fakeCommands(t, true)
+	paths := testPaths(t)
+	require.NoError(t, os.MkdirAll(paths.etcdDir(), 0700))
+
+	archive, manifest := createArchive(t, paths)
+	assert.Equal(t, DatastoreEtcd, manifest.Datastore)
+	assert.True(t, strings.HasPrefix(manifest.Snapshot, "datastore/unbind-backup-"))
+	assert.Contains(t, (*commands)[0], "k3s etcd-snapshot save --name unbind-backup-")
+
+	dir, extracted := extractArchive(t, archive)
+	*commands = nil
+	require.NoError(t, Restore(context.Background(), dir, extracted, paths, nil))
+	assert.Equal(t, []string{
+		"systemctl stop k3s",
+		"k3s server --cluster-reset --cluster-reset-restore-path=" + filepath.Join(dir, filepath.FromSlash(extracted.Snapshot)),
+		"systemctl start k3s",
+	}, *commands)
+}
+
+func TestRestore_DatastoreMismatch(t *testing.T) {
+	fakeCommands(t, true)
+	paths := testPaths(t)
+	archive, _ := createArchive(t, paths)
+	dir, manifest := extractArchive(t, archive)
+
+	require.NoError(t, os.MkdirAll(paths.etcdDir(), 0700))
+	err := Restore(context.Background(), dir, manifest, paths, nil)
+	assert.ErrorContains(t, err, "backup is of a sqlite datastore but this server runs etcd")
+}
+
+func TestPrune_LocalTarget(t *testing.T) {
+	target := &LocalTarget{Dir: t.TempDir()}
+	ctx := context.Background()
+	for _, name := range []string{
+		"unbind-backup-20250103T030000Z.tar.gz",
+		"unbind-backup-20250101T030000Z.tar.gz",
+		"unbind-backup-20250102T030000Z.tar.gz",
+	} {
+		require.NoError(t, target.Put(ctx, name, writeTestFile(t, name)))
+	}
+	require.NoError(t, os.WriteFile(filepath.Join(target.Dir, "notes.txt"), nil, 0600))
+
+	deleted, err := Prune(ctx, target, 2)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"unbind-backup-20250101T030000Z.tar.gz"}, deleted)
+
+	objects, err := target.List(ctx)
+	require.NoError(t, err)
+	require.Len(t, objects, 2)
+	assert.Equal(t, "unbind-backup-20250102T030000Z.tar.gz", objects[0].Name)
+	assert.FileExists(t, filepath.Join(target.Dir, "notes.txt"))
+
+	deleted, err = Prune(ctx, target, 0)
+	require.NoError(t, err)
+	assert.Empty(t, deleted)
+}
+
+func TestInstallSchedule(t *testing.T) {
+	commands := fakeCommands(t, true)
+	root := t.TempDir()
+	files := ScheduleFiles{UnitDir: root, EnvFile: filepath.Join(root, "unbind", EnvFileName)}
+
+	require.NoError(t, InstallSchedule(context.Background(), Schedule{
+		OnCalendar: "*-*-* 03:00:00",
+		Command:    []string{"/usr/local/bin/unbind", "backup", "create"},
+		Env:        map[string]string{"UNBIND_BACKUP_TARGET": "s3://bucket", "AWS_SECRET_ACCESS_KEY": `se"cret`, "AWS_SESSION_TOKEN": ""},
+	}, files))
+
+	service, err := os.ReadFile(filepath.Join(root, "unbind-backup.service"))
+	require.NoError(t, err)
+	assert.Contains(t, string(service), "EnvironmentFile="+files.EnvFile)
+	assert.Contains(t, string(service), "ExecStart=/usr/local/bin/unbind backup create")
+	timer, err := os.ReadFile(filepath.Join(root, "unbind-backup.timer"))
+	require.NoError(t, err)
+	assert.Contains(t, string(timer), "OnCalendar=*-*-* 03:00:00")
+
+	env, err := os.ReadFile(files.EnvFile)
+	require.NoError(t, err)
+	assert.Equal(t, "AWS_SECRET_ACCESS_KEY=\"se\\\"cret\"\nUNBIND_BACKUP_TARGET=\"s3://bucket\"\n", string(env))
+	info, err := os.Stat(files.EnvFile)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+	assert.Equal(t, []string{"systemctl daemon-reload", "systemctl enable --now unbind-backup.timer"}, *commands)
+
+	*commands = nil
+	require.NoError(t, RemoveSchedule(context.Background(), files))
+	assert.NoFileExists(t, filepath.Join(root, "unbind-backup.timer"))
+	assert.NoFileExists(t, files.EnvFile)
+	assert.Equal(t, []string{"systemctl disable --now unbind-backup.timer", "systemctl daemon-reload"}, *commands)
+}
+
+func TestRestoreOnFreshServer(t *testing.T) {
+	commands := fakeCommands(t, true)
+	archive, _ := createArchive(t, testPaths(t))
+	dir, manifest := extractArchive(t, archive)
+	assert.Equal(t, "DOMAIN=example.com\n", readFile(t, filepath.Join(ExtractedConfigDir(dir), "config")))
+
+	// A fresh K3s install with its own token and certificates
+	fresh := Paths{ServerDir: filepath.Join(t.TempDir(), "server"), ConfigDir: filepath.Join(t.TempDir(), "unbind")}
+	for _, dir := range []string{fresh.dbDir(), fresh.tlsDir(), fresh.credDir()} {
+		require.NoError(t, os.MkdirAll(dir, 0700))
+	}
+	require.NoError(t, os.WriteFile(fresh.sqlitePath(), []byte("empty cluster"), 0600))
+	require.NoError(t, os.WriteFile(fresh.tokenPath(), []byte("fresh token"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(fresh.tlsDir(), "server-ca.crt"), []byte("fresh CA"), 0600))
+	*commands = nil
+
+	require.NoError(t, RestoreOnFreshServer(context.Background(), dir, manifest, fresh, nil))
+	assert.Equal(t, []string{"systemctl stop k3s", "systemctl stop k3s", "systemctl start k3s"}, *commands)
+	assert.NoDirExists(t, fresh.tlsDir(), "K3s takes the cluster's certificates from the datastore")
+	assert.NoDirExists(t, fresh.credDir())
+	assert.Equal(t, "sqlite state", readFile(t, fresh.sqlitePath()))
+	assert.Equal(t, "K10abc::server:secret\n", readFile(t, fresh.tokenPath()))
+	assert.Equal(t, "DOMAIN=example.com\n", readFile(t, filepath.Join(fresh.ConfigDir, "config")))
+}
+
+func readFile(t *testing.T, path string) string {
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	return string(data)
+}
+
+// writeTarGz writes an archive holding files, by name
+func writeTarGz(t *testing.T, files map[string]string) *bytes.Buffer {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for name, content := range files {
+		require.NoError(t, tw.WriteHeader(&tar.Header{Name: name, Mode: 0600, Size: int64(len(content)), Typeflag: tar.TypeReg}))
+		_, err := tw.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+	require.NoError(t, gz.Close())
+	return &buf
+}
+
+func TestExtract_UnsafeEntryPath(t *testing.T) {
+	for _, name := range []string{"../escape", "/etc/passwd", "datastore/../../escape"} {
+		t.Run(name, func(t *testing.T) {
+			dir := filepath.Join(t.TempDir(), "extracted")
+			_, err := Extract(writeTarGz(t, map[string]string{name: "data"}), dir)
+			assert.ErrorContains(t, err, "unsafe path")
+		})
+	}
+}
+
+func TestExtract_UnsafeSnapshotPath(t *testing.T) {
+	outside := filepath.Join(t.TempDir(), "state.db")
+	require.NoError(t, os.WriteFile(outside, []byte("not from the archive"), 0600))
+
+	for _, snapshot := range []string{"../../state.db", outside, "datastore/../manifest.json", "config/unbind/config", "datastore"} {
+		t.Run(snapshot, func(t *testing.T) {
+			manifest := fmt.Sprintf(`{"formatVersion":1,"snapshot":%q}`, snapshot)
+			archive := writeTarGz(t, map[string]string{
+				manifestName:           manifest,
+				"datastore/state.db":   "sqlite state",
+				"config/unbind/config": "DOMAIN=example.com\n",
+			})
+			_, err := Extract(archive, t.TempDir())
+			assert.ErrorContains(t, err, "unsafe snapshot path")
+		})
+	}
+
+	archive := writeTarGz(t, map[string]string{
+		manifestName:         `{"formatVersion":1,"snapshot":"datastore/state.db"}`,
+		"datastore/state.db": "sqlite state",
+	})
+	manifest, err := Extract(archive, t.TempDir())
+	require.NoError(t, err)
+	assert.Equal(t, "datastore/state.db", manifest.Snapshot)
+}
@@ -0,0 +1,246 @@
+package backup
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// runCommand runs a command and returns its combined output, mockable for tests
+var runCommand = func(ctx context.Context, name string, args ...string) (string, error) {
+	output, err := exec.CommandContext(ctx, name, args...).CombinedOutput()
+	if err != nil {
+		return string(output), fmt.Errorf("%s %s failed: %w: %s", name, strings.Join(args, " "), err, strings.TrimSpace(string(output)))
+	}
+	return string(output), nil
+}
+
+// lookPath finds a binary, mockable for tests
+var lookPath = exec.LookPath
+
+// DetectDatastore reports whether K3s runs embedded etcd (HA) or the default sqlite datastore
+func DetectDatastore(paths Paths) Datastore {
+	if info, err := os.Stat(paths.etcdDir()); err == nil && info.IsDir() {
+		return DatastoreEtcd
+	}
+	return DatastoreSQLite
+}
+
+// snapshotSQLite writes a consistent copy of the sqlite datastore to dest. The sqlite3 CLI takes
+// an online backup when it is installed, otherwise K3s is stopped for the copy. Workloads keep
+// running while K3s is stopped, only the API is briefly unavailable.
+func snapshotSQLite(ctx context.Context, paths Paths, dest string, logFn func(string)) error {
+	if _, err := lookPath("sqlite3"); err == nil {
+		_, err := runCommand(ctx, "sqlite3", paths.sqlitePath(), fmt.Sprintf(".backup '%s'", dest))
+		return err
+	}
+
+	logFn("sqlite3 is not installed, stopping K3s for a consistent copy...")
+	if _, err := runCommand(ctx, "systemctl", "stop", "k3s"); err != nil {
+		return err
+	}
+	defer func() {
+		// Restart even if the copy was cancelled
+		if _, err := runCommand(context.Background(), "systemctl", "start", "k3s"); err != nil {
+			logFn(fmt.Sprintf("Failed to restart K3s: %v", err))
+		}
+	}()
+
+	// A cleanly stopped K3s has checkpointed its write-ahead log into state.db
+	return copyFile(paths.sqlitePath(), dest, 0600)
+}
+
+// snapshotEtcd saves an etcd snapshot into dir and returns its path
+func snapshotEtcd(ctx context.Context, dir, name string) (string, error) {
+	if _, err := runCommand(ctx, "k3s", "etcd-snapshot", "save", "--name", name, "--dir", dir); err != nil {
+		return "", err
+	}
+
+	// K3s appends the node name and a timestamp to the snapshot name
+	matches, err := filepath.Glob(filepath.Join(dir, name+"*"))
+	if err != nil || len(matches) != 1 {
+		return "", fmt.Errorf("could not find the etcd snapshot written to %s", dir)
+	}
+	return matches[0], nil
+}
+
+// Restore replaces the datastore, K3s token and Unbind configuration with the contents of an
+// extracted backup. K3s is stopped during the restore and started again afterwards. The replaced
+// datastore and token are kept next to the new ones, and put back when the restore fails.
+func Restore(ctx context.Context, extractedDir string, manifest *Manifest, paths Paths, logFn func(string)) (err error) {
+	if logFn == nil {
+		logFn = func(string) {}
+	}
+	if current := DetectDatastore(paths); current != manifest.Datastore && dirExists(paths.dbDir()) {
+		return fmt.Errorf("the backup is of a %s datastore but this server runs %s", manifest.Datastore, current)
+	}
+
+	logFn("Stopping K3s...")
+	if _, err := runCommand(ctx, "systemctl", "stop", "k3s"); err != nil {
+		return err
+	}
+
+	// K3s can't start on the old datastore with the new token, so a failed restore puts both back
+	suffix := ".before-restore-" + time.Now().UTC().Format("20060102T150405Z")
+	previousToken, previousDB := "", ""
+	defer func() {
+		if err == nil {
+			return
+		}
+		logFn("Restore failed, putting the previous datastore back...")
+		if rollbackErr := rollbackRestore(paths, previousToken, previousDB); rollbackErr != nil {
+			logFn(fmt.Sprintf("Failed to put the previous datastore back: %v", rollbackErr))
+		}
+		if _, startErr := runCommand(context.Background(), "systemctl", "start", "k3s"); startErr != nil {
+			logFn(fmt.Sprintf("Failed to restart K3s: %v", startErr))
+		}
+	}()
+
+	// The datastore is encrypted with the token, so it goes back first
+	logFn("Restoring the K3s token...")
+	if err := os.MkdirAll(paths.ServerDir, 0700); err != nil {
+		return err
+	}
+	if _, err := os.Stat(paths.tokenPath()); err == nil {
+		if err := copyFile(paths.tokenPath(), paths.tokenPath()+suffix, 0600); err != nil {
+			return fmt.Errorf("failed to keep the current K3s token: %w", err)
+		}
+		previousToken = paths.tokenPath() + suffix
+	}
+	if err := copyFile(filepath.Join(extractedDir, tokenName), paths.tokenPath(), 0600); err != nil {
+		return fmt.Errorf("failed to restore the K3s token: %w", err)
+	}
+
+	snapshot := filepath.Join(extractedDir, filepath.FromSlash(manifest.Snapshot))
+	switch manifest.Datastore {
+	case DatastoreEtcd:
+		logFn("Restoring the etcd snapshot, this resets the cluster to a single server...")
+		if _, err := runCommand(ctx, "k3s", "server", "--cluster-reset", "--cluster-reset-restore-path="+snapshot); err != nil {
+			return err
+		}
+	default:
+		logFn("Restoring the sqlite datastore...")
+		if dirExists(paths.dbDir()) {
+			if err := os.Rename(paths.dbDir(), paths.dbDir()+suffix); err != nil {
+				return fmt.Errorf("failed to move the current datastore aside: %w", err)
+			}
+			previousDB = paths.dbDir() + suffix
+			logFn(fmt.Sprintf("The previous datastore was kept at %s", previousDB))
+		}
+		if err := os.MkdirAll(paths.dbDir(), 0700); err != nil {
+			return err
+		}
+		if err := copyFile(snapshot, paths.sqlitePath(), 0600); err != nil {
+			return fmt.Errorf("failed to restore the datastore: %w", err)
+		}
+	}
+
+	logFn(fmt.Sprintf("Restoring %s...", paths.ConfigDir))
+	if err := restoreDir(filepath.Join(extractedDir, configDir), paths.ConfigDir); err != nil {
+		return fmt.Errorf("failed to restore %s: %w", paths.ConfigDir, err)
+	}
+
+	logFn("Starting K3s...")
+	if _, err := runCommand(ctx, "systemctl", "start", "k3s"); err != nil {
+		return err
+	}
+	return nil
+}
+
+// rollbackRestore puts back the token and sqlite datastore Restore kept aside, empty when there
+// was none
+func rollbackRestore(paths Paths, previousToken, previousDB string) error {
+	if previousToken != "" {
+		if err := copyFile(previousToken, paths.tokenPath(), 0600); err != nil {
+			return err
+		}
+	}
+	if previousDB != "" {
+		if err := os.RemoveAll(paths.dbDir()); err != nil {
+			return err
+		}
+		if err := os.Rename(previousDB, paths.dbDir()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RestoreOnFreshServer restores a backup onto a server K3s was just installed on. The
+// certificates that install generated are removed so K3s takes the cluster's own from the
+// restored datastore, which agents and kubeconfigs from before the restore still trust.
+func RestoreOnFreshServer(ctx context.Context, extractedDir string, manifest *Manifest, paths Paths, logFn func(string)) error {
+	if logFn == nil {
+		logFn = func(string) {}
+	}
+
+	logFn("Stopping K3s...")
+	if _, err := runCommand(ctx, "systemctl", "stop", "k3s"); err != nil {
+		return err
+	}
+	for _, dir := range []string{paths.tlsDir(), paths.credDir()} {
+		if err := os.RemoveAll(dir); err != nil {
+			return fmt.Errorf("failed to remove %s: %w", dir, err)
+		}
+	}
+	return Restore(ctx, extractedDir, manifest, paths, logFn)
+}
+
+// restoreDir copies the files under src into dst, keeping their modes
+func restoreDir(src, dst string) error {
+	if !dirExists(src) {
+		return nil
+	}
+	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil || !info.Mode().IsRegular() {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return err
+		}
+		return copyFile(path, target, info.Mode().Perm())
+	})
+}
+
+// copyFile copies src to dst atomically
+func copyFile(src, dst string, mode os.FileMode) error {
+	source, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer source.Close()
+
+	tmp, err := os.CreateTemp(filepath.Dir(dst), ".copy-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, source); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(mode); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), dst)
+}
+
+func dirExists(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
@@ -0,0 +1,269 @@
+package backup
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/unbindapp/unbind-installer/internal/awsauth"
+)
+
+// S3Config configures the connection to an S3 compatible service
+type S3Config struct {
+	// Endpoint of S3 compatible services such as MinIO, e.g. https://minio.example.com:9000.
+	// Buckets are addressed by path on custom endpoints. Empty means AWS S3.
+	Endpoint    string
+	Region      string
+	Credentials awsauth.Credentials
+}
+
+// S3Target keeps backups in a bucket, under an optional prefix
+type S3Target struct {
+	Bucket string
+	Prefix string
+
+	config   S3Config
+	endpoint *url.URL
+	signer   *awsauth.Signer
+	client   *http.Client
+}
+
+// NewS3Target creates a target for a bucket
+func NewS3Target(bucket, prefix string, config S3Config) (*S3Target, error) {
+	if config.Region == "" {
+		config.Region = "us-east-1"
+	}
+	if config.Credentials.AccessKeyID == "" || config.Credentials.SecretAccessKey == "" {
+		return nil, fmt.Errorf("S3 backups need an access key, set AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY")
+	}
+
+	endpoint := config.Endpoint
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://%s.s3.%s.amazonaws.com", bucket, config.Region)
+	}
+	parsed, err := url.Parse(endpoint)
+	if err != nil || parsed.Host == "" {
+		return nil, fmt.Errorf("invalid S3 endpoint %q", config.Endpoint)
+	}
+
+	return &S3Target{
+		Bucket:   bucket,
+		Prefix:   strings.Trim(prefix, "/"),
+		config:   config,
+		endpoint: parsed,
+		signer:   awsauth.NewSigner(config.Credentials, config.Region, "s3"),
+		client:   &http.Client{},
+	}, nil
+}
+
+func (self *S3Target) String() string {
+	if self.Prefix == "" {
+		return "s3://" + self.Bucket
+	}
+	return "s3://" + self.Bucket + "/" + self.Prefix
+}
+
+// key returns the object key of a backup
+func (self *S3Target) key(name string) string {
+	if self.Prefix == "" {
+		return name
+	}
+	return self.Prefix + "/" + name
+}
+
+// url returns the URL of an object key, or of the bucket when key is empty
+func (self *S3Target) url(key string, query url.Values) *url.URL {
+	u := *self.endpoint
+	if self.config.Endpoint != "" {
+		u.Path = path.Join("/", self.Bucket, key)
+	} else {
+		u.Path = "/" + key
+	}
+	u.RawQuery = query.Encode()
+	return &u
+}
+
+// do signs and sends a request, turning S3 error responses into errors
+func (self *S3Target) do(req *http.Request, payloadHash string) (*http.Response, error) {
+	self.signer.SignWithPayloadHash(req, payloadHash)
+	resp, err := self.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach %s: %w", self.endpoint.Host, err)
+	}
+	if resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		return nil, s3Error(resp)
+	}
+	return resp, nil
+}
+
+func (self *S3Target) Put(ctx context.Context, name string, filePath string) error {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	// The signature covers the payload hash, so hash the archive before sending it
+	hash := sha256.New()
+	size, err := io.Copy(hash, file)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, self.url(self.key(name), nil).String(), file)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+	req.Header.Set("Content-Type", "application/gzip")
+
+	resp, err := self.do(req, hex.EncodeToString(hash.Sum(nil)))
+	if err != nil {
+		return fmt.Errorf("failed to upload %s to %s: %w", name, self, err)
+	}
+	resp.Body.Close()
+	return nil
+}
+
+func (self *S3Target) Get(ctx context.Context, name string) (io.ReadCloser, error) {
+	if !isArchiveName(name) {
+		return nil, fmt.Errorf("%s is not a backup archive", name)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, self.url(self.key(name), nil).String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := self.do(req, awsauth.UnsignedPayload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s from %s: %w", name, self, err)
+	}
+	return resp.Body, nil
+}
+
+// listBucketResult is the ListObjectsV2 response
+type listBucketResult struct {
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+	Contents              []struct {
+		Key          string    `xml:"Key"`
+		Size         int64     `xml:"Size"`
+		LastModified time.Time `xml:"LastModified"`
+	} `xml:"Contents"`
+}
+
+func (self *S3Target) List(ctx context.Context) ([]Object, error) {
+	prefix := self.key(archivePrefix)
+	objects := []Object{}
+	continuation := ""
+
+	for {
+		query := url.Values{"list-type": {"2"}, "prefix": {prefix}}
+		if continuation != "" {
+			query.Set("continuation-token", continuation)
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, self.url("", query).String(), nil)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := self.do(req, awsauth.UnsignedPayload)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list backups in %s: %w", self, err)
+		}
+
+		var result listBucketResult
+		err = xml.NewDecoder(resp.Body).Decode(&result)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse the backup list from %s: %w", self, err)
+		}
+
+		for _, content := range result.Contents {
+			name := strings.TrimPrefix(content.Key, self.key(""))
+			if isArchiveName(name) {
+				objects = append(objects, Object{Name: name, Size: content.Size, LastModified: content.LastModified})
+			}
+		}
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			break
+		}
+		continuation = result.NextContinuationToken
+	}
+
+	sortObjects(objects)
+	return objects, nil
+}
+
+func (self *S3Target) Delete(ctx context.Context, name string) error {
+	if !isArchiveName(name) {
+		return fmt.Errorf("%s is not a backup archive", name)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, self.url(self.key(name), nil).String(), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := self.do(req, awsauth.UnsignedPayload)
+	if err != nil {
+		return fmt.Errorf("failed to delete %s from %s: %w", name, self, err)
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// writeCheckName is the object CheckWritable writes and deletes again
+const writeCheckName = ".unbind-write-check"
+
+// CheckWritable uploads and deletes a small object, proving the bucket exists and the
+// credentials may write to it
+func (self *S3Target) CheckWritable(ctx context.Context) error {
+	data := []byte("unbind write check\n")
+	sum := sha256.Sum256(data)
+	url := self.url(self.key(writeCheckName), nil).String()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	resp, err := self.do(req, hex.EncodeToString(sum[:]))
+	if err != nil {
+		return fmt.Errorf("cannot write to %s: %w", self, err)
+	}
+	resp.Body.Close()
+
+	req, err = http.NewRequestWithContext(ctx, http.MethodDelete, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err = self.do(req, awsauth.UnsignedPayload)
+	if err != nil {
+		return fmt.Errorf("cannot delete from %s: %w", self, err)
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// s3Error reads the code and message of an S3 error response
+func s3Error(resp *http.Response) error {
+	var body struct {
+		Code    string `xml:"Code"`
+		Message string `xml:"Message"`
+	}
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if xml.Unmarshal(data, &body) == nil && body.Code != "" {
+		return fmt.Errorf("%s: %s (HTTP %d)", body.Code, body.Message, resp.StatusCode)
+	}
+	return fmt.Errorf("HTTP %d", resp.StatusCode)
+}
@@ -0,0 +1,232 @@
+package backup
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/unbindapp/unbind-installer/internal/awsauth"
+)
+
+// fakeS3 is a MinIO style stand-in keeping objects of one bucket in memory
+type fakeS3 struct {
+	t       *testing.T
+	bucket  string
+	pageMax int
+
+	mu      sync.Mutex
+	objects map[string][]byte
+}
+
+func newFakeS3(t *testing.T, bucket string) (*fakeS3, *httptest.Server) {
+	fake := &fakeS3{t: t, bucket: bucket, pageMax: 1000, objects: map[string][]byte{}}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+	return fake, server
+}
+
+func (self *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=minio/") {
+		self.error(w, http.StatusForbidden, "AccessDenied", "missing signature")
+		return
+	}
+
+	bucket, key, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	if bucket != self.bucket {
+		self.error(w, http.StatusNotFound, "NoSuchBucket", "The specified bucket does not exist")
+		return
+	}
+
+	switch {
+	case r.Method == http.MethodPut:
+		data, err := io.ReadAll(r.Body)
+		require.NoError(self.t, err)
+		sum := sha256.Sum256(data)
+		if r.Header.Get("X-Amz-Content-Sha256") != hex.EncodeToString(sum[:]) {
+			self.error(w, http.StatusBadRequest, "XAmzContentSHA256Mismatch", "content hash mismatch")
+			return
+		}
+		self.objects[key] = data
+	case r.Method == http.MethodGet && key == "":
+		self.list(w, r)
+	case r.Method == http.MethodGet:
+		data, ok := self.objects[key]
+		if !ok {
+			self.error(w, http.StatusNotFound, "NoSuchKey", "The specified key does not exist.")
+			return
+		}
+		w.Write(data)
+	case r.Method == http.MethodDelete:
+		delete(self.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// list answers ListObjectsV2, using the next key as the continuation token
+func (self *fakeS3) list(w http.ResponseWriter, r *http.Request) {
+	assert.Equal(self.t, "2", r.URL.Query().Get("list-type"))
+
+	keys := []string{}
+	for key := range self.objects {
+		if strings.HasPrefix(key, r.URL.Query().Get("prefix")) && key >= r.URL.Query().Get("continuation-token") {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	type content struct {
+		Key  string `xml:"Key"`
+		Size int64  `xml:"Size"`
+	}
+	result := struct {
+		XMLName               xml.Name  `xml:"ListBucketResult"`
+		IsTruncated           bool      `xml:"IsTruncated"`
+		NextContinuationToken string    `xml:"NextContinuationToken,omitempty"`
+		Contents              []content `xml:"Contents"`
+	}{}
+	for i, key := range keys {
+		if i == self.pageMax {
+			result.IsTruncated = true
+			result.NextContinuationToken = key
+			break
+		}
+		result.Contents = append(result.Contents, content{Key: key, Size: int64(len(self.objects[key]))})
+	}
+	require.NoError(self.t, xml.NewEncoder(w).Encode(result))
+}
+
+func (self *fakeS3) error(w http.ResponseWriter, status int, code, message string) {
+	w.WriteHeader(status)
+	xml.NewEncoder(w).Encode(struct {
+		XMLName xml.Name `xml:"Error"`
+		Code    string   `xml:"Code"`
+		Message string   `xml:"Message"`
+	}{Code: code, Message: message})
+}
+
+func testS3Config(endpoint string) S3Config {
+	return S3Config{
+		Endpoint:    endpoint,
+		Credentials: awsauth.Credentials{AccessKeyID: "minio", SecretAccessKey: "minio123"},
+	}
+}
+
+func writeTestFile(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "archive.tar.gz")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0600))
+	return path
+}
+
+func TestS3Target_RoundTrip(t *testing.T) {
+	fake, server := newFakeS3(t, "backups")
+	fake.pageMax = 2
+
+	target, err := ParseTarget("s3://backups/cluster-a/", testS3Config(server.URL))
+	require.NoError(t, err)
+	assert.Equal(t, "s3://backups/cluster-a", target.String())
+
+	ctx := context.Background()
+	names := []string{
+		"unbind-backup-20250101T030000Z.tar.gz",
+		"unbind-backup-20250103T030000Z.tar.gz",
+		"unbind-backup-20250102T030000Z.tar.gz",
+	}
+	for _, name := range names {
+		require.NoError(t, target.Put(ctx, name, writeTestFile(t, "archive "+name)))
+	}
+	// Objects that aren't backups are left alone
+	fake.objects["cluster-a/notes.txt"] = []byte("hello")
+	fake.objects["other/unbind-backup-20250101T030000Z.tar.gz"] = []byte("other cluster")
+
+	objects, err := target.List(ctx)
+	require.NoError(t, err)
+	require.Len(t, objects, 3)
+	assert.Equal(t, names[0], objects[0].Name)
+	assert.Equal(t, names[2], objects[1].Name)
+	assert.Equal(t, names[1], objects[2].Name)
+	assert.Equal(t, int64(len("archive "+names[0])), objects[0].Size)
+
+	body, err := target.Get(ctx, names[1])
+	require.NoError(t, err)
+	data, err := io.ReadAll(body)
+	body.Close()
+	require.NoError(t, err)
+	assert.Equal(t, "archive "+names[1], string(data))
+
+	deleted, err := Prune(ctx, target, 1)
+	require.NoError(t, err)
+	assert.Equal(t, []string{names[0], names[2]}, deleted)
+	assert.Contains(t, fake.objects, "cluster-a/"+names[1])
+	assert.Contains(t, fake.objects, "cluster-a/notes.txt")
+	assert.Contains(t, fake.objects, "other/"+names[0])
+}
+
+func TestS3Target_Errors(t *testing.T) {
+	_, server := newFakeS3(t, "backups")
+	ctx := context.Background()
+
+	target, err := ParseTarget("s3://missing", testS3Config(server.URL))
+	require.NoError(t, err)
+	_, err = target.List(ctx)
+	assert.ErrorContains(t, err, "NoSuchBucket")
+
+	target, err = ParseTarget("s3://backups", testS3Config(server.URL))
+	require.NoError(t, err)
+	_, err = target.Get(ctx, "unbind-backup-20250101T030000Z.tar.gz")
+	assert.ErrorContains(t, err, "NoSuchKey")
+	_, err = target.Get(ctx, "../etc/passwd")
+	assert.ErrorContains(t, err, "not a backup archive")
+
+	_, err = ParseTarget("s3://backups", S3Config{Endpoint: server.URL})
+	assert.ErrorContains(t, err, "AWS_ACCESS_KEY_ID")
+}
+
+func TestParseTarget(t *testing.T) {
+	target, err := ParseTarget("/var/backups/unbind", S3Config{})
+	require.NoError(t, err)
+	assert.Equal(t, &LocalTarget{Dir: "/var/backups/unbind"}, target)
+
+	target, err = ParseTarget("file:///mnt/backups", S3Config{})
+	require.NoError(t, err)
+	assert.Equal(t, &LocalTarget{Dir: "/mnt/backups"}, target)
+
+	s3, err := ParseTarget("s3://bucket/prefix", S3Config{Region: "eu-west-1", Credentials: awsauth.Credentials{AccessKeyID: "a", SecretAccessKey: "b"}})
+	require.NoError(t, err)
+	assert.Equal(t, "https://bucket.s3.eu-west-1.amazonaws.com/prefix/x", s3.(*S3Target).url("prefix/x", nil).String())
+
+	_, err = ParseTarget("backups", S3Config{})
+	assert.Error(t, err)
+	_, err = ParseTarget("s3://", S3Config{})
+	assert.Error(t, err)
+}
+
+func TestS3Target_CheckWritable(t *testing.T) {
+	fake, server := newFakeS3(t, "backups")
+	ctx := context.Background()
+
+	target, err := ParseTarget("s3://backups/longhorn", testS3Config(server.URL))
+	require.NoError(t, err)
+	require.NoError(t, target.(*S3Target).CheckWritable(ctx))
+	assert.Empty(t, fake.objects)
+
+	target, err = ParseTarget("s3://missing", testS3Config(server.URL))
+	require.NoError(t, err)
+	assert.ErrorContains(t, target.(*S3Target).CheckWritable(ctx), "NoSuchBucket")
+}
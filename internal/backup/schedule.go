@@ -0,0 +1,129 @@
+package backup
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	// scheduleUnit is the name of the systemd service and timer running scheduled backups
+	scheduleUnit = "unbind-backup"
+	// EnvFileName holds the target and credentials of scheduled backups, inside the config dir
+	EnvFileName = "backup.env"
+)
+
+// Schedule configures periodic backups
+type Schedule struct {
+	OnCalendar string            // systemd calendar expression, e.g. daily or *-*-* 03:00:00
+	Command    []string          // Backup command the service runs
+	Env        map[string]string // Target, retention and credentials, kept out of the unit files
+}
+
+// ScheduleFiles are where the schedule is installed
+type ScheduleFiles struct {
+	UnitDir string // systemd unit directory
+	EnvFile string
+}
+
+// DefaultScheduleFiles returns the locations used on a real server
+func DefaultScheduleFiles(paths Paths) ScheduleFiles {
+	return ScheduleFiles{
+		UnitDir: "/etc/systemd/system",
+		EnvFile: filepath.Join(paths.ConfigDir, EnvFileName),
+	}
+}
+
+func (self ScheduleFiles) servicePath() string {
+	return filepath.Join(self.UnitDir, scheduleUnit+".service")
+}
+
+func (self ScheduleFiles) timerPath() string {
+	return filepath.Join(self.UnitDir, scheduleUnit+".timer")
+}
+
+// InstallSchedule writes the backup service, timer and environment file and enables the timer
+func InstallSchedule(ctx context.Context, schedule Schedule, files ScheduleFiles) error {
+	service := fmt.Sprintf(`[Unit]
+Description=Unbind datastore backup
+After=k3s.service
+
+[Service]
+Type=oneshot
+EnvironmentFile=%s
+ExecStart=%s
+`, files.EnvFile, strings.Join(schedule.Command, " "))
+
+	timer := fmt.Sprintf(`[Unit]
+Description=Scheduled Unbind datastore backups
+
+[Timer]
+OnCalendar=%s
+RandomizedDelaySec=5m
+Persistent=true
+
+[Install]
+WantedBy=timers.target
+`, schedule.OnCalendar)
+
+	// Credentials live in the environment file, readable by root only
+	if err := os.MkdirAll(filepath.Dir(files.EnvFile), 0755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(files.EnvFile, []byte(envFile(schedule.Env)), 0600); err != nil {
+		return fmt.Errorf("failed to write %s: %w", files.EnvFile, err)
+	}
+	if err := os.Chmod(files.EnvFile, 0600); err != nil {
+		return err
+	}
+	if err := os.WriteFile(files.servicePath(), []byte(service), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", files.servicePath(), err)
+	}
+	if err := os.WriteFile(files.timerPath(), []byte(timer), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", files.timerPath(), err)
+	}
+
+	if _, err := runCommand(ctx, "systemctl", "daemon-reload"); err != nil {
+		return err
+	}
+	_, err := runCommand(ctx, "systemctl", "enable", "--now", scheduleUnit+".timer")
+	return err
+}
+
+// RemoveSchedule disables the timer and removes the files InstallSchedule wrote
+func RemoveSchedule(ctx context.Context, files ScheduleFiles) error {
+	if _, err := os.Stat(files.timerPath()); os.IsNotExist(err) {
+		return nil
+	}
+	if _, err := runCommand(ctx, "systemctl", "disable", "--now", scheduleUnit+".timer"); err != nil {
+		return err
+	}
+	for _, path := range []string{files.timerPath(), files.servicePath(), files.EnvFile} {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	_, err := runCommand(ctx, "systemctl", "daemon-reload")
+	return err
+}
+
+// envFile renders a systemd environment file, values are quoted so spaces survive
+func envFile(env map[string]string) string {
+	keys := make([]string, 0, len(env))
+	for key, value := range env {
+		if value != "" {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	var s strings.Builder
+	for _, key := range keys {
+		value := strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(env[key])
+		fmt.Fprintf(&s, "%s=\"%s\"\n", key, value)
+	}
+	return s.String()
+}
@@ -0,0 +1,154 @@
+package backup
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Object is a backup archive stored on a target
+type Object struct {
+	Name         string
+	Size         int64
+	LastModified time.Time
+}
+
+// Target stores backup archives, on local disk or in an S3 compatible bucket
+type Target interface {
+	// Put uploads the archive at path under name
+	Put(ctx context.Context, name string, path string) error
+	Get(ctx context.Context, name string) (io.ReadCloser, error)
+	// List returns the backup archives, oldest first
+	List(ctx context.Context) ([]Object, error)
+	Delete(ctx context.Context, name string) error
+	String() string
+}
+
+// ParseTarget returns the target for a spec: a local directory, file:///dir or s3://bucket/prefix
+func ParseTarget(spec string, s3Config S3Config) (Target, error) {
+	switch {
+	case strings.HasPrefix(spec, "s3://"):
+		bucket, prefix, _ := strings.Cut(strings.TrimPrefix(spec, "s3://"), "/")
+		if bucket == "" {
+			return nil, fmt.Errorf("missing bucket in %s", spec)
+		}
+		return NewS3Target(bucket, prefix, s3Config)
+	case strings.HasPrefix(spec, "file://"):
+		return &LocalTarget{Dir: strings.TrimPrefix(spec, "file://")}, nil
+	case filepath.IsAbs(spec):
+		return &LocalTarget{Dir: spec}, nil
+	default:
+		return nil, fmt.Errorf("unsupported backup target %q, use an absolute directory or s3://bucket/prefix", spec)
+	}
+}
+
+// isArchiveName reports whether name is a backup archive created by Create
+func isArchiveName(name string) bool {
+	return strings.HasPrefix(name, archivePrefix) && strings.HasSuffix(name, archiveSuffix) && !strings.Contains(name, "/")
+}
+
+// sortObjects orders backups oldest first, archive names sort by creation time
+func sortObjects(objects []Object) {
+	sort.Slice(objects, func(i, j int) bool { return objects[i].Name < objects[j].Name })
+}
+
+// LocalTarget keeps backups in a directory on this server
+type LocalTarget struct {
+	Dir string
+}
+
+func (self *LocalTarget) String() string {
+	return self.Dir
+}
+
+func (self *LocalTarget) Put(ctx context.Context, name string, path string) error {
+	if err := os.MkdirAll(self.Dir, 0700); err != nil {
+		return fmt.Errorf("failed to create %s: %w", self.Dir, err)
+	}
+
+	source, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer source.Close()
+
+	// Written under a temporary name so a partial copy is never listed as a backup
+	tmp, err := os.CreateTemp(self.Dir, ".upload-*")
+	if err != nil {
+		return fmt.Errorf("failed to write backup: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, source); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write backup: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write backup: %w", err)
+	}
+	return os.Rename(tmp.Name(), filepath.Join(self.Dir, name))
+}
+
+func (self *LocalTarget) Get(ctx context.Context, name string) (io.ReadCloser, error) {
+	if !isArchiveName(name) {
+		return nil, fmt.Errorf("%s is not a backup archive", name)
+	}
+	return os.Open(filepath.Join(self.Dir, name))
+}
+
+func (self *LocalTarget) List(ctx context.Context) ([]Object, error) {
+	entries, err := os.ReadDir(self.Dir)
+	if os.IsNotExist(err) {
+		return []Object{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	objects := []Object{}
+	for _, entry := range entries {
+		if entry.IsDir() || !isArchiveName(entry.Name()) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return nil, err
+		}
+		objects = append(objects, Object{Name: entry.Name(), Size: info.Size(), LastModified: info.ModTime()})
+	}
+	sortObjects(objects)
+	return objects, nil
+}
+
+func (self *LocalTarget) Delete(ctx context.Context, name string) error {
+	if !isArchiveName(name) {
+		return fmt.Errorf("%s is not a backup archive", name)
+	}
+	return os.Remove(filepath.Join(self.Dir, name))
+}
+
+// Prune deletes the oldest backups so only the newest keep remain, returning the deleted names
+func Prune(ctx context.Context, target Target, keep int) ([]string, error) {
+	if keep <= 0 {
+		return nil, nil
+	}
+	objects, err := target.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	deleted := []string{}
+	for len(objects) > keep {
+		if err := target.Delete(ctx, objects[0].Name); err != nil {
+			return deleted, fmt.Errorf("failed to delete %s: %w", objects[0].Name, err)
+		}
+		deleted = append(deleted, objects[0].Name)
+		objects = objects[1:]
+	}
+	return deleted, nil
+}
@@ -0,0 +1,112 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+func newAddNodeCommand(opts Options) *cobra.Command {
+	var (
+		ttl         time.Duration
+		role        string
+		description string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "add-node",
+		Short: "Show instructions for adding a new node",
+		Long: "Show instructions for adding a new node.\n\n" +
+			"Agents join with a bootstrap token that expires after --ttl, list and revoke tokens with\n" +
+			"unbind token. K3s only lets servers join with the permanent server token.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := checkInstallation(opts); err != nil {
+				return err
+			}
+			if role != roleAgent && role != roleServer {
+				return fmt.Errorf("--role must be %s or %s", roleAgent, roleServer)
+			}
+			if role == roleAgent && ttl <= 0 {
+				return fmt.Errorf("--ttl must be positive")
+			}
+
+			serverToken, err := readServerToken(opts)
+			if err != nil {
+				return err
+			}
+
+			cfg, err := loadConfig(opts)
+			if err != nil {
+				return err
+			}
+			if cfg.ClusterIP == "" {
+				return fmt.Errorf("could not find the cluster IP in %s", opts.ConfigPath)
+			}
+
+			token := serverToken
+			if role == roleAgent {
+				if token, err = createBootstrapToken(cmd.Context(), ttl, description, serverToken); err != nil {
+					return fmt.Errorf("failed to create a join token: %w", err)
+				}
+			}
+
+			p := newPrinter(opts.Stdout)
+			p.Banner()
+
+			// Keep the full version including +k3s1 so agents match the server
+			k3sVersion, err := opts.K3sVersion()
+			if err != nil {
+				k3sVersion = cfg.K3sVersion
+			}
+			if k3sVersion == "" {
+				p.Warning("Warning: Could not detect K3s version.")
+				p.Colored(p.warning, "The command below will install the latest version of K3s.")
+				p.Colored(p.warning, "If you need a specific version, add INSTALL_K3S_VERSION=<version> to the command.")
+				p.Line("")
+			}
+
+			p.Info("Add Node Instructions")
+			p.Bold("To add a new %s node to your Unbind cluster, run the following command on the new server:", role)
+			p.Line("")
+			p.Command(joinCommand(cfg.ClusterIP, token, k3sVersion, role))
+			if k3sVersion != "" {
+				p.Line("")
+				p.Colored(p.success, "This will install K3s version: %s", k3sVersion)
+			}
+			p.Line("")
+			if role == roleAgent {
+				p.Colored(p.info, "The join token %s expires at %s.", tokenID(token),
+					time.Now().Add(ttl).Local().Format("2006-01-02 15:04 MST"))
+				p.Colored(p.info, "Revoke it early with: unbind token revoke %s", tokenID(token))
+			} else {
+				p.Colored(p.warning, "Server nodes join with the server token, it never expires and grants full control of the cluster.")
+				p.Colored(p.warning, "Only share this command over a secure channel.")
+			}
+			p.Colored(p.warning, "Note: Make sure the new server can reach this server on port 6443")
+			return nil
+		},
+	}
+
+	cmd.Flags().DurationVar(&ttl, "ttl", 24*time.Hour, "how long the agent join token stays valid")
+	cmd.Flags().StringVar(&role, "role", roleAgent, "role of the new node, agent or server")
+	cmd.Flags().StringVar(&description, "description", bootstrapTokenDescription, "description stored with the join token")
+	return cmd
+}
+
+// joinCommand builds the k3s install command for a new node
+func joinCommand(clusterIP, token, k3sVersion, role string) string {
+	env := []string{}
+	if k3sVersion != "" {
+		env = append(env, "INSTALL_K3S_VERSION="+k3sVersion)
+	}
+	serverURL := fmt.Sprintf("https://%s:6443", clusterIP)
+	if role == roleServer {
+		env = append(env, "K3S_TOKEN="+token)
+		return "curl -sfL https://get.k3s.io | " + strings.Join(env, " ") + " sh -s - server --server " + serverURL
+	}
+	env = append(env, "K3S_URL="+serverURL, "K3S_TOKEN="+token)
+	return "curl -sfL https://get.k3s.io | " + strings.Join(env, " ") + " sh -"
+}
@@ -0,0 +1,374 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/unbindapp/unbind-installer/internal/awsauth"
+	"github.com/unbindapp/unbind-installer/internal/backup"
+	"github.com/unbindapp/unbind-installer/internal/installer"
+)
+
+// Environment variables that configure the backup target, the scheduled backup service reads
+// them from its environment file
+const (
+	envBackupTarget     = "UNBIND_BACKUP_TARGET"
+	envBackupKeep       = "UNBIND_BACKUP_KEEP"
+	envBackupS3Endpoint = "UNBIND_BACKUP_S3_ENDPOINT"
+	envBackupS3Region   = "UNBIND_BACKUP_S3_REGION"
+)
+
+// Mockable for tests, the real ones run k3s, sqlite3 and systemctl
+var (
+	createBackup     = backup.Create
+	restoreDatastore = backup.Restore
+)
+
+// defaultBackupTarget keeps backups on this server when no target is configured
+const defaultBackupTarget = "/var/backups/unbind"
+
+// targetFlags are the flags selecting where backups are stored
+type targetFlags struct {
+	target     string
+	s3Endpoint string
+	s3Region   string
+}
+
+func (self *targetFlags) register(cmd *cobra.Command) {
+	target := os.Getenv(envBackupTarget)
+	if target == "" {
+		target = defaultBackupTarget
+	}
+	region := os.Getenv(envBackupS3Region)
+	if region == "" {
+		region = os.Getenv("AWS_REGION")
+	}
+
+	cmd.Flags().StringVar(&self.target, "target", target, "where backups are stored, a directory or s3://bucket/prefix ($"+envBackupTarget+")")
+	cmd.Flags().StringVar(&self.s3Endpoint, "s3-endpoint", os.Getenv(envBackupS3Endpoint), "endpoint of an S3 compatible service such as MinIO, empty for AWS ($"+envBackupS3Endpoint+")")
+	cmd.Flags().StringVar(&self.s3Region, "s3-region", region, "S3 region ($"+envBackupS3Region+")")
+}
+
+// open returns the target, S3 credentials come from the standard AWS environment variables
+func (self *targetFlags) open() (backup.Target, error) {
+	return backup.ParseTarget(self.target, self.s3Config())
+}
+
+func (self *targetFlags) s3Config() backup.S3Config {
+	return backup.S3Config{
+		Endpoint: self.s3Endpoint,
+		Region:   self.s3Region,
+		Credentials: awsauth.Credentials{
+			AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+			SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+			SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+		},
+	}
+}
+
+// env returns the settings for the scheduled backup service
+func (self *targetFlags) env() map[string]string {
+	s3 := self.s3Config()
+	return map[string]string{
+		envBackupTarget:         self.target,
+		envBackupS3Endpoint:     self.s3Endpoint,
+		envBackupS3Region:       self.s3Region,
+		"AWS_ACCESS_KEY_ID":     s3.Credentials.AccessKeyID,
+		"AWS_SECRET_ACCESS_KEY": s3.Credentials.SecretAccessKey,
+		"AWS_SESSION_TOKEN":     s3.Credentials.SessionToken,
+	}
+}
+
+func defaultKeep() int {
+	if keep, err := strconv.Atoi(os.Getenv(envBackupKeep)); err == nil {
+		return keep
+	}
+	return 7
+}
+
+func newBackupCommand(version string, opts Options) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "backup",
+		Short: "Back up the cluster datastore, K3s token and Unbind configuration",
+		Long: "Back up the cluster datastore, K3s token and Unbind configuration.\n\n" +
+			"Backups go to a local directory or any S3 compatible bucket. For S3, set AWS_ACCESS_KEY_ID\n" +
+			"and AWS_SECRET_ACCESS_KEY. Restore a backup with unbind restore.",
+	}
+	cmd.AddCommand(
+		newBackupCreateCommand(version, opts),
+		newBackupListCommand(opts),
+		newBackupScheduleCommand(opts),
+	)
+	return cmd
+}
+
+func newBackupCreateCommand(version string, opts Options) *cobra.Command {
+	var (
+		target targetFlags
+		keep   int
+	)
+
+	cmd := &cobra.Command{
+		Use:   "create",
+		Short: "Take a backup now",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := checkInstallation(opts); err != nil {
+				return err
+			}
+			destination, err := target.open()
+			if err != nil {
+				return err
+			}
+
+			p := newPrinter(opts.Stdout)
+			k3sVersion, _ := opts.K3sVersion()
+
+			var archive string
+			var manifest *backup.Manifest
+			if err := runWithLogs(opts.Stdout, func(logChan chan<- string) error {
+				archive, manifest, err = createBackup(cmd.Context(), backup.CreateOptions{
+					Paths:            opts.BackupPaths,
+					K3sVersion:       k3sVersion,
+					InstallerVersion: version,
+					LogFn:            func(message string) { logChan <- message },
+				})
+				return err
+			}); err != nil {
+				return err
+			}
+			defer os.Remove(archive)
+
+			name := backup.ArchiveName(manifest.CreatedAt)
+			p.Subtle("Uploading %s to %s...", name, destination)
+			if err := destination.Put(cmd.Context(), name, archive); err != nil {
+				return err
+			}
+
+			deleted, err := backup.Prune(cmd.Context(), destination, keep)
+			for _, old := range deleted {
+				p.Subtle("Deleted old backup %s", old)
+			}
+			if err != nil {
+				return fmt.Errorf("backup %s was saved but old backups couldn't be cleaned up: %w", name, err)
+			}
+
+			p.Success(fmt.Sprintf("Backup %s saved to %s", name, destination))
+			return nil
+		},
+	}
+
+	target.register(cmd)
+	cmd.Flags().IntVar(&keep, "keep", defaultKeep(), "number of backups to keep, older ones are deleted, 0 keeps all ($"+envBackupKeep+")")
+	return cmd
+}
+
+func newBackupListCommand(opts Options) *cobra.Command {
+	var target targetFlags
+
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List backups",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			destination, err := target.open()
+			if err != nil {
+				return err
+			}
+			objects, err := destination.List(cmd.Context())
+			if err != nil {
+				return err
+			}
+
+			p := newPrinter(opts.Stdout)
+			if len(objects) == 0 {
+				p.Info(fmt.Sprintf("No backups in %s", destination))
+				return nil
+			}
+			p.Bold("%-40s %10s", "NAME", "SIZE")
+			for _, object := range objects {
+				p.Line("%-40s %10s", object.Name, formatBytes(object.Size))
+			}
+			return nil
+		},
+	}
+
+	target.register(cmd)
+	return cmd
+}
+
+func newBackupScheduleCommand(opts Options) *cobra.Command {
+	var (
+		target     targetFlags
+		keep       int
+		onCalendar string
+		disable    bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "schedule",
+		Short: "Take backups on a schedule with a systemd timer",
+		Long: "Take backups on a schedule with a systemd timer.\n\n" +
+			"The target and S3 credentials are saved to " + backup.EnvFileName + " in the Unbind configuration\n" +
+			"directory, readable by root only.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := checkInstallation(opts); err != nil {
+				return err
+			}
+
+			p := newPrinter(opts.Stdout)
+			files := backup.DefaultScheduleFiles(opts.BackupPaths)
+			if opts.ScheduleUnitDir != "" {
+				files.UnitDir = opts.ScheduleUnitDir
+			}
+
+			if disable {
+				if err := backup.RemoveSchedule(cmd.Context(), files); err != nil {
+					return err
+				}
+				p.Success("Scheduled backups are disabled")
+				return nil
+			}
+
+			// Check the target now rather than when the first scheduled backup fails
+			destination, err := target.open()
+			if err != nil {
+				return err
+			}
+			if _, err := destination.List(cmd.Context()); err != nil {
+				return err
+			}
+
+			env := target.env()
+			env[envBackupKeep] = strconv.Itoa(keep)
+			if err := backup.InstallSchedule(cmd.Context(), backup.Schedule{
+				OnCalendar: onCalendar,
+				Command:    []string{installer.ManagementCLIPath, "backup", "create"},
+				Env:        env,
+			}, files); err != nil {
+				return err
+			}
+
+			p.Success(fmt.Sprintf("Backups to %s are scheduled %s, keeping the last %d", destination, onCalendar, keep))
+			p.Subtle("Check the schedule with: systemctl list-timers unbind-backup.timer")
+			return nil
+		},
+	}
+
+	target.register(cmd)
+	cmd.Flags().IntVar(&keep, "keep", defaultKeep(), "number of backups to keep, older ones are deleted, 0 keeps all")
+	cmd.Flags().StringVar(&onCalendar, "on-calendar", "daily", "when to take backups, as a systemd calendar expression")
+	cmd.Flags().BoolVar(&disable, "disable", false, "stop taking scheduled backups")
+	return cmd
+}
+
+func newRestoreCommand(opts Options) *cobra.Command {
+	var (
+		target targetFlags
+		yes    bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "restore <backup>",
+		Short: "Restore the cluster datastore from a backup",
+		Long: "Restore the cluster datastore, K3s token and Unbind configuration from a backup.\n\n" +
+			"The backup is a name from unbind backup list, or the path of an archive on this server.\n" +
+			"K3s is stopped during the restore. The replaced sqlite datastore is kept next to the new one.",
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := checkInstallation(opts); err != nil {
+				return err
+			}
+
+			p := newPrinter(opts.Stdout)
+			p.Banner()
+			p.Box("WARNING: Restoring a backup", p.error)
+			p.Colored(p.error, "The cluster state will be replaced with the state in the backup.")
+			p.Colored(p.error, "Changes made since the backup was taken will be lost.")
+			p.Line("")
+
+			if !yes && !confirm(opts.Stdin, opts.Stdout, "Restore "+args[0]+"? (y/N) ") {
+				p.Info("Restore cancelled.")
+				return nil
+			}
+
+			manifest, err := restoreBackup(cmd.Context(), opts, &target, args[0], opts.Stdout)
+			if err != nil {
+				return err
+			}
+			p.Success(fmt.Sprintf("Restored the backup taken on %s at %s", manifest.Hostname, manifest.CreatedAt.Local().Format("2006-01-02 15:04 MST")))
+			return nil
+		},
+	}
+
+	target.register(cmd)
+	cmd.Flags().BoolVarP(&yes, "yes", "y", false, "skip the confirmation prompt")
+	return cmd
+}
+
+// restoreBackup fetches, extracts and restores a backup by name or local path
+func restoreBackup(ctx context.Context, opts Options, target *targetFlags, name string, out io.Writer) (*backup.Manifest, error) {
+	workDir, manifest, err := fetchBackup(ctx, target, name)
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(workDir)
+
+	err = runWithLogs(out, func(logChan chan<- string) error {
+		return restoreDatastore(ctx, workDir, manifest, opts.BackupPaths, func(message string) { logChan <- message })
+	})
+	return manifest, err
+}
+
+// fetchBackup extracts a backup, by name from the target or by local path, into a temporary
+// directory the caller removes
+func fetchBackup(ctx context.Context, target *targetFlags, name string) (string, *backup.Manifest, error) {
+	var source io.ReadCloser
+	if strings.ContainsRune(name, os.PathSeparator) {
+		file, err := os.Open(name)
+		if err != nil {
+			return "", nil, err
+		}
+		source = file
+	} else {
+		destination, err := target.open()
+		if err != nil {
+			return "", nil, err
+		}
+		if source, err = destination.Get(ctx, name); err != nil {
+			return "", nil, err
+		}
+	}
+	defer source.Close()
+
+	workDir, err := os.MkdirTemp("", "unbind-restore-")
+	if err != nil {
+		return "", nil, err
+	}
+	manifest, err := backup.Extract(source, workDir)
+	if err != nil {
+		os.RemoveAll(workDir)
+		return "", nil, err
+	}
+	return workDir, manifest, nil
+}
+
+// formatBytes renders a size for humans
+func formatBytes(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,113 @@
+package cli
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/unbindapp/unbind-installer/internal/backup"
+)
+
+// fakeBackups replaces taking and restoring snapshots, returning the manifests restored
+func fakeBackups(t *testing.T, createdAt time.Time) *[]*backup.Manifest {
+	originalCreate, originalRestore := createBackup, restoreDatastore
+	t.Cleanup(func() { createBackup, restoreDatastore = originalCreate, originalRestore })
+
+	createBackup = func(ctx context.Context, opts backup.CreateOptions) (string, *backup.Manifest, error) {
+		manifest := &backup.Manifest{
+			FormatVersion:    1,
+			CreatedAt:        createdAt,
+			Hostname:         "server-1",
+			Datastore:        backup.DatastoreSQLite,
+			Snapshot:         "datastore/state.db",
+			K3sVersion:       opts.K3sVersion,
+			InstallerVersion: opts.InstallerVersion,
+		}
+		return writeTestArchive(t, manifest, nil), manifest, nil
+	}
+
+	restored := []*backup.Manifest{}
+	restoreDatastore = func(ctx context.Context, extractedDir string, manifest *backup.Manifest, paths backup.Paths, logFn func(string)) error {
+		data, err := os.ReadFile(filepath.Join(extractedDir, "datastore", "state.db"))
+		require.NoError(t, err)
+		assert.Equal(t, "sqlite state", string(data))
+		restored = append(restored, manifest)
+		return nil
+	}
+	return &restored
+}
+
+// writeTestArchive writes a backup archive with a sqlite snapshot and any extra files
+func writeTestArchive(t *testing.T, manifest *backup.Manifest, extra map[string]string) string {
+	file, err := os.CreateTemp(t.TempDir(), "archive-*.tar.gz")
+	require.NoError(t, err)
+	defer file.Close()
+
+	gz := gzip.NewWriter(file)
+	tw := tar.NewWriter(gz)
+	manifestData, err := json.Marshal(manifest)
+	require.NoError(t, err)
+	files := map[string][]byte{"manifest.json": manifestData, "datastore/state.db": []byte("sqlite state")}
+	for name, content := range extra {
+		files[name] = []byte(content)
+	}
+	for name, data := range files {
+		require.NoError(t, tw.WriteHeader(&tar.Header{Name: name, Mode: 0600, Size: int64(len(data))}))
+		_, err := tw.Write(data)
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+	require.NoError(t, gz.Close())
+	return file.Name()
+}
+
+func TestBackupCreateListRestore(t *testing.T) {
+	opts, out := testOptions(t, "y\n")
+	target := filepath.Join(t.TempDir(), "backups")
+	createdAt := time.Date(2025, 6, 1, 3, 0, 0, 0, time.UTC)
+	restored := fakeBackups(t, createdAt)
+
+	// Older backups beyond --keep are deleted
+	require.NoError(t, os.MkdirAll(target, 0700))
+	for _, name := range []string{"unbind-backup-20250101T030000Z.tar.gz", "unbind-backup-20250102T030000Z.tar.gz"} {
+		require.NoError(t, os.WriteFile(filepath.Join(target, name), []byte("old"), 0600))
+	}
+
+	require.NoError(t, run(opts, "backup", "create", "--target", target, "--keep", "2"))
+	assert.Contains(t, out.String(), "Backup unbind-backup-20250601T030000Z.tar.gz saved to "+target)
+	assert.Contains(t, out.String(), "Deleted old backup unbind-backup-20250101T030000Z.tar.gz")
+
+	out.Reset()
+	require.NoError(t, run(opts, "backup", "list", "--target", target))
+	assert.NotContains(t, out.String(), "unbind-backup-20250101T030000Z.tar.gz")
+	assert.Contains(t, out.String(), "unbind-backup-20250102T030000Z.tar.gz")
+	assert.Contains(t, out.String(), "unbind-backup-20250601T030000Z.tar.gz")
+
+	require.NoError(t, run(opts, "restore", "unbind-backup-20250601T030000Z.tar.gz", "--target", target))
+	require.Len(t, *restored, 1)
+	assert.Equal(t, "v1.33.1+k3s1", (*restored)[0].K3sVersion)
+	assert.Equal(t, "v1.2.3", (*restored)[0].InstallerVersion)
+	assert.Contains(t, out.String(), "Restored the backup taken on server-1")
+}
+
+func TestRestore_Cancelled(t *testing.T) {
+	opts, out := testOptions(t, "n\n")
+	restored := fakeBackups(t, time.Now())
+	archive := writeTestArchive(t, &backup.Manifest{FormatVersion: 1, Datastore: backup.DatastoreSQLite, Snapshot: "datastore/state.db"}, nil)
+
+	require.NoError(t, run(opts, "restore", archive))
+	assert.Empty(t, *restored)
+	assert.Contains(t, out.String(), "Restore cancelled.")
+}
+
+func TestBackupList_UnsupportedTarget(t *testing.T) {
+	opts, _ := testOptions(t, "")
+	assert.ErrorContains(t, run(opts, "backup", "list", "--target", "backups"), "unsupported backup target")
+}
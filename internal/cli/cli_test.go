@@ -0,0 +1,181 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testOptions points the commands at a fake installation in a temp dir
+func testOptions(t *testing.T, stdin string) (Options, *bytes.Buffer) {
+	dir := t.TempDir()
+	opts := Options{
+		ConfigPath:           filepath.Join(dir, "config"),
+		NodeTokenPath:        filepath.Join(dir, "node-token"),
+		UninstallScriptPath:  filepath.Join(dir, "k3s-uninstall.sh"),
+		KubeConfigPath:       filepath.Join(dir, "k3s.yaml"),
+		PackageRecordPath:    filepath.Join(dir, "installed-packages"),
+		RepositoryRecordPath: filepath.Join(dir, "enabled-repositories"),
+		K3sVersion:           func() (string, error) { return "v1.33.1+k3s1", nil },
+		ServiceState:         func(name string) string { return "active" },
+		CertificateExpiry: func(ctx context.Context, domain string) (time.Time, error) {
+			return time.Now().Add(60 * 24 * time.Hour), nil
+		},
+		HostResources: func() (*HostResources, error) {
+			return &HostResources{DiskUsedPercent: 40, MemoryUsedPercent: 50}, nil
+		},
+		Stdin: strings.NewReader(stdin),
+	}
+	out := &bytes.Buffer{}
+	opts.Stdout = out
+	opts.Stderr = out
+
+	require.NoError(t, os.WriteFile(opts.UninstallScriptPath, []byte("#!/bin/sh\n"), 0755))
+	require.NoError(t, os.WriteFile(opts.NodeTokenPath, []byte("K10abc::server:secret\n"), 0600))
+	require.NoError(t, os.WriteFile(opts.ConfigPath, []byte("CLUSTER_IP=10.0.0.5\nK3S_VERSION=v1.32.0+k3s1\n"), 0644))
+	return opts, out
+}
+
+func run(opts Options, args ...string) error {
+	root := NewRootCommand("v1.2.3", opts)
+	root.SetArgs(args)
+	return root.Execute()
+}
+
+func TestAddNode(t *testing.T) {
+	calls := fakeK3s(t, "abcdef.0123456789abcdef\n")
+	opts, out := testOptions(t, "")
+
+	require.NoError(t, run(opts, "add-node", "--ttl", "2h"))
+	assert.Equal(t, [][]string{{"token", "create", "--ttl", "2h0m0s", "--description", "created by unbind add-node"}}, *calls)
+	assert.Contains(t, out.String(),
+		"curl -sfL https://get.k3s.io | INSTALL_K3S_VERSION=v1.33.1+k3s1 K3S_URL=https://10.0.0.5:6443 K3S_TOKEN=K10abc::abcdef.0123456789abcdef sh -")
+	assert.NotContains(t, out.String(), "server:secret")
+	assert.Contains(t, out.String(), "unbind token revoke abcdef")
+}
+
+func TestAddNode_Server(t *testing.T) {
+	calls := fakeK3s(t, "")
+	opts, out := testOptions(t, "")
+
+	require.NoError(t, run(opts, "add-node", "--role", "server"))
+	assert.Empty(t, *calls, "servers can't join with bootstrap tokens")
+	assert.Contains(t, out.String(),
+		"curl -sfL https://get.k3s.io | INSTALL_K3S_VERSION=v1.33.1+k3s1 K3S_TOKEN=K10abc::server:secret sh -s - server --server https://10.0.0.5:6443")
+	assert.Contains(t, out.String(), "never expires")
+}
+
+func TestAddNode_FallsBackToConfiguredVersion(t *testing.T) {
+	fakeK3s(t, "abcdef.0123456789abcdef\n")
+	opts, out := testOptions(t, "")
+	opts.K3sVersion = func() (string, error) { return "", errors.New("k3s not found") }
+
+	require.NoError(t, run(opts, "add-node"))
+	assert.Contains(t, out.String(), "INSTALL_K3S_VERSION=v1.32.0+k3s1 ")
+}
+
+func TestAddNode_MissingConfig(t *testing.T) {
+	opts, _ := testOptions(t, "")
+	require.NoError(t, os.Remove(opts.ConfigPath))
+
+	err := run(opts, "add-node")
+	assert.ErrorContains(t, err, "could not find the Unbind configuration file")
+}
+
+func TestCommandsRequireInstallation(t *testing.T) {
+	opts, _ := testOptions(t, "")
+	require.NoError(t, os.Remove(opts.UninstallScriptPath))
+
+	for _, command := range []string{"add-node", "uninstall"} {
+		err := run(opts, command)
+		assert.ErrorContains(t, err, "no Unbind installation detected", command)
+	}
+}
+
+func TestUninstall(t *testing.T) {
+	var uninstalled string
+	original := uninstallFunc
+	t.Cleanup(func() { uninstallFunc = original })
+	uninstallFunc = func(scriptPath string, logChan chan<- string) error {
+		uninstalled = scriptPath
+		logChan <- "Removing k3s"
+		return nil
+	}
+
+	t.Run("cancelled", func(t *testing.T) {
+		uninstalled = ""
+		opts, out := testOptions(t, "n\n")
+
+		require.NoError(t, run(opts, "uninstall"))
+		assert.Empty(t, uninstalled)
+		assert.Contains(t, out.String(), "Uninstallation cancelled.")
+	})
+
+	t.Run("confirmed", func(t *testing.T) {
+		uninstalled = ""
+		opts, out := testOptions(t, "yes\n")
+
+		require.NoError(t, run(opts, "uninstall"))
+		assert.Equal(t, opts.UninstallScriptPath, uninstalled)
+		assert.Contains(t, out.String(), "Removing k3s")
+	})
+
+	t.Run("yes flag", func(t *testing.T) {
+		uninstalled = ""
+		opts, _ := testOptions(t, "")
+
+		require.NoError(t, run(opts, "uninstall", "--yes"))
+		assert.Equal(t, opts.UninstallScriptPath, uninstalled)
+	})
+}
+
+func TestUninstall_RemovesRecordedPackages(t *testing.T) {
+	originalUninstall, originalRemove := uninstallFunc, removePackagesFunc
+	t.Cleanup(func() { uninstallFunc, removePackagesFunc = originalUninstall, originalRemove })
+	uninstallFunc = func(string, chan<- string) error { return nil }
+	var removed string
+	removePackagesFunc = func(ctx context.Context, packageRecordPath, repositoryRecordPath string, logChan chan<- string) error {
+		removed = packageRecordPath
+		return nil
+	}
+
+	opts, out := testOptions(t, "")
+	require.NoError(t, os.WriteFile(opts.PackageRecordPath, []byte("apache2-utils\nopen-iscsi\n"), 0644))
+
+	require.NoError(t, run(opts, "uninstall", "--yes", "--keep-packages"))
+	assert.Empty(t, removed)
+	assert.NotContains(t, out.String(), "will be removed")
+
+	require.NoError(t, run(opts, "uninstall", "--yes"))
+	assert.Equal(t, opts.PackageRecordPath, removed)
+	assert.Contains(t, out.String(), "The packages the installer added will be removed: apache2-utils, open-iscsi")
+
+	// Repositories the installer enabled are disabled even when no packages were recorded
+	removed = ""
+	require.NoError(t, os.Remove(opts.PackageRecordPath))
+	require.NoError(t, os.WriteFile(opts.RepositoryRecordPath, []byte("sle-module-development-tools\n"), 0644))
+	require.NoError(t, run(opts, "uninstall", "--yes"))
+	assert.Equal(t, opts.PackageRecordPath, removed)
+	assert.Contains(t, out.String(), "The repositories the installer enabled will be disabled: sle-module-development-tools")
+
+	// Nothing to remove when the installer didn't add any packages
+	removed = ""
+	require.NoError(t, os.Remove(opts.RepositoryRecordPath))
+	require.NoError(t, run(opts, "uninstall", "--yes"))
+	assert.Empty(t, removed)
+}
+
+func TestVersion(t *testing.T) {
+	opts, out := testOptions(t, "")
+
+	require.NoError(t, run(opts, "version"))
+	assert.Equal(t, "unbind v1.2.3\n", out.String())
+}
@@ -0,0 +1,143 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/unbindapp/unbind-installer/internal/osinfo"
+	"github.com/unbindapp/unbind-installer/internal/pkgmanager"
+)
+
+// Mockable for tests
+var (
+	// runDownload runs a command downloading packages, its output goes to out
+	runDownload = func(ctx context.Context, name string, args []string, out io.Writer) error {
+		cmd := exec.CommandContext(ctx, name, args...)
+		cmd.Stdout, cmd.Stderr = out, out
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("downloading packages failed: %w", err)
+		}
+		return nil
+	}
+	// findContainerRuntime returns the path of docker or podman
+	findContainerRuntime = exec.LookPath
+)
+
+// containerPackageDir is where the output directory is mounted in the download container
+const containerPackageDir = "/packages"
+
+func newDownloadPackagesCommand(opts Options) *cobra.Command {
+	var (
+		distribution string
+		version      string
+		output       string
+		runtime      string
+		local        bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "download-packages",
+		Short: "Download the required packages for servers without internet access",
+		Long: "Download the packages the installer needs, with their dependencies, into a directory.\n\n" +
+			"The packages are downloaded in a docker or podman container of the target distribution\n" +
+			"release, so the directory holds everything a minimal installation of it is missing. Copy\n" +
+			"the directory to the offline server and install from it:\n\n" +
+			"  ./" + migrateInstallerName + " --package-source <directory>\n\n" +
+			"With --local the packages are downloaded with this server's package manager instead,\n" +
+			"dependencies installed on this server are left out.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if distribution == "" {
+				info, err := osinfo.GetOSInfo()
+				if err != nil {
+					return fmt.Errorf("pass --distribution and --version, this server isn't supported: %w", err)
+				}
+				if info.ID != info.Distribution {
+					return fmt.Errorf("pass --distribution and --version of the %s release %s %s is based on", info.Distribution, info.ID, info.VersionID)
+				}
+				distribution, version = info.Distribution, info.VersionID
+			}
+			if !slices.Contains(osinfo.AllSupportedDistros, distribution) {
+				return fmt.Errorf("unsupported distribution %s, use one of %s", distribution, strings.Join(osinfo.AllSupportedDistros, ", "))
+			}
+			if !osinfo.IsVersionSupported(distribution, version) {
+				return fmt.Errorf("%s %s isn't supported", distribution, version)
+			}
+			release := strings.TrimSpace(distribution + " " + version)
+
+			dir, err := filepath.Abs(output)
+			if err != nil {
+				return err
+			}
+			if err := os.MkdirAll(dir, 0755); err != nil {
+				return err
+			}
+			packages := pkgmanager.GetDistributionPackages(distribution)
+
+			p := newPrinter(opts.Stdout)
+			p.Colored(p.info, "Downloading %d packages for %s into %s...", len(packages), release, dir)
+			if local {
+				script, err := pkgmanager.DownloadScript(distribution, dir, packages)
+				if err != nil {
+					return err
+				}
+				if err := runDownload(cmd.Context(), "sh", []string{"-c", script}, opts.Stdout); err != nil {
+					return err
+				}
+			} else {
+				runtimePath, err := containerRuntime(runtime)
+				if err != nil {
+					return err
+				}
+				image, err := pkgmanager.DownloadImage(distribution, version)
+				if err != nil {
+					return err
+				}
+				script, err := pkgmanager.DownloadScript(distribution, containerPackageDir, packages)
+				if err != nil {
+					return err
+				}
+				args := []string{"run", "--rm", "-v", dir + ":" + containerPackageDir + ":z", image, "sh", "-c", script}
+				if err := runDownload(cmd.Context(), runtimePath, args, opts.Stdout); err != nil {
+					return err
+				}
+			}
+
+			p.Success(fmt.Sprintf("Downloaded the packages for %s", release))
+			p.Line("Copy %s to the offline server and install with:", dir)
+			p.Command("./" + migrateInstallerName + " --package-source " + dir)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&distribution, "distribution", "", "distribution to download for, e.g. ubuntu or rocky (default this server's)")
+	cmd.Flags().StringVar(&version, "version", "", "release of the distribution, e.g. 24.04 or 9 (default this server's)")
+	cmd.Flags().StringVarP(&output, "output", "o", "unbind-packages", "directory to download the packages into")
+	cmd.Flags().StringVar(&runtime, "runtime", "", "container runtime to download in, docker or podman (default whichever is installed)")
+	cmd.Flags().BoolVar(&local, "local", false, "download with this server's package manager instead of a container")
+	return cmd
+}
+
+// containerRuntime returns the path of the named container runtime, or of docker or podman
+func containerRuntime(name string) (string, error) {
+	candidates := []string{"docker", "podman"}
+	if name != "" {
+		candidates = []string{name}
+	}
+	for _, candidate := range candidates {
+		if path, err := findContainerRuntime(candidate); err == nil {
+			return path, nil
+		}
+	}
+	if name != "" {
+		return "", fmt.Errorf("%s isn't installed", name)
+	}
+	return "", fmt.Errorf("neither docker nor podman is installed, install one or pass --local on a server of the target release")
+}
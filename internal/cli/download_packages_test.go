@@ -0,0 +1,72 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"io"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeDownload records the download commands instead of running them
+func fakeDownload(t *testing.T, runtimes ...string) *[][]string {
+	originalRun, originalFind := runDownload, findContainerRuntime
+	t.Cleanup(func() { runDownload, findContainerRuntime = originalRun, originalFind })
+
+	var calls [][]string
+	runDownload = func(ctx context.Context, name string, args []string, out io.Writer) error {
+		calls = append(calls, append([]string{name}, args...))
+		return nil
+	}
+	findContainerRuntime = func(name string) (string, error) {
+		for _, runtime := range runtimes {
+			if runtime == name {
+				return "/usr/bin/" + name, nil
+			}
+		}
+		return "", errors.New("not found")
+	}
+	return &calls
+}
+
+func TestDownloadPackages_Container(t *testing.T) {
+	calls := fakeDownload(t, "podman")
+	opts, out := testOptions(t, "")
+	dir := filepath.Join(t.TempDir(), "packages")
+
+	require.NoError(t, run(opts, "download-packages", "--distribution", "rocky", "--version", "9.5", "--output", dir))
+	require.Len(t, *calls, 1)
+	call := (*calls)[0]
+	assert.Equal(t, []string{"/usr/bin/podman", "run", "--rm", "-v", dir + ":/packages:z", "rockylinux/rockylinux:9", "sh", "-c"}, call[:8])
+	assert.Contains(t, call[8], "dnf download --resolve --destdir '/packages' ca-certificates curl git httpd-tools")
+	assert.Contains(t, out.String(), "--package-source "+dir)
+}
+
+func TestDownloadPackages_Local(t *testing.T) {
+	calls := fakeDownload(t)
+	opts, _ := testOptions(t, "")
+	dir := t.TempDir()
+
+	require.NoError(t, run(opts, "download-packages", "--distribution", "ubuntu", "--version", "24.04", "--output", dir, "--local"))
+	require.Len(t, *calls, 1)
+	assert.Equal(t, []string{"sh", "-c"}, (*calls)[0][:2])
+	assert.Contains(t, (*calls)[0][2], "apt-get install -y --reinstall --download-only -o Dir::Cache::archives='"+dir+"'")
+}
+
+func TestDownloadPackages_Errors(t *testing.T) {
+	calls := fakeDownload(t)
+	opts, _ := testOptions(t, "")
+
+	err := run(opts, "download-packages", "--distribution", "ubuntu", "--version", "24.04", "--output", t.TempDir())
+	assert.ErrorContains(t, err, "neither docker nor podman is installed")
+
+	err = run(opts, "download-packages", "--distribution", "ubuntu", "--version", "18.04", "--output", t.TempDir(), "--local")
+	assert.ErrorContains(t, err, "ubuntu 18.04 isn't supported")
+
+	err = run(opts, "download-packages", "--distribution", "gentoo", "--output", t.TempDir(), "--local")
+	assert.ErrorContains(t, err, "unsupported distribution gentoo")
+	assert.Empty(t, *calls)
+}
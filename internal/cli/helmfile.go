@@ -0,0 +1,101 @@
+package cli
+
+import (
+	"context"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/unbindapp/unbind-installer/internal/config"
+	"github.com/unbindapp/unbind-installer/internal/installer"
+	"github.com/unbindapp/unbind-installer/internal/registry"
+)
+
+// syncHelmfile re-runs the installer's helmfile sync, mockable for tests.
+// Step descriptions go to progress, everything the installer logs goes to logChan.
+var syncHelmfile = func(ctx context.Context, kubeConfigPath string, syncOpts installer.SyncHelmfileOptions, logChan chan<- string, progress func(string)) error {
+	progressChan := make(chan installer.UnbindInstallUpdateMsg, 100)
+	done := make(chan struct{})
+	defer func() {
+		close(progressChan)
+		<-done
+	}()
+	go func() {
+		defer close(done)
+		last := ""
+		for update := range progressChan {
+			if update.Description != "" && update.Description != last {
+				progress(update.Description)
+				last = update.Description
+			}
+		}
+	}()
+
+	unbindInstaller, err := installer.NewUnbindInstaller(kubeConfigPath, logChan, progressChan, nil)
+	if err != nil {
+		return err
+	}
+	return unbindInstaller.SyncHelmfileWithSteps(ctx, syncOpts)
+}
+
+// runHelmfileSync syncs the platform, printing the installer's progress, or its full log when verbose
+func runHelmfileSync(ctx context.Context, opts Options, syncOpts installer.SyncHelmfileOptions, verbose bool) error {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Minute)
+	defer cancel()
+
+	p := newPrinter(opts.Stdout)
+	progress := func(description string) { p.Subtle("%s", description) }
+	logOut := io.Discard
+	if verbose {
+		// Progress descriptions are logged too
+		progress = func(string) {}
+		logOut = opts.Stdout
+	}
+
+	return runWithLogs(logOut, func(logChan chan<- string) error {
+		return syncHelmfile(ctx, opts.KubeConfigPath, syncOpts, logChan, progress)
+	})
+}
+
+// platformSyncOptions rebuilds the helmfile sync options the installer used from the saved config.
+// External registry passwords aren't saved, callers set RegistryPassword unless RegistryExistingSecret is set.
+func platformSyncOptions(cfg *config.Config) installer.SyncHelmfileOptions {
+	syncOpts := installer.SyncHelmfileOptions{
+		UnbindDomain: cfg.UnbindDomain,
+		BaseDomain:   cfg.BaseDomain,
+	}
+
+	// The credential secret is already in the cluster
+	if cfg.LonghornBackupTarget != "" {
+		syncOpts.LonghornBackup = &installer.LonghornBackupOptions{
+			Target:    cfg.LonghornBackupTarget,
+			HasSecret: strings.HasPrefix(cfg.LonghornBackupTarget, "s3://"),
+			Schedule:  cfg.LonghornBackupSchedule,
+			Retain:    cfg.LonghornBackupRetain,
+		}
+	}
+
+	if cfg.RegistryType == config.RegistryExternal {
+		syncOpts.DisableRegistry = true
+		syncOpts.RegistryHost = cfg.RegistryHost
+		syncOpts.RegistryUsername = cfg.RegistryUsername
+		// Short-lived tokens are already kept fresh by the refresher in the cluster
+		if registry.NeedsCredentialRefresher(registry.Provider(cfg.RegistryProvider)) {
+			syncOpts.RegistryHost = registry.APIHost(cfg.RegistryHost)
+			syncOpts.RegistryUsername = registry.ECRUsername
+			syncOpts.RegistryExistingSecret = installer.RegistryCredentialsSecretName
+		}
+		return syncOpts
+	}
+
+	syncOpts.UnbindRegistryDomain = cfg.RegistryDomain
+	if cfg.RegistryStorageSizeGB > 0 {
+		syncOpts.RegistrySettings = &installer.RegistrySettings{
+			StorageSizeGB: cfg.RegistryStorageSizeGB,
+			StorageClass:  cfg.RegistryStorageClass,
+			RetainTags:    cfg.RegistryRetainTags,
+			GCSchedule:    cfg.RegistryGCSchedule,
+		}
+	}
+	return syncOpts
+}
@@ -0,0 +1,272 @@
+package cli
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// logComponent is a platform component whose logs can be read by name
+type logComponent struct {
+	description string
+	namespace   string
+	// Pods are found through the deployment's selector, or labelSelector when there is no deployment
+	deployment    string
+	labelSelector string
+	// journalUnit reads the systemd journal instead of pods
+	journalUnit string
+}
+
+var logComponents = map[string]logComponent{
+	"k3s":             {description: "K3s service journal", journalUnit: "k3s"},
+	"api":             {description: "Unbind API", namespace: "unbind-system", deployment: "unbind-api-deployment"},
+	"ui":              {description: "Unbind web UI", namespace: "unbind-system", deployment: "unbind-ui-deployment"},
+	"auth":            {description: "Unbind authentication service", namespace: "unbind-system", deployment: "unbind-auth-deployment"},
+	"dex":             {description: "Dex identity provider", namespace: "unbind-system", deployment: "dex"},
+	"kube-oidc-proxy": {description: "Kubernetes OIDC proxy", namespace: "unbind-system", deployment: "kube-oidc-proxy"},
+	"longhorn":        {description: "Longhorn storage manager", namespace: "longhorn-system", labelSelector: "app=longhorn-manager"},
+}
+
+// logOptions are the flags shared by pod logs and the journal
+type logOptions struct {
+	follow bool
+	since  time.Duration
+	tail   int64
+	grep   *regexp.Regexp
+}
+
+func newLogsCommand(opts Options) *cobra.Command {
+	var (
+		follow    bool
+		since     time.Duration
+		tail      int64
+		grep      string
+		namespace string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "logs [component]",
+		Short: "Show logs of Unbind platform components, K3s and Longhorn",
+		Long: "Show logs of Unbind platform components, K3s and Longhorn.\n\n" +
+			"Run without a component to list the known components. Any other name is read as a\n" +
+			"deployment in the namespace given by --namespace.",
+		Args:      cobra.MaximumNArgs(1),
+		ValidArgs: logComponentNames(),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 {
+				printLogComponents(newPrinter(opts.Stdout))
+				return nil
+			}
+
+			logOpts := logOptions{follow: follow, since: since, tail: tail}
+			if grep != "" {
+				pattern, err := regexp.Compile(grep)
+				if err != nil {
+					return fmt.Errorf("invalid --grep pattern: %w", err)
+				}
+				logOpts.grep = pattern
+			}
+
+			component, ok := logComponents[args[0]]
+			if !ok {
+				component = logComponent{namespace: namespace, deployment: args[0]}
+			}
+
+			out := newLineWriter(opts.Stdout, logOpts.grep)
+			if component.journalUnit != "" {
+				return opts.Journal(cmd.Context(), journalArgs(component.journalUnit, logOpts), out)
+			}
+
+			clientset, _, err := opts.KubeClients(opts.KubeConfigPath)
+			if err != nil {
+				return fmt.Errorf("failed to connect to the cluster: %w", err)
+			}
+			return streamComponentLogs(cmd.Context(), clientset, component, logOpts, out)
+		},
+	}
+
+	cmd.Flags().BoolVarP(&follow, "follow", "f", false, "keep streaming new log lines")
+	cmd.Flags().DurationVar(&since, "since", 0, "only show logs newer than this, e.g. 10m or 2h")
+	cmd.Flags().Int64Var(&tail, "tail", -1, "number of recent lines to show per container, -1 shows all")
+	cmd.Flags().StringVar(&grep, "grep", "", "only show lines matching this regular expression")
+	cmd.Flags().StringVarP(&namespace, "namespace", "n", "unbind-system", "namespace of deployments that aren't a known component")
+	return cmd
+}
+
+func logComponentNames() []string {
+	names := make([]string, 0, len(logComponents))
+	for name := range logComponents {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func printLogComponents(p *printer) {
+	p.Bold("Components")
+	for _, name := range logComponentNames() {
+		p.Line("  %-16s %s", name, logComponents[name].description)
+	}
+	p.Line("")
+	p.Subtle("Usage: unbind logs <component> [--follow] [--since 1h] [--tail 100] [--grep pattern]")
+}
+
+// journalArgs builds the journalctl arguments for a unit
+func journalArgs(unit string, logOpts logOptions) []string {
+	args := []string{"-u", unit, "--no-pager", "--output", "short-iso"}
+	if logOpts.since > 0 {
+		args = append(args, "--since", time.Now().Add(-logOpts.since).Format("2006-01-02 15:04:05"))
+	}
+	if logOpts.tail >= 0 {
+		args = append(args, "--lines", fmt.Sprint(logOpts.tail))
+	}
+	if logOpts.follow {
+		args = append(args, "--follow")
+	}
+	return args
+}
+
+// runJournal runs journalctl, writing its output to out
+func runJournal(ctx context.Context, args []string, out io.Writer) error {
+	cmd := exec.CommandContext(ctx, "journalctl", args...)
+	cmd.Stdout = out
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil && ctx.Err() == nil {
+		return fmt.Errorf("journalctl failed: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+// streamComponentLogs writes the logs of every container in the component's pods, prefixed with pod and container
+func streamComponentLogs(ctx context.Context, clientset kubernetes.Interface, component logComponent, logOpts logOptions, out *lineWriter) error {
+	selector := component.labelSelector
+	if component.deployment != "" {
+		deployment, err := clientset.AppsV1().Deployments(component.namespace).Get(ctx, component.deployment, metav1.GetOptions{})
+		if err != nil {
+			return fmt.Errorf("failed to find deployment %s in %s: %w", component.deployment, component.namespace, err)
+		}
+		selector = metav1.FormatLabelSelector(deployment.Spec.Selector)
+	}
+
+	pods, err := clientset.CoreV1().Pods(component.namespace).List(ctx, metav1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		return fmt.Errorf("failed to list pods in %s: %w", component.namespace, err)
+	}
+	if len(pods.Items) == 0 {
+		return fmt.Errorf("no pods found in %s matching %s", component.namespace, selector)
+	}
+
+	podLogOptions := corev1.PodLogOptions{Follow: logOpts.follow}
+	if logOpts.since > 0 {
+		seconds := int64(logOpts.since.Seconds())
+		podLogOptions.SinceSeconds = &seconds
+	}
+	if logOpts.tail >= 0 {
+		podLogOptions.TailLines = &logOpts.tail
+	}
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+	)
+	for _, pod := range pods.Items {
+		for _, container := range pod.Spec.Containers {
+			options := podLogOptions
+			options.Container = container.Name
+			prefix := fmt.Sprintf("[%s/%s] ", pod.Name, container.Name)
+
+			stream := func() error {
+				body, err := clientset.CoreV1().Pods(pod.Namespace).GetLogs(pod.Name, &options).Stream(ctx)
+				if err != nil {
+					return fmt.Errorf("failed to read logs of %s: %w", strings.Trim(prefix, "[] "), err)
+				}
+				defer body.Close()
+				return out.copyLines(prefix, body)
+			}
+
+			// Only follow concurrently, dumps stay grouped per container
+			if !logOpts.follow {
+				if err := stream(); err != nil {
+					return err
+				}
+				continue
+			}
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if err := stream(); err != nil && ctx.Err() == nil {
+					mu.Lock()
+					if firstErr == nil {
+						firstErr = err
+					}
+					mu.Unlock()
+				}
+			}()
+		}
+	}
+	wg.Wait()
+	return firstErr
+}
+
+// lineWriter writes whole lines from concurrent streams, dropping lines that don't match grep
+type lineWriter struct {
+	mu      sync.Mutex
+	out     io.Writer
+	grep    *regexp.Regexp
+	partial []byte
+}
+
+func newLineWriter(out io.Writer, grep *regexp.Regexp) *lineWriter {
+	return &lineWriter{out: out, grep: grep}
+}
+
+// Write lets the writer take raw output such as journalctl's
+func (self *lineWriter) Write(data []byte) (int, error) {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+
+	self.partial = append(self.partial, data...)
+	for {
+		index := bytes.IndexByte(self.partial, '\n')
+		if index < 0 {
+			break
+		}
+		self.writeLine("", string(self.partial[:index]))
+		self.partial = self.partial[index+1:]
+	}
+	return len(data), nil
+}
+
+// copyLines writes each line of r with a prefix
+func (self *lineWriter) copyLines(prefix string, r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		self.mu.Lock()
+		self.writeLine(prefix, scanner.Text())
+		self.mu.Unlock()
+	}
+	return scanner.Err()
+}
+
+func (self *lineWriter) writeLine(prefix, line string) {
+	if self.grep != nil && !self.grep.MatchString(line) {
+		return
+	}
+	fmt.Fprintln(self.out, prefix+line)
+}
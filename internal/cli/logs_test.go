@@ -0,0 +1,83 @@
+package cli
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func authDeployment() []runtime.Object {
+	labels := map[string]string{"app": "unbind-auth"}
+	return []runtime.Object{
+		&appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "unbind-auth-deployment", Namespace: "unbind-system"},
+			Spec:       appsv1.DeploymentSpec{Selector: &metav1.LabelSelector{MatchLabels: labels}},
+		},
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "auth-1", Namespace: "unbind-system", Labels: labels},
+			Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "auth"}}},
+		},
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "other-1", Namespace: "unbind-system", Labels: map[string]string{"app": "other"}},
+			Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "other"}}},
+		},
+	}
+}
+
+func TestLogs_Component(t *testing.T) {
+	opts, out := testOptions(t, "")
+	opts.KubeClients = fakeCluster(authDeployment())
+
+	require.NoError(t, run(opts, "logs", "auth"))
+	// The fake clientset answers every log request with "fake logs"
+	assert.Equal(t, "[auth-1/auth] fake logs\n", out.String())
+
+	out.Reset()
+	require.NoError(t, run(opts, "logs", "auth", "--grep", "^nothing"))
+	assert.Empty(t, out.String())
+}
+
+func TestLogs_UnknownDeployment(t *testing.T) {
+	opts, _ := testOptions(t, "")
+	opts.KubeClients = fakeCluster(authDeployment())
+
+	err := run(opts, "logs", "missing")
+	assert.ErrorContains(t, err, "failed to find deployment missing in unbind-system")
+}
+
+func TestLogs_Journal(t *testing.T) {
+	opts, out := testOptions(t, "")
+	var journalArgs []string
+	opts.Journal = func(ctx context.Context, args []string, w io.Writer) error {
+		journalArgs = args
+		_, err := io.WriteString(w, "starting k3s\nerror: etcd timeout\nk3s ready\n")
+		return err
+	}
+
+	require.NoError(t, run(opts, "logs", "k3s", "--since", "1h", "--tail", "50", "--follow", "--grep", "error"))
+	assert.Equal(t, "error: etcd timeout\n", out.String())
+
+	assert.Equal(t, []string{"-u", "k3s", "--no-pager", "--output", "short-iso", "--lines", "50", "--follow"},
+		append(journalArgs[:5:5], journalArgs[7:]...))
+	assert.Equal(t, "--since", journalArgs[5])
+	since, err := time.ParseInLocation("2006-01-02 15:04:05", journalArgs[6], time.Local)
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(-time.Hour), since, time.Minute)
+}
+
+func TestLogs_ListsComponents(t *testing.T) {
+	opts, out := testOptions(t, "")
+
+	require.NoError(t, run(opts, "logs"))
+	for _, name := range []string{"k3s", "auth", "dex", "kube-oidc-proxy", "longhorn"} {
+		assert.Contains(t, out.String(), name)
+	}
+}
@@ -0,0 +1,386 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/unbindapp/unbind-installer/internal/backup"
+	"github.com/unbindapp/unbind-installer/internal/config"
+	"github.com/unbindapp/unbind-installer/internal/longhorn"
+)
+
+// migrateInstallerName is what the installer binary is called on the new server
+const migrateInstallerName = "unbind-installer"
+
+// Mockable for tests
+var (
+	// copyToHost copies a file to another server with scp, prompts for passwords and host keys
+	// go to the terminal
+	copyToHost = func(ctx context.Context, source, destination string, sshArgs []string) error {
+		cmd := exec.CommandContext(ctx, "scp", append(append([]string{"-q"}, sshArgs...), source, destination)...)
+		cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("scp to %s failed: %w", destination, err)
+		}
+		return nil
+	}
+	// stopK3s stops K3s on this server
+	stopK3s = func(ctx context.Context) error {
+		output, err := exec.CommandContext(ctx, "systemctl", "stop", "k3s").CombinedOutput()
+		if err != nil {
+			return fmt.Errorf("failed to stop K3s: %w: %s", err, strings.TrimSpace(string(output)))
+		}
+		return nil
+	}
+)
+
+func newMigrateCommand(version string, opts Options) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "migrate",
+		Short: "Move this Unbind installation to a new server",
+		Long: "Move this Unbind installation to a new server.\n\n" +
+			"Volume data moves through the Longhorn backup target, everything else through an archive\n" +
+			"copied over SSH or by hand. Unbind keeps running on the old server until the final sync:\n\n" +
+			"  1. On the old server:  unbind migrate export --to root@new-server\n" +
+			"  2. On the new server:  ./" + migrateInstallerName + " migrate import <archive>\n" +
+			"     Check the new server, and lower the TTL of your DNS records.\n" +
+			"  3. On the old server:  unbind migrate export --final --to root@new-server\n" +
+			"     Workloads are scaled down for the volume backups and K3s is stopped once the export\n" +
+			"     is taken, unbind migrate cancel brings them back if the migration is abandoned.\n" +
+			"  4. On the new server:  unbind migrate import --final <archive>\n" +
+			"  5. Point your DNS records at the new server.\n\n" +
+			"Downtime is limited to steps 3 to 5.",
+	}
+	cmd.AddCommand(
+		newMigrateExportCommand(version, opts),
+		newMigrateImportCommand(opts),
+		newMigrateCancelCommand(opts),
+	)
+	return cmd
+}
+
+func newMigrateExportCommand(version string, opts Options) *cobra.Command {
+	var (
+		to          string
+		output      string
+		sshPort     int
+		sshKey      string
+		final       bool
+		skipVolumes bool
+		yes         bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "export",
+		Short: "Export this installation for a new server",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) (err error) {
+			if err := checkInstallation(opts); err != nil {
+				return err
+			}
+			cfg, err := loadConfig(opts)
+			if err != nil {
+				return err
+			}
+			if cfg.LonghornBackupTarget == "" && !skipVolumes {
+				return fmt.Errorf("volume data is moved through the Longhorn backup target and none is configured, pass --skip-volumes to move Unbind without its volume data")
+			}
+
+			p := newPrinter(opts.Stdout)
+			p.Banner()
+			if final {
+				p.Box("Final export", p.warning)
+				p.Colored(p.warning, "Workloads are scaled down and K3s is stopped on this server for the export, Unbind goes offline")
+				p.Colored(p.warning, "until the export is imported on the new server and DNS points at it.")
+				p.Line("")
+				if !yes && !confirm(opts.Stdin, opts.Stdout, "Take the final export and stop K3s? (y/N) ") {
+					p.Info("Export cancelled.")
+					return nil
+				}
+			}
+
+			logFn := func(message string) { p.Subtle("  %s", message) }
+			var held []string
+			k3sStopped := false
+			if !skipVolumes {
+				kubeClient, dynamicClient, clientErr := opts.KubeClients(opts.KubeConfigPath)
+				if clientErr != nil {
+					return clientErr
+				}
+				if final {
+					// Writes after the volume backups would be lost, Longhorn keeps the volumes attached
+					// for the backups while the workloads using them are scaled down
+					p.Colored(p.info, "Stopping Unbind workloads...")
+					held, err = longhorn.HoldAttached(cmd.Context(), dynamicClient, logFn)
+					defer func() {
+						// Put Unbind back the way it was when the export fails before K3s is stopped
+						if err == nil || k3sStopped {
+							return
+						}
+						p.Colored(p.warning, "Scaling the workloads back up...")
+						ctx := context.WithoutCancel(cmd.Context())
+						if restoreErr := errors.Join(longhorn.ReleaseAttached(ctx, dynamicClient, held),
+							scaleUpWorkloads(ctx, kubeClient, logFn)); restoreErr != nil {
+							p.Colored(p.error, "%s", restoreErr)
+						}
+					}()
+					if err != nil {
+						return err
+					}
+					if err := scaleDownWorkloads(cmd.Context(), kubeClient, logFn); err != nil {
+						return err
+					}
+				}
+
+				p.Colored(p.info, "Backing up volumes to %s...", cfg.LonghornBackupTarget)
+				_, detached, err := longhorn.BackupVolumes(cmd.Context(), dynamicClient, logFn)
+				if err != nil {
+					return err
+				}
+				if len(detached) > 0 {
+					p.Colored(p.warning, "%d volume(s) aren't in use and can't be backed up now, their latest backup will be used: %s",
+						len(detached), strings.Join(detached, ", "))
+				}
+				// The attachment tickets mustn't end up in the exported cluster state
+				if err := longhorn.ReleaseAttached(cmd.Context(), dynamicClient, held); err != nil {
+					return err
+				}
+			}
+
+			p.Colored(p.info, "Exporting the cluster state...")
+			k3sVersion, _ := opts.K3sVersion()
+			var archive string
+			var manifest *backup.Manifest
+			if err := runWithLogs(opts.Stdout, func(logChan chan<- string) error {
+				archive, manifest, err = createBackup(cmd.Context(), backup.CreateOptions{
+					Paths:            opts.BackupPaths,
+					K3sVersion:       k3sVersion,
+					InstallerVersion: version,
+					LogFn:            func(message string) { logChan <- message },
+				})
+				return err
+			}); err != nil {
+				return err
+			}
+			defer os.Remove(archive)
+			name := backup.ArchiveName(manifest.CreatedAt)
+
+			if final {
+				p.Colored(p.info, "Stopping K3s...")
+				if err := stopK3s(cmd.Context()); err != nil {
+					return err
+				}
+				k3sStopped = true
+				p.Subtle("If the migration is abandoned, start it again with systemctl start k3s and run unbind migrate cancel.")
+			}
+
+			imported := ""
+			if to != "" {
+				host, dir, _ := strings.Cut(to, ":")
+				remote := func(file string) string { return host + ":" + path.Join(dir, file) }
+				sshArgs := []string{"-P", strconv.Itoa(sshPort)}
+				if sshKey != "" {
+					sshArgs = append(sshArgs, "-i", sshKey)
+				}
+
+				p.Colored(p.info, "Copying %s to %s...", name, host)
+				if err := copyToHost(cmd.Context(), archive, remote(name), sshArgs); err != nil {
+					return err
+				}
+				if !final {
+					// The first import runs before Unbind is installed on the new server
+					executable, err := os.Executable()
+					if err != nil {
+						return fmt.Errorf("failed to locate the unbind binary: %w", err)
+					}
+					p.Colored(p.info, "Copying the installer to %s...", host)
+					if err := copyToHost(cmd.Context(), executable, remote(migrateInstallerName), sshArgs); err != nil {
+						return err
+					}
+				}
+				imported = path.Join(dir, name)
+			} else {
+				if err := (&backup.LocalTarget{Dir: output}).Put(cmd.Context(), name, archive); err != nil {
+					return err
+				}
+				imported = filepath.Join(output, name)
+				p.Subtle("Copy %s and the installer binary to the new server.", imported)
+			}
+
+			p.Success(fmt.Sprintf("Exported %s", name))
+			p.Line("Next, on the new server, run:")
+			if final {
+				p.Command("unbind migrate import --final " + imported)
+			} else {
+				p.Command("./" + migrateInstallerName + " migrate import " + imported)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&to, "to", "", "copy the export to this server over SSH, user@host or user@host:dir")
+	cmd.Flags().StringVar(&output, "output", ".", "directory to save the export in when not copying it over SSH")
+	cmd.Flags().IntVar(&sshPort, "ssh-port", 22, "SSH port of the new server")
+	cmd.Flags().StringVar(&sshKey, "ssh-key", "", "SSH private key for the new server")
+	cmd.Flags().BoolVar(&final, "final", false, "take the final export and stop K3s on this server")
+	cmd.Flags().BoolVar(&skipVolumes, "skip-volumes", false, "don't back up Longhorn volumes, the new server gets empty volumes")
+	cmd.Flags().BoolVarP(&yes, "yes", "y", false, "skip the confirmation prompt")
+	return cmd
+}
+
+func newMigrateImportCommand(opts Options) *cobra.Command {
+	var (
+		registryPasswordFile string
+		packageSource        string
+		final                bool
+		yes                  bool
+		verbose              bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "import <archive>",
+		Short: "Install Unbind on this server from an export of the old server",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			_, err := os.Stat(opts.UninstallScriptPath)
+			installed := err == nil
+			if installed && !final {
+				return fmt.Errorf("Unbind is already installed on this server, pass --final to import the final export of the old server")
+			}
+			if !installed && final {
+				return fmt.Errorf("Unbind isn't installed on this server yet, import a first export without --final")
+			}
+
+			archive, err := filepath.Abs(args[0])
+			if err != nil {
+				return err
+			}
+
+			p := newPrinter(opts.Stdout)
+			p.Banner()
+
+			plan, err := planRestore(cmd.Context(), opts, p, &targetFlags{}, archive, registryPasswordFile, packageSource)
+			if err != nil {
+				return err
+			}
+			defer plan.cleanup()
+
+			plan.print(p)
+			question := "Install K3s and import the export? (y/N) "
+			if final {
+				question = "Replace the state of this server with the final export? (y/N) "
+			}
+			if !yes && !confirm(opts.Stdin, opts.Stdout, question) {
+				p.Info("Import cancelled.")
+				return nil
+			}
+
+			if final {
+				p.Colored(p.info, "Restoring the cluster state...")
+				if err := runWithLogs(opts.Stdout, func(logChan chan<- string) error {
+					return restoreDatastore(cmd.Context(), plan.workDir, plan.manifest, opts.BackupPaths, func(message string) { logChan <- message })
+				}); err != nil {
+					return err
+				}
+			} else if err := plan.installCluster(cmd.Context(), opts, p, yes, verbose); err != nil {
+				return err
+			}
+			if err := plan.recover(cmd.Context(), opts, p, verbose); err != nil {
+				return err
+			}
+			if final {
+				// The final export scaled the workloads down while their volumes were backed up
+				kubeClient, _, err := opts.KubeClients(opts.KubeConfigPath)
+				if err != nil {
+					return err
+				}
+				p.Colored(p.info, "Starting Unbind workloads...")
+				if err := scaleUpWorkloads(cmd.Context(), kubeClient, func(message string) { p.Subtle("  %s", message) }); err != nil {
+					return err
+				}
+			}
+
+			p.Success(fmt.Sprintf("Unbind from %s is running on this server", plan.manifest.Hostname))
+			printDNSChanges(p, plan.cfg, plan.ips.ExternalIP)
+			if !final {
+				p.Line("Check this server, then finish with the final sync on the old server:")
+				p.Command("unbind migrate export --final --to <user>@" + plan.ips.ExternalIP)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&registryPasswordFile, "registry-password-file", "", "read the external registry password or key from this file instead of prompting")
+	cmd.Flags().StringVar(&packageSource, "package-source", "", packageSourceUsage)
+	cmd.Flags().BoolVar(&final, "final", false, "import the final export onto this server, replacing the state of the first import")
+	cmd.Flags().BoolVarP(&yes, "yes", "y", false, "skip the confirmation prompt")
+	cmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "show the full installer log")
+	return cmd
+}
+
+func newMigrateCancelCommand(opts Options) *cobra.Command {
+	return &cobra.Command{
+		Use:   "cancel",
+		Short: "Bring back the workloads the final export scaled down on this server",
+		Long: "Bring back the workloads the final export scaled down on this server.\n\n" +
+			"Start K3s first with systemctl start k3s when the final export stopped it.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := checkInstallation(opts); err != nil {
+				return err
+			}
+			kubeClient, _, err := opts.KubeClients(opts.KubeConfigPath)
+			if err != nil {
+				return err
+			}
+
+			p := newPrinter(opts.Stdout)
+			p.Colored(p.info, "Starting Unbind workloads...")
+			if err := scaleUpWorkloads(cmd.Context(), kubeClient, func(message string) { p.Subtle("  %s", message) }); err != nil {
+				return err
+			}
+			p.Success("The migration is cancelled, Unbind runs on this server again")
+			return nil
+		},
+	}
+}
+
+// printDNSChanges lists the DNS records that have to point at the new server
+func printDNSChanges(p *printer, cfg *config.Config, externalIP string) {
+	records := []string{}
+	if cfg.BaseDomain != "" {
+		records = append(records, cfg.BaseDomain)
+	}
+	for _, domain := range []string{cfg.UnbindDomain, cfg.RegistryDomain} {
+		if domain != "" && !coveredByWildcard(domain, cfg.BaseDomain) {
+			records = append(records, domain)
+		}
+	}
+
+	p.Info("DNS changes")
+	p.Line("Point these records at %s:", externalIP)
+	for _, record := range records {
+		p.Line("  %-40s A  %s", record, externalIP)
+	}
+	if cfg.RegistryType == config.RegistrySelfHosted {
+		p.Subtle("The registry domain can't be proxied by Cloudflare.")
+	}
+	p.Line("")
+}
+
+// coveredByWildcard reports whether a wildcard record such as *.example.com resolves domain
+func coveredByWildcard(domain, wildcard string) bool {
+	if wildcard == "" {
+		return false
+	}
+	label, ok := strings.CutSuffix(domain, "."+strings.TrimPrefix(wildcard, "*."))
+	return ok && label != "" && !strings.Contains(label, ".")
+}
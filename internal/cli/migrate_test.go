@@ -0,0 +1,288 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/unbindapp/unbind-installer/internal/backup"
+	"github.com/unbindapp/unbind-installer/internal/config"
+	"github.com/unbindapp/unbind-installer/internal/longhorn"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/utils/ptr"
+)
+
+// fakeMigration records the files copied over SSH and whether K3s was stopped
+type fakeMigration struct {
+	copied     map[string]string
+	sshArgs    []string
+	k3sStopped bool
+}
+
+func fakeMigrationHosts(t *testing.T) *fakeMigration {
+	originalCopy, originalStop := copyToHost, stopK3s
+	t.Cleanup(func() { copyToHost, stopK3s = originalCopy, originalStop })
+
+	migration := &fakeMigration{copied: map[string]string{}}
+	copyToHost = func(ctx context.Context, source, destination string, sshArgs []string) error {
+		migration.copied[destination] = source
+		migration.sshArgs = sshArgs
+		return nil
+	}
+	stopK3s = func(ctx context.Context) error {
+		migration.k3sStopped = true
+		return nil
+	}
+	return migration
+}
+
+func TestMigrateExport(t *testing.T) {
+	opts, out := testOptions(t, "")
+	writeConfig(t, opts, &config.Config{UnbindDomain: "unbind.example.com", LonghornBackupTarget: "s3://backups@us-east-1/"})
+	fakeBackups(t, time.Date(2025, 6, 1, 3, 0, 0, 0, time.UTC))
+	migration := fakeMigrationHosts(t)
+
+	_, dynamicClient := restoredCluster(t)
+	dynamicClient.(*dynamicfake.FakeDynamicClient).PrependReactor("create", "backups", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		action.(k8stesting.CreateAction).GetObject().(*unstructured.Unstructured).Object["status"] = map[string]interface{}{"state": "Completed"}
+		return false, nil, nil
+	})
+	volume, err := dynamicClient.Resource(longhorn.VolumeResource).Namespace(longhorn.Namespace).Get(context.Background(), "pvc-data", metav1.GetOptions{})
+	require.NoError(t, err)
+	volume.Object["status"] = map[string]interface{}{"state": "attached"}
+	_, err = dynamicClient.Resource(longhorn.VolumeResource).Namespace(longhorn.Namespace).Update(context.Background(), volume, metav1.UpdateOptions{})
+	require.NoError(t, err)
+	opts.KubeClients = func(string) (kubernetes.Interface, dynamic.Interface, error) { return nil, dynamicClient, nil }
+
+	require.NoError(t, run(opts, "migrate", "export", "--to", "root@new-server:/root/migration", "--ssh-port", "2222"))
+
+	name := "unbind-backup-20250601T030000Z.tar.gz"
+	assert.Contains(t, migration.copied, "root@new-server:/root/migration/"+name)
+	assert.Contains(t, migration.copied, "root@new-server:/root/migration/unbind-installer", "the installer goes along for the first import")
+	assert.Equal(t, []string{"-P", "2222"}, migration.sshArgs)
+	assert.False(t, migration.k3sStopped, "Unbind keeps running until the final export")
+	assert.Contains(t, out.String(), "Backing up volume pvc-data...")
+	assert.Contains(t, out.String(), "./unbind-installer migrate import /root/migration/"+name)
+
+	backups, err := dynamicClient.Resource(longhorn.BackupResource).Namespace(longhorn.Namespace).List(context.Background(), metav1.ListOptions{})
+	require.NoError(t, err)
+	assert.Len(t, backups.Items, 2, "a fresh backup of the attached volume is taken")
+}
+
+func TestMigrateExport_Final(t *testing.T) {
+	opts, out := testOptions(t, "y\n")
+	writeConfig(t, opts, &config.Config{UnbindDomain: "unbind.example.com"})
+	fakeBackups(t, time.Date(2025, 6, 2, 3, 0, 0, 0, time.UTC))
+	migration := fakeMigrationHosts(t)
+	output := t.TempDir()
+
+	require.NoError(t, run(opts, "migrate", "export", "--final", "--skip-volumes", "--output", output))
+
+	assert.True(t, migration.k3sStopped)
+	assert.Empty(t, migration.copied)
+	assert.FileExists(t, filepath.Join(output, "unbind-backup-20250602T030000Z.tar.gz"))
+	assert.Contains(t, out.String(), "unbind migrate import --final "+filepath.Join(output, "unbind-backup-20250602T030000Z.tar.gz"))
+}
+
+// finalExportCluster is a cluster with an app using an attached volume, recording the changes the
+// final export makes in events
+func finalExportCluster(t *testing.T, events *[]string) (*fake.Clientset, *dynamicfake.FakeDynamicClient) {
+	kubeClient := fake.NewSimpleClientset(
+		&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "team-1"}, Spec: appsv1.DeploymentSpec{Replicas: ptr.To(int32(2))}},
+		&appsv1.StatefulSet{ObjectMeta: metav1.ObjectMeta{Name: "database", Namespace: "unbind-system"}},
+		&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "longhorn-ui", Namespace: "longhorn-system"}, Spec: appsv1.DeploymentSpec{Replicas: ptr.To(int32(1))}},
+	)
+	kubeClient.PrependReactor("update", "*", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		object := action.(k8stesting.UpdateAction).GetObject().(metav1.Object)
+		*events = append(*events, "scale "+object.GetName())
+		return false, nil, nil
+	})
+
+	volume := longhornVolume("pvc-data", "attached", "healthy")
+	volume.Object["status"].(map[string]interface{})["currentNodeID"] = "server-1"
+	attachment := longhornVolume("pvc-data", "", "")
+	attachment.SetKind("VolumeAttachment")
+	attachment.Object["spec"] = map[string]interface{}{"attachmentTickets": map[string]interface{}{}}
+	dynamicClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{
+			longhorn.VolumeResource:           "VolumeList",
+			longhorn.BackupResource:           "BackupList",
+			longhorn.VolumeAttachmentResource: "VolumeAttachmentList",
+		}, volume, attachment)
+	dynamicClient.PrependReactor("update", "volumeattachments", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		tickets, _, _ := unstructured.NestedMap(action.(k8stesting.UpdateAction).GetObject().(*unstructured.Unstructured).Object, "spec", "attachmentTickets")
+		*events = append(*events, fmt.Sprintf("%d attachment ticket(s)", len(tickets)))
+		return false, nil, nil
+	})
+	dynamicClient.PrependReactor("create", "backups", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		action.(k8stesting.CreateAction).GetObject().(*unstructured.Unstructured).Object["status"] = map[string]interface{}{"state": "Completed"}
+		*events = append(*events, "back up volumes")
+		return false, nil, nil
+	})
+	return kubeClient, dynamicClient
+}
+
+func TestMigrateExport_FinalScalesDownBeforeBackups(t *testing.T) {
+	opts, out := testOptions(t, "")
+	writeConfig(t, opts, &config.Config{UnbindDomain: "unbind.example.com", LonghornBackupTarget: "s3://backups@us-east-1/"})
+	fakeBackups(t, time.Date(2025, 6, 2, 3, 0, 0, 0, time.UTC))
+	migration := fakeMigrationHosts(t)
+	events := []string{}
+	kubeClient, dynamicClient := finalExportCluster(t, &events)
+	opts.KubeClients = func(string) (kubernetes.Interface, dynamic.Interface, error) { return kubeClient, dynamicClient, nil }
+
+	snapshot := createBackup
+	createBackup = func(ctx context.Context, backupOpts backup.CreateOptions) (string, *backup.Manifest, error) {
+		events = append(events, "snapshot datastore")
+		return snapshot(ctx, backupOpts)
+	}
+	stop := stopK3s
+	stopK3s = func(ctx context.Context) error {
+		events = append(events, "stop k3s")
+		return stop(ctx)
+	}
+
+	require.NoError(t, run(opts, "migrate", "export", "--final", "--yes", "--output", t.TempDir()))
+	assert.True(t, migration.k3sStopped)
+	assert.Equal(t, []string{
+		"1 attachment ticket(s)",
+		"scale app",
+		"scale database",
+		"back up volumes",
+		"0 attachment ticket(s)",
+		"snapshot datastore",
+		"stop k3s",
+	}, events, "volumes are backed up with nothing writing to them and the datastore snapshot comes last")
+	assert.Contains(t, out.String(), "Keeping volume pvc-data attached to server-1")
+
+	// The replicas travel with the datastore snapshot for the import
+	app, err := kubeClient.AppsV1().Deployments("team-1").Get(context.Background(), "app", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, int32(0), *app.Spec.Replicas)
+	assert.Equal(t, "2", app.Annotations[migrateReplicasAnnotation])
+	database, err := kubeClient.AppsV1().StatefulSets("unbind-system").Get(context.Background(), "database", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "1", database.Annotations[migrateReplicasAnnotation])
+	longhornUI, err := kubeClient.AppsV1().Deployments("longhorn-system").Get(context.Background(), "longhorn-ui", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), *longhornUI.Spec.Replicas, "Longhorn keeps running for the backups")
+}
+
+func TestMigrateExport_FinalScalesBackUpOnFailure(t *testing.T) {
+	opts, out := testOptions(t, "")
+	writeConfig(t, opts, &config.Config{UnbindDomain: "unbind.example.com", LonghornBackupTarget: "s3://backups@us-east-1/"})
+	fakeBackups(t, time.Now())
+	migration := fakeMigrationHosts(t)
+	events := []string{}
+	kubeClient, dynamicClient := finalExportCluster(t, &events)
+	opts.KubeClients = func(string) (kubernetes.Interface, dynamic.Interface, error) { return kubeClient, dynamicClient, nil }
+	createBackup = func(ctx context.Context, backupOpts backup.CreateOptions) (string, *backup.Manifest, error) {
+		return "", nil, errors.New("disk full")
+	}
+
+	assert.ErrorContains(t, run(opts, "migrate", "export", "--final", "--yes"), "disk full")
+	assert.False(t, migration.k3sStopped)
+	assert.Contains(t, out.String(), "Scaling the workloads back up...")
+
+	app, err := kubeClient.AppsV1().Deployments("team-1").Get(context.Background(), "app", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), *app.Spec.Replicas)
+	assert.NotContains(t, app.Annotations, migrateReplicasAnnotation)
+	attachment, err := dynamicClient.Resource(longhorn.VolumeAttachmentResource).Namespace(longhorn.Namespace).Get(context.Background(), "pvc-data", metav1.GetOptions{})
+	require.NoError(t, err)
+	tickets, _, _ := unstructured.NestedMap(attachment.Object, "spec", "attachmentTickets")
+	assert.Empty(t, tickets)
+}
+
+func TestMigrateCancel(t *testing.T) {
+	opts, out := testOptions(t, "")
+	kubeClient := fake.NewSimpleClientset(&appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "team-1", Annotations: map[string]string{migrateReplicasAnnotation: "3"}},
+		Spec:       appsv1.DeploymentSpec{Replicas: ptr.To(int32(0))},
+	})
+	opts.KubeClients = func(string) (kubernetes.Interface, dynamic.Interface, error) { return kubeClient, nil, nil }
+
+	require.NoError(t, run(opts, "migrate", "cancel"))
+	app, err := kubeClient.AppsV1().Deployments("team-1").Get(context.Background(), "app", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, int32(3), *app.Spec.Replicas)
+	assert.Contains(t, out.String(), "Scaled up deployment team-1/app")
+}
+
+func TestMigrateExport_RequiresBackupTarget(t *testing.T) {
+	opts, _ := testOptions(t, "")
+	writeConfig(t, opts, &config.Config{UnbindDomain: "unbind.example.com"})
+
+	assert.ErrorContains(t, run(opts, "migrate", "export"), "--skip-volumes")
+}
+
+func TestMigrateImport_Final(t *testing.T) {
+	opts, out := testOptions(t, "")
+	restored := fakeBackups(t, time.Now())
+	fakeServerRestore(t)
+	fakeDNS(t, true)
+	synced := fakeSync(t)
+	kubeClient, dynamicClient := restoredCluster(t)
+	opts.KubeClients = func(string) (kubernetes.Interface, dynamic.Interface, error) { return kubeClient, dynamicClient, nil }
+	// Scaled down by the final export
+	_, err := kubeClient.AppsV1().Deployments("team-1").Create(context.Background(), &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "team-1", Annotations: map[string]string{migrateReplicasAnnotation: "2"}},
+		Spec:       appsv1.DeploymentSpec{Replicas: ptr.To(int32(0))},
+	}, metav1.CreateOptions{})
+	require.NoError(t, err)
+
+	archive := writeTestArchive(t, &backup.Manifest{
+		FormatVersion: 1,
+		Hostname:      "old-server",
+		Datastore:     backup.DatastoreSQLite,
+		Snapshot:      "datastore/state.db",
+		K3sVersion:    "v1.32.5+k3s1",
+	}, map[string]string{
+		"etc-unbind/config": "UNBIND_DOMAIN=example.com\nBASE_DOMAIN=*.example.com\nREGISTRY_TYPE=self-hosted\n" +
+			"REGISTRY_DOMAIN=unbind-registry.example.com\n",
+	})
+
+	// The first import must not overwrite an installation by accident
+	assert.ErrorContains(t, run(opts, "migrate", "import", archive, "--yes"), "pass --final")
+
+	require.NoError(t, run(opts, "migrate", "import", archive, "--final", "--yes"))
+	require.Len(t, *restored, 1, "the final export replaces the state of the first import")
+	assert.Equal(t, "example.com", synced.UnbindDomain)
+	assert.Contains(t, out.String(), "Unbind from old-server is running on this server")
+	assert.Contains(t, out.String(), "*.example.com")
+	assert.NotContains(t, out.String(), "unbind-registry.example.com ", "the wildcard covers the registry")
+	assert.NotContains(t, out.String(), "migrate export --final")
+
+	app, err := kubeClient.AppsV1().Deployments("team-1").Get(context.Background(), "app", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), *app.Spec.Replicas, "the workloads the final export scaled down are started again")
+}
+
+func TestMigrateImport_FinalNeedsFirstImport(t *testing.T) {
+	opts, _ := testOptions(t, "")
+	require.NoError(t, os.Remove(opts.UninstallScriptPath))
+
+	assert.ErrorContains(t, run(opts, "migrate", "import", "export.tar.gz", "--final"), "without --final")
+}
+
+func TestCoveredByWildcard(t *testing.T) {
+	assert.True(t, coveredByWildcard("unbind.example.com", "*.example.com"))
+	assert.False(t, coveredByWildcard("example.com", "*.example.com"))
+	assert.False(t, coveredByWildcard("a.b.example.com", "*.example.com"))
+	assert.False(t, coveredByWildcard("unbind.example.com", ""))
+}
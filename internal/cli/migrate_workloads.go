@@ -0,0 +1,164 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// migrateReplicasAnnotation records the replicas of a workload scaled down for the final export,
+// it travels with the cluster state so the import scales the workload back up
+const migrateReplicasAnnotation = "unbind-installer/migrate-replicas"
+
+// migrateSystemNamespaces keep running during the final export, Longhorn has to take the backups
+var migrateSystemNamespaces = []string{"kube-system", "longhorn-system"}
+
+// workloadStopTimeout is how long the final export waits for pods with volumes to stop
+var workloadStopTimeout = 10 * time.Minute
+
+// scaleDownWorkloads scales every deployment and statefulset outside the system namespaces to zero,
+// so nothing writes to volumes while they are backed up, and waits for pods with volumes to stop
+func scaleDownWorkloads(ctx context.Context, kubeClient kubernetes.Interface, logFn func(string)) error {
+	deployments, err := kubeClient.AppsV1().Deployments("").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to list deployments: %w", err)
+	}
+	// Deployments go first, operators among them would otherwise scale their statefulsets back up
+	for _, deployment := range deployments.Items {
+		if !scalable(&deployment.ObjectMeta, deployment.Spec.Replicas) {
+			continue
+		}
+		recordReplicas(&deployment.ObjectMeta, deployment.Spec.Replicas)
+		deployment.Spec.Replicas = new(int32)
+		if _, err := kubeClient.AppsV1().Deployments(deployment.Namespace).Update(ctx, &deployment, metav1.UpdateOptions{}); err != nil {
+			return fmt.Errorf("failed to scale down deployment %s/%s: %w", deployment.Namespace, deployment.Name, err)
+		}
+		logFn(fmt.Sprintf("Scaled down deployment %s/%s", deployment.Namespace, deployment.Name))
+	}
+
+	statefulSets, err := kubeClient.AppsV1().StatefulSets("").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to list statefulsets: %w", err)
+	}
+	for _, statefulSet := range statefulSets.Items {
+		if !scalable(&statefulSet.ObjectMeta, statefulSet.Spec.Replicas) {
+			continue
+		}
+		recordReplicas(&statefulSet.ObjectMeta, statefulSet.Spec.Replicas)
+		statefulSet.Spec.Replicas = new(int32)
+		if _, err := kubeClient.AppsV1().StatefulSets(statefulSet.Namespace).Update(ctx, &statefulSet, metav1.UpdateOptions{}); err != nil {
+			return fmt.Errorf("failed to scale down statefulset %s/%s: %w", statefulSet.Namespace, statefulSet.Name, err)
+		}
+		logFn(fmt.Sprintf("Scaled down statefulset %s/%s", statefulSet.Namespace, statefulSet.Name))
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, workloadStopTimeout)
+	defer cancel()
+	logFn("Waiting for pods with volumes to stop...")
+	for {
+		running, err := podsWithVolumes(ctx, kubeClient)
+		if err == nil && running == 0 {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%d pod(s) with volumes didn't stop: %w", running, ctx.Err())
+		case <-time.After(pollInterval):
+		}
+	}
+}
+
+// scaleUpWorkloads scales the workloads scaleDownWorkloads scaled down back to their replicas
+func scaleUpWorkloads(ctx context.Context, kubeClient kubernetes.Interface, logFn func(string)) error {
+	statefulSets, err := kubeClient.AppsV1().StatefulSets("").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to list statefulsets: %w", err)
+	}
+	for _, statefulSet := range statefulSets.Items {
+		replicas, ok := recordedReplicas(&statefulSet.ObjectMeta)
+		if !ok {
+			continue
+		}
+		statefulSet.Spec.Replicas = &replicas
+		if _, err := kubeClient.AppsV1().StatefulSets(statefulSet.Namespace).Update(ctx, &statefulSet, metav1.UpdateOptions{}); err != nil {
+			return fmt.Errorf("failed to scale up statefulset %s/%s: %w", statefulSet.Namespace, statefulSet.Name, err)
+		}
+		logFn(fmt.Sprintf("Scaled up statefulset %s/%s", statefulSet.Namespace, statefulSet.Name))
+	}
+
+	deployments, err := kubeClient.AppsV1().Deployments("").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to list deployments: %w", err)
+	}
+	for _, deployment := range deployments.Items {
+		replicas, ok := recordedReplicas(&deployment.ObjectMeta)
+		if !ok {
+			continue
+		}
+		deployment.Spec.Replicas = &replicas
+		if _, err := kubeClient.AppsV1().Deployments(deployment.Namespace).Update(ctx, &deployment, metav1.UpdateOptions{}); err != nil {
+			return fmt.Errorf("failed to scale up deployment %s/%s: %w", deployment.Namespace, deployment.Name, err)
+		}
+		logFn(fmt.Sprintf("Scaled up deployment %s/%s", deployment.Namespace, deployment.Name))
+	}
+	return nil
+}
+
+func scalable(meta *metav1.ObjectMeta, replicas *int32) bool {
+	if slices.Contains(migrateSystemNamespaces, meta.Namespace) {
+		return false
+	}
+	// Workloads without replicas set run one
+	return replicas == nil || *replicas > 0
+}
+
+func recordReplicas(meta *metav1.ObjectMeta, replicas *int32) {
+	count := int32(1)
+	if replicas != nil {
+		count = *replicas
+	}
+	if meta.Annotations == nil {
+		meta.Annotations = map[string]string{}
+	}
+	meta.Annotations[migrateReplicasAnnotation] = strconv.Itoa(int(count))
+}
+
+// recordedReplicas reads and removes the replicas recorded by recordReplicas
+func recordedReplicas(meta *metav1.ObjectMeta) (int32, bool) {
+	value, ok := meta.Annotations[migrateReplicasAnnotation]
+	if !ok {
+		return 0, false
+	}
+	delete(meta.Annotations, migrateReplicasAnnotation)
+	replicas, err := strconv.ParseInt(value, 10, 32)
+	if err != nil {
+		replicas = 1
+	}
+	return int32(replicas), true
+}
+
+// podsWithVolumes counts the pods outside the system namespaces that mount persistent volume claims
+func podsWithVolumes(ctx context.Context, kubeClient kubernetes.Interface) (int, error) {
+	pods, err := kubeClient.CoreV1().Pods("").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return 0, err
+	}
+	count := 0
+	for _, pod := range pods.Items {
+		if slices.Contains(migrateSystemNamespaces, pod.Namespace) {
+			continue
+		}
+		for _, volume := range pod.Spec.Volumes {
+			if volume.PersistentVolumeClaim != nil {
+				count++
+				break
+			}
+		}
+	}
+	return count, nil
+}
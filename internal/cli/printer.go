@@ -0,0 +1,90 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+const banner = ` _   _       _     _           _
+| | | |_ __ | |__ (_)_ __   __| |
+| | | | '_ \| '_ \| | '_ \ / _  |
+| |_| | | | | |_) | | | | | (_| |
+ \___/|_| |_|_.__/|_|_| |_|\__,_|`
+
+// printer writes styled CLI output, matching the colors of the installer
+type printer struct {
+	out io.Writer
+
+	banner  lipgloss.Style
+	bold    lipgloss.Style
+	subtle  lipgloss.Style
+	command lipgloss.Style
+	box     lipgloss.Style
+	success lipgloss.Color
+	warning lipgloss.Color
+	error   lipgloss.Color
+	info    lipgloss.Color
+}
+
+func newPrinter(out io.Writer) *printer {
+	return &printer{
+		out:     out,
+		banner:  lipgloss.NewStyle().Foreground(lipgloss.Color("#009900")),
+		bold:    lipgloss.NewStyle().Bold(true),
+		subtle:  lipgloss.NewStyle().Foreground(lipgloss.Color("#888888")),
+		command: lipgloss.NewStyle().Foreground(lipgloss.Color("#00AAAA")),
+		box:     lipgloss.NewStyle().Border(lipgloss.DoubleBorder()).Padding(0, 1),
+		success: lipgloss.Color("#009900"),
+		warning: lipgloss.Color("#CCAA00"),
+		error:   lipgloss.Color("#CC0000"),
+		info:    lipgloss.Color("#0066CC"),
+	}
+}
+
+// Banner prints the Unbind logo
+func (self *printer) Banner() {
+	fmt.Fprintln(self.out, self.banner.Render(banner))
+	fmt.Fprintln(self.out)
+}
+
+// Box prints a message in a colored box
+func (self *printer) Box(message string, color lipgloss.Color) {
+	fmt.Fprintln(self.out, self.box.BorderForeground(color).Foreground(color).Render(message))
+}
+
+func (self *printer) Success(message string) { self.Box(message, self.success) }
+func (self *printer) Warning(message string) { self.Box(message, self.warning) }
+func (self *printer) Error(message string)   { self.Box("Error: "+message, self.error) }
+func (self *printer) Info(message string)    { self.Box(message, self.info) }
+
+// Line prints a line of plain text
+func (self *printer) Line(format string, args ...interface{}) {
+	fmt.Fprintf(self.out, format+"\n", args...)
+}
+
+// Bold prints a line of bold text
+func (self *printer) Bold(format string, args ...interface{}) {
+	fmt.Fprintln(self.out, self.bold.Render(fmt.Sprintf(format, args...)))
+}
+
+// Subtle prints a line of dimmed text
+func (self *printer) Subtle(format string, args ...interface{}) {
+	fmt.Fprintln(self.out, self.subtle.Render(fmt.Sprintf(format, args...)))
+}
+
+// Command prints a shell command for the user to run
+func (self *printer) Command(command string) {
+	fmt.Fprintln(self.out, self.command.Render(command))
+}
+
+// Colored prints a line of text in a color
+func (self *printer) Colored(color lipgloss.Color, format string, args ...interface{}) {
+	fmt.Fprintln(self.out, self.colored(color, fmt.Sprintf(format, args...)))
+}
+
+// colored renders text in a color, for mixing colors within a line
+func (self *printer) colored(color lipgloss.Color, text string) string {
+	return lipgloss.NewStyle().Foreground(color).Render(text)
+}
@@ -0,0 +1,228 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/unbindapp/unbind-installer/internal/config"
+	"github.com/unbindapp/unbind-installer/internal/network"
+	"github.com/unbindapp/unbind-installer/internal/registry"
+	"github.com/unbindapp/unbind-installer/internal/utils"
+	"golang.org/x/term"
+)
+
+// DNS checks, mockable for tests
+var (
+	detectIPs      = network.DetectIPs
+	validateDomain = network.ValidateDomain
+	detectWildcard = network.DetectWildcard
+)
+
+func newReconfigureDomainCommand(opts Options) *cobra.Command {
+	var (
+		registryDomain       string
+		registryPasswordFile string
+		skipDNSCheck         bool
+		yes                  bool
+		verbose              bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "reconfigure-domain <domain>",
+		Short: "Move Unbind to a new domain without reinstalling",
+		Long: "Move Unbind to a new domain without reinstalling.\n\n" +
+			"The domain can be a wildcard such as *.example.com, or the domain Unbind is served on.\n" +
+			"DNS for the new domains is validated before anything changes, then the platform is\n" +
+			"synced with the new domains and the saved configuration is updated.",
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := checkInstallation(opts); err != nil {
+				return err
+			}
+			cfg, err := loadConfig(opts)
+			if err != nil {
+				return err
+			}
+			if cfg.RegistryType == "" {
+				return fmt.Errorf("%s doesn't record which registry Unbind uses, set REGISTRY_TYPE to %s or %s and try again",
+					opts.ConfigPath, config.RegistrySelfHosted, config.RegistryExternal)
+			}
+
+			domain := strings.ToLower(strings.TrimSpace(args[0]))
+			base := strings.TrimPrefix(domain, "*.")
+			if !utils.IsDNSName(base) {
+				return fmt.Errorf("%s is not a valid domain", domain)
+			}
+			if registryDomain != "" && !utils.IsDNSName(registryDomain) {
+				return fmt.Errorf("%s is not a valid registry domain", registryDomain)
+			}
+
+			p := newPrinter(opts.Stdout)
+			p.Banner()
+
+			updated := *cfg
+			updated.UnbindDomain = base
+			updated.BaseDomain = ""
+
+			if skipDNSCheck {
+				p.Colored(p.warning, "Skipping DNS validation")
+				if strings.HasPrefix(domain, "*.") {
+					updated.BaseDomain = domain
+				}
+			} else {
+				wildcard, externalIP, err := checkDomainDNS(p, base)
+				if err != nil {
+					return err
+				}
+				if wildcard {
+					updated.BaseDomain = domain
+				}
+				if updated.RegistryType == config.RegistrySelfHosted {
+					updated.RegistryDomain = registryDomainOrDefault(registryDomain, &updated)
+					if err := checkRegistryDomainDNS(p, updated.RegistryDomain, externalIP); err != nil {
+						return err
+					}
+				}
+			}
+			if updated.RegistryType == config.RegistrySelfHosted {
+				updated.RegistryDomain = registryDomainOrDefault(registryDomain, &updated)
+			}
+
+			p.Info("Domain change")
+			p.Line("  Unbind:   %s → %s", cfg.UnbindDomain, updated.UnbindDomain)
+			if updated.RegistryType == config.RegistrySelfHosted {
+				p.Line("  Registry: %s → %s", cfg.RegistryDomain, updated.RegistryDomain)
+			}
+			if updated.BaseDomain != "" {
+				p.Line("  Wildcard: %s", updated.BaseDomain)
+			}
+			p.Line("")
+			p.Colored(p.warning, "Users will need to sign in again at the new domain.")
+			p.Line("")
+
+			syncOpts := platformSyncOptions(&updated)
+			if syncOpts.DisableRegistry && syncOpts.RegistryExistingSecret == "" {
+				password, err := readRegistryPassword(opts, &updated, registryPasswordFile)
+				if err != nil {
+					return err
+				}
+				syncOpts.RegistryPassword = password
+			}
+
+			if !yes && !confirm(opts.Stdin, opts.Stdout, "Move Unbind to the new domain? (y/N) ") {
+				p.Info("Domain change cancelled.")
+				return nil
+			}
+
+			p.Colored(p.warning, "Updating Unbind, this can take a few minutes...")
+			if err := runHelmfileSync(cmd.Context(), opts, syncOpts, verbose); err != nil {
+				return fmt.Errorf("failed to update Unbind: %w", err)
+			}
+
+			if err := updated.Save(opts.ConfigPath); err != nil {
+				return err
+			}
+
+			p.Success(fmt.Sprintf("Unbind is now available at https://%s", updated.UnbindDomain))
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&registryDomain, "registry-domain", "", "new domain of the self-hosted registry (default unbind-registry.<domain> for wildcards, registry.<domain> otherwise)")
+	cmd.Flags().StringVar(&registryPasswordFile, "registry-password-file", "", "read the external registry password or key from this file instead of prompting")
+	cmd.Flags().BoolVar(&skipDNSCheck, "skip-dns-check", false, "don't validate DNS for the new domains")
+	cmd.Flags().BoolVarP(&yes, "yes", "y", false, "skip the confirmation prompt")
+	cmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "show the full installer log")
+	return cmd
+}
+
+// checkDomainDNS runs the installer's DNS validation for a domain, reporting whether it is a
+// wildcard and the external IP it was checked against
+func checkDomainDNS(p *printer, base string) (bool, string, error) {
+	p.Colored(p.info, "Validating DNS for %s...", base)
+	logFn := func(message string) { p.Subtle("  %s", message) }
+
+	ips, err := detectIPs(logFn)
+	if err != nil {
+		return false, "", fmt.Errorf("failed to detect this server's IP addresses: %w", err)
+	}
+
+	valid, behindCF := validateDomain(base, ips.ExternalIP, true, logFn)
+	wildcard, _ := detectWildcard(base, ips.ExternalIP, logFn)
+	if wildcard {
+		p.Colored(p.success, "Wildcard DNS detected for *.%s", base)
+		return true, ips.ExternalIP, nil
+	}
+	if valid || behindCF {
+		p.Colored(p.success, "%s resolves to this server", base)
+		return false, ips.ExternalIP, nil
+	}
+	return false, "", fmt.Errorf("%s doesn't resolve to this server (%s), update its DNS record and try again", base, ips.ExternalIP)
+}
+
+// checkRegistryDomainDNS validates the registry domain, which can't be proxied by Cloudflare
+func checkRegistryDomainDNS(p *printer, registryDomain, externalIP string) error {
+	p.Colored(p.info, "Validating DNS for %s...", registryDomain)
+	valid, behindCF := validateDomain(registryDomain, externalIP, false, func(message string) { p.Subtle("  %s", message) })
+	if behindCF {
+		return fmt.Errorf("%s is behind the Cloudflare proxy, the registry domain must point straight at this server", registryDomain)
+	}
+	if !valid {
+		return fmt.Errorf("%s doesn't resolve to this server (%s), update its DNS record and try again", registryDomain, externalIP)
+	}
+	p.Colored(p.success, "%s resolves to this server", registryDomain)
+	return nil
+}
+
+// registryDomainOrDefault returns the requested registry domain, or the one the installer suggests
+func registryDomainOrDefault(registryDomain string, cfg *config.Config) string {
+	if registryDomain != "" {
+		return registryDomain
+	}
+	if cfg.BaseDomain != "" {
+		return "unbind-registry." + strings.TrimPrefix(cfg.BaseDomain, "*.")
+	}
+	return "registry." + cfg.UnbindDomain
+}
+
+// readRegistryPassword reads the external registry password from a file or a prompt, the
+// installer doesn't keep it on disk
+func readRegistryPassword(opts Options, cfg *config.Config, path string) (string, error) {
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("failed to read the registry password: %w", err)
+		}
+		return strings.TrimSpace(string(data)), nil
+	}
+
+	if registry.Provider(cfg.RegistryProvider) == registry.ProviderGAR {
+		return "", fmt.Errorf("pass the service account key for %s with --registry-password-file", cfg.RegistryHost)
+	}
+
+	fmt.Fprintf(opts.Stdout, "Password for %s on %s: ", cfg.RegistryUsername, cfg.RegistryHost)
+	var password string
+	if opts.terminal != nil {
+		data, err := term.ReadPassword(int(opts.terminal.Fd()))
+		fmt.Fprintln(opts.Stdout)
+		if err != nil {
+			return "", fmt.Errorf("failed to read the registry password: %w", err)
+		}
+		password = string(data)
+	} else {
+		line, err := readLine(opts.Stdin)
+		if err != nil && err != io.EOF {
+			return "", fmt.Errorf("failed to read the registry password: %w", err)
+		}
+		password = line
+	}
+
+	password = strings.TrimSpace(password)
+	if password == "" {
+		return "", fmt.Errorf("the registry password is required to update Unbind")
+	}
+	return password, nil
+}
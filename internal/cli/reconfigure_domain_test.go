@@ -0,0 +1,151 @@
+package cli
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/unbindapp/unbind-installer/internal/config"
+	"github.com/unbindapp/unbind-installer/internal/installer"
+	"github.com/unbindapp/unbind-installer/internal/network"
+)
+
+// fakeDNS makes the listed domains resolve to this server, wildcard enables wildcard detection
+func fakeDNS(t *testing.T, wildcard bool, resolving ...string) {
+	originalIPs, originalValidate, originalWildcard := detectIPs, validateDomain, detectWildcard
+	t.Cleanup(func() { detectIPs, validateDomain, detectWildcard = originalIPs, originalValidate, originalWildcard })
+
+	detectIPs = func(func(string)) (*network.IPInfo, error) {
+		return &network.IPInfo{InternalIP: "10.0.0.5", ExternalIP: "203.0.113.10"}, nil
+	}
+	validateDomain = func(domain, expectedIP string, allowCloudflare bool, logFn func(string)) (bool, bool) {
+		assert.Equal(t, "203.0.113.10", expectedIP)
+		for _, d := range resolving {
+			if d == domain {
+				return true, false
+			}
+		}
+		return wildcard, false
+	}
+	detectWildcard = func(base, expectedIP string, logFn func(string)) (bool, bool) {
+		return wildcard, false
+	}
+}
+
+// fakeSync records the options of the helmfile sync
+func fakeSync(t *testing.T) *installer.SyncHelmfileOptions {
+	original := syncHelmfile
+	t.Cleanup(func() { syncHelmfile = original })
+
+	synced := &installer.SyncHelmfileOptions{}
+	syncHelmfile = func(ctx context.Context, kubeConfigPath string, syncOpts installer.SyncHelmfileOptions, logChan chan<- string, progress func(string)) error {
+		*synced = syncOpts
+		progress("Running helmfile sync")
+		return nil
+	}
+	return synced
+}
+
+func writeConfig(t *testing.T, opts Options, cfg *config.Config) {
+	require.NoError(t, cfg.Save(opts.ConfigPath))
+}
+
+func TestReconfigureDomain_WildcardSelfHosted(t *testing.T) {
+	opts, out := testOptions(t, "")
+	writeConfig(t, opts, &config.Config{
+		ClusterIP:              "10.0.0.5",
+		UnbindDomain:           "unbind.old.com",
+		RegistryType:           config.RegistrySelfHosted,
+		RegistryDomain:         "registry.old.com",
+		RegistryStorageSizeGB:  50,
+		RegistryStorageClass:   "longhorn",
+		RegistryRetainTags:     10,
+		RegistryGCSchedule:     "0 3 * * *",
+		LonghornBackupTarget:   "s3://backups@us-east-1/cluster-a",
+		LonghornBackupSchedule: "0 2 * * *",
+		LonghornBackupRetain:   7,
+	})
+	fakeDNS(t, true)
+	synced := fakeSync(t)
+
+	require.NoError(t, run(opts, "reconfigure-domain", "*.new.com", "--yes"))
+
+	assert.Equal(t, "new.com", synced.UnbindDomain)
+	assert.Equal(t, "*.new.com", synced.BaseDomain)
+	assert.Equal(t, "unbind-registry.new.com", synced.UnbindRegistryDomain)
+	assert.False(t, synced.DisableRegistry)
+	require.NotNil(t, synced.RegistrySettings)
+	assert.Equal(t, 50, synced.RegistrySettings.StorageSizeGB)
+	// The Longhorn backup target is kept, its credential secret already exists in the cluster
+	require.NotNil(t, synced.LonghornBackup)
+	assert.Equal(t, "s3://backups@us-east-1/cluster-a", synced.LonghornBackup.Target)
+	assert.True(t, synced.LonghornBackup.HasSecret)
+	assert.Nil(t, synced.LonghornBackup.Credentials)
+	assert.Contains(t, out.String(), "Unbind is now available at https://new.com")
+
+	saved, err := config.Load(opts.ConfigPath)
+	require.NoError(t, err)
+	assert.Equal(t, "new.com", saved.UnbindDomain)
+	assert.Equal(t, "*.new.com", saved.BaseDomain)
+	assert.Equal(t, "unbind-registry.new.com", saved.RegistryDomain)
+	assert.Equal(t, "10.0.0.5", saved.ClusterIP)
+}
+
+func TestReconfigureDomain_ExternalRegistryPrompt(t *testing.T) {
+	opts, _ := testOptions(t, "s3cret\ny\n")
+	writeConfig(t, opts, &config.Config{
+		ClusterIP:        "10.0.0.5",
+		UnbindDomain:     "unbind.old.com",
+		RegistryType:     config.RegistryExternal,
+		RegistryHost:     "ghcr.io",
+		RegistryUsername: "octocat",
+	})
+	fakeDNS(t, false, "unbind.new.com")
+	synced := fakeSync(t)
+
+	require.NoError(t, run(opts, "reconfigure-domain", "unbind.new.com"))
+
+	assert.Equal(t, "unbind.new.com", synced.UnbindDomain)
+	assert.Empty(t, synced.BaseDomain)
+	assert.True(t, synced.DisableRegistry)
+	assert.Equal(t, "ghcr.io", synced.RegistryHost)
+	assert.Equal(t, "octocat", synced.RegistryUsername)
+	assert.Equal(t, "s3cret", synced.RegistryPassword)
+}
+
+func TestReconfigureDomain_ECRUsesRefreshedSecret(t *testing.T) {
+	opts, _ := testOptions(t, "")
+	writeConfig(t, opts, &config.Config{
+		UnbindDomain:     "unbind.old.com",
+		RegistryType:     config.RegistryExternal,
+		RegistryHost:     "123456789012.dkr.ecr.us-east-1.amazonaws.com",
+		RegistryProvider: "ecr",
+	})
+	fakeDNS(t, false, "unbind.new.com")
+	synced := fakeSync(t)
+
+	require.NoError(t, run(opts, "reconfigure-domain", "unbind.new.com", "--yes"))
+	assert.Equal(t, installer.RegistryCredentialsSecretName, synced.RegistryExistingSecret)
+	assert.Empty(t, synced.RegistryPassword)
+}
+
+func TestReconfigureDomain_DNSNotReady(t *testing.T) {
+	opts, _ := testOptions(t, "")
+	writeConfig(t, opts, &config.Config{
+		UnbindDomain:   "unbind.old.com",
+		RegistryType:   config.RegistrySelfHosted,
+		RegistryDomain: "registry.old.com",
+	})
+	fakeDNS(t, false, "unbind.new.com")
+	synced := fakeSync(t)
+
+	err := run(opts, "reconfigure-domain", "unbind.new.com", "--yes")
+	assert.ErrorContains(t, err, "registry.unbind.new.com doesn't resolve to this server")
+	assert.Empty(t, synced.UnbindDomain, "nothing is synced before DNS is valid")
+
+	saved, err := os.ReadFile(opts.ConfigPath)
+	require.NoError(t, err)
+	assert.Contains(t, string(saved), "UNBIND_DOMAIN=unbind.old.com")
+}
@@ -0,0 +1,347 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/unbindapp/unbind-installer/internal/k3s"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+)
+
+var (
+	longhornReplicaResource = schema.GroupVersionResource{
+		Group:    "longhorn.io",
+		Version:  "v1beta2",
+		Resource: "replicas",
+	}
+	longhornNodeResource = schema.GroupVersionResource{
+		Group:    "longhorn.io",
+		Version:  "v1beta2",
+		Resource: "nodes",
+	}
+)
+
+// agentUninstallScript is what the k3s installer leaves on agent nodes, server nodes have
+// k3s.K3sUninstallScriptPath
+const agentUninstallScript = "/usr/local/bin/k3s-agent-uninstall.sh"
+
+// How often remove-node checks on evictions and replica rebuilds, shortened in tests
+var pollInterval = 5 * time.Second
+
+// runSSH runs a command on a remote host, mockable for tests
+var runSSH = func(ctx context.Context, target string, command string, out chan<- string) error {
+	cmd := exec.CommandContext(ctx, "ssh", "-o", "BatchMode=yes", target, command)
+	output, err := cmd.CombinedOutput()
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		if line != "" {
+			out <- line
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("ssh %s failed: %w", target, err)
+	}
+	return nil
+}
+
+func newRemoveNodeCommand(opts Options) *cobra.Command {
+	var (
+		sshTarget string
+		timeout   time.Duration
+		yes       bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "remove-node <name>",
+		Short: "Safely remove a node from the cluster",
+		Long: "Safely remove a node from the cluster.\n\n" +
+			"The node is cordoned and drained, Longhorn moves its volume replicas to the other nodes,\n" +
+			"then the node is deleted. Refuses to continue when the node holds the last healthy\n" +
+			"replica of a volume.",
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := checkInstallation(opts); err != nil {
+				return err
+			}
+
+			clientset, dynamicClient, err := opts.KubeClients(opts.KubeConfigPath)
+			if err != nil {
+				return fmt.Errorf("failed to connect to the cluster: %w", err)
+			}
+
+			ctx := cmd.Context()
+			name := args[0]
+			p := newPrinter(opts.Stdout)
+			p.Banner()
+
+			server, err := checkNodeRemovable(ctx, clientset, name)
+			if err != nil {
+				return err
+			}
+			uninstallScript := agentUninstallScript
+			if server {
+				uninstallScript = k3s.K3sUninstallScriptPath
+			}
+			volumes, err := replicasOnNode(ctx, dynamicClient, name)
+			if err != nil {
+				return err
+			}
+			if lost := volumesLostWithNode(volumes); len(lost) > 0 {
+				return fmt.Errorf("removing %s would lose the last healthy replica of %s, wait for Longhorn to rebuild the volumes or add replicas first",
+					name, strings.Join(lost, ", "))
+			}
+
+			p.Box(fmt.Sprintf("Removing node %s", name), p.warning)
+			p.Line("Workloads will be moved to the other nodes and %d volume(s) will be rebuilt elsewhere.", len(volumes))
+			p.Line("")
+			if !yes && !confirm(opts.Stdin, opts.Stdout, "Remove the node? (y/N) ") {
+				p.Info("Node removal cancelled.")
+				return nil
+			}
+
+			ctx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+
+			p.Colored(p.info, "Cordoning %s...", name)
+			if err := cordonNode(ctx, clientset, name); err != nil {
+				return err
+			}
+
+			p.Colored(p.info, "Draining %s...", name)
+			if err := drainNode(ctx, clientset, name, func(message string) { p.Subtle("  %s", message) }); err != nil {
+				return err
+			}
+
+			if len(volumes) > 0 {
+				p.Colored(p.info, "Waiting for Longhorn to rebuild replicas elsewhere...")
+				if err := evictLonghornReplicas(ctx, dynamicClient, name, func(message string) { p.Subtle("  %s", message) }); err != nil {
+					return err
+				}
+			}
+
+			p.Colored(p.info, "Deleting node %s...", name)
+			if err := clientset.CoreV1().Nodes().Delete(ctx, name, metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+				return fmt.Errorf("failed to delete node %s: %w", name, err)
+			}
+			// Longhorn keeps its own node object, it can only go once the Kubernetes node is gone
+			if err := dynamicClient.Resource(longhornNodeResource).Namespace("longhorn-system").Delete(ctx, name, metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+				p.Colored(p.warning, "Could not remove %s from Longhorn: %v", name, err)
+			}
+
+			if sshTarget != "" {
+				p.Colored(p.info, "Uninstalling K3s on %s...", sshTarget)
+				if err := runWithLogs(opts.Stdout, func(logChan chan<- string) error {
+					return runSSH(ctx, sshTarget, "sudo "+uninstallScript, logChan)
+				}); err != nil {
+					p.Colored(p.error, "Cleanup over SSH failed: %v", err)
+					printNodeCleanup(p, uninstallScript)
+					return exitError{code: 1}
+				}
+				p.Success(fmt.Sprintf("Node %s has been removed and cleaned up.", name))
+				return nil
+			}
+
+			p.Success(fmt.Sprintf("Node %s has been removed from the cluster.", name))
+			printNodeCleanup(p, uninstallScript)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&sshTarget, "ssh", "", "run the K3s cleanup over SSH on this target, e.g. root@10.0.0.6")
+	cmd.Flags().DurationVar(&timeout, "timeout", 30*time.Minute, "give up if draining and rebuilding takes longer than this")
+	cmd.Flags().BoolVarP(&yes, "yes", "y", false, "skip the confirmation prompt")
+	return cmd
+}
+
+func printNodeCleanup(p *printer, uninstallScript string) {
+	p.Line("")
+	p.Bold("To finish, run this on the removed server to uninstall K3s and clean up:")
+	p.Line("")
+	p.Command("sudo " + uninstallScript)
+}
+
+// checkNodeRemovable refuses to remove the last control plane node, that would remove the cluster.
+// It reports whether the node is a server, servers and agents are uninstalled with different scripts.
+func checkNodeRemovable(ctx context.Context, clientset kubernetes.Interface, name string) (bool, error) {
+	node, err := clientset.CoreV1().Nodes().Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return false, fmt.Errorf("failed to find node %s: %w", name, err)
+	}
+	if _, ok := node.Labels["node-role.kubernetes.io/control-plane"]; !ok {
+		return false, nil
+	}
+
+	servers, err := clientset.CoreV1().Nodes().List(ctx, metav1.ListOptions{LabelSelector: "node-role.kubernetes.io/control-plane"})
+	if err != nil {
+		return true, fmt.Errorf("failed to list nodes: %w", err)
+	}
+	if len(servers.Items) <= 1 {
+		return true, fmt.Errorf("%s is the only server node, use unbind uninstall to remove Unbind entirely", name)
+	}
+	return true, nil
+}
+
+// volumeReplicas counts the replicas of a volume that has at least one replica on the node being removed
+type volumeReplicas struct {
+	name           string
+	onNode         int
+	healthyOnOther int
+}
+
+// replicasOnNode finds the volumes with replicas on the node
+func replicasOnNode(ctx context.Context, dynamicClient dynamic.Interface, nodeName string) ([]volumeReplicas, error) {
+	replicas, err := dynamicClient.Resource(longhornReplicaResource).Namespace("longhorn-system").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list longhorn replicas: %w", err)
+	}
+
+	volumes := map[string]*volumeReplicas{}
+	for _, replica := range replicas.Items {
+		volumeName, _, _ := unstructured.NestedString(replica.Object, "spec", "volumeName")
+		replicaNode, _, _ := unstructured.NestedString(replica.Object, "spec", "nodeID")
+		if volumes[volumeName] == nil {
+			volumes[volumeName] = &volumeReplicas{name: volumeName}
+		}
+		if replicaNode == nodeName {
+			volumes[volumeName].onNode++
+		} else if replicaHealthy(replica) {
+			volumes[volumeName].healthyOnOther++
+		}
+	}
+
+	result := []volumeReplicas{}
+	for _, volume := range volumes {
+		if volume.onNode > 0 {
+			result = append(result, *volume)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].name < result[j].name })
+	return result, nil
+}
+
+// replicaHealthy reports whether a replica holds good data: running, or stopped with its volume detached
+func replicaHealthy(replica unstructured.Unstructured) bool {
+	failedAt, _, _ := unstructured.NestedString(replica.Object, "spec", "failedAt")
+	state, _, _ := unstructured.NestedString(replica.Object, "status", "currentState")
+	return failedAt == "" && (state == "running" || state == "stopped")
+}
+
+// volumesLostWithNode returns the volumes with no healthy replica outside the node
+func volumesLostWithNode(volumes []volumeReplicas) []string {
+	lost := []string{}
+	for _, volume := range volumes {
+		if volume.healthyOnOther == 0 {
+			lost = append(lost, volume.name)
+		}
+	}
+	return lost
+}
+
+func cordonNode(ctx context.Context, clientset kubernetes.Interface, name string) error {
+	patch := []byte(`{"spec":{"unschedulable":true}}`)
+	if _, err := clientset.CoreV1().Nodes().Patch(ctx, name, types.MergePatchType, patch, metav1.PatchOptions{}); err != nil {
+		return fmt.Errorf("failed to cordon node %s: %w", name, err)
+	}
+	return nil
+}
+
+// drainNode evicts every pod on the node except daemonset and static pods, like kubectl drain.
+// Evictions blocked by a disruption budget are retried until the context ends.
+func drainNode(ctx context.Context, clientset kubernetes.Interface, name string, logFn func(string)) error {
+	for {
+		pods, err := clientset.CoreV1().Pods(metav1.NamespaceAll).List(ctx, metav1.ListOptions{
+			FieldSelector: "spec.nodeName=" + name,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to list pods on %s: %w", name, err)
+		}
+
+		remaining := 0
+		for _, pod := range pods.Items {
+			if pod.Spec.NodeName != name || !drainablePod(pod) {
+				continue
+			}
+			remaining++
+			if pod.DeletionTimestamp != nil {
+				continue
+			}
+
+			eviction := &policyv1.Eviction{ObjectMeta: metav1.ObjectMeta{Name: pod.Name, Namespace: pod.Namespace}}
+			err := clientset.PolicyV1().Evictions(pod.Namespace).Evict(ctx, eviction)
+			switch {
+			case err == nil:
+				logFn(fmt.Sprintf("evicted %s/%s", pod.Namespace, pod.Name))
+			case apierrors.IsNotFound(err):
+				remaining--
+			case apierrors.IsTooManyRequests(err):
+				logFn(fmt.Sprintf("%s/%s is protected by a disruption budget, retrying", pod.Namespace, pod.Name))
+			default:
+				return fmt.Errorf("failed to evict %s/%s: %w", pod.Namespace, pod.Name, err)
+			}
+		}
+
+		if remaining == 0 {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("timed out draining %s, %d pod(s) left", name, remaining)
+		case <-time.After(pollInterval):
+		}
+	}
+}
+
+// drainablePod skips pods that can't move: daemonset pods run on every node and static pods belong to the node
+func drainablePod(pod corev1.Pod) bool {
+	if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+		return false
+	}
+	if _, mirror := pod.Annotations[corev1.MirrorPodAnnotationKey]; mirror {
+		return false
+	}
+	for _, owner := range pod.OwnerReferences {
+		if owner.Kind == "DaemonSet" {
+			return false
+		}
+	}
+	return true
+}
+
+// evictLonghornReplicas asks Longhorn to move the node's replicas and waits until every volume
+// is rebuilt on other nodes
+func evictLonghornReplicas(ctx context.Context, dynamicClient dynamic.Interface, name string, logFn func(string)) error {
+	patch := []byte(`{"spec":{"allowScheduling":false,"evictionRequested":true}}`)
+	if _, err := dynamicClient.Resource(longhornNodeResource).Namespace("longhorn-system").Patch(ctx, name, types.MergePatchType, patch, metav1.PatchOptions{}); err != nil {
+		return fmt.Errorf("failed to request eviction of longhorn replicas from %s: %w", name, err)
+	}
+
+	for {
+		volumes, err := replicasOnNode(ctx, dynamicClient, name)
+		if err != nil {
+			return err
+		}
+		if len(volumes) == 0 {
+			return nil
+		}
+		logFn(fmt.Sprintf("%d volume(s) still have replicas on %s", len(volumes), name))
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("timed out waiting for longhorn to move replicas off %s", name)
+		case <-time.After(pollInterval):
+		}
+	}
+}
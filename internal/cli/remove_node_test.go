@@ -0,0 +1,168 @@
+package cli
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func longhornReplica(name, volume, node, state string) *unstructured.Unstructured {
+	replica := &unstructured.Unstructured{Object: map[string]interface{}{
+		"spec":   map[string]interface{}{"volumeName": volume, "nodeID": node},
+		"status": map[string]interface{}{"currentState": state},
+	}}
+	replica.SetAPIVersion("longhorn.io/v1beta2")
+	replica.SetKind("Replica")
+	replica.SetName(name)
+	replica.SetNamespace("longhorn-system")
+	return replica
+}
+
+func longhornNode(name string) *unstructured.Unstructured {
+	node := &unstructured.Unstructured{Object: map[string]interface{}{"spec": map[string]interface{}{"allowScheduling": true}}}
+	node.SetAPIVersion("longhorn.io/v1beta2")
+	node.SetKind("Node")
+	node.SetName(name)
+	node.SetNamespace("longhorn-system")
+	return node
+}
+
+func agentNode(name string) *corev1.Node {
+	return &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: name}}
+}
+
+func podOn(name, node string, owner string) *corev1.Pod {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "unbind-system"},
+		Spec:       corev1.PodSpec{NodeName: node},
+		Status:     corev1.PodStatus{Phase: corev1.PodRunning},
+	}
+	if owner != "" {
+		pod.OwnerReferences = []metav1.OwnerReference{{Kind: owner, Name: "owner"}}
+	}
+	return pod
+}
+
+func TestRemoveNode(t *testing.T) {
+	pollInterval = time.Millisecond
+	t.Cleanup(func() { pollInterval = 5 * time.Second })
+
+	clientset := fake.NewSimpleClientset(
+		readyNode("server-1"),
+		agentNode("agent-1"),
+		podOn("api-1", "agent-1", "ReplicaSet"),
+		podOn("longhorn-manager-1", "agent-1", "DaemonSet"),
+		podOn("api-2", "server-1", "ReplicaSet"),
+	)
+	// Evicting a pod deletes it, like the API server does
+	clientset.PrependReactor("create", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if action.GetSubresource() != "eviction" {
+			return false, nil, nil
+		}
+		name := action.(k8stesting.CreateAction).GetObject().(metav1.Object).GetName()
+		return true, nil, clientset.Tracker().Delete(action.GetResource(), action.GetNamespace(), name)
+	})
+
+	dynamicClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{longhornReplicaResource: "ReplicaList", longhornNodeResource: "NodeList"},
+		longhornNode("agent-1"),
+		longhornReplica("vol-a-r1", "vol-a", "server-1", "running"),
+		longhornReplica("vol-a-r2", "vol-a", "agent-1", "running"),
+	)
+	// Longhorn moves the replica away once eviction is requested
+	dynamicClient.PrependReactor("patch", "nodes", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return false, nil, dynamicClient.Tracker().Delete(longhornReplicaResource, "longhorn-system", "vol-a-r2")
+	})
+
+	opts, out := testOptions(t, "")
+	opts.KubeClients = func(string) (kubernetes.Interface, dynamic.Interface, error) {
+		return clientset, dynamicClient, nil
+	}
+
+	require.NoError(t, run(opts, "remove-node", "agent-1", "--yes"))
+
+	_, err := clientset.CoreV1().Nodes().Get(context.Background(), "agent-1", metav1.GetOptions{})
+	assert.Error(t, err, "the node is deleted")
+	_, err = clientset.CoreV1().Pods("unbind-system").Get(context.Background(), "api-1", metav1.GetOptions{})
+	assert.Error(t, err, "the pod is evicted")
+	_, err = clientset.CoreV1().Pods("unbind-system").Get(context.Background(), "api-2", metav1.GetOptions{})
+	assert.NoError(t, err, "pods on other nodes stay")
+
+	assert.Contains(t, out.String(), "evicted unbind-system/api-1")
+	assert.NotContains(t, out.String(), "longhorn-manager-1")
+	assert.Contains(t, out.String(), "sudo /usr/local/bin/k3s-agent-uninstall.sh")
+}
+
+func TestRemoveNode_RefusesLastReplica(t *testing.T) {
+	opts, _ := testOptions(t, "")
+	opts.KubeClients = fakeCluster([]runtime.Object{readyNode("server-1"), agentNode("agent-1")},
+		longhornReplica("vol-a-r1", "vol-a", "agent-1", "running"),
+		longhornReplica("vol-a-r2", "vol-a", "server-1", "error"),
+		longhornReplica("vol-b-r1", "vol-b", "agent-1", "running"),
+		longhornReplica("vol-b-r2", "vol-b", "server-1", "running"),
+	)
+
+	err := run(opts, "remove-node", "agent-1", "--yes")
+	assert.ErrorContains(t, err, "would lose the last healthy replica of vol-a,")
+}
+
+func TestRemoveNode_RefusesOnlyServer(t *testing.T) {
+	opts, _ := testOptions(t, "")
+	opts.KubeClients = fakeCluster([]runtime.Object{readyNode("server-1"), agentNode("agent-1")})
+
+	err := run(opts, "remove-node", "server-1", "--yes")
+	assert.ErrorContains(t, err, "server-1 is the only server node")
+}
+
+func TestRemoveNode_CleansUpOverSSH(t *testing.T) {
+	original := runSSH
+	t.Cleanup(func() { runSSH = original })
+	var sshTarget, sshCommand string
+	runSSH = func(ctx context.Context, target, command string, out chan<- string) error {
+		sshTarget, sshCommand = target, command
+		out <- "k3s-agent uninstalled"
+		return nil
+	}
+
+	opts, out := testOptions(t, "")
+	opts.KubeClients = fakeCluster([]runtime.Object{readyNode("server-1"), agentNode("agent-1")}, longhornNode("agent-1"))
+
+	require.NoError(t, run(opts, "remove-node", "agent-1", "--yes", "--ssh", "root@10.0.0.6"))
+	assert.Equal(t, "root@10.0.0.6", sshTarget)
+	assert.Equal(t, "sudo /usr/local/bin/k3s-agent-uninstall.sh", sshCommand)
+	assert.Contains(t, out.String(), "removed and cleaned up")
+}
+
+func TestRemoveNode_Server(t *testing.T) {
+	original := runSSH
+	t.Cleanup(func() { runSSH = original })
+	var sshCommand string
+	runSSH = func(ctx context.Context, target, command string, out chan<- string) error {
+		sshCommand = command
+		return nil
+	}
+
+	opts, out := testOptions(t, "")
+	opts.KubeClients = fakeCluster([]runtime.Object{readyNode("server-1"), readyNode("server-2")}, longhornNode("server-2"))
+	require.NoError(t, run(opts, "remove-node", "server-2", "--yes"))
+	assert.Contains(t, out.String(), "sudo /usr/local/bin/k3s-uninstall.sh")
+	assert.NotContains(t, out.String(), "k3s-agent-uninstall.sh")
+
+	opts, _ = testOptions(t, "")
+	opts.KubeClients = fakeCluster([]runtime.Object{readyNode("server-1"), readyNode("server-2")}, longhornNode("server-2"))
+	require.NoError(t, run(opts, "remove-node", "server-2", "--yes", "--ssh", "root@10.0.0.5"))
+	assert.Equal(t, "sudo /usr/local/bin/k3s-uninstall.sh", sshCommand)
+}
@@ -0,0 +1,402 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/unbindapp/unbind-installer/internal/backup"
+	"github.com/unbindapp/unbind-installer/internal/config"
+	"github.com/unbindapp/unbind-installer/internal/installer"
+	"github.com/unbindapp/unbind-installer/internal/k3s"
+	"github.com/unbindapp/unbind-installer/internal/longhorn"
+	"github.com/unbindapp/unbind-installer/internal/network"
+	"github.com/unbindapp/unbind-installer/internal/osinfo"
+	"github.com/unbindapp/unbind-installer/internal/pkgmanager"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Mockable for tests, the real ones install packages, K3s and the CLI on this server
+var (
+	installDependencies   = installSystemPackages
+	installK3s            = installK3sServer
+	restoreFreshDatastore = backup.RestoreOnFreshServer
+	installCLIBinary      = installer.InstallCLIBinary
+	checkEnvironment      = checkHostEnvironment
+)
+
+// nodePollInterval is how often restores check whether this server joined the restored cluster
+var nodePollInterval = 5 * time.Second
+
+func newRestoreServerCommand(opts Options) *cobra.Command {
+	var (
+		target               targetFlags
+		registryPasswordFile string
+		packageSource        string
+		yes                  bool
+		verbose              bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "restore-server <backup>",
+		Short: "Rebuild an Unbind installation on a fresh server from a backup",
+		Long: "Rebuild an Unbind installation on a fresh server from a backup.\n\n" +
+			"The backup is a name from unbind backup list, or the path of an archive on this server.\n" +
+			"The K3s version the backup was taken with is installed, the cluster state and Unbind\n" +
+			"configuration are restored, Longhorn volumes are restored from their latest backups on\n" +
+			"the Longhorn backup target, then the platform is synced with the saved domains.\n\n" +
+			"Run it with the installer binary, e.g. ./unbind-installer restore-server <backup>.",
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if _, err := os.Stat(opts.UninstallScriptPath); err == nil {
+				return fmt.Errorf("Unbind is already installed on this server, use unbind restore to roll it back to a backup")
+			}
+
+			p := newPrinter(opts.Stdout)
+			p.Banner()
+
+			plan, err := planRestore(cmd.Context(), opts, p, &target, args[0], registryPasswordFile, packageSource)
+			if err != nil {
+				return err
+			}
+			defer plan.cleanup()
+
+			plan.print(p)
+			if !yes && !confirm(opts.Stdin, opts.Stdout, "Install K3s and restore the backup? (y/N) ") {
+				p.Info("Restore cancelled.")
+				return nil
+			}
+
+			if err := plan.installCluster(cmd.Context(), opts, p, yes, verbose); err != nil {
+				return err
+			}
+			if err := plan.recover(cmd.Context(), opts, p, verbose); err != nil {
+				return err
+			}
+
+			p.Success(fmt.Sprintf("Unbind is restored at https://%s", plan.cfg.UnbindDomain))
+			if valid, behindCF := validateDomain(plan.cfg.UnbindDomain, plan.ips.ExternalIP, true, func(string) {}); !valid && !behindCF {
+				p.Colored(p.warning, "%s doesn't resolve to this server yet, point its DNS records at %s", plan.cfg.UnbindDomain, plan.ips.ExternalIP)
+			}
+			if plan.manifest.Datastore == backup.DatastoreEtcd {
+				p.Subtle("Other servers of the old cluster must be reinstalled and joined again with unbind add-node.")
+			}
+			return nil
+		},
+	}
+
+	target.register(cmd)
+	cmd.Flags().StringVar(&registryPasswordFile, "registry-password-file", "", "read the external registry password or key from this file instead of prompting")
+	cmd.Flags().StringVar(&packageSource, "package-source", "", packageSourceUsage)
+	cmd.Flags().BoolVarP(&yes, "yes", "y", false, "skip the confirmation prompt")
+	cmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "show the full installer log")
+	return cmd
+}
+
+// packageSourceUsage describes the --package-source flag of the commands that install packages
+const packageSourceUsage = "install the required packages from a directory of package files or a file:// repository, see download-packages"
+
+// restorePlan is a backup extracted and ready to be restored onto this server
+type restorePlan struct {
+	workDir    string
+	manifest   *backup.Manifest
+	cfg        *config.Config
+	k3sVersion string
+	ips        *network.IPInfo
+	syncOpts   installer.SyncHelmfileOptions
+	// Local packages to install from, nil for the configured repositories
+	packageSource *pkgmanager.LocalSource
+}
+
+// planRestore fetches a backup and gathers everything the restore needs before anything on
+// this server changes, so missing input fails early
+func planRestore(ctx context.Context, opts Options, p *printer, target *targetFlags, name, registryPasswordFile, packageSource string) (*restorePlan, error) {
+	var source *pkgmanager.LocalSource
+	if packageSource != "" {
+		var err error
+		if source, err = pkgmanager.ParseLocalSource(packageSource); err != nil {
+			return nil, err
+		}
+	}
+
+	p.Colored(p.info, "Fetching %s...", name)
+	workDir, manifest, err := fetchBackup(ctx, target, name)
+	if err != nil {
+		return nil, err
+	}
+	plan := &restorePlan{workDir: workDir, manifest: manifest, packageSource: source}
+
+	plan.cfg, err = config.Load(filepath.Join(backup.ExtractedConfigDir(workDir), filepath.Base(opts.ConfigPath)))
+	if err != nil {
+		plan.cleanup()
+		return nil, fmt.Errorf("the backup has no Unbind configuration, it can't be restored onto a new server: %w", err)
+	}
+
+	plan.k3sVersion = manifest.K3sVersion
+	if plan.k3sVersion == "" {
+		plan.k3sVersion = plan.cfg.K3sVersion
+	}
+	if plan.k3sVersion == "" {
+		plan.k3sVersion = k3s.K3S_VERSION
+		p.Colored(p.warning, "The backup doesn't record its K3s version, installing %s", plan.k3sVersion)
+	}
+
+	if plan.ips, err = detectIPs(func(string) {}); err != nil {
+		plan.cleanup()
+		return nil, fmt.Errorf("failed to detect this server's IP addresses: %w", err)
+	}
+
+	plan.syncOpts = platformSyncOptions(plan.cfg)
+	if plan.syncOpts.DisableRegistry && plan.syncOpts.RegistryExistingSecret == "" {
+		password, err := readRegistryPassword(opts, plan.cfg, registryPasswordFile)
+		if err != nil {
+			plan.cleanup()
+			return nil, err
+		}
+		plan.syncOpts.RegistryPassword = password
+	}
+	return plan, nil
+}
+
+func (self *restorePlan) cleanup() {
+	os.RemoveAll(self.workDir)
+}
+
+// print summarizes what will be restored
+func (self *restorePlan) print(p *printer) {
+	p.Info("Restore onto this server")
+	p.Line("  Backup:  taken on %s at %s", self.manifest.Hostname, self.manifest.CreatedAt.Local().Format("2006-01-02 15:04 MST"))
+	p.Line("  K3s:     %s (%s datastore)", self.k3sVersion, self.manifest.Datastore)
+	p.Line("  Unbind:  https://%s", self.cfg.UnbindDomain)
+	if self.cfg.LonghornBackupTarget != "" {
+		p.Line("  Volumes: restored from %s", self.cfg.LonghornBackupTarget)
+	} else {
+		p.Line("  Volumes: no Longhorn backup target was configured, volume data can't be restored")
+	}
+	p.Line("")
+}
+
+// installCluster installs the required packages and K3s, then restores the cluster state
+func (self *restorePlan) installCluster(ctx context.Context, opts Options, p *printer, yes, verbose bool) error {
+	if err := checkEnvironment(p); err != nil {
+		return err
+	}
+
+	logOut := io.Discard
+	if verbose {
+		logOut = opts.Stdout
+	}
+
+	// Extra repositories some packages need are only enabled with consent, and so is the system
+	// upgrade pacman runs along with the install
+	consent := packageConsent{
+		repositories: func(repos []pkgmanager.Repository) bool {
+			return yes || confirm(opts.Stdin, opts.Stdout,
+				fmt.Sprintf("Enable the %s repositories to install the required packages? (y/N) ", pkgmanager.RepositoryNames(repos)))
+		},
+		systemUpgrade: func() bool {
+			p.Colored(p.warning, "pacman upgrades the whole system to install packages, reboot the server afterwards if the kernel is upgraded.")
+			return yes || confirm(opts.Stdin, opts.Stdout, "Upgrade the system to install the required packages? (y/N) ")
+		},
+	}
+
+	p.Colored(p.info, "Installing required packages...")
+	if err := runWithLogs(logOut, func(logChan chan<- string) error {
+		return installDependencies(ctx, self.packageSource, consent, logChan)
+	}); err != nil {
+		return fmt.Errorf("failed to install required packages: %w", err)
+	}
+
+	p.Colored(p.info, "Installing K3s %s, this can take a few minutes...", self.k3sVersion)
+	if err := runWithLogs(logOut, func(logChan chan<- string) error {
+		return installK3s(ctx, self.k3sVersion, self.manifest.Datastore == backup.DatastoreEtcd, logChan)
+	}); err != nil {
+		return fmt.Errorf("failed to install K3s: %w", err)
+	}
+
+	p.Colored(p.info, "Restoring the cluster state...")
+	return runWithLogs(opts.Stdout, func(logChan chan<- string) error {
+		return restoreFreshDatastore(ctx, self.workDir, self.manifest, opts.BackupPaths, func(message string) { logChan <- message })
+	})
+}
+
+// recover brings the restored cluster back up on this server: the lost server's node is removed,
+// Longhorn volumes are restored from their backups and the platform is synced. The configuration
+// is saved with this server's address and the management CLI is installed.
+func (self *restorePlan) recover(ctx context.Context, opts Options, p *printer, verbose bool) error {
+	kubeClient, dynamicClient, err := opts.KubeClients(opts.KubeConfigPath)
+	if err != nil {
+		return err
+	}
+	logFn := func(message string) { p.Subtle("  %s", message) }
+	if err := replaceLostNode(ctx, kubeClient, self.manifest.Hostname, logFn); err != nil {
+		return err
+	}
+
+	if self.cfg.LonghornBackupTarget != "" {
+		p.Colored(p.info, "Restoring Longhorn volumes...")
+		credentialSecret := ""
+		if strings.HasPrefix(self.cfg.LonghornBackupTarget, "s3://") {
+			credentialSecret = longhorn.CredentialSecretName
+		}
+
+		longhornCtx, cancel := context.WithTimeout(ctx, 15*time.Minute)
+		err := longhorn.ReconnectBackupTarget(longhornCtx, dynamicClient, self.cfg.LonghornBackupTarget, credentialSecret, logFn)
+		if err == nil {
+			var missing []string
+			_, missing, err = longhorn.RestoreVolumes(longhornCtx, dynamicClient, logFn)
+			if len(missing) > 0 {
+				p.Colored(p.warning, "%d volume(s) had no backup and will be empty: %s", len(missing), strings.Join(missing, ", "))
+			}
+		}
+		cancel()
+		if err != nil {
+			return fmt.Errorf("failed to restore Longhorn volumes: %w", err)
+		}
+	}
+
+	p.Colored(p.warning, "Updating Unbind, this can take a few minutes...")
+	if err := runHelmfileSync(ctx, opts, self.syncOpts, verbose); err != nil {
+		return fmt.Errorf("failed to update Unbind: %w", err)
+	}
+
+	self.cfg.ClusterIP = self.ips.InternalIP
+	self.cfg.K3sVersion = self.k3sVersion
+	if err := self.cfg.Save(opts.ConfigPath); err != nil {
+		return err
+	}
+	return installCLIBinary(installer.ManagementCLIPath)
+}
+
+// replaceLostNode waits for this server to become ready in the restored cluster, then deletes the
+// node of the server the backup was taken on so its pods are rescheduled here
+func replaceLostNode(ctx context.Context, kubeClient kubernetes.Interface, lost string, logFn func(string)) error {
+	hostname, err := os.Hostname()
+	if err != nil {
+		return err
+	}
+	current := strings.ToLower(hostname)
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Minute)
+	defer cancel()
+
+	logFn(fmt.Sprintf("Waiting for %s to be ready...", current))
+	for {
+		node, err := kubeClient.CoreV1().Nodes().Get(ctx, current, metav1.GetOptions{})
+		if err == nil && nodeReady(node) {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%s didn't become ready in the restored cluster: %w", current, ctx.Err())
+		case <-time.After(nodePollInterval):
+		}
+	}
+
+	lost = strings.ToLower(lost)
+	if lost == "" || lost == current {
+		return nil
+	}
+	logFn(fmt.Sprintf("Removing node %s, the server the backup was taken on...", lost))
+	if err := kubeClient.CoreV1().Nodes().Delete(ctx, lost, metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("failed to remove node %s: %w", lost, err)
+	}
+	return nil
+}
+
+func nodeReady(node *corev1.Node) bool {
+	for _, condition := range node.Status.Conditions {
+		if condition.Type == corev1.NodeReady {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// checkHostEnvironment fails in containers and other environments K3s can't run in, and prints
+// warnings for those where parts of Unbind won't work
+func checkHostEnvironment(p *printer) error {
+	info, err := osinfo.GetOSInfo()
+	if err != nil {
+		return err
+	}
+	for _, issue := range osinfo.EnvironmentIssues(info) {
+		if issue.Blocking {
+			return errors.New(issue.Message)
+		}
+		p.Colored(p.warning, "%s", issue.Message)
+	}
+	return nil
+}
+
+// packageConsent asks before installing packages changes more than the packages themselves
+type packageConsent struct {
+	repositories  func(repos []pkgmanager.Repository) bool
+	systemUpgrade func() bool
+}
+
+// installSystemPackages installs the packages the installer needs on this distribution, from source
+// when it isn't nil. Consent is asked before enabling the extra repositories some of them come from
+// and before upgrading the whole system.
+func installSystemPackages(ctx context.Context, source *pkgmanager.LocalSource, consent packageConsent, logChan chan<- string) error {
+	info, err := osinfo.GetOSInfo()
+	if err != nil {
+		return err
+	}
+	manager, err := pkgmanager.NewPackageManager(info.Distribution, logChan, pkgmanager.DefaultLockTimeout, source)
+	if err != nil {
+		return err
+	}
+
+	packages := pkgmanager.GetDistributionPackages(info.Distribution)
+	// Local package sources have to hold the packages from extra repositories themselves, they skip
+	// the SELinux policy which the K3s installer adds itself
+	if source == nil {
+		packages = pkgmanager.GetRequiredPackages(info)
+		missing, err := pkgmanager.MissingPackages(ctx, manager, packages)
+		if err != nil {
+			return err
+		}
+		repos, err := pkgmanager.RequiredRepositories(ctx, info, missing)
+		if err != nil {
+			return err
+		}
+		if len(repos) > 0 {
+			if !consent.repositories(repos) {
+				return fmt.Errorf("the %s repositories are needed to install %s", pkgmanager.RepositoryNames(repos), strings.Join(missing, ", "))
+			}
+			if err := pkgmanager.EnableRepositories(ctx, repos, pkgmanager.DefaultRepositoryRecordPath, func(message string) { logChan <- message }); err != nil {
+				return err
+			}
+		}
+		if len(missing) > 0 && pkgmanager.UpgradesSystem(info.Distribution, source) && !consent.systemUpgrade() {
+			return fmt.Errorf("installing %s on %s needs a full system upgrade", strings.Join(missing, ", "), info.Distribution)
+		}
+	}
+
+	_, err = pkgmanager.InstallMissing(ctx, manager, packages, pkgmanager.DefaultRecordPath, nil)
+	return err
+}
+
+// installK3sServer installs a K3s server of the given version
+func installK3sServer(ctx context.Context, version string, clusterInit bool, logChan chan<- string) error {
+	k3sInstaller := k3s.NewInstaller(logChan, nil, nil)
+	k3sInstaller.Version = version
+	k3sInstaller.ClusterInit = clusterInit
+	if info, err := osinfo.GetOSInfo(); err == nil {
+		k3sInstaller.MemoryTotal = info.MemoryTotal
+		k3sInstaller.CPUCount = info.CPUCount
+		k3sInstaller.SELinux = info.SELinux
+	}
+	_, err := k3sInstaller.Install(ctx)
+	return err
+}
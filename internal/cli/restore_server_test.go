@@ -0,0 +1,154 @@
+package cli
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/unbindapp/unbind-installer/internal/backup"
+	"github.com/unbindapp/unbind-installer/internal/config"
+	"github.com/unbindapp/unbind-installer/internal/longhorn"
+	"github.com/unbindapp/unbind-installer/internal/pkgmanager"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// serverRestore records what restore-server did to this server
+type serverRestore struct {
+	k3sVersion  string
+	clusterInit bool
+	restored    *backup.Manifest
+	cliPath     string
+}
+
+func fakeServerRestore(t *testing.T) *serverRestore {
+	originalDeps, originalK3s, originalRestore, originalCLI := installDependencies, installK3s, restoreFreshDatastore, installCLIBinary
+	originalCheck := checkEnvironment
+	t.Cleanup(func() {
+		installDependencies, installK3s, restoreFreshDatastore, installCLIBinary = originalDeps, originalK3s, originalRestore, originalCLI
+		checkEnvironment = originalCheck
+	})
+	checkEnvironment = func(*printer) error { return nil }
+
+	restore := &serverRestore{}
+	installDependencies = func(ctx context.Context, source *pkgmanager.LocalSource, consent packageConsent, logChan chan<- string) error {
+		return nil
+	}
+	installK3s = func(ctx context.Context, version string, clusterInit bool, logChan chan<- string) error {
+		restore.k3sVersion, restore.clusterInit = version, clusterInit
+		return nil
+	}
+	restoreFreshDatastore = func(ctx context.Context, extractedDir string, manifest *backup.Manifest, paths backup.Paths, logFn func(string)) error {
+		restore.restored = manifest
+		return nil
+	}
+	installCLIBinary = func(path string) error {
+		restore.cliPath = path
+		return nil
+	}
+	return restore
+}
+
+func restoredCluster(t *testing.T) (kubernetes.Interface, dynamic.Interface) {
+	hostname, err := os.Hostname()
+	require.NoError(t, err)
+
+	kubeClient := fake.NewSimpleClientset(
+		&corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: strings.ToLower(hostname)},
+			Status:     corev1.NodeStatus{Conditions: []corev1.NodeCondition{{Type: corev1.NodeReady, Status: corev1.ConditionTrue}}},
+		},
+		&corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: "old-server"},
+			Status:     corev1.NodeStatus{Conditions: []corev1.NodeCondition{{Type: corev1.NodeReady, Status: corev1.ConditionUnknown}}},
+		},
+	)
+
+	backupTarget := longhornVolume("default", "", "")
+	backupTarget.SetKind("BackupTarget")
+	backupTarget.Object["status"] = map[string]interface{}{"available": true}
+	volume := longhornVolume("pvc-data", "detached", "faulted")
+	volume.Object["spec"] = map[string]interface{}{"size": "1073741824"}
+	completed := longhornVolume("backup-1", "", "")
+	completed.SetKind("Backup")
+	completed.SetLabels(map[string]string{"backup-volume": "pvc-data"})
+	completed.Object["status"] = map[string]interface{}{"state": "Completed", "url": "s3://backups@us-east-1/?backup=backup-1&volume=pvc-data"}
+
+	dynamicClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{
+			longhorn.VolumeResource:       "VolumeList",
+			longhorn.BackupResource:       "BackupList",
+			longhorn.BackupTargetResource: "BackupTargetList",
+		}, backupTarget, volume, completed)
+	return kubeClient, dynamicClient
+}
+
+func TestRestoreServer(t *testing.T) {
+	opts, out := testOptions(t, "")
+	require.NoError(t, os.Remove(opts.UninstallScriptPath))
+	restore := fakeServerRestore(t)
+	fakeDNS(t, false, "unbind.example.com")
+	synced := fakeSync(t)
+	kubeClient, dynamicClient := restoredCluster(t)
+	opts.KubeClients = func(string) (kubernetes.Interface, dynamic.Interface, error) { return kubeClient, dynamicClient, nil }
+
+	archive := writeTestArchive(t, &backup.Manifest{
+		FormatVersion: 1,
+		CreatedAt:     time.Date(2025, 6, 1, 3, 0, 0, 0, time.UTC),
+		Hostname:      "old-server",
+		Datastore:     backup.DatastoreSQLite,
+		Snapshot:      "datastore/state.db",
+		K3sVersion:    "v1.32.5+k3s1",
+	}, map[string]string{
+		"etc-unbind/config": "CLUSTER_IP=10.0.0.9\nUNBIND_DOMAIN=unbind.example.com\nREGISTRY_TYPE=self-hosted\n" +
+			"REGISTRY_DOMAIN=registry.example.com\nLONGHORN_BACKUP_TARGET=s3://backups@us-east-1/\n",
+	})
+
+	require.NoError(t, run(opts, "restore-server", archive, "--yes"))
+
+	assert.Equal(t, "v1.32.5+k3s1", restore.k3sVersion, "the K3s version the backup was taken with is installed")
+	assert.False(t, restore.clusterInit)
+	require.NotNil(t, restore.restored)
+	assert.Equal(t, "old-server", restore.restored.Hostname)
+	assert.Equal(t, "/usr/local/bin/unbind", restore.cliPath)
+
+	_, err := kubeClient.CoreV1().Nodes().Get(context.Background(), "old-server", metav1.GetOptions{})
+	assert.True(t, apierrors.IsNotFound(err), "the lost server's node is removed")
+
+	volume, err := dynamicClient.Resource(longhorn.VolumeResource).Namespace(longhorn.Namespace).Get(context.Background(), "pvc-data", metav1.GetOptions{})
+	require.NoError(t, err)
+	fromBackup, _, _ := unstructured.NestedString(volume.Object, "spec", "fromBackup")
+	assert.Equal(t, "s3://backups@us-east-1/?backup=backup-1&volume=pvc-data", fromBackup)
+
+	assert.Equal(t, "unbind.example.com", synced.UnbindDomain)
+	assert.Equal(t, "registry.example.com", synced.UnbindRegistryDomain)
+	require.NotNil(t, synced.LonghornBackup)
+	assert.True(t, synced.LonghornBackup.HasSecret)
+
+	saved, err := config.Load(opts.ConfigPath)
+	require.NoError(t, err)
+	assert.Equal(t, "10.0.0.5", saved.ClusterIP, "agents join at this server's address")
+	assert.Equal(t, "v1.32.5+k3s1", saved.K3sVersion)
+	assert.Contains(t, out.String(), "Unbind is restored at https://unbind.example.com")
+}
+
+func TestRestoreServer_RefusesExistingInstallation(t *testing.T) {
+	opts, _ := testOptions(t, "")
+	restore := fakeServerRestore(t)
+
+	err := run(opts, "restore-server", "unbind-backup-20250601T030000Z.tar.gz")
+	assert.ErrorContains(t, err, "Unbind is already installed on this server")
+	assert.Empty(t, restore.k3sVersion)
+}
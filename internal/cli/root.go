@@ -0,0 +1,176 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/unbindapp/unbind-installer/internal/backup"
+	"github.com/unbindapp/unbind-installer/internal/config"
+	"github.com/unbindapp/unbind-installer/internal/k3s"
+	"github.com/unbindapp/unbind-installer/internal/pkgmanager"
+	"golang.org/x/term"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+)
+
+// BinaryName is the name the management CLI is installed under, the installer
+// binary runs the CLI instead of the TUI when invoked with this name
+const BinaryName = "unbind"
+
+// Options holds paths and hooks the commands use, tests point them at fixtures
+type Options struct {
+	ConfigPath           string
+	NodeTokenPath        string
+	UninstallScriptPath  string
+	KubeConfigPath       string
+	PackageRecordPath    string // System packages the installer added
+	RepositoryRecordPath string // Package repositories the installer enabled
+	BackupPaths          backup.Paths
+	// ScheduleUnitDir overrides where the backup timer is installed
+	ScheduleUnitDir string
+
+	// K3sVersion reports the running k3s version
+	K3sVersion func() (string, error)
+	// ServiceState reports the systemd state of a service
+	ServiceState func(name string) string
+	// KubeClients connects to the cluster
+	KubeClients func(kubeConfigPath string) (kubernetes.Interface, dynamic.Interface, error)
+	// CertificateExpiry returns when the certificate served for a domain expires
+	CertificateExpiry func(ctx context.Context, domain string) (time.Time, error)
+	// HostResources reports disk and memory usage of this server
+	HostResources func() (*HostResources, error)
+	// Journal runs journalctl with args, writing its output to out
+	Journal func(ctx context.Context, args []string, out io.Writer) error
+
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+
+	// Set when stdin is a terminal, for reading passwords without echo
+	terminal *os.File
+}
+
+// DefaultOptions returns the options for a real server
+func DefaultOptions() Options {
+	return Options{
+		ConfigPath:           config.DefaultPath,
+		NodeTokenPath:        "/var/lib/rancher/k3s/server/node-token",
+		UninstallScriptPath:  k3s.K3sUninstallScriptPath,
+		KubeConfigPath:       "/etc/rancher/k3s/k3s.yaml",
+		PackageRecordPath:    pkgmanager.DefaultRecordPath,
+		RepositoryRecordPath: pkgmanager.DefaultRepositoryRecordPath,
+		BackupPaths:          backup.DefaultPaths(),
+		K3sVersion:           installedK3sVersion,
+		ServiceState:         systemdServiceState,
+		KubeClients:          newKubeClients,
+		CertificateExpiry:    servedCertificateExpiry,
+		HostResources:        localHostResources,
+		Journal:              runJournal,
+		Stdin:                os.Stdin,
+		Stdout:               os.Stdout,
+		Stderr:               os.Stderr,
+	}
+}
+
+// NewRootCommand builds the unbind command tree
+func NewRootCommand(version string, opts Options) *cobra.Command {
+	// Prompts share one buffered reader so piped answers aren't lost between them
+	if file, ok := opts.Stdin.(*os.File); ok && term.IsTerminal(int(file.Fd())) {
+		opts.terminal = file
+	}
+	opts.Stdin = bufio.NewReader(opts.Stdin)
+
+	root := &cobra.Command{
+		Use:           BinaryName,
+		Short:         "Manage your Unbind installation",
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		Version:       version,
+	}
+	root.SetIn(opts.Stdin)
+	root.SetOut(opts.Stdout)
+	root.SetErr(opts.Stderr)
+	root.SetVersionTemplate("unbind {{.Version}}\n")
+
+	root.AddCommand(
+		newUninstallCommand(opts),
+		newAddNodeCommand(opts),
+		newRemoveNodeCommand(opts),
+		newTokenCommand(opts),
+		newStatusCommand(opts),
+		newLogsCommand(opts),
+		newReconfigureDomainCommand(opts),
+		newBackupCommand(version, opts),
+		newRestoreCommand(opts),
+		newRestoreServerCommand(opts),
+		newMigrateCommand(version, opts),
+		newDownloadPackagesCommand(opts),
+		newVersionCommand(version),
+	)
+
+	return root
+}
+
+// Execute runs the CLI with args and returns the process exit code
+func Execute(version string, args []string) int {
+	opts := DefaultOptions()
+	root := NewRootCommand(version, opts)
+	root.SetArgs(args)
+
+	if err := root.Execute(); err != nil {
+		var exit exitError
+		if errors.As(err, &exit) {
+			return exit.code
+		}
+		newPrinter(opts.Stderr).Error(err.Error())
+		return 1
+	}
+	return 0
+}
+
+// exitError ends the CLI with an exit code after the command already reported why
+type exitError struct {
+	code int
+}
+
+func (self exitError) Error() string {
+	return fmt.Sprintf("exit status %d", self.code)
+}
+
+// loadConfig reads the cluster config, explaining what's wrong when it's missing
+func loadConfig(opts Options) (*config.Config, error) {
+	cfg, err := config.Load(opts.ConfigPath)
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("could not find the Unbind configuration file %s", opts.ConfigPath)
+	}
+	return cfg, err
+}
+
+// checkInstallation fails unless this server runs an Unbind (k3s server) installation
+func checkInstallation(opts Options) error {
+	if _, err := os.Stat(opts.UninstallScriptPath); err != nil {
+		return fmt.Errorf("no Unbind installation detected, this command should only be run on a server with Unbind installed")
+	}
+	return nil
+}
+
+// installedK3sVersion parses `k3s --version`, e.g. "k3s version v1.33.1+k3s1 (99d91538)"
+func installedK3sVersion() (string, error) {
+	output, err := exec.Command("k3s", "--version").Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to run k3s --version: %w", err)
+	}
+	fields := strings.Fields(strings.SplitN(string(output), "\n", 2)[0])
+	if len(fields) < 3 {
+		return "", fmt.Errorf("unexpected k3s --version output: %s", strings.TrimSpace(string(output)))
+	}
+	return fields[2], nil
+}
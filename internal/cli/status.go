@@ -0,0 +1,146 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+func newStatusCommand(opts Options) *cobra.Command {
+	var asJSON bool
+
+	cmd := &cobra.Command{
+		Use:   "status",
+		Short: "Show the health of the cluster and the Unbind platform",
+		Long: "Show the health of the cluster and the Unbind platform.\n\n" +
+			"Exits with status 1 when any check reports a problem, so it can be used from monitoring.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := checkInstallation(opts); err != nil {
+				return err
+			}
+
+			ctx, cancel := context.WithTimeout(cmd.Context(), 2*time.Minute)
+			defer cancel()
+			report := collectStatus(ctx, opts)
+
+			if asJSON {
+				encoder := json.NewEncoder(opts.Stdout)
+				encoder.SetIndent("", "  ")
+				if err := encoder.Encode(report); err != nil {
+					return err
+				}
+			} else {
+				printStatus(newPrinter(opts.Stdout), report)
+			}
+
+			if !report.Healthy {
+				return exitError{code: 1}
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&asJSON, "json", false, "print the report as JSON")
+	return cmd
+}
+
+// printStatus renders the report for humans
+func printStatus(p *printer, report *StatusReport) {
+	check := func(ok bool) string {
+		if ok {
+			return p.colored(p.success, "✓")
+		}
+		return p.colored(p.error, "✗")
+	}
+
+	p.Bold("K3s")
+	p.Line("  %s %s service is %s", check(report.K3s.Active), report.K3s.Name, report.K3s.State)
+	if report.Host != nil {
+		p.Line("  %s disk %.0f%% used, memory %.0f%% used",
+			check(report.Host.DiskUsedPercent < hostPressurePercent && report.Host.MemoryUsedPercent < hostPressurePercent),
+			report.Host.DiskUsedPercent, report.Host.MemoryUsedPercent)
+	}
+	p.Line("")
+
+	if len(report.Nodes) > 0 {
+		p.Bold("Nodes")
+		for _, node := range report.Nodes {
+			state := "Ready"
+			if !node.Ready {
+				state = "NotReady"
+			}
+			pressure := []string{}
+			if node.DiskPressure {
+				pressure = append(pressure, "disk")
+			}
+			if node.MemoryPressure {
+				pressure = append(pressure, "memory")
+			}
+			if node.PIDPressure {
+				pressure = append(pressure, "pid")
+			}
+			details := fmt.Sprintf("%s %s", state, node.Version)
+			if len(node.Roles) > 0 {
+				details += " (" + strings.Join(node.Roles, ",") + ")"
+			}
+			if len(pressure) > 0 {
+				details += ", pressure: " + strings.Join(pressure, ", ")
+			}
+			p.Line("  %s %s: %s", check(node.Ready && len(pressure) == 0), node.Name, details)
+		}
+		p.Line("")
+	}
+
+	if len(report.Releases) > 0 {
+		p.Bold("Helm releases")
+		for _, release := range report.Releases {
+			p.Line("  %s %s/%s: %s (revision %d)", check(release.Status == "deployed"),
+				release.Namespace, release.Name, release.Status, release.Revision)
+		}
+		p.Line("")
+	}
+
+	if len(report.Pods) > 0 {
+		p.Bold("Pods")
+		for _, pods := range report.Pods {
+			p.Line("  %s %s: %d/%d healthy", check(len(pods.Unhealthy) == 0), pods.Namespace, pods.Healthy, pods.Total)
+			for _, pod := range pods.Unhealthy {
+				p.Subtle("      %s: %s, %d restarts", pod.Name, pod.Reason, pod.Restarts)
+			}
+		}
+		p.Line("")
+	}
+
+	if len(report.Certificates) > 0 {
+		p.Bold("Certificates")
+		for _, certificate := range report.Certificates {
+			if certificate.Error != "" {
+				p.Line("  %s %s: %s", check(false), certificate.Domain, certificate.Error)
+				continue
+			}
+			p.Line("  %s %s: expires %s (%d days)", check(time.Until(*certificate.NotAfter) >= certificateWarningPeriod),
+				certificate.Domain, certificate.NotAfter.Format("2006-01-02"), certificate.DaysLeft)
+		}
+		p.Line("")
+	}
+
+	if len(report.Volumes) > 0 {
+		p.Bold("Longhorn volumes")
+		for _, volume := range report.Volumes {
+			healthy := volume.State != "attached" || volume.Robustness == "healthy"
+			p.Line("  %s %s: %s, %s", check(healthy), volume.Name, volume.State, volume.Robustness)
+		}
+		p.Line("")
+	}
+
+	if report.Healthy {
+		p.Success("Unbind is healthy")
+		return
+	}
+	p.Box(fmt.Sprintf("%d problem(s) found\n\n• %s", len(report.Problems), strings.Join(report.Problems, "\n• ")), p.error)
+}
@@ -0,0 +1,452 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// Namespaces whose pods the status command checks
+var statusNamespaces = []string{"unbind-system", "longhorn-system"}
+
+var longhornVolumeResource = schema.GroupVersionResource{
+	Group:    "longhorn.io",
+	Version:  "v1beta2",
+	Resource: "volumes",
+}
+
+const (
+	// Certificates expiring sooner than this are reported as a problem
+	certificateWarningPeriod = 14 * 24 * time.Hour
+	// Host disk or memory usage above this percentage is reported as a problem
+	hostPressurePercent = 90
+)
+
+// StatusReport is the health overview printed by unbind status
+type StatusReport struct {
+	Healthy      bool                `json:"healthy"`
+	Problems     []string            `json:"problems"`
+	CheckedAt    time.Time           `json:"checkedAt"`
+	K3s          ServiceStatus       `json:"k3s"`
+	Host         *HostResources      `json:"host,omitempty"`
+	Nodes        []NodeStatus        `json:"nodes"`
+	Releases     []ReleaseStatus     `json:"releases"`
+	Pods         []NamespacePods     `json:"pods"`
+	Certificates []CertificateStatus `json:"certificates"`
+	Volumes      []VolumeStatus      `json:"volumes"`
+}
+
+type ServiceStatus struct {
+	Name   string `json:"name"`
+	State  string `json:"state"`
+	Active bool   `json:"active"`
+}
+
+type HostResources struct {
+	DiskUsedPercent   float64 `json:"diskUsedPercent"`
+	MemoryUsedPercent float64 `json:"memoryUsedPercent"`
+}
+
+type NodeStatus struct {
+	Name           string   `json:"name"`
+	Roles          []string `json:"roles"`
+	Version        string   `json:"version"`
+	Ready          bool     `json:"ready"`
+	DiskPressure   bool     `json:"diskPressure"`
+	MemoryPressure bool     `json:"memoryPressure"`
+	PIDPressure    bool     `json:"pidPressure"`
+}
+
+type ReleaseStatus struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+	Revision  int    `json:"revision"`
+	Status    string `json:"status"`
+}
+
+type NamespacePods struct {
+	Namespace string       `json:"namespace"`
+	Total     int          `json:"total"`
+	Healthy   int          `json:"healthy"`
+	Unhealthy []PodProblem `json:"unhealthy"`
+}
+
+type PodProblem struct {
+	Name     string `json:"name"`
+	Phase    string `json:"phase"`
+	Reason   string `json:"reason"`
+	Restarts int32  `json:"restarts"`
+}
+
+type CertificateStatus struct {
+	Domain   string     `json:"domain"`
+	NotAfter *time.Time `json:"notAfter,omitempty"`
+	DaysLeft int        `json:"daysLeft"`
+	Error    string     `json:"error,omitempty"`
+}
+
+type VolumeStatus struct {
+	Name       string `json:"name"`
+	State      string `json:"state"`
+	Robustness string `json:"robustness"`
+}
+
+// collectStatus gathers the health of the installation, each failed check becomes a problem
+func collectStatus(ctx context.Context, opts Options) *StatusReport {
+	report := &StatusReport{
+		CheckedAt: time.Now().UTC(),
+		Problems:  []string{},
+	}
+	problem := func(format string, args ...interface{}) {
+		report.Problems = append(report.Problems, fmt.Sprintf(format, args...))
+	}
+
+	report.K3s = ServiceStatus{Name: "k3s", State: opts.ServiceState("k3s")}
+	report.K3s.Active = report.K3s.State == "active"
+	if !report.K3s.Active {
+		problem("k3s service is %s", report.K3s.State)
+	}
+
+	if host, err := opts.HostResources(); err != nil {
+		problem("failed to read host resources: %v", err)
+	} else {
+		report.Host = host
+		if host.DiskUsedPercent >= hostPressurePercent {
+			problem("host disk is %.0f%% full", host.DiskUsedPercent)
+		}
+		if host.MemoryUsedPercent >= hostPressurePercent {
+			problem("host memory is %.0f%% used", host.MemoryUsedPercent)
+		}
+	}
+
+	if cfg, err := loadConfig(opts); err != nil {
+		problem("%v", err)
+	} else {
+		for _, domain := range []string{cfg.UnbindDomain, cfg.RegistryDomain} {
+			if domain == "" {
+				continue
+			}
+			certificate := CertificateStatus{Domain: domain}
+			notAfter, err := opts.CertificateExpiry(ctx, domain)
+			if err != nil {
+				certificate.Error = err.Error()
+				problem("failed to check the certificate for %s: %v", domain, err)
+			} else {
+				certificate.NotAfter = &notAfter
+				certificate.DaysLeft = int(time.Until(notAfter).Hours() / 24)
+				if time.Until(notAfter) < certificateWarningPeriod {
+					problem("certificate for %s expires in %d days", domain, certificate.DaysLeft)
+				}
+			}
+			report.Certificates = append(report.Certificates, certificate)
+		}
+	}
+
+	if !report.K3s.Active {
+		report.Healthy = false
+		return report
+	}
+
+	clientset, dynamicClient, err := opts.KubeClients(opts.KubeConfigPath)
+	if err != nil {
+		problem("failed to connect to the cluster: %v", err)
+		return report
+	}
+
+	if err := collectNodes(ctx, clientset, report); err != nil {
+		problem("failed to list nodes: %v", err)
+	}
+	if err := collectReleases(ctx, clientset, report); err != nil {
+		problem("failed to list helm releases: %v", err)
+	}
+	for _, namespace := range statusNamespaces {
+		if err := collectPods(ctx, clientset, namespace, report); err != nil {
+			problem("failed to list pods in %s: %v", namespace, err)
+		}
+	}
+	if err := collectVolumes(ctx, dynamicClient, report); err != nil {
+		problem("failed to list longhorn volumes: %v", err)
+	}
+
+	for _, node := range report.Nodes {
+		if !node.Ready {
+			problem("node %s is not ready", node.Name)
+		}
+		if node.DiskPressure {
+			problem("node %s has disk pressure", node.Name)
+		}
+		if node.MemoryPressure {
+			problem("node %s has memory pressure", node.Name)
+		}
+		if node.PIDPressure {
+			problem("node %s has PID pressure", node.Name)
+		}
+	}
+	for _, release := range report.Releases {
+		if release.Status != "deployed" {
+			problem("helm release %s/%s is %s", release.Namespace, release.Name, release.Status)
+		}
+	}
+	for _, pods := range report.Pods {
+		for _, pod := range pods.Unhealthy {
+			problem("pod %s/%s is %s", pods.Namespace, pod.Name, pod.Reason)
+		}
+	}
+	for _, volume := range report.Volumes {
+		if volume.State == "attached" && volume.Robustness != "healthy" {
+			problem("longhorn volume %s is %s", volume.Name, volume.Robustness)
+		}
+	}
+
+	report.Healthy = len(report.Problems) == 0
+	return report
+}
+
+func collectNodes(ctx context.Context, clientset kubernetes.Interface, report *StatusReport) error {
+	nodes, err := clientset.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+
+	for _, node := range nodes.Items {
+		status := NodeStatus{
+			Name:    node.Name,
+			Version: node.Status.NodeInfo.KubeletVersion,
+			Roles:   []string{},
+		}
+		for label := range node.Labels {
+			if role, ok := strings.CutPrefix(label, "node-role.kubernetes.io/"); ok {
+				status.Roles = append(status.Roles, role)
+			}
+		}
+		sort.Strings(status.Roles)
+
+		for _, condition := range node.Status.Conditions {
+			isTrue := condition.Status == corev1.ConditionTrue
+			switch condition.Type {
+			case corev1.NodeReady:
+				status.Ready = isTrue
+			case corev1.NodeDiskPressure:
+				status.DiskPressure = isTrue
+			case corev1.NodeMemoryPressure:
+				status.MemoryPressure = isTrue
+			case corev1.NodePIDPressure:
+				status.PIDPressure = isTrue
+			}
+		}
+		report.Nodes = append(report.Nodes, status)
+	}
+	sort.Slice(report.Nodes, func(i, j int) bool { return report.Nodes[i].Name < report.Nodes[j].Name })
+	return nil
+}
+
+// collectReleases reads the release secrets helm keeps for every revision, the newest revision
+// of each release carries its current status in the labels
+func collectReleases(ctx context.Context, clientset kubernetes.Interface, report *StatusReport) error {
+	secrets, err := clientset.CoreV1().Secrets(metav1.NamespaceAll).List(ctx, metav1.ListOptions{
+		LabelSelector: "owner=helm",
+	})
+	if err != nil {
+		return err
+	}
+
+	latest := map[string]ReleaseStatus{}
+	for _, secret := range secrets.Items {
+		revision, _ := strconv.Atoi(secret.Labels["version"])
+		release := ReleaseStatus{
+			Name:      secret.Labels["name"],
+			Namespace: secret.Namespace,
+			Revision:  revision,
+			Status:    secret.Labels["status"],
+		}
+		key := release.Namespace + "/" + release.Name
+		if current, ok := latest[key]; !ok || release.Revision > current.Revision {
+			latest[key] = release
+		}
+	}
+
+	for _, release := range latest {
+		report.Releases = append(report.Releases, release)
+	}
+	sort.Slice(report.Releases, func(i, j int) bool {
+		if report.Releases[i].Namespace != report.Releases[j].Namespace {
+			return report.Releases[i].Namespace < report.Releases[j].Namespace
+		}
+		return report.Releases[i].Name < report.Releases[j].Name
+	})
+	return nil
+}
+
+func collectPods(ctx context.Context, clientset kubernetes.Interface, namespace string, report *StatusReport) error {
+	pods, err := clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+
+	summary := NamespacePods{Namespace: namespace, Unhealthy: []PodProblem{}}
+	for _, pod := range pods.Items {
+		summary.Total++
+		if reason, healthy := podHealth(pod); healthy {
+			summary.Healthy++
+		} else {
+			var restarts int32
+			for _, container := range pod.Status.ContainerStatuses {
+				restarts += container.RestartCount
+			}
+			summary.Unhealthy = append(summary.Unhealthy, PodProblem{
+				Name:     pod.Name,
+				Phase:    string(pod.Status.Phase),
+				Reason:   reason,
+				Restarts: restarts,
+			})
+		}
+	}
+	report.Pods = append(report.Pods, summary)
+	return nil
+}
+
+// podHealth reports whether a pod is healthy, and why not if it isn't.
+// Completed job pods count as healthy.
+func podHealth(pod corev1.Pod) (string, bool) {
+	switch pod.Status.Phase {
+	case corev1.PodSucceeded:
+		return "", true
+	case corev1.PodRunning:
+		for _, container := range pod.Status.ContainerStatuses {
+			if container.State.Waiting != nil && container.State.Waiting.Reason != "" {
+				return container.State.Waiting.Reason, false
+			}
+			if !container.Ready {
+				return "not ready", false
+			}
+		}
+		return "", true
+	default:
+		for _, container := range pod.Status.ContainerStatuses {
+			if container.State.Waiting != nil && container.State.Waiting.Reason != "" {
+				return container.State.Waiting.Reason, false
+			}
+		}
+		if pod.Status.Reason != "" {
+			return pod.Status.Reason, false
+		}
+		return strings.ToLower(string(pod.Status.Phase)), false
+	}
+}
+
+func collectVolumes(ctx context.Context, dynamicClient dynamic.Interface, report *StatusReport) error {
+	volumes, err := dynamicClient.Resource(longhornVolumeResource).Namespace("longhorn-system").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+
+	for _, volume := range volumes.Items {
+		state, _, _ := unstructured.NestedString(volume.Object, "status", "state")
+		robustness, _, _ := unstructured.NestedString(volume.Object, "status", "robustness")
+		report.Volumes = append(report.Volumes, VolumeStatus{
+			Name:       volume.GetName(),
+			State:      state,
+			Robustness: robustness,
+		})
+	}
+	sort.Slice(report.Volumes, func(i, j int) bool { return report.Volumes[i].Name < report.Volumes[j].Name })
+	return nil
+}
+
+// newKubeClients connects to the cluster with the k3s kubeconfig
+func newKubeClients(kubeConfigPath string) (kubernetes.Interface, dynamic.Interface, error) {
+	kubeConfig, err := clientcmd.BuildConfigFromFlags("", kubeConfigPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	kubeConfig.Timeout = 15 * time.Second
+
+	clientset, err := kubernetes.NewForConfig(kubeConfig)
+	if err != nil {
+		return nil, nil, err
+	}
+	dynamicClient, err := dynamic.NewForConfig(kubeConfig)
+	if err != nil {
+		return nil, nil, err
+	}
+	return clientset, dynamicClient, nil
+}
+
+// systemdServiceState returns the state systemctl reports for a service, e.g. active or failed
+func systemdServiceState(name string) string {
+	// is-active exits non-zero for anything but active, the state is still printed
+	output, _ := exec.Command("systemctl", "is-active", name).Output()
+	if state := strings.TrimSpace(string(output)); state != "" {
+		return state
+	}
+	return "unknown"
+}
+
+// servedCertificateExpiry connects to domain over TLS and returns when the served certificate expires
+func servedCertificateExpiry(ctx context.Context, domain string) (time.Time, error) {
+	dialer := &tls.Dialer{
+		NetDialer: &net.Dialer{Timeout: 10 * time.Second},
+		// Expired or self-signed certificates should still be reported, not fail the check
+		Config: &tls.Config{ServerName: domain, InsecureSkipVerify: true},
+	}
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(domain, "443"))
+	if err != nil {
+		return time.Time{}, err
+	}
+	defer conn.Close()
+
+	certificates := conn.(*tls.Conn).ConnectionState().PeerCertificates
+	if len(certificates) == 0 {
+		return time.Time{}, fmt.Errorf("no certificate presented")
+	}
+	return certificates[0].NotAfter, nil
+}
+
+// localHostResources reads disk usage of / and memory usage from /proc/meminfo
+func localHostResources() (*HostResources, error) {
+	var fs syscall.Statfs_t
+	if err := syscall.Statfs("/", &fs); err != nil {
+		return nil, fmt.Errorf("failed to stat /: %w", err)
+	}
+	resources := &HostResources{}
+	if fs.Blocks > 0 {
+		resources.DiskUsedPercent = 100 * float64(fs.Blocks-fs.Bfree) / float64(fs.Blocks-fs.Bfree+fs.Bavail)
+	}
+
+	file, err := os.Open("/proc/meminfo")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open /proc/meminfo: %w", err)
+	}
+	defer file.Close()
+
+	memory := map[string]float64{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) >= 2 {
+			value, _ := strconv.ParseFloat(fields[1], 64)
+			memory[strings.TrimSuffix(fields[0], ":")] = value
+		}
+	}
+	if memory["MemTotal"] > 0 {
+		resources.MemoryUsedPercent = 100 * (memory["MemTotal"] - memory["MemAvailable"]) / memory["MemTotal"]
+	}
+	return resources, nil
+}
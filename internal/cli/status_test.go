@@ -0,0 +1,127 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func helmReleaseSecret(name, namespace, revision, status string) *corev1.Secret {
+	return &corev1.Secret{ObjectMeta: metav1.ObjectMeta{
+		Name:      "sh.helm.release.v1." + name + ".v" + revision,
+		Namespace: namespace,
+		Labels:    map[string]string{"owner": "helm", "name": name, "version": revision, "status": status},
+	}}
+}
+
+func longhornVolume(name, state, robustness string) *unstructured.Unstructured {
+	volume := &unstructured.Unstructured{Object: map[string]interface{}{
+		"status": map[string]interface{}{"state": state, "robustness": robustness},
+	}}
+	volume.SetAPIVersion("longhorn.io/v1beta2")
+	volume.SetKind("Volume")
+	volume.SetName(name)
+	volume.SetNamespace("longhorn-system")
+	return volume
+}
+
+func fakeCluster(objects []runtime.Object, longhornObjects ...runtime.Object) func(string) (kubernetes.Interface, dynamic.Interface, error) {
+	return func(string) (kubernetes.Interface, dynamic.Interface, error) {
+		dynamicClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+			map[schema.GroupVersionResource]string{
+				longhornVolumeResource:  "VolumeList",
+				longhornReplicaResource: "ReplicaList",
+				longhornNodeResource:    "NodeList",
+			}, longhornObjects...)
+		return fake.NewSimpleClientset(objects...), dynamicClient, nil
+	}
+}
+
+func readyNode(name string, conditions ...corev1.NodeCondition) *corev1.Node {
+	return &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{"node-role.kubernetes.io/control-plane": "true"}},
+		Status: corev1.NodeStatus{
+			NodeInfo:   corev1.NodeSystemInfo{KubeletVersion: "v1.33.1+k3s1"},
+			Conditions: append([]corev1.NodeCondition{{Type: corev1.NodeReady, Status: corev1.ConditionTrue}}, conditions...),
+		},
+	}
+}
+
+func pod(name, namespace string, phase corev1.PodPhase, ready bool) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+		Status: corev1.PodStatus{
+			Phase:             phase,
+			ContainerStatuses: []corev1.ContainerStatus{{Name: "main", Ready: ready}},
+		},
+	}
+}
+
+func TestStatus_Healthy(t *testing.T) {
+	opts, out := testOptions(t, "")
+	opts.KubeClients = fakeCluster([]runtime.Object{
+		readyNode("server-1"),
+		helmReleaseSecret("unbind-api", "unbind-system", "1", "superseded"),
+		helmReleaseSecret("unbind-api", "unbind-system", "2", "deployed"),
+		pod("api-1", "unbind-system", corev1.PodRunning, true),
+		pod("migrate-1", "unbind-system", corev1.PodSucceeded, false),
+		pod("longhorn-manager-1", "longhorn-system", corev1.PodRunning, true),
+	}, longhornVolume("pvc-1", "attached", "healthy"), longhornVolume("pvc-2", "detached", "unknown"))
+
+	require.NoError(t, run(opts, "status"))
+	assert.Contains(t, out.String(), "unbind-system/unbind-api: deployed (revision 2)")
+	assert.Contains(t, out.String(), "unbind-system: 2/2 healthy")
+	assert.Contains(t, out.String(), "Unbind is healthy")
+}
+
+func TestStatus_ProblemsAsJSON(t *testing.T) {
+	opts, _ := testOptions(t, "")
+	out := &bytes.Buffer{}
+	opts.Stdout = out
+	opts.KubeClients = fakeCluster([]runtime.Object{
+		readyNode("server-1", corev1.NodeCondition{Type: corev1.NodeDiskPressure, Status: corev1.ConditionTrue}),
+		helmReleaseSecret("unbind-api", "unbind-system", "3", "failed"),
+		pod("api-1", "unbind-system", corev1.PodRunning, false),
+	}, longhornVolume("pvc-1", "attached", "degraded"))
+
+	err := run(opts, "status", "--json")
+	var exit exitError
+	require.True(t, errors.As(err, &exit))
+	assert.Equal(t, 1, exit.code)
+
+	var report StatusReport
+	require.NoError(t, json.Unmarshal(out.Bytes(), &report))
+	assert.False(t, report.Healthy)
+	assert.ElementsMatch(t, []string{
+		"node server-1 has disk pressure",
+		"helm release unbind-system/unbind-api is failed",
+		"pod unbind-system/api-1 is not ready",
+		"longhorn volume pvc-1 is degraded",
+	}, report.Problems)
+}
+
+func TestStatus_K3sDown(t *testing.T) {
+	opts, out := testOptions(t, "")
+	opts.ServiceState = func(string) string { return "failed" }
+	opts.KubeClients = func(string) (kubernetes.Interface, dynamic.Interface, error) {
+		t.Fatal("the cluster should not be queried while k3s is down")
+		return nil, nil, nil
+	}
+
+	err := run(opts, "status")
+	assert.Error(t, err)
+	assert.Contains(t, out.String(), "k3s service is failed")
+}
@@ -0,0 +1,181 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Node roles a join command can be created for
+const (
+	roleAgent  = "agent"
+	roleServer = "server"
+)
+
+// bootstrapTokenDescription marks the tokens add-node creates
+const bootstrapTokenDescription = "created by unbind add-node"
+
+// bootstrapToken matches id.secret, optionally in the secure K10<ca-hash>:: form
+var bootstrapToken = regexp.MustCompile(`^(K10[0-9a-f]+::)?([a-z0-9]{6})\.([a-z0-9]{16})$`)
+
+// runK3s runs a k3s subcommand and returns its output, mockable for tests
+var runK3s = func(ctx context.Context, args ...string) (string, error) {
+	output, err := exec.CommandContext(ctx, "k3s", args...).CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("k3s %s failed: %w: %s", strings.Join(args, " "), err, strings.TrimSpace(string(output)))
+	}
+	return string(output), nil
+}
+
+// createBootstrapToken creates an expiring agent join token with k3s token create. Tokens are
+// returned in the secure form that pins the cluster CA, using the hash from the server token.
+func createBootstrapToken(ctx context.Context, ttl time.Duration, description, serverToken string) (string, error) {
+	output, err := runK3s(ctx, "token", "create", "--ttl", ttl.String(), "--description", description)
+	if err != nil {
+		return "", err
+	}
+
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	token := strings.TrimSpace(lines[len(lines)-1])
+	match := bootstrapToken.FindStringSubmatch(token)
+	if match == nil {
+		return "", fmt.Errorf("unexpected k3s token create output: %s", strings.TrimSpace(output))
+	}
+	if match[1] == "" {
+		if caHash, _, ok := strings.Cut(serverToken, "::"); ok && strings.HasPrefix(caHash, "K10") {
+			token = caHash + "::" + token
+		}
+	}
+	return token, nil
+}
+
+// tokenID returns the public id of a bootstrap token, the part used to list and revoke it
+func tokenID(token string) string {
+	if match := bootstrapToken.FindStringSubmatch(token); match != nil {
+		return match[2]
+	}
+	return ""
+}
+
+// joinTokenInfo is a bootstrap token as stored by Kubernetes, without its secret
+type joinTokenInfo struct {
+	ID          string
+	Description string
+	Expires     *time.Time
+	Groups      string
+}
+
+// listBootstrapTokens reads the bootstrap token secrets k3s token create writes to kube-system
+func listBootstrapTokens(ctx context.Context, opts Options) ([]joinTokenInfo, error) {
+	clientset, _, err := opts.KubeClients(opts.KubeConfigPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to the cluster: %w", err)
+	}
+	secrets, err := clientset.CoreV1().Secrets("kube-system").List(ctx, metav1.ListOptions{
+		FieldSelector: "type=" + string(corev1.SecretTypeBootstrapToken),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tokens: %w", err)
+	}
+
+	tokens := []joinTokenInfo{}
+	for _, secret := range secrets.Items {
+		if secret.Type != corev1.SecretTypeBootstrapToken {
+			continue
+		}
+		token := joinTokenInfo{
+			ID:          string(secret.Data["token-id"]),
+			Description: string(secret.Data["description"]),
+			Groups:      string(secret.Data["auth-extra-groups"]),
+		}
+		if expiration, err := time.Parse(time.RFC3339, string(secret.Data["expiration"])); err == nil {
+			token.Expires = &expiration
+		}
+		tokens = append(tokens, token)
+	}
+	sort.Slice(tokens, func(i, j int) bool { return tokens[i].ID < tokens[j].ID })
+	return tokens, nil
+}
+
+func newTokenCommand(opts Options) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "token",
+		Short: "List and revoke node join tokens",
+	}
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "list",
+		Short: "List the join tokens of the cluster",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := checkInstallation(opts); err != nil {
+				return err
+			}
+			tokens, err := listBootstrapTokens(cmd.Context(), opts)
+			if err != nil {
+				return err
+			}
+
+			p := newPrinter(opts.Stdout)
+			if len(tokens) == 0 {
+				p.Info("No join tokens, create one with unbind add-node")
+				return nil
+			}
+			p.Bold("%-8s %-22s %s", "ID", "EXPIRES", "DESCRIPTION")
+			for _, token := range tokens {
+				expires := "never"
+				if token.Expires != nil {
+					expires = token.Expires.Local().Format("2006-01-02 15:04 MST")
+					if token.Expires.Before(time.Now()) {
+						expires = "expired"
+					}
+				}
+				p.Line("%-8s %-22s %s", token.ID, expires, token.Description)
+			}
+			p.Line("")
+			p.Subtle("The server token in %s isn't listed, it never expires and can't be revoked here.", opts.NodeTokenPath)
+			return nil
+		},
+	})
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "revoke <id>",
+		Short: "Revoke a join token so no more nodes can join with it",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := checkInstallation(opts); err != nil {
+				return err
+			}
+
+			id := args[0]
+			if full := tokenID(id); full != "" {
+				id = full
+			}
+			if _, err := runK3s(cmd.Context(), "token", "delete", id); err != nil {
+				return err
+			}
+			newPrinter(opts.Stdout).Success(fmt.Sprintf("Token %s has been revoked. Nodes that already joined are not affected.", id))
+			return nil
+		},
+	})
+
+	return cmd
+}
+
+// readServerToken reads the server join token, which never expires and grants server join rights
+func readServerToken(opts Options) (string, error) {
+	token, err := os.ReadFile(opts.NodeTokenPath)
+	if err != nil || strings.TrimSpace(string(token)) == "" {
+		return "", fmt.Errorf("could not find the node token at %s, is k3s running?", opts.NodeTokenPath)
+	}
+	return strings.TrimSpace(string(token)), nil
+}
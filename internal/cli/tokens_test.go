@@ -0,0 +1,80 @@
+package cli
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// fakeK3s records k3s invocations and answers them with output
+func fakeK3s(t *testing.T, output string) *[][]string {
+	original := runK3s
+	t.Cleanup(func() { runK3s = original })
+
+	calls := &[][]string{}
+	runK3s = func(ctx context.Context, args ...string) (string, error) {
+		*calls = append(*calls, args)
+		return output, nil
+	}
+	return calls
+}
+
+func bootstrapTokenSecret(id, description string, expiration time.Time) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "bootstrap-token-" + id, Namespace: "kube-system"},
+		Type:       corev1.SecretTypeBootstrapToken,
+		Data: map[string][]byte{
+			"token-id":     []byte(id),
+			"token-secret": []byte("0123456789abcdef"),
+			"description":  []byte(description),
+			"expiration":   []byte(expiration.Format(time.RFC3339)),
+		},
+	}
+}
+
+func TestCreateBootstrapToken(t *testing.T) {
+	fakeK3s(t, "K10ffee::abcdef.0123456789abcdef\n")
+	token, err := createBootstrapToken(context.Background(), time.Hour, "test", "K10abc::server:secret")
+	require.NoError(t, err)
+	assert.Equal(t, "K10ffee::abcdef.0123456789abcdef", token, "secure tokens from k3s are kept as they are")
+
+	fakeK3s(t, "level=info msg=\"warning\"\nabcdef.0123456789abcdef\n")
+	token, err = createBootstrapToken(context.Background(), time.Hour, "test", "K10abc::server:secret")
+	require.NoError(t, err)
+	assert.Equal(t, "K10abc::abcdef.0123456789abcdef", token)
+	assert.Equal(t, "abcdef", tokenID(token))
+
+	fakeK3s(t, "something unexpected\n")
+	_, err = createBootstrapToken(context.Background(), time.Hour, "test", "K10abc::server:secret")
+	assert.ErrorContains(t, err, "unexpected k3s token create output")
+}
+
+func TestTokenList(t *testing.T) {
+	opts, out := testOptions(t, "")
+	opts.KubeClients = fakeCluster([]runtime.Object{
+		bootstrapTokenSecret("abcdef", "created by unbind add-node", time.Now().Add(time.Hour)),
+		bootstrapTokenSecret("zzzzzz", "old", time.Now().Add(-time.Hour)),
+		&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: "kube-system"}},
+	})
+
+	require.NoError(t, run(opts, "token", "list"))
+	assert.Contains(t, out.String(), "abcdef")
+	assert.Contains(t, out.String(), "created by unbind add-node")
+	assert.Regexp(t, `zzzzzz\s+expired`, out.String())
+	assert.NotContains(t, out.String(), "0123456789abcdef")
+}
+
+func TestTokenRevoke(t *testing.T) {
+	calls := fakeK3s(t, "")
+	opts, out := testOptions(t, "")
+
+	require.NoError(t, run(opts, "token", "revoke", "K10abc::abcdef.0123456789abcdef"))
+	assert.Equal(t, [][]string{{"token", "delete", "abcdef"}}, *calls)
+	assert.Contains(t, out.String(), "Token abcdef has been revoked")
+}
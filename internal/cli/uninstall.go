@@ -0,0 +1,148 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/unbindapp/unbind-installer/internal/k3s"
+	"github.com/unbindapp/unbind-installer/internal/osinfo"
+	"github.com/unbindapp/unbind-installer/internal/pkgmanager"
+)
+
+// Mockable for tests
+var (
+	// uninstallFunc removes k3s and Longhorn
+	uninstallFunc = k3s.Uninstall
+	// removePackagesFunc removes the recorded system packages, then disables the recorded repositories
+	removePackagesFunc = removeRecordedPackages
+)
+
+func newUninstallCommand(opts Options) *cobra.Command {
+	var (
+		yes          bool
+		keepPackages bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "uninstall",
+		Short: "Uninstall Unbind (WARNING: this permanently deletes all data)",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := checkInstallation(opts); err != nil {
+				return err
+			}
+
+			p := newPrinter(opts.Stdout)
+			p.Banner()
+			p.Box("WARNING: Unbind Uninstallation", p.error)
+			p.Colored(p.error, "This will permanently delete all Unbind data and configurations.")
+			p.Colored(p.error, "This action cannot be undone.")
+			p.Line("")
+
+			packages, err := pkgmanager.LoadRecord(opts.PackageRecordPath)
+			if err != nil {
+				return err
+			}
+			repos, err := pkgmanager.LoadRecord(opts.RepositoryRecordPath)
+			if err != nil {
+				return err
+			}
+			removePackages := (len(packages) > 0 || len(repos) > 0) && !keepPackages
+			if removePackages {
+				if len(packages) > 0 {
+					p.Line("The packages the installer added will be removed: %s", strings.Join(packages, ", "))
+				}
+				if len(repos) > 0 {
+					p.Line("The repositories the installer enabled will be disabled: %s", strings.Join(repos, ", "))
+				}
+				p.Subtle("Pass --keep-packages to keep them.")
+				p.Line("")
+			}
+
+			if !yes && !confirm(opts.Stdin, opts.Stdout, "Are you sure you want to continue? (y/N) ") {
+				p.Info("Uninstallation cancelled.")
+				return nil
+			}
+
+			p.Colored(p.warning, "Uninstalling Unbind...")
+			if err := runWithLogs(opts.Stdout, func(logChan chan<- string) error {
+				return uninstallFunc(opts.UninstallScriptPath, logChan)
+			}); err != nil {
+				return err
+			}
+
+			if removePackages {
+				p.Colored(p.warning, "Removing packages...")
+				if err := runWithLogs(opts.Stdout, func(logChan chan<- string) error {
+					return removePackagesFunc(cmd.Context(), opts.PackageRecordPath, opts.RepositoryRecordPath, logChan)
+				}); err != nil {
+					return err
+				}
+			}
+
+			p.Success("Unbind has been uninstalled successfully.")
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVarP(&yes, "yes", "y", false, "skip the confirmation prompt")
+	cmd.Flags().BoolVar(&keepPackages, "keep-packages", false, "keep the system packages and repositories the installer added")
+	return cmd
+}
+
+// removeRecordedPackages removes the packages the installer added on this distribution, then
+// disables the repositories it enabled for them
+func removeRecordedPackages(ctx context.Context, packageRecordPath, repositoryRecordPath string, logChan chan<- string) error {
+	info, err := osinfo.GetOSInfo()
+	if err != nil {
+		return err
+	}
+	manager, err := pkgmanager.NewPackageManager(info.Distribution, logChan, pkgmanager.DefaultLockTimeout, nil)
+	if err != nil {
+		return err
+	}
+	if _, err := pkgmanager.RemoveRecorded(ctx, manager, packageRecordPath); err != nil {
+		return err
+	}
+	_, err = pkgmanager.DisableRecordedRepositories(ctx, info, repositoryRecordPath, func(message string) { logChan <- message })
+	return err
+}
+
+// confirm asks a yes/no question, anything but y or yes is a no
+func confirm(in io.Reader, out io.Writer, question string) bool {
+	fmt.Fprint(out, question)
+	answer, _ := readLine(in)
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+// readLine reads one line, in is the command's buffered stdin
+func readLine(in io.Reader) (string, error) {
+	if reader, ok := in.(*bufio.Reader); ok {
+		return reader.ReadString('\n')
+	}
+	return bufio.NewReader(in).ReadString('\n')
+}
+
+// runWithLogs runs fn with a log channel that is printed as messages arrive
+func runWithLogs(out io.Writer, fn func(logChan chan<- string) error) error {
+	logChan := make(chan string, 100)
+	done := make(chan struct{})
+
+	p := newPrinter(out)
+	go func() {
+		defer close(done)
+		for message := range logChan {
+			p.Subtle("%s", strings.TrimRight(message, "\n"))
+		}
+	}()
+
+	err := fn(logChan)
+	close(logChan)
+	<-done
+	return err
+}
@@ -0,0 +1,27 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+func newVersionCommand(version string) *cobra.Command {
+	var short bool
+
+	cmd := &cobra.Command{
+		Use:   "version",
+		Short: "Print the version of the unbind CLI",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			if short {
+				fmt.Fprintln(cmd.OutOrStdout(), version)
+				return
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "unbind %s\n", version)
+		},
+	}
+
+	cmd.Flags().BoolVar(&short, "short", false, "print only the version number")
+	return cmd
+}
@@ -0,0 +1,261 @@
+package config
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DefaultPath is where the installer saves the cluster configuration
+const DefaultPath = "/etc/unbind/config"
+
+// Config is the cluster configuration shared by the installer and the unbind CLI.
+// It is stored as shell compatible KEY=VALUE lines so older tooling that greps
+// CLUSTER_IP keeps working. Keys this version doesn't know about are preserved.
+type Config struct {
+	ClusterIP  string `config:"CLUSTER_IP"`
+	K3sVersion string `config:"K3S_VERSION"`
+
+	// Domains
+	UnbindDomain string `config:"UNBIND_DOMAIN"`
+	BaseDomain   string `config:"BASE_DOMAIN"` // Set for wildcard installs
+
+	// Registry
+	RegistryType          string `config:"REGISTRY_TYPE"` // RegistrySelfHosted or RegistryExternal
+	RegistryDomain        string `config:"REGISTRY_DOMAIN"`
+	RegistryHost          string `config:"REGISTRY_HOST"`
+	RegistryProvider      string `config:"REGISTRY_PROVIDER"` // Set for registries with short-lived or key-based credentials
+	RegistryUsername      string `config:"REGISTRY_USERNAME"`
+	RegistryStorageSizeGB int    `config:"REGISTRY_STORAGE_SIZE_GB"`
+	RegistryStorageClass  string `config:"REGISTRY_STORAGE_CLASS"`
+	RegistryRetainTags    int    `config:"REGISTRY_RETAIN_TAGS"`
+	RegistryGCSchedule    string `config:"REGISTRY_GC_SCHEDULE"`
+
+	// Longhorn volume backups, credentials stay in the cluster
+	LonghornBackupTarget   string `config:"LONGHORN_BACKUP_TARGET"`
+	LonghornBackupSchedule string `config:"LONGHORN_BACKUP_SCHEDULE"`
+	LonghornBackupRetain   int    `config:"LONGHORN_BACKUP_RETAIN"`
+
+	// Unknown keys, kept so saving doesn't drop settings written by newer versions
+	extra map[string]string
+}
+
+// Registry types
+const (
+	RegistrySelfHosted = "self-hosted"
+	RegistryExternal   = "external"
+)
+
+// Load reads the config at path
+func Load(path string) (*Config, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	cfg, err := Parse(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// Parse reads KEY=VALUE lines, blank lines and # comments are ignored
+func Parse(r io.Reader) (*Config, error) {
+	cfg := &Config{}
+	fields := cfg.fields()
+
+	scanner := bufio.NewScanner(r)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		key, rawValue, ok := strings.Cut(strings.TrimPrefix(line, "export "), "=")
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected KEY=VALUE", lineNumber)
+		}
+		key = strings.TrimSpace(key)
+
+		value, err := unquote(strings.TrimSpace(rawValue))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNumber, err)
+		}
+
+		field, known := fields[key]
+		if !known {
+			if cfg.extra == nil {
+				cfg.extra = map[string]string{}
+			}
+			cfg.extra[key] = value
+			continue
+		}
+
+		switch field.Kind() {
+		case reflect.Int:
+			if value == "" {
+				continue
+			}
+			number, err := strconv.Atoi(value)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %s must be a number", lineNumber, key)
+			}
+			field.SetInt(int64(number))
+		default:
+			field.SetString(value)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// Save writes the config atomically, so a crash never leaves a half written file
+func (self *Config) Save(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".config-*")
+	if err != nil {
+		return fmt.Errorf("failed to create config file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.WriteString(self.String()); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+	if err := tmp.Chmod(0644); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to replace config file: %w", err)
+	}
+	return nil
+}
+
+// String renders the config file, known keys in declaration order then unknown keys sorted
+func (self *Config) String() string {
+	var s strings.Builder
+
+	value := reflect.ValueOf(self).Elem()
+	for i := 0; i < value.NumField(); i++ {
+		key := value.Type().Field(i).Tag.Get("config")
+		if key == "" {
+			continue
+		}
+
+		field := value.Field(i)
+		switch field.Kind() {
+		case reflect.Int:
+			if field.Int() != 0 {
+				fmt.Fprintf(&s, "%s=%d\n", key, field.Int())
+			}
+		default:
+			if field.String() != "" {
+				fmt.Fprintf(&s, "%s=%s\n", key, quote(field.String()))
+			}
+		}
+	}
+
+	keys := make([]string, 0, len(self.extra))
+	for key := range self.extra {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		fmt.Fprintf(&s, "%s=%s\n", key, quote(self.extra[key]))
+	}
+
+	return s.String()
+}
+
+// Get returns the value of any key, known or not
+func (self *Config) Get(key string) string {
+	if field, ok := self.fields()[key]; ok {
+		if field.Kind() == reflect.Int {
+			if field.Int() == 0 {
+				return ""
+			}
+			return strconv.FormatInt(field.Int(), 10)
+		}
+		return field.String()
+	}
+	return self.extra[key]
+}
+
+// Merge copies the non-empty settings of other into the config
+func (self *Config) Merge(other *Config) {
+	fields := self.fields()
+	for key, field := range other.fields() {
+		if !field.IsZero() {
+			fields[key].Set(field)
+		}
+	}
+	for key, value := range other.extra {
+		if self.extra == nil {
+			self.extra = map[string]string{}
+		}
+		self.extra[key] = value
+	}
+}
+
+// fields maps config keys to the struct fields holding them
+func (self *Config) fields() map[string]reflect.Value {
+	fields := map[string]reflect.Value{}
+	value := reflect.ValueOf(self).Elem()
+	for i := 0; i < value.NumField(); i++ {
+		if key := value.Type().Field(i).Tag.Get("config"); key != "" {
+			fields[key] = value.Field(i)
+		}
+	}
+	return fields
+}
+
+// quote double quotes values the shell would otherwise split or expand
+func quote(value string) string {
+	if value == "" || strings.ContainsAny(value, " \t\"'$`\\#*?;&|<>(){}[]!~") {
+		return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, `$`, `\$`, "`", "\\`").Replace(value) + `"`
+	}
+	return value
+}
+
+// unquote reverses quote, and accepts single quoted values too
+func unquote(value string) (string, error) {
+	if len(value) >= 2 && value[0] == '\'' && value[len(value)-1] == '\'' {
+		return value[1 : len(value)-1], nil
+	}
+	if len(value) >= 2 && value[0] == '"' && value[len(value)-1] == '"' {
+		var s strings.Builder
+		inner := value[1 : len(value)-1]
+		for i := 0; i < len(inner); i++ {
+			if inner[i] == '\\' && i+1 < len(inner) {
+				i++
+			}
+			s.WriteByte(inner[i])
+		}
+		return s.String(), nil
+	}
+	if strings.ContainsAny(value, `"'`) {
+		return "", fmt.Errorf("unbalanced quotes in %s", value)
+	}
+	return value, nil
+}
@@ -0,0 +1,89 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse_LegacyConfig(t *testing.T) {
+	// Written by the old bash management script installer
+	cfg, err := Parse(strings.NewReader("CLUSTER_IP=10.0.0.5\n"))
+	require.NoError(t, err)
+	assert.Equal(t, "10.0.0.5", cfg.ClusterIP)
+	assert.Empty(t, cfg.UnbindDomain)
+}
+
+func TestParse_AllValues(t *testing.T) {
+	cfg, err := Parse(strings.NewReader(`# Unbind configuration
+CLUSTER_IP=10.0.0.5
+export UNBIND_DOMAIN=unbind.example.com
+REGISTRY_TYPE=self-hosted
+REGISTRY_STORAGE_SIZE_GB=20
+REGISTRY_GC_SCHEDULE="0 3 * * *"
+REGISTRY_STORAGE_CLASS='longhorn'
+FUTURE_SETTING=kept
+`))
+	require.NoError(t, err)
+	assert.Equal(t, "unbind.example.com", cfg.UnbindDomain)
+	assert.Equal(t, RegistrySelfHosted, cfg.RegistryType)
+	assert.Equal(t, 20, cfg.RegistryStorageSizeGB)
+	assert.Equal(t, "0 3 * * *", cfg.RegistryGCSchedule)
+	assert.Equal(t, "longhorn", cfg.RegistryStorageClass)
+	assert.Equal(t, "kept", cfg.Get("FUTURE_SETTING"))
+	assert.Equal(t, "20", cfg.Get("REGISTRY_STORAGE_SIZE_GB"))
+}
+
+func TestParse_Errors(t *testing.T) {
+	_, err := Parse(strings.NewReader("CLUSTER_IP\n"))
+	assert.ErrorContains(t, err, "line 1")
+
+	_, err = Parse(strings.NewReader("REGISTRY_RETAIN_TAGS=ten\n"))
+	assert.ErrorContains(t, err, "must be a number")
+
+	_, err = Parse(strings.NewReader("UNBIND_DOMAIN=\"unterminated\n"))
+	assert.ErrorContains(t, err, "unbalanced quotes")
+}
+
+func TestSaveLoadRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "unbind", "config")
+
+	cfg := &Config{
+		ClusterIP:          "10.0.0.5",
+		UnbindDomain:       "unbind.example.com",
+		RegistryGCSchedule: `0 3 * * 1,4`,
+		RegistryUsername:   `we"ird$user`,
+		RegistryRetainTags: 10,
+		extra:              map[string]string{"FUTURE_SETTING": "kept"},
+	}
+	require.NoError(t, cfg.Save(path))
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0644), info.Mode().Perm())
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(data), "CLUSTER_IP=10.0.0.5\n"), "CLUSTER_IP stays first for grep based tooling")
+	assert.Contains(t, string(data), `REGISTRY_GC_SCHEDULE="0 3 * * 1,4"`)
+
+	loaded, err := Load(path)
+	require.NoError(t, err)
+	assert.Equal(t, cfg, loaded)
+}
+
+func TestMerge(t *testing.T) {
+	cfg, err := Parse(strings.NewReader("CLUSTER_IP=10.0.0.5\nREGISTRY_RETAIN_TAGS=5\nFUTURE_SETTING=kept\n"))
+	require.NoError(t, err)
+
+	cfg.Merge(&Config{ClusterIP: "10.0.0.6", UnbindDomain: "unbind.example.com"})
+
+	assert.Equal(t, "10.0.0.6", cfg.ClusterIP)
+	assert.Equal(t, "unbind.example.com", cfg.UnbindDomain)
+	assert.Equal(t, 5, cfg.RegistryRetainTags)
+	assert.Equal(t, "kept", cfg.Get("FUTURE_SETTING"))
+}
@@ -18,7 +18,11 @@ var (
 	ErrDistributionDetectionFailed = NewCustomError(ErrTypeDistributionDetectionFailed, "")
 	ErrUnsupportedDistribution     = NewCustomError(ErrTypeUnsupportedDistribution, "")
 	ErrUnsupportedVersion          = NewCustomError(ErrTypeUnsupportedVersion, "")
+	ErrUnsupportedEnvironment      = NewCustomError(ErrTypeUnsupportedEnvironment, "")
 	ErrUnbindInstallFailed         = NewCustomError(ErrTypeUnbindInstallFailed, "")
+	ErrRegistryInvalidCredentials  = NewCustomError(ErrTypeRegistryInvalidCredentials, "")
+	ErrRegistryPushDenied          = NewCustomError(ErrTypeRegistryPushDenied, "")
+	ErrRegistryUnreachable         = NewCustomError(ErrTypeRegistryUnreachable, "")
 )
 
 // More dynamic errors
@@ -31,6 +35,10 @@ const (
 	ErrTypeK3sInstallFailed
 	ErrTypeK3sUninstallFailed
 	ErrTypeUnbindInstallFailed
+	ErrTypeRegistryInvalidCredentials
+	ErrTypeRegistryPushDenied
+	ErrTypeRegistryUnreachable
+	ErrTypeUnsupportedEnvironment
 )
 
 var errorTypeStrings = map[ErrorType]string{
@@ -42,6 +50,10 @@ var errorTypeStrings = map[ErrorType]string{
 	ErrTypeK3sInstallFailed:            "ErrK3sInstallFailed",
 	ErrTypeK3sUninstallFailed:          "ErrK3sUninstallFailed",
 	ErrTypeUnbindInstallFailed:         "ErrUnbindInstallFailed",
+	ErrTypeRegistryInvalidCredentials:  "ErrRegistryInvalidCredentials",
+	ErrTypeRegistryPushDenied:          "ErrRegistryPushDenied",
+	ErrTypeRegistryUnreachable:         "ErrRegistryUnreachable",
+	ErrTypeUnsupportedEnvironment:      "ErrUnsupportedEnvironment",
 }
 
 func (e ErrorType) String() string {
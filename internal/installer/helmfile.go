@@ -83,10 +83,51 @@ type SyncHelmfileOptions struct {
 	RepoURL              string
 
 	// Registry configuration
-	DisableRegistry  bool   // Whether to disable the local registry component
-	RegistryUsername string // External registry username
-	RegistryPassword string // External registry password
-	RegistryHost     string // External registry host
+	DisableRegistry  bool              // Whether to disable the local registry component
+	RegistrySettings *RegistrySettings // Storage and cleanup of the self-hosted registry, chart defaults when nil
+	RegistryUsername string            // External registry username
+	RegistryPassword string            // External registry password
+	RegistryHost     string            // External registry host
+
+	// Use this dockerconfigjson secret instead of the username and password, set when
+	// the credentials are short-lived and rotated in the cluster
+	RegistryExistingSecret string
+	ECRRefresher           *ECRRefresherOptions // Deploy the ECR credential refresher before syncing
+
+	// Off-node backups of Longhorn volumes, none when nil
+	LonghornBackup *LonghornBackupOptions
+}
+
+// RegistrySettings controls the size and cleanup of the self-hosted registry
+type RegistrySettings struct {
+	StorageSizeGB int
+	StorageClass  string
+	RetainTags    int    // Tags kept per repository, older ones are deleted
+	GCSchedule    string // Cron schedule for registry garbage collection
+}
+
+// stateValues returns the settings as helmfile state value assignments, unset ones keep the chart
+// defaults
+func (self *RegistrySettings) stateValues() []string {
+	values := []string{}
+	if self.StorageSizeGB > 0 {
+		values = append(values, fmt.Sprintf("registry.storage.size=%dGi", self.StorageSizeGB))
+	}
+	if self.StorageClass != "" {
+		values = append(values, "registry.storage.storageClass="+escapeStateValue(self.StorageClass))
+	}
+	if self.RetainTags > 0 {
+		values = append(values, fmt.Sprintf("registry.retention.keepLastTags=%d", self.RetainTags))
+	}
+	if self.GCSchedule != "" {
+		values = append(values, "registry.gc.schedule="+escapeStateValue(self.GCSchedule))
+	}
+	return values
+}
+
+// escapeStateValue escapes characters --state-values-set treats as separators
+func escapeStateValue(value string) string {
+	return strings.NewReplacer(`\`, `\\`, ",", `\,`).Replace(value)
 }
 
 // SyncHelmfileWithSteps performs a helmfile sync operation using the unbind-charts repository
@@ -102,6 +143,15 @@ func (self *UnbindInstaller) SyncHelmfileWithSteps(ctx context.Context, opts Syn
 	// Initialize state for this dependency
 	self.ensureStateInitialized(dependencyName)
 
+	// Never let credentials reach the logs
+	self.redactor.Add(opts.RegistryPassword)
+	if opts.ECRRefresher != nil {
+		self.redactor.Add(opts.ECRRefresher.SecretAccessKey, opts.ECRRefresher.InitialPassword)
+	}
+	if opts.LonghornBackup != nil {
+		self.redactor.Add(opts.LonghornBackup.Credentials["AWS_SECRET_ACCESS_KEY"])
+	}
+
 	// Mark the beginning of installation
 	self.logProgress(dependencyName, 0.0, fmt.Sprintf("Starting helmfile sync with base domain %s", opts.BaseDomain), nil, StatusInstalling)
 
@@ -247,10 +297,39 @@ func (self *UnbindInstaller) SyncHelmfileWithSteps(ctx context.Context, opts Syn
 				return nil
 			},
 		},
+		{
+			Description: "Configuring registry credentials",
+			Progress:    0.12,
+			Action: func(ctx context.Context) error {
+				if opts.ECRRefresher == nil {
+					return nil
+				}
+
+				self.logProgress(dependencyName, 0.12, "Deploying ECR credential refresher...", nil, StatusInstalling)
+				if err := self.DeployECRCredentialRefresher(ctx, *opts.ECRRefresher); err != nil {
+					return fmt.Errorf("failed to deploy ECR credential refresher: %w", err)
+				}
+				self.sendLog("ECR credential refresher deployed")
+				return nil
+			},
+		},
+		{
+			Description: "Configuring Longhorn backup target",
+			Progress:    0.14,
+			Action: func(ctx context.Context) error {
+				if opts.LonghornBackup == nil || opts.LonghornBackup.Credentials == nil {
+					return nil
+				}
+				return self.applyLonghornBackupSecret(ctx, opts.LonghornBackup)
+			},
+		},
 		{
 			Description: "Running helmfile sync",
 			Progress:    0.15,
 			Action: func(ctx context.Context) error {
+				// Secrets go in a private values file, anything on the command line is visible in ps
+				secrets := secretValues{}
+
 				// Construct arguments for helmfile command
 				args := []string{
 					"--file", filepath.Join(repoDir, "helmfile.yaml"),
@@ -276,12 +355,26 @@ func (self *UnbindInstaller) SyncHelmfileWithSteps(ctx context.Context, opts Syn
 						args = append(args, "--state-values-set", "externalRegistry.username="+opts.RegistryUsername)
 					}
 
-					if opts.RegistryPassword != "" {
-						args = append(args, "--state-values-set", "externalRegistry.password="+opts.RegistryPassword)
+					if opts.RegistryExistingSecret != "" {
+						args = append(args, "--state-values-set", "externalRegistry.existingSecret="+opts.RegistryExistingSecret)
+					} else if opts.RegistryPassword != "" {
+						secrets.Set("externalRegistry.password", opts.RegistryPassword)
 					}
 				} else {
 					// Use self-hosted registry
 					args = append(args, "--state-values-set", "externalRegistry.enabled=false")
+
+					if opts.RegistrySettings != nil {
+						for _, value := range opts.RegistrySettings.stateValues() {
+							args = append(args, "--state-values-set", value)
+						}
+					}
+				}
+
+				if opts.LonghornBackup != nil {
+					for _, value := range opts.LonghornBackup.stateValues() {
+						args = append(args, "--state-values-set", value)
+					}
 				}
 
 				// Add any additional values if present
@@ -289,6 +382,15 @@ func (self *UnbindInstaller) SyncHelmfileWithSteps(ctx context.Context, opts Syn
 					args = append(args, "--state-values-set", fmt.Sprintf("%s=%v", key, value))
 				}
 
+				if len(secrets) > 0 {
+					secretsFile, err := writeSecretValuesFile(secrets)
+					if err != nil {
+						return err
+					}
+					defer os.Remove(secretsFile)
+					args = append(args, "--state-values-file", secretsFile)
+				}
+
 				// Add final "sync" command
 				args = append(args, "sync")
 
@@ -435,6 +537,16 @@ func (self *UnbindInstaller) SyncHelmfileWithSteps(ctx context.Context, opts Syn
 				return nil
 			},
 		},
+		{
+			Description: "Scheduling Longhorn backups",
+			Progress:    0.92,
+			Action: func(ctx context.Context) error {
+				if opts.LonghornBackup == nil {
+					return nil
+				}
+				return self.applyLonghornRecurringBackup(ctx, opts.LonghornBackup)
+			},
+		},
 		{
 			Description: "Cleaning up temporary files",
 			Progress:    0.95,
@@ -0,0 +1,47 @@
+package installer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegistrySettings_StateValues(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings RegistrySettings
+		want     []string
+	}{
+		{
+			name:     "all set",
+			settings: RegistrySettings{StorageSizeGB: 50, StorageClass: "longhorn", RetainTags: 10, GCSchedule: "0 3 * * *"},
+			want: []string{
+				"registry.storage.size=50Gi",
+				"registry.storage.storageClass=longhorn",
+				"registry.retention.keepLastTags=10",
+				"registry.gc.schedule=0 3 * * *",
+			},
+		},
+		{
+			name:     "escaped schedule",
+			settings: RegistrySettings{StorageSizeGB: 20, GCSchedule: "0 1,13 * * *"},
+			want:     []string{"registry.storage.size=20Gi", `registry.gc.schedule=0 1\,13 * * *`},
+		},
+		{
+			name:     "size and retention unset",
+			settings: RegistrySettings{StorageClass: "local-path", GCSchedule: "30 2 * * 0"},
+			want:     []string{"registry.storage.storageClass=local-path", "registry.gc.schedule=30 2 * * 0"},
+		},
+		{
+			name:     "nothing set",
+			settings: RegistrySettings{},
+			want:     []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.settings.stateValues())
+		})
+	}
+}
@@ -5,7 +5,9 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/unbindapp/unbind-installer/internal/utils"
 	"helm.sh/helm/v3/pkg/cli"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/clientcmd"
 )
@@ -13,6 +15,7 @@ import (
 type UnbindInstaller struct {
 	progressChan   chan<- UnbindInstallUpdateMsg
 	kubeClient     *kubernetes.Clientset
+	dynamicClient  dynamic.Interface
 	LogChan        chan<- string
 	FactChan       chan<- string
 	helmEnv        *cli.EnvSettings
@@ -20,6 +23,8 @@ type UnbindInstaller struct {
 	kubeConfigPath string
 	// Fact rotator for educational information
 	factRotator *FactRotator
+	// Hides credentials in logs and progress descriptions
+	redactor *utils.Redactor
 }
 
 // dependencyState tracks status info for each component
@@ -54,14 +59,22 @@ func NewUnbindInstaller(kubeConfig string, logChan chan<- string, progressChan c
 		return nil, err
 	}
 
+	dynamicClient, err := dynamic.NewForConfig(config)
+	if err != nil {
+		logChan <- "Error creating Kubernetes client: " + err.Error()
+		return nil, err
+	}
+
 	installer := &UnbindInstaller{
 		progressChan:   progressChan,
 		kubeConfigPath: kubeConfig,
 		kubeClient:     clientset,
+		dynamicClient:  dynamicClient,
 		LogChan:        logChan,
 		FactChan:       factChan,
 		helmEnv:        cli.New(),
 		state:          make(map[string]*dependencyState),
+		redactor:       utils.NewRedactor(),
 	}
 
 	// Initialize fact rotator with the facts from helmfile.go
@@ -159,6 +172,8 @@ var minProgressInterval = 50 * time.Millisecond // Reduced interval for smoother
 func (self *UnbindInstaller) logProgress(name string, progress float64, description string, err error, status InstallerStatus) {
 	// Ensure state is initialized
 	self.ensureStateInitialized(name)
+	description = self.redactor.Redact(description)
+	err = self.redactor.RedactError(err)
 
 	// Send log message
 	if description != "" {
@@ -234,7 +249,7 @@ func (self *UnbindInstaller) sendUpdateMessage(name string) {
 // sendLog outputs messages to the log channel
 func (self *UnbindInstaller) sendLog(message string) {
 	if self.LogChan != nil {
-		self.LogChan <- message
+		self.LogChan <- self.redactor.Redact(message)
 	}
 }
 
@@ -0,0 +1,27 @@
+package installer
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/unbindapp/unbind-installer/internal/utils"
+)
+
+func TestLogProgress_RedactsSecrets(t *testing.T) {
+	logChan := make(chan string, 1)
+	progressChan := make(chan UnbindInstallUpdateMsg, 1)
+	installer := &UnbindInstaller{
+		LogChan:      logChan,
+		progressChan: progressChan,
+		state:        make(map[string]*dependencyState),
+		redactor:     utils.NewRedactor(),
+	}
+	installer.redactor.Add("hunter22")
+
+	installer.logProgress("unbind", 0.5, "Logging in with hunter22", errors.New("login with hunter22 failed"), StatusFailed)
+	assert.Equal(t, "Logging in with [REDACTED]", <-logChan)
+	msg := <-progressChan
+	assert.Equal(t, "Logging in with [REDACTED]", msg.Description)
+	assert.EqualError(t, msg.Error, "login with [REDACTED] failed")
+}
@@ -0,0 +1,68 @@
+package installer
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/unbindapp/unbind-installer/internal/longhorn"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// LonghornBackupOptions configures where Longhorn backs up volumes and how often
+type LonghornBackupOptions struct {
+	Target      string            // Longhorn backupTarget, e.g. s3://bucket@region/prefix or nfs://server:/path
+	Credentials map[string]string // Contents of the credential secret, nil when it exists already or isn't needed
+	HasSecret   bool              // Whether Longhorn should use the credential secret
+	Schedule    string            // Cron schedule of the recurring backup job
+	Retain      int               // Backups kept per volume
+}
+
+// stateValues returns the settings as helmfile state value assignments
+func (self *LonghornBackupOptions) stateValues() []string {
+	values := []string{"longhorn.defaultSettings.backupTarget=" + escapeStateValue(self.Target)}
+	if self.HasSecret {
+		values = append(values, "longhorn.defaultSettings.backupTargetCredentialSecret="+longhorn.CredentialSecretName)
+	}
+	return values
+}
+
+// applyLonghornBackupSecret stores the backup target credentials where Longhorn reads them,
+// before Longhorn is installed so the first backup target check succeeds
+func (self *UnbindInstaller) applyLonghornBackupSecret(ctx context.Context, opts *LonghornBackupOptions) error {
+	if err := self.ensureNamespace(ctx, longhorn.Namespace); err != nil {
+		return err
+	}
+
+	self.sendLog("Storing Longhorn backup target credentials")
+	return self.applySecret(ctx, &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: longhorn.CredentialSecretName, Namespace: longhorn.Namespace},
+		Type:       corev1.SecretTypeOpaque,
+		StringData: opts.Credentials,
+	})
+}
+
+// applyLonghornRecurringBackup creates the recurring backup job or updates its schedule and retention
+func (self *UnbindInstaller) applyLonghornRecurringBackup(ctx context.Context, opts *LonghornBackupOptions) error {
+	job := longhorn.RecurringBackupJob(opts.Schedule, opts.Retain)
+	jobs := self.dynamicClient.Resource(longhorn.RecurringJobResource).Namespace(longhorn.Namespace)
+
+	self.sendLog(fmt.Sprintf("Scheduling Longhorn backups (%s, keeping %d per volume)", opts.Schedule, opts.Retain))
+	existing, err := jobs.Get(ctx, job.GetName(), metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		if _, err := jobs.Create(ctx, job, metav1.CreateOptions{}); err != nil {
+			return fmt.Errorf("failed to create recurring job %s: %w", job.GetName(), err)
+		}
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get recurring job %s: %w", job.GetName(), err)
+	}
+
+	existing.Object["spec"] = job.Object["spec"]
+	if _, err := jobs.Update(ctx, existing, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf("failed to update recurring job %s: %w", job.GetName(), err)
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package installer
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/unbindapp/unbind-installer/internal/longhorn"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+)
+
+func TestLonghornBackupOptions_StateValues(t *testing.T) {
+	s3 := &LonghornBackupOptions{Target: "s3://backups@us-east-1/cluster-a", HasSecret: true}
+	assert.Equal(t, []string{
+		"longhorn.defaultSettings.backupTarget=s3://backups@us-east-1/cluster-a",
+		"longhorn.defaultSettings.backupTargetCredentialSecret=" + longhorn.CredentialSecretName,
+	}, s3.stateValues())
+
+	nfs := &LonghornBackupOptions{Target: "nfs://nfs.example.com:/exports/longhorn"}
+	assert.Len(t, nfs.stateValues(), 1)
+}
+
+func TestApplyLonghornRecurringBackup(t *testing.T) {
+	dynamicClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{longhorn.RecurringJobResource: "RecurringJobList"})
+	installer := &UnbindInstaller{dynamicClient: dynamicClient}
+	jobs := dynamicClient.Resource(longhorn.RecurringJobResource).Namespace(longhorn.Namespace)
+	ctx := context.Background()
+
+	require.NoError(t, installer.applyLonghornRecurringBackup(ctx, &LonghornBackupOptions{Schedule: "0 2 * * *", Retain: 7}))
+	job, err := jobs.Get(ctx, longhorn.RecurringJobName, metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "0 2 * * *", job.Object["spec"].(map[string]interface{})["cron"])
+
+	// Running again updates the existing job
+	require.NoError(t, installer.applyLonghornRecurringBackup(ctx, &LonghornBackupOptions{Schedule: "30 4 * * 0", Retain: 3}))
+	job, err = jobs.Get(ctx, longhorn.RecurringJobName, metav1.GetOptions{})
+	require.NoError(t, err)
+	spec := job.Object["spec"].(map[string]interface{})
+	assert.Equal(t, "30 4 * * 0", spec["cron"])
+	assert.Equal(t, int64(3), spec["retain"])
+}
@@ -0,0 +1,80 @@
+package installer
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/unbindapp/unbind-installer/internal/config"
+)
+
+// ManagementCLIPath is where the unbind management CLI is installed
+const ManagementCLIPath = "/usr/local/bin/unbind"
+
+// InstallManagementCLI saves the cluster config and installs the unbind management CLI.
+// Settings already in the config file that cfg doesn't set are kept.
+func InstallManagementCLI(cfg *config.Config) error {
+	if cfg != nil {
+		if err := mergeConfig(cfg, config.DefaultPath).Save(config.DefaultPath); err != nil {
+			return fmt.Errorf("failed to write config file: %w", err)
+		}
+	}
+
+	return InstallCLIBinary(ManagementCLIPath)
+}
+
+// InstallCLIBinary copies the running executable to path, the installer binary runs the CLI
+// when invoked as unbind. The copy is renamed into place so a running unbind is never half written.
+func InstallCLIBinary(path string) error {
+	executable, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to locate the installer binary: %w", err)
+	}
+	if executable, err = filepath.EvalSymlinks(executable); err != nil {
+		return fmt.Errorf("failed to locate the installer binary: %w", err)
+	}
+	if target, err := filepath.EvalSymlinks(path); err == nil && target == executable {
+		// Already running the installed copy
+		return nil
+	}
+
+	source, err := os.Open(executable)
+	if err != nil {
+		return fmt.Errorf("failed to read the installer binary: %w", err)
+	}
+	defer source.Close()
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".unbind-*")
+	if err != nil {
+		return fmt.Errorf("failed to write management CLI: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, source); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write management CLI: %w", err)
+	}
+	if err := tmp.Chmod(0755); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write management CLI: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write management CLI: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to install management CLI: %w", err)
+	}
+	return nil
+}
+
+// mergeConfig overlays cfg on the config saved at path, if there is one
+func mergeConfig(cfg *config.Config, path string) *config.Config {
+	existing, err := config.Load(path)
+	if err != nil {
+		return cfg
+	}
+	existing.Merge(cfg)
+	return existing
+}
@@ -0,0 +1,260 @@
+package installer
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// RegistryCredentialsSecretName is the dockerconfigjson secret the charts use when a refresher manages the credentials
+	RegistryCredentialsSecretName = "unbind-registry-credentials"
+
+	unbindNamespace         = "unbind-system"
+	ecrAccessKeySecretName  = "unbind-ecr-access-key"
+	ecrRefresherName        = "unbind-ecr-credential-refresher"
+	ecrRefresherSchedule    = "0 */6 * * *" // Tokens live 12 hours, refresh twice within that window
+	ecrRefresherAWSCLIImage = "public.ecr.aws/aws-cli/aws-cli:2.27.31"
+	ecrRefresherKubectl     = "bitnami/kubectl:1.33"
+)
+
+// ECRRefresherOptions configures the in-cluster ECR credential refresher
+type ECRRefresherOptions struct {
+	RegistryHost    string // <account>.dkr.ecr.<region>.amazonaws.com
+	Region          string
+	AccessKeyID     string
+	SecretAccessKey string
+
+	// Token from the initial login, used to seed the secret until the first refresh runs
+	InitialUsername string
+	InitialPassword string
+}
+
+// DeployECRCredentialRefresher stores the access key in the cluster and schedules a CronJob that
+// exchanges it for a fresh ECR token and rewrites the registry pull/push secret
+func (self *UnbindInstaller) DeployECRCredentialRefresher(ctx context.Context, opts ECRRefresherOptions) error {
+	if err := self.ensureNamespace(ctx, unbindNamespace); err != nil {
+		return err
+	}
+
+	self.sendLog("Storing AWS access key for the ECR credential refresher")
+	if err := self.applySecret(ctx, &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: ecrAccessKeySecretName, Namespace: unbindNamespace},
+		Type:       corev1.SecretTypeOpaque,
+		StringData: map[string]string{
+			"AWS_ACCESS_KEY_ID":     opts.AccessKeyID,
+			"AWS_SECRET_ACCESS_KEY": opts.SecretAccessKey,
+			"AWS_DEFAULT_REGION":    opts.Region,
+		},
+	}); err != nil {
+		return err
+	}
+
+	self.sendLog("Seeding registry credentials secret with the current ECR token")
+	dockerConfig, err := dockerConfigJSON(opts.RegistryHost, opts.InitialUsername, opts.InitialPassword)
+	if err != nil {
+		return err
+	}
+	if err := self.applySecret(ctx, &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: RegistryCredentialsSecretName, Namespace: unbindNamespace},
+		Type:       corev1.SecretTypeDockerConfigJson,
+		Data:       map[string][]byte{corev1.DockerConfigJsonKey: dockerConfig},
+	}); err != nil {
+		return err
+	}
+
+	if err := self.applyRefresherRBAC(ctx); err != nil {
+		return err
+	}
+
+	self.sendLog(fmt.Sprintf("Scheduling %s (%s)", ecrRefresherName, ecrRefresherSchedule))
+	return self.applyCronJob(ctx, ecrRefresherCronJob(opts))
+}
+
+// ecrRefresherCronJob builds the refresher: an aws-cli init container fetches the token into a
+// shared volume, then kubectl rewrites the dockerconfigjson secret with it
+func ecrRefresherCronJob(opts ECRRefresherOptions) *batchv1.CronJob {
+	successfulJobs := int32(1)
+	failedJobs := int32(3)
+	backoffLimit := int32(3)
+
+	refreshScript := fmt.Sprintf(`kubectl create secret docker-registry %s \
+  --namespace %s \
+  --docker-server=%s \
+  --docker-username=AWS \
+  --docker-password="$(cat /work/password)" \
+  --dry-run=client -o yaml | kubectl apply -f -`, RegistryCredentialsSecretName, unbindNamespace, opts.RegistryHost)
+
+	return &batchv1.CronJob{
+		ObjectMeta: metav1.ObjectMeta{Name: ecrRefresherName, Namespace: unbindNamespace},
+		Spec: batchv1.CronJobSpec{
+			Schedule:                   ecrRefresherSchedule,
+			ConcurrencyPolicy:          batchv1.ForbidConcurrent,
+			SuccessfulJobsHistoryLimit: &successfulJobs,
+			FailedJobsHistoryLimit:     &failedJobs,
+			JobTemplate: batchv1.JobTemplateSpec{
+				Spec: batchv1.JobSpec{
+					BackoffLimit: &backoffLimit,
+					Template: corev1.PodTemplateSpec{
+						Spec: corev1.PodSpec{
+							ServiceAccountName: ecrRefresherName,
+							RestartPolicy:      corev1.RestartPolicyOnFailure,
+							InitContainers: []corev1.Container{{
+								Name:    "get-login-password",
+								Image:   ecrRefresherAWSCLIImage,
+								Command: []string{"sh", "-c", "aws ecr get-login-password > /work/password"},
+								EnvFrom: []corev1.EnvFromSource{{
+									SecretRef: &corev1.SecretEnvSource{
+										LocalObjectReference: corev1.LocalObjectReference{Name: ecrAccessKeySecretName},
+									},
+								}},
+								VolumeMounts: []corev1.VolumeMount{{Name: "work", MountPath: "/work"}},
+							}},
+							Containers: []corev1.Container{{
+								Name:         "update-secret",
+								Image:        ecrRefresherKubectl,
+								Command:      []string{"sh", "-c", refreshScript},
+								VolumeMounts: []corev1.VolumeMount{{Name: "work", MountPath: "/work", ReadOnly: true}},
+							}},
+							Volumes: []corev1.Volume{{
+								Name: "work",
+								VolumeSource: corev1.VolumeSource{
+									EmptyDir: &corev1.EmptyDirVolumeSource{Medium: corev1.StorageMediumMemory},
+								},
+							}},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// applyRefresherRBAC lets the refresher rewrite only the registry credentials secret
+func (self *UnbindInstaller) applyRefresherRBAC(ctx context.Context) error {
+	serviceAccounts := self.kubeClient.CoreV1().ServiceAccounts(unbindNamespace)
+	if _, err := serviceAccounts.Create(ctx, &corev1.ServiceAccount{
+		ObjectMeta: metav1.ObjectMeta{Name: ecrRefresherName, Namespace: unbindNamespace},
+	}, metav1.CreateOptions{}); err != nil && !apierrors.IsAlreadyExists(err) {
+		return fmt.Errorf("failed to create refresher service account: %w", err)
+	}
+
+	role := &rbacv1.Role{
+		ObjectMeta: metav1.ObjectMeta{Name: ecrRefresherName, Namespace: unbindNamespace},
+		Rules: []rbacv1.PolicyRule{{
+			APIGroups:     []string{""},
+			Resources:     []string{"secrets"},
+			ResourceNames: []string{RegistryCredentialsSecretName},
+			Verbs:         []string{"get", "patch", "update"},
+		}},
+	}
+	roles := self.kubeClient.RbacV1().Roles(unbindNamespace)
+	if existing, err := roles.Get(ctx, role.Name, metav1.GetOptions{}); err == nil {
+		existing.Rules = role.Rules
+		if _, err := roles.Update(ctx, existing, metav1.UpdateOptions{}); err != nil {
+			return fmt.Errorf("failed to update refresher role: %w", err)
+		}
+	} else if apierrors.IsNotFound(err) {
+		if _, err := roles.Create(ctx, role, metav1.CreateOptions{}); err != nil {
+			return fmt.Errorf("failed to create refresher role: %w", err)
+		}
+	} else {
+		return fmt.Errorf("failed to get refresher role: %w", err)
+	}
+
+	if _, err := self.kubeClient.RbacV1().RoleBindings(unbindNamespace).Create(ctx, &rbacv1.RoleBinding{
+		ObjectMeta: metav1.ObjectMeta{Name: ecrRefresherName, Namespace: unbindNamespace},
+		RoleRef: rbacv1.RoleRef{
+			APIGroup: rbacv1.GroupName,
+			Kind:     "Role",
+			Name:     ecrRefresherName,
+		},
+		Subjects: []rbacv1.Subject{{
+			Kind:      rbacv1.ServiceAccountKind,
+			Name:      ecrRefresherName,
+			Namespace: unbindNamespace,
+		}},
+	}, metav1.CreateOptions{}); err != nil && !apierrors.IsAlreadyExists(err) {
+		return fmt.Errorf("failed to create refresher role binding: %w", err)
+	}
+
+	return nil
+}
+
+// ensureNamespace creates a namespace if it doesn't exist yet
+func (self *UnbindInstaller) ensureNamespace(ctx context.Context, name string) error {
+	_, err := self.kubeClient.CoreV1().Namespaces().Create(ctx, &corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+	}, metav1.CreateOptions{})
+	if err != nil && !apierrors.IsAlreadyExists(err) {
+		return fmt.Errorf("failed to create namespace %s: %w", name, err)
+	}
+	return nil
+}
+
+// applySecret creates a secret or replaces the contents of an existing one
+func (self *UnbindInstaller) applySecret(ctx context.Context, secret *corev1.Secret) error {
+	secrets := self.kubeClient.CoreV1().Secrets(secret.Namespace)
+
+	existing, err := secrets.Get(ctx, secret.Name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		if _, err := secrets.Create(ctx, secret, metav1.CreateOptions{}); err != nil {
+			return fmt.Errorf("failed to create secret %s: %w", secret.Name, err)
+		}
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get secret %s: %w", secret.Name, err)
+	}
+
+	existing.Type = secret.Type
+	existing.Data = secret.Data
+	existing.StringData = secret.StringData
+	if _, err := secrets.Update(ctx, existing, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf("failed to update secret %s: %w", secret.Name, err)
+	}
+	return nil
+}
+
+// applyCronJob creates a CronJob or replaces the spec of an existing one
+func (self *UnbindInstaller) applyCronJob(ctx context.Context, cronJob *batchv1.CronJob) error {
+	cronJobs := self.kubeClient.BatchV1().CronJobs(cronJob.Namespace)
+
+	existing, err := cronJobs.Get(ctx, cronJob.Name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		if _, err := cronJobs.Create(ctx, cronJob, metav1.CreateOptions{}); err != nil {
+			return fmt.Errorf("failed to create cronjob %s: %w", cronJob.Name, err)
+		}
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get cronjob %s: %w", cronJob.Name, err)
+	}
+
+	existing.Spec = cronJob.Spec
+	if _, err := cronJobs.Update(ctx, existing, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf("failed to update cronjob %s: %w", cronJob.Name, err)
+	}
+	return nil
+}
+
+// dockerConfigJSON renders a .dockerconfigjson document for a single registry
+func dockerConfigJSON(host, username, password string) ([]byte, error) {
+	auth := base64.StdEncoding.EncodeToString([]byte(username + ":" + password))
+	return json.Marshal(map[string]interface{}{
+		"auths": map[string]interface{}{
+			host: map[string]string{
+				"username": username,
+				"password": password,
+				"auth":     auth,
+			},
+		},
+	})
+}
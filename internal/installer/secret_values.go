@@ -0,0 +1,50 @@
+package installer
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// secretValues collects helmfile state values that must not appear on the command line
+type secretValues map[string]interface{}
+
+// Set stores value under a dotted key such as externalRegistry.password
+func (self secretValues) Set(key string, value interface{}) {
+	parts := strings.Split(key, ".")
+	current := map[string]interface{}(self)
+	for _, part := range parts[:len(parts)-1] {
+		next, ok := current[part].(map[string]interface{})
+		if !ok {
+			next = map[string]interface{}{}
+			current[part] = next
+		}
+		current = next
+	}
+	current[parts[len(parts)-1]] = value
+}
+
+// writeSecretValuesFile writes the values to a 0600 file for --state-values-file.
+// JSON is valid YAML, so helmfile reads it like any other values file.
+// The caller must remove the file once helmfile is done.
+func writeSecretValuesFile(values secretValues) (string, error) {
+	file, err := os.CreateTemp("", "unbind-secret-values-*.yaml")
+	if err != nil {
+		return "", fmt.Errorf("failed to create secret values file: %w", err)
+	}
+	defer file.Close()
+
+	// CreateTemp already uses 0600, be explicit in case umask or the platform differ
+	if err := file.Chmod(0600); err != nil {
+		os.Remove(file.Name())
+		return "", fmt.Errorf("failed to restrict secret values file: %w", err)
+	}
+
+	if err := json.NewEncoder(file).Encode(values); err != nil {
+		os.Remove(file.Name())
+		return "", fmt.Errorf("failed to write secret values file: %w", err)
+	}
+
+	return file.Name(), nil
+}
@@ -0,0 +1,32 @@
+package installer
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteSecretValuesFile(t *testing.T) {
+	values := secretValues{}
+	values.Set("externalRegistry.password", "hunter2")
+	values.Set("externalRegistry.token.value", "abc")
+
+	path, err := writeSecretValuesFile(values)
+	require.NoError(t, err)
+	defer os.Remove(path)
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	var decoded map[string]map[string]interface{}
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, "hunter2", decoded["externalRegistry"]["password"])
+	assert.Equal(t, map[string]interface{}{"value": "abc"}, decoded["externalRegistry"]["token"])
+}
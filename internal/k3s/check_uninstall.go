@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"github.com/unbindapp/unbind-installer/internal/errdefs"
+	"github.com/unbindapp/unbind-installer/internal/system"
 )
 
 const (
@@ -121,9 +122,21 @@ cleanup:
 	time.Sleep(10 * time.Second)
 
 	// 1. Log out of any leftover iSCSI sessions
-	err = runCommand(logChan, "iscsiadm", "-m", "session", "|", "grep", "'io.longhorn'", "|", "awk", "'{print $2}'", "|", "sed", "'s/\\[\\([0-9]*\\)\\]/\\1/'", "|", "xargs", "-r", "-I{}", "iscsiadm", "-m", "session", "-u", "-r", "{}")
-	if err != nil {
-		logChan <- "Warning: Failed to logout of iSCSI sessions, continuing anyway"
+	// Sessions look like: tcp: [3] 10.42.0.12:3260,1 iqn.2019-10.io.longhorn:pvc-... (non-flash)
+	cmd := exec.Command("iscsiadm", "-m", "session")
+	output, err := cmd.Output()
+	if err == nil {
+		scanner := bufio.NewScanner(bytes.NewReader(output))
+		for scanner.Scan() {
+			fields := strings.Fields(scanner.Text())
+			if len(fields) < 4 || !strings.Contains(fields[3], "io.longhorn") {
+				continue
+			}
+			sessionID := strings.Trim(fields[1], "[]")
+			if err := runCommand(logChan, "iscsiadm", "-m", "session", "-u", "-r", sessionID); err != nil {
+				logChan <- fmt.Sprintf("Warning: Failed to logout of iSCSI session %s, continuing anyway", sessionID)
+			}
+		}
 	}
 
 	err = runCommand(logChan, "iscsiadm", "-m", "node", "--targetname", "iqn.*.longhorn*", "-o", "delete")
@@ -132,8 +145,8 @@ cleanup:
 	}
 
 	// 2. Remove device-mapper entries
-	cmd := exec.Command("dmsetup", "ls")
-	output, err := cmd.Output()
+	cmd = exec.Command("dmsetup", "ls")
+	output, err = cmd.Output()
 	if err == nil {
 		scanner := bufio.NewScanner(bytes.NewReader(output))
 		for scanner.Scan() {
@@ -184,6 +197,9 @@ cleanup:
 		}
 	}
 
+	// 5. Remove the SELinux policy installed for Longhorn's iSCSI volumes
+	system.RemoveLonghornPolicy(logChan)
+
 	logChan <- "Longhorn cleanup completed, proceeding with K3s uninstall..."
 
 	// Now proceed with K3s uninstall
@@ -1,6 +1,7 @@
 package k3s
 
 import (
+	"cmp"
 	"context"
 	"fmt"
 	"io"
@@ -10,8 +11,11 @@ import (
 	"os/exec"
 	"path/filepath"
 	"runtime"
+	"slices"
 	"strings"
 	"time"
+
+	"github.com/unbindapp/unbind-installer/internal/system"
 )
 
 const K3S_VERSION = "v1.33.1+k3s1"
@@ -98,6 +102,16 @@ type Installer struct {
 	UpdateChan chan<- K3SUpdateMessage
 	// Fact message channel
 	FactChan chan<- string
+	// Version to install, K3S_VERSION when empty
+	Version string
+	// ClusterInit runs embedded etcd instead of sqlite, for restoring etcd backups
+	ClusterInit bool
+	// MemoryTotal in bytes and CPUCount size the resources reserved for the system, zero keeps
+	// the defaults
+	MemoryTotal uint64
+	CPUCount    int
+	// SELinux is the SELinux mode, enforcing or permissive enable K3s' SELinux support
+	SELinux string
 	// Installation state
 	state struct {
 		startTime time.Time
@@ -207,17 +221,61 @@ func (self *Installer) sendFact(fact string) {
 	}
 }
 
+// resourceProfile is what the kubelet keeps for the system and when it starts evicting pods
+type resourceProfile struct {
+	systemMemory string
+	systemCPU    string
+	kubeMemory   string
+	kubeCPU      string
+	evictionSoft string
+	evictionHard string
+}
+
+// profileFor sizes the reserved resources to the server, small servers can't spare the defaults
+// and large ones need more headroom
+func profileFor(memoryTotal uint64, cpuCount int) resourceProfile {
+	profile := resourceProfile{
+		systemMemory: "512Mi", systemCPU: "400m",
+		kubeMemory: "256Mi", kubeCPU: "200m",
+		evictionSoft: "300Mi", evictionHard: "150Mi",
+	}
+
+	const gb = 1 << 30
+	switch {
+	case memoryTotal == 0:
+	case memoryTotal <= 2*gb:
+		profile.systemMemory, profile.kubeMemory = "256Mi", "128Mi"
+		profile.evictionSoft, profile.evictionHard = "200Mi", "100Mi"
+	case memoryTotal > 16*gb:
+		profile.systemMemory, profile.kubeMemory = "1Gi", "512Mi"
+		profile.evictionSoft, profile.evictionHard = "500Mi", "250Mi"
+	}
+
+	switch {
+	case cpuCount == 0:
+	case cpuCount == 1:
+		profile.systemCPU, profile.kubeCPU = "200m", "100m"
+	case cpuCount >= 8:
+		profile.systemCPU, profile.kubeCPU = "500m", "300m"
+	}
+	return profile
+}
+
+func (self resourceProfile) flags() string {
+	return fmt.Sprintf("--kubelet-arg=eviction-soft=memory.available<%s ", self.evictionSoft) +
+		"--kubelet-arg=eviction-soft-grace-period=memory.available=2m " +
+		fmt.Sprintf("--kubelet-arg=eviction-hard=memory.available<%s ", self.evictionHard) +
+		"--kubelet-arg=eviction-minimum-reclaim=memory.available=128Mi " +
+		fmt.Sprintf("--kubelet-arg=system-reserved=memory=%s,cpu=%s ", self.systemMemory, self.systemCPU) +
+		fmt.Sprintf("--kubelet-arg=kube-reserved=memory=%s,cpu=%s ", self.kubeMemory, self.kubeCPU)
+}
+
 // Install sets up k3s and returns the kubeconfig path
 func (self *Installer) Install(ctx context.Context) (string, error) {
 	k3sInstallFlags := "--disable=traefik --disable=local-storage " +
 		"--kubelet-arg=fail-swap-on=false " +
 		"--kubelet-arg=config=/etc/rancher/k3s/kubelet-config.yaml " +
-		"--kubelet-arg=eviction-soft=memory.available<300Mi " +
-		"--kubelet-arg=eviction-soft-grace-period=memory.available=2m " +
-		"--kubelet-arg=eviction-hard=memory.available<150Mi " +
-		"--kubelet-arg=eviction-minimum-reclaim=memory.available=128Mi " +
-		"--kubelet-arg=system-reserved=memory=512Mi,cpu=400m " +
-		"--kubelet-arg=kube-reserved=memory=256Mi,cpu=200m " +
+		profileFor(self.MemoryTotal, self.CPUCount).flags() +
 		"--kubelet-arg=image-gc-high-threshold=85 " +
 		"--kubelet-arg=image-gc-low-threshold=80 " +
 		"--kube-controller-manager-arg=terminated-pod-gc-threshold=10 " +
@@ -228,15 +286,27 @@ func (self *Installer) Install(ctx context.Context) (string, error) {
 		"--kube-apiserver-arg=event-ttl=10m " +
 		"--kube-apiserver-arg=audit-log-maxage=7 " +
 		"--kube-apiserver-arg=audit-log-maxbackup=3 " +
-		"--kube-apiserver-arg=audit-log-maxsize=50 " +
-		"--datastore-endpoint=sqlite:///var/lib/rancher/k3s/server/db/state.db?" +
-		"_journal_mode=WAL&" +
-		"_synchronous=NORMAL&" +
-		"_cache_size=20000&" +
-		"_temp_store=MEMORY&" +
-		"_mmap_size=134217728&" +
-		"_page_size=4096&" +
-		"_wal_checkpoint=PASSIVE"
+		"--kube-apiserver-arg=audit-log-maxsize=50 "
+	if self.selinuxEnabled() {
+		k3sInstallFlags += "--selinux "
+	}
+	if self.ClusterInit {
+		k3sInstallFlags += "--cluster-init"
+	} else {
+		k3sInstallFlags += "--datastore-endpoint=sqlite:///var/lib/rancher/k3s/server/db/state.db?" +
+			"_journal_mode=WAL&" +
+			"_synchronous=NORMAL&" +
+			"_cache_size=20000&" +
+			"_temp_store=MEMORY&" +
+			"_mmap_size=134217728&" +
+			"_page_size=4096&" +
+			"_wal_checkpoint=PASSIVE"
+	}
+
+	version := self.Version
+	if version == "" {
+		version = K3S_VERSION
+	}
 
 	var kubeconfigPath string
 
@@ -369,8 +439,9 @@ memorySwap:
 				installCmd := exec.CommandContext(ctx, "/bin/sh", "/tmp/k3s-installer.sh")
 				installCmd.Env = append(os.Environ(),
 					fmt.Sprintf("INSTALL_K3S_EXEC=%s", k3sInstallFlags),
-					fmt.Sprintf("INSTALL_K3S_VERSION=%s", K3S_VERSION),
+					fmt.Sprintf("INSTALL_K3S_VERSION=%s", version),
 				)
+				installCmd.Env = append(installCmd.Env, self.selinuxInstallEnv(ctx)...)
 
 				installOutput, err := installCmd.CombinedOutput()
 				close(factsDone) // Stop showing facts
@@ -980,6 +1051,13 @@ LimitNPROC=65536
 		},
 	}
 
+	if self.selinuxEnabled() {
+		steps = append(steps, self.selinuxSteps()...)
+		slices.SortStableFunc(steps, func(a, b InstallationStep) int {
+			return cmp.Compare(a.Progress, b.Progress)
+		})
+	}
+
 	// Execute all installation steps
 	for _, step := range steps {
 		// Log the current step
@@ -1004,6 +1082,62 @@ LimitNPROC=65536
 	return kubeconfigPath, nil
 }
 
+func (self *Installer) selinuxEnabled() bool {
+	return self.SELinux == "enforcing" || self.SELinux == "permissive"
+}
+
+// k3sSELinuxInstalled reports whether the k3s-selinux package is installed, mockable for tests
+var k3sSELinuxInstalled = func(ctx context.Context) bool {
+	return exec.CommandContext(ctx, "rpm", "-q", "k3s-selinux").Run() == nil
+}
+
+// selinuxInstallEnv returns the SELinux settings of the K3s install script. The script adds
+// Rancher's repository for k3s-selinux unless it's installed.
+func (self *Installer) selinuxInstallEnv(ctx context.Context) []string {
+	if self.selinuxEnabled() && k3sSELinuxInstalled(ctx) {
+		return []string{"INSTALL_K3S_SKIP_SELINUX_RPM=true"}
+	}
+	return nil
+}
+
+// selinuxSteps install the policy Longhorn needs and check that SELinux didn't deny K3s, containers
+// or iSCSI anything while they started
+func (self *Installer) selinuxSteps() []InstallationStep {
+	return []InstallationStep{
+		{
+			Description: "Installing the Longhorn SELinux policy",
+			Progress:    0.80,
+			Action: func(ctx context.Context) error {
+				return system.InstallLonghornPolicy(self.LogChan)
+			},
+		},
+		{
+			Description: "Checking for SELinux denials",
+			Progress:    0.96,
+			Action: func(ctx context.Context) error {
+				denials, err := system.AVCDenials(self.state.startTime, "container_", "k3s_", "iscsid_t", "spc_t")
+				if err != nil {
+					self.log(fmt.Sprintf("Warning: Could not check for SELinux denials: %v", err))
+					return nil
+				}
+				if len(denials) == 0 {
+					self.log("SELinux didn't deny anything during startup")
+					return nil
+				}
+
+				for _, denial := range denials {
+					self.log(fmt.Sprintf("SELinux denial: %s", denial))
+				}
+				if self.SELinux == "permissive" {
+					self.log(fmt.Sprintf("Warning: SELinux would have denied %d accesses in enforcing mode", len(denials)))
+					return nil
+				}
+				return fmt.Errorf("SELinux denied %d accesses during startup, see ausearch -m AVC -ts recent", len(denials))
+			},
+		},
+	}
+}
+
 // GetLastUpdateMessage returns current status
 func (self *Installer) GetLastUpdateMessage() K3SUpdateMessage {
 	return self.state.lastMsg
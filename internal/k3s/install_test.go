@@ -0,0 +1,33 @@
+package k3s
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSELinuxInstallEnv(t *testing.T) {
+	original := k3sSELinuxInstalled
+	t.Cleanup(func() { k3sSELinuxInstalled = original })
+
+	tests := []struct {
+		selinux   string
+		installed bool
+		enabled   bool
+		env       []string
+	}{
+		{selinux: "enforcing", installed: true, enabled: true, env: []string{"INSTALL_K3S_SKIP_SELINUX_RPM=true"}},
+		{selinux: "permissive", installed: true, enabled: true, env: []string{"INSTALL_K3S_SKIP_SELINUX_RPM=true"}},
+		// The install script adds Rancher's repository itself
+		{selinux: "enforcing", installed: false, enabled: true},
+		{selinux: "disabled", installed: true},
+		{selinux: "", installed: true},
+	}
+	for _, tt := range tests {
+		k3sSELinuxInstalled = func(context.Context) bool { return tt.installed }
+		installer := &Installer{SELinux: tt.selinux}
+		assert.Equal(t, tt.enabled, installer.selinuxEnabled(), tt.selinux)
+		assert.Equal(t, tt.env, installer.selinuxInstallEnv(context.Background()), "%s, installed %v", tt.selinux, tt.installed)
+	}
+}
@@ -0,0 +1,80 @@
+package longhorn
+
+import (
+	"context"
+	"fmt"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+)
+
+// VolumeAttachmentResource is the Longhorn VolumeAttachment resource, named after its volume
+var VolumeAttachmentResource = schema.GroupVersionResource{Group: "longhorn.io", Version: "v1beta2", Resource: "volumeattachments"}
+
+// holdTicket is the attachment ticket that keeps volumes attached without their workloads
+const holdTicket = "unbind-hold"
+
+// HoldAttached adds an attachment ticket to every attached volume so Longhorn keeps it attached to
+// its node once the workloads using it are scaled down, and it can still be backed up. It returns
+// the held volumes for ReleaseAttached.
+func HoldAttached(ctx context.Context, client dynamic.Interface, logFn func(string)) ([]string, error) {
+	volumes, err := client.Resource(VolumeResource).Namespace(Namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list Longhorn volumes: %w", err)
+	}
+
+	held := []string{}
+	for _, volume := range volumes.Items {
+		state, _, _ := unstructured.NestedString(volume.Object, "status", "state")
+		node, _, _ := unstructured.NestedString(volume.Object, "status", "currentNodeID")
+		if state != "attached" || node == "" {
+			continue
+		}
+
+		ticket := map[string]interface{}{
+			"id":     holdTicket,
+			"type":   "longhorn-api",
+			"nodeID": node,
+			// Matches the ticket of the CSI driver, so the volume stays attached as it is
+			"parameters": map[string]interface{}{"disableFrontend": "false"},
+		}
+		if err := updateAttachment(ctx, client, volume.GetName(), func(attachment *unstructured.Unstructured) error {
+			return unstructured.SetNestedField(attachment.Object, ticket, "spec", "attachmentTickets", holdTicket)
+		}); err != nil {
+			return held, fmt.Errorf("failed to keep volume %s attached: %w", volume.GetName(), err)
+		}
+		logFn(fmt.Sprintf("Keeping volume %s attached to %s", volume.GetName(), node))
+		held = append(held, volume.GetName())
+	}
+	return held, nil
+}
+
+// ReleaseAttached removes the tickets HoldAttached added, Longhorn detaches volumes nothing else uses
+func ReleaseAttached(ctx context.Context, client dynamic.Interface, volumes []string) error {
+	for _, volume := range volumes {
+		err := updateAttachment(ctx, client, volume, func(attachment *unstructured.Unstructured) error {
+			unstructured.RemoveNestedField(attachment.Object, "spec", "attachmentTickets", holdTicket)
+			return nil
+		})
+		if err != nil && !apierrors.IsNotFound(err) {
+			return fmt.Errorf("failed to release volume %s: %w", volume, err)
+		}
+	}
+	return nil
+}
+
+func updateAttachment(ctx context.Context, client dynamic.Interface, volume string, change func(*unstructured.Unstructured) error) error {
+	attachments := client.Resource(VolumeAttachmentResource).Namespace(Namespace)
+	attachment, err := attachments.Get(ctx, volume, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	if err := change(attachment); err != nil {
+		return err
+	}
+	_, err = attachments.Update(ctx, attachment, metav1.UpdateOptions{})
+	return err
+}
@@ -0,0 +1,45 @@
+package longhorn
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestHoldAndReleaseAttached(t *testing.T) {
+	csiTicket := map[string]interface{}{"id": "csi-pvc-data", "type": "csi-attacher", "nodeID": "server-1"}
+	client := newFakeClient(
+		longhornObject("Volume", "pvc-data", map[string]interface{}{"status": map[string]interface{}{"state": "attached", "currentNodeID": "server-1"}}),
+		longhornObject("Volume", "pvc-idle", map[string]interface{}{"status": map[string]interface{}{"state": "detached"}}),
+		longhornObject("VolumeAttachment", "pvc-data", map[string]interface{}{
+			"spec": map[string]interface{}{"attachmentTickets": map[string]interface{}{"csi-pvc-data": csiTicket}},
+		}),
+	)
+	ctx := context.Background()
+	tickets := func() map[string]interface{} {
+		attachment, err := client.Resource(VolumeAttachmentResource).Namespace(Namespace).Get(ctx, "pvc-data", metav1.GetOptions{})
+		require.NoError(t, err)
+		tickets, _, _ := unstructured.NestedMap(attachment.Object, "spec", "attachmentTickets")
+		return tickets
+	}
+
+	held, err := HoldAttached(ctx, client, func(string) {})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"pvc-data"}, held, "detached volumes have nothing to hold")
+	assert.Equal(t, map[string]interface{}{
+		"csi-pvc-data": csiTicket,
+		holdTicket: map[string]interface{}{
+			"id":         holdTicket,
+			"type":       "longhorn-api",
+			"nodeID":     "server-1",
+			"parameters": map[string]interface{}{"disableFrontend": "false"},
+		},
+	}, tickets())
+
+	require.NoError(t, ReleaseAttached(ctx, client, append(held, "pvc-deleted")))
+	assert.Equal(t, map[string]interface{}{"csi-pvc-data": csiTicket}, tickets())
+}
@@ -0,0 +1,210 @@
+package longhorn
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/unbindapp/unbind-installer/internal/awsauth"
+	"github.com/unbindapp/unbind-installer/internal/backup"
+	"github.com/unbindapp/unbind-installer/internal/utils"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const (
+	// Namespace Longhorn is installed in
+	Namespace = "longhorn-system"
+	// CredentialSecretName holds the S3 credentials Longhorn uses for the backup target
+	CredentialSecretName = "unbind-longhorn-backup"
+	// RecurringJobName is the recurring backup job for volumes in the default group
+	RecurringJobName = "unbind-backup"
+
+	// Defaults for the recurring backup job
+	DefaultBackupSchedule = "0 2 * * *"
+	DefaultBackupRetain   = 7
+)
+
+// RecurringJobResource is the Longhorn RecurringJob resource
+var RecurringJobResource = schema.GroupVersionResource{Group: "longhorn.io", Version: "v1beta2", Resource: "recurringjobs"}
+
+// runCommand runs a command, mockable for tests
+var runCommand = func(ctx context.Context, name string, args ...string) error {
+	output, err := exec.CommandContext(ctx, name, args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%s failed: %w: %s", name, err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// dial opens a TCP connection, mockable for tests
+var dial = func(ctx context.Context, address string) (net.Conn, error) {
+	var dialer net.Dialer
+	return dialer.DialContext(ctx, "tcp", address)
+}
+
+// BackupTargetType is where Longhorn keeps volume backups
+type BackupTargetType string
+
+const (
+	BackupTargetS3  BackupTargetType = "s3"
+	BackupTargetNFS BackupTargetType = "nfs"
+)
+
+// BackupTarget is an off-node location for Longhorn volume backups
+type BackupTarget struct {
+	Type BackupTargetType
+
+	// S3 compatible bucket
+	Bucket          string
+	Prefix          string
+	Region          string
+	Endpoint        string // Empty for AWS S3, e.g. https://minio.example.com:9000 otherwise
+	AccessKeyID     string
+	SecretAccessKey string
+
+	// NFS export, e.g. nfs.example.com:/exports/longhorn
+	NFSExport string
+}
+
+// ParseBucket splits s3://bucket/prefix or bucket/prefix
+func ParseBucket(spec string) (bucket, prefix string) {
+	bucket, prefix, _ = strings.Cut(strings.TrimPrefix(strings.TrimSpace(spec), "s3://"), "/")
+	return bucket, strings.Trim(prefix, "/")
+}
+
+// Validate checks the settings without contacting the target
+func (self *BackupTarget) Validate() error {
+	switch self.Type {
+	case BackupTargetS3:
+		if self.Bucket == "" {
+			return fmt.Errorf("a bucket is required")
+		}
+		if self.Region == "" {
+			return fmt.Errorf("a region is required, S3 compatible services usually accept us-east-1")
+		}
+		if self.AccessKeyID == "" || self.SecretAccessKey == "" {
+			return fmt.Errorf("an access key ID and secret access key are required")
+		}
+		if self.Endpoint != "" {
+			endpoint, err := url.Parse(self.Endpoint)
+			if err != nil || (endpoint.Scheme != "http" && endpoint.Scheme != "https") || endpoint.Host == "" {
+				return fmt.Errorf("invalid endpoint '%s', expected a URL such as https://minio.example.com:9000", self.Endpoint)
+			}
+		}
+	case BackupTargetNFS:
+		host, path, ok := strings.Cut(strings.TrimPrefix(self.NFSExport, "nfs://"), ":")
+		if !ok || !strings.HasPrefix(path, "/") || (!utils.IsDNSName(host) && !utils.IsIP(host)) {
+			return fmt.Errorf("invalid NFS export '%s', expected server:/path", self.NFSExport)
+		}
+	default:
+		return fmt.Errorf("unknown backup target type '%s'", self.Type)
+	}
+	return nil
+}
+
+// URL returns the target in the form of Longhorn's backupTarget setting
+func (self *BackupTarget) URL() string {
+	if self.Type == BackupTargetNFS {
+		return "nfs://" + strings.TrimPrefix(self.NFSExport, "nfs://")
+	}
+	return fmt.Sprintf("s3://%s@%s/%s", self.Bucket, self.Region, self.Prefix)
+}
+
+// CredentialSecretData returns the contents of the credential secret, nil when none is needed
+func (self *BackupTarget) CredentialSecretData() map[string]string {
+	if self.Type != BackupTargetS3 {
+		return nil
+	}
+	data := map[string]string{
+		"AWS_ACCESS_KEY_ID":     self.AccessKeyID,
+		"AWS_SECRET_ACCESS_KEY": self.SecretAccessKey,
+	}
+	if self.Endpoint != "" {
+		data["AWS_ENDPOINTS"] = self.Endpoint
+	}
+	return data
+}
+
+// CheckWritable makes sure the target is reachable and a file can be written to it
+func (self *BackupTarget) CheckWritable(ctx context.Context, logFn func(string)) error {
+	if self.Type == BackupTargetNFS {
+		return self.checkNFS(ctx, logFn)
+	}
+
+	target, err := backup.NewS3Target(self.Bucket, self.Prefix, backup.S3Config{
+		Endpoint: self.Endpoint,
+		Region:   self.Region,
+		Credentials: awsauth.Credentials{
+			AccessKeyID:     self.AccessKeyID,
+			SecretAccessKey: self.SecretAccessKey,
+		},
+	})
+	if err != nil {
+		return err
+	}
+	logFn(fmt.Sprintf("Writing a test object to %s...", target))
+	return target.CheckWritable(ctx)
+}
+
+// checkNFS connects to the NFS server, then mounts the export and writes a test file the way
+// Longhorn will
+func (self *BackupTarget) checkNFS(ctx context.Context, logFn func(string)) error {
+	export := strings.TrimPrefix(self.NFSExport, "nfs://")
+	host, _, _ := strings.Cut(export, ":")
+
+	logFn(fmt.Sprintf("Connecting to NFS server %s...", host))
+	conn, err := dial(ctx, net.JoinHostPort(host, "2049"))
+	if err != nil {
+		return fmt.Errorf("cannot reach NFS server %s on port 2049: %w", host, err)
+	}
+	conn.Close()
+
+	mountDir, err := os.MkdirTemp("", "unbind-nfs-check-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(mountDir)
+
+	logFn(fmt.Sprintf("Mounting %s...", export))
+	if err := runCommand(ctx, "mount", "-t", "nfs4", "-o", "soft,timeo=50,retrans=1", export, mountDir); err != nil {
+		return fmt.Errorf("cannot mount %s: %w", export, err)
+	}
+	defer func() {
+		if err := runCommand(context.Background(), "umount", mountDir); err != nil {
+			logFn(fmt.Sprintf("Warning: failed to unmount %s: %v", mountDir, err))
+		}
+	}()
+
+	probe := filepath.Join(mountDir, ".unbind-write-check")
+	if err := os.WriteFile(probe, []byte("unbind write check\n"), 0600); err != nil {
+		return fmt.Errorf("cannot write to %s: %w", export, err)
+	}
+	return os.Remove(probe)
+}
+
+// RecurringBackupJob returns a recurring job backing up every volume in the default group
+func RecurringBackupJob(schedule string, retain int) *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "longhorn.io/v1beta2",
+		"kind":       "RecurringJob",
+		"metadata": map[string]interface{}{
+			"name":      RecurringJobName,
+			"namespace": Namespace,
+		},
+		"spec": map[string]interface{}{
+			"name":        RecurringJobName,
+			"task":        "backup",
+			"cron":        schedule,
+			"retain":      int64(retain),
+			"concurrency": int64(1),
+			"groups":      []interface{}{"default"},
+			"labels":      map[string]interface{}{},
+		},
+	}}
+}
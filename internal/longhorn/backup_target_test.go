@@ -0,0 +1,109 @@
+package longhorn
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackupTarget_Validate(t *testing.T) {
+	valid := BackupTarget{Type: BackupTargetS3, Bucket: "backups", Region: "us-east-1", AccessKeyID: "minio", SecretAccessKey: "minio123"}
+	assert.NoError(t, valid.Validate())
+
+	withEndpoint := valid
+	withEndpoint.Endpoint = "https://minio.example.com:9000"
+	assert.NoError(t, withEndpoint.Validate())
+
+	badEndpoint := valid
+	badEndpoint.Endpoint = "minio.example.com"
+	assert.ErrorContains(t, badEndpoint.Validate(), "invalid endpoint")
+
+	noRegion := valid
+	noRegion.Region = ""
+	assert.ErrorContains(t, noRegion.Validate(), "region")
+
+	noKeys := valid
+	noKeys.SecretAccessKey = ""
+	assert.ErrorContains(t, noKeys.Validate(), "secret access key")
+
+	assert.NoError(t, (&BackupTarget{Type: BackupTargetNFS, NFSExport: "nfs.example.com:/exports/longhorn"}).Validate())
+	assert.NoError(t, (&BackupTarget{Type: BackupTargetNFS, NFSExport: "nfs://10.0.0.20:/longhorn"}).Validate())
+	assert.ErrorContains(t, (&BackupTarget{Type: BackupTargetNFS, NFSExport: "nfs.example.com"}).Validate(), "server:/path")
+	assert.ErrorContains(t, (&BackupTarget{Type: BackupTargetNFS, NFSExport: "nfs.example.com:exports"}).Validate(), "server:/path")
+}
+
+func TestBackupTarget_URLAndSecret(t *testing.T) {
+	bucket, prefix := ParseBucket("s3://backups/cluster-a/")
+	target := BackupTarget{Type: BackupTargetS3, Bucket: bucket, Prefix: prefix, Region: "us-east-1", AccessKeyID: "minio", SecretAccessKey: "minio123"}
+	assert.Equal(t, "s3://backups@us-east-1/cluster-a", target.URL())
+	assert.Equal(t, map[string]string{"AWS_ACCESS_KEY_ID": "minio", "AWS_SECRET_ACCESS_KEY": "minio123"}, target.CredentialSecretData())
+
+	target.Endpoint = "https://minio.example.com:9000"
+	assert.Equal(t, "https://minio.example.com:9000", target.CredentialSecretData()["AWS_ENDPOINTS"])
+
+	nfs := BackupTarget{Type: BackupTargetNFS, NFSExport: "nfs.example.com:/exports/longhorn"}
+	assert.Equal(t, "nfs://nfs.example.com:/exports/longhorn", nfs.URL())
+	assert.Nil(t, nfs.CredentialSecretData())
+}
+
+// fakeNFS answers dials and mounts, recording the commands run
+func fakeNFS(t *testing.T, dialErr, mountErr error) *[]string {
+	originalDial, originalRun := dial, runCommand
+	t.Cleanup(func() { dial, runCommand = originalDial, originalRun })
+
+	commands := []string{}
+	dial = func(ctx context.Context, address string) (net.Conn, error) {
+		assert.Equal(t, "nfs.example.com:2049", address)
+		if dialErr != nil {
+			return nil, dialErr
+		}
+		client, server := net.Pipe()
+		server.Close()
+		return client, nil
+	}
+	runCommand = func(ctx context.Context, name string, args ...string) error {
+		commands = append(commands, name)
+		if name == "mount" {
+			assert.Equal(t, "nfs.example.com:/exports/longhorn", args[len(args)-2])
+			return mountErr
+		}
+		return nil
+	}
+	return &commands
+}
+
+func TestBackupTarget_CheckNFS(t *testing.T) {
+	target := BackupTarget{Type: BackupTargetNFS, NFSExport: "nfs.example.com:/exports/longhorn"}
+	ctx := context.Background()
+
+	commands := fakeNFS(t, nil, nil)
+	require.NoError(t, target.CheckWritable(ctx, func(string) {}))
+	assert.Equal(t, []string{"mount", "umount"}, *commands)
+	leftovers, _ := filepath.Glob(filepath.Join(os.TempDir(), "unbind-nfs-check-*"))
+	assert.Empty(t, leftovers)
+
+	fakeNFS(t, errors.New("connection refused"), nil)
+	assert.ErrorContains(t, target.CheckWritable(ctx, func(string) {}), "cannot reach NFS server nfs.example.com on port 2049")
+
+	commands = fakeNFS(t, nil, errors.New("access denied by server"))
+	assert.ErrorContains(t, target.CheckWritable(ctx, func(string) {}), "access denied by server")
+	assert.Equal(t, []string{"mount"}, *commands)
+}
+
+func TestRecurringBackupJob(t *testing.T) {
+	job := RecurringBackupJob("0 4 * * *", 14)
+	assert.Equal(t, RecurringJobName, job.GetName())
+	assert.Equal(t, Namespace, job.GetNamespace())
+
+	spec := job.Object["spec"].(map[string]interface{})
+	assert.Equal(t, "backup", spec["task"])
+	assert.Equal(t, "0 4 * * *", spec["cron"])
+	assert.Equal(t, int64(14), spec["retain"])
+	assert.Equal(t, []interface{}{"default"}, spec["groups"])
+}
@@ -0,0 +1,109 @@
+package longhorn
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+)
+
+// SnapshotResource is the Longhorn Snapshot resource
+var SnapshotResource = schema.GroupVersionResource{Group: "longhorn.io", Version: "v1beta2", Resource: "snapshots"}
+
+// snapshotVolumeLabel links a snapshot to its volume
+const snapshotVolumeLabel = "longhornvolume"
+
+// BackupVolumes backs up every attached volume to the backup target now and waits for the backups
+// to complete. Longhorn can only snapshot attached volumes, detached ones are returned so callers
+// can point out that their latest earlier backup will be used.
+func BackupVolumes(ctx context.Context, client dynamic.Interface, logFn func(string)) (backedUp, detached []string, err error) {
+	volumes, err := client.Resource(VolumeResource).Namespace(Namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list Longhorn volumes: %w", err)
+	}
+
+	// Start every backup first, Longhorn runs them concurrently
+	name := "unbind-" + time.Now().UTC().Format("20060102t150405")
+	pending := map[string]string{}
+	for _, volume := range volumes.Items {
+		if state, _, _ := unstructured.NestedString(volume.Object, "status", "state"); state != "attached" {
+			detached = append(detached, volume.GetName())
+			continue
+		}
+
+		backupName, err := startBackup(ctx, client, volume.GetName(), name)
+		if err != nil {
+			return backedUp, detached, fmt.Errorf("failed to back up volume %s: %w", volume.GetName(), err)
+		}
+		logFn(fmt.Sprintf("Backing up volume %s...", volume.GetName()))
+		pending[backupName] = volume.GetName()
+	}
+
+	backups := client.Resource(BackupResource).Namespace(Namespace)
+	for len(pending) > 0 {
+		for backupName, volume := range pending {
+			backup, err := backups.Get(ctx, backupName, metav1.GetOptions{})
+			if err != nil {
+				return backedUp, detached, fmt.Errorf("failed to get backup %s: %w", backupName, err)
+			}
+			switch state, _, _ := unstructured.NestedString(backup.Object, "status", "state"); state {
+			case "Completed":
+				logFn(fmt.Sprintf("Volume %s is backed up", volume))
+				backedUp = append(backedUp, volume)
+				delete(pending, backupName)
+			case "Error":
+				message, _, _ := unstructured.NestedString(backup.Object, "status", "error")
+				return backedUp, detached, fmt.Errorf("backup of volume %s failed: %s", volume, message)
+			}
+		}
+		if len(pending) == 0 {
+			break
+		}
+		if err := sleep(ctx); err != nil {
+			return backedUp, detached, fmt.Errorf("backups didn't complete: %w", err)
+		}
+	}
+	return backedUp, detached, nil
+}
+
+// startBackup snapshots a volume and creates a backup of the snapshot, returning the backup name
+func startBackup(ctx context.Context, client dynamic.Interface, volume, name string) (string, error) {
+	snapshotName := name + "-" + volume
+	snapshot := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "longhorn.io/v1beta2",
+		"kind":       "Snapshot",
+		"metadata": map[string]interface{}{
+			"name":      snapshotName,
+			"namespace": Namespace,
+			"labels":    map[string]interface{}{snapshotVolumeLabel: volume},
+		},
+		"spec": map[string]interface{}{
+			"volume":         volume,
+			"createSnapshot": true,
+		},
+	}}
+	if _, err := client.Resource(SnapshotResource).Namespace(Namespace).Create(ctx, snapshot, metav1.CreateOptions{}); err != nil {
+		return "", err
+	}
+
+	backup := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "longhorn.io/v1beta2",
+		"kind":       "Backup",
+		"metadata": map[string]interface{}{
+			"name":      snapshotName,
+			"namespace": Namespace,
+			"labels":    map[string]interface{}{backupVolumeLabel: volume},
+		},
+		"spec": map[string]interface{}{
+			"snapshotName": snapshotName,
+		},
+	}}
+	if _, err := client.Resource(BackupResource).Namespace(Namespace).Create(ctx, backup, metav1.CreateOptions{}); err != nil {
+		return "", err
+	}
+	return snapshotName, nil
+}
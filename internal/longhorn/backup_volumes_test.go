@@ -0,0 +1,65 @@
+package longhorn
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// completeBackups makes Longhorn finish backups as soon as they are created, with the given state
+func completeBackups(client *dynamicfake.FakeDynamicClient, state string) {
+	client.PrependReactor("create", "backups", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		backup := action.(k8stesting.CreateAction).GetObject().(*unstructured.Unstructured)
+		backup.Object["status"] = map[string]interface{}{"state": state, "error": "backup target unavailable"}
+		return false, nil, nil
+	})
+}
+
+func TestBackupVolumes(t *testing.T) {
+	client := newFakeClient(
+		longhornObject("Volume", "pvc-data", map[string]interface{}{"status": map[string]interface{}{"state": "attached"}}),
+		longhornObject("Volume", "pvc-idle", map[string]interface{}{"status": map[string]interface{}{"state": "detached"}}),
+	).(*dynamicfake.FakeDynamicClient)
+	completeBackups(client, "Completed")
+	ctx := context.Background()
+
+	backedUp, detached, err := BackupVolumes(ctx, client, func(string) {})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"pvc-data"}, backedUp)
+	assert.Equal(t, []string{"pvc-idle"}, detached)
+
+	snapshots, err := client.Resource(SnapshotResource).Namespace(Namespace).List(ctx, metav1.ListOptions{})
+	require.NoError(t, err)
+	require.Len(t, snapshots.Items, 1)
+	volume, _, _ := unstructured.NestedString(snapshots.Items[0].Object, "spec", "volume")
+	assert.Equal(t, "pvc-data", volume)
+
+	backups, err := client.Resource(BackupResource).Namespace(Namespace).List(ctx, metav1.ListOptions{})
+	require.NoError(t, err)
+	require.Len(t, backups.Items, 1)
+	assert.Equal(t, "pvc-data", backups.Items[0].GetLabels()[backupVolumeLabel])
+	snapshotName, _, _ := unstructured.NestedString(backups.Items[0].Object, "spec", "snapshotName")
+	assert.Equal(t, snapshots.Items[0].GetName(), snapshotName)
+}
+
+func TestBackupVolumes_Failed(t *testing.T) {
+	original := pollInterval
+	t.Cleanup(func() { pollInterval = original })
+	pollInterval = time.Millisecond
+
+	client := newFakeClient(
+		longhornObject("Volume", "pvc-data", map[string]interface{}{"status": map[string]interface{}{"state": "attached"}}),
+	).(*dynamicfake.FakeDynamicClient)
+	completeBackups(client, "Error")
+
+	_, _, err := BackupVolumes(context.Background(), client, func(string) {})
+	assert.ErrorContains(t, err, "backup of volume pvc-data failed: backup target unavailable")
+}
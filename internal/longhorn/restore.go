@@ -0,0 +1,194 @@
+package longhorn
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+)
+
+// Longhorn resources used to restore volumes
+var (
+	VolumeResource       = schema.GroupVersionResource{Group: "longhorn.io", Version: "v1beta2", Resource: "volumes"}
+	BackupResource       = schema.GroupVersionResource{Group: "longhorn.io", Version: "v1beta2", Resource: "backups"}
+	BackupTargetResource = schema.GroupVersionResource{Group: "longhorn.io", Version: "v1beta2", Resource: "backuptargets"}
+)
+
+const (
+	// defaultBackupTargetName is the backup target Longhorn creates from its settings
+	defaultBackupTargetName = "default"
+	// backupVolumeLabel links a backup to the volume it was taken of
+	backupVolumeLabel = "backup-volume"
+)
+
+// pollInterval is how often restores check on Longhorn, shortened in tests
+var pollInterval = 5 * time.Second
+
+// volumeSpecFields are the volume settings kept when a volume is recreated from a backup.
+// Everything else, such as the node it was attached to, belonged to the lost server.
+var volumeSpecFields = []string{
+	"size",
+	"numberOfReplicas",
+	"accessMode",
+	"frontend",
+	"dataEngine",
+	"dataLocality",
+	"encrypted",
+	"staleReplicaTimeout",
+}
+
+// ReconnectBackupTarget points Longhorn at the backup target, asks it to sync the backups stored
+// there and waits until the target is available
+func ReconnectBackupTarget(ctx context.Context, client dynamic.Interface, targetURL, credentialSecret string, logFn func(string)) error {
+	targets := client.Resource(BackupTargetResource).Namespace(Namespace)
+
+	logFn(fmt.Sprintf("Connecting Longhorn to %s...", targetURL))
+	for {
+		target, err := targets.Get(ctx, defaultBackupTargetName, metav1.GetOptions{})
+		if err == nil {
+			spec := map[string]interface{}{}
+			if existing, ok := target.Object["spec"].(map[string]interface{}); ok {
+				spec = existing
+			}
+			spec["backupTargetURL"] = targetURL
+			spec["credentialSecret"] = credentialSecret
+			spec["syncRequestedAt"] = time.Now().UTC().Format(time.RFC3339)
+			target.Object["spec"] = spec
+			_, err = targets.Update(ctx, target, metav1.UpdateOptions{})
+		}
+		// Longhorn creates the target once its manager is running
+		if err != nil && !apierrors.IsNotFound(err) && !apierrors.IsConflict(err) {
+			return fmt.Errorf("failed to update the Longhorn backup target: %w", err)
+		}
+		if err == nil {
+			break
+		}
+		if err := sleep(ctx); err != nil {
+			return fmt.Errorf("Longhorn didn't start: %w", err)
+		}
+	}
+
+	logFn("Waiting for Longhorn to sync the backup target...")
+	for {
+		target, err := targets.Get(ctx, defaultBackupTargetName, metav1.GetOptions{})
+		if err != nil {
+			return fmt.Errorf("failed to get the Longhorn backup target: %w", err)
+		}
+		if available, _, _ := unstructured.NestedBool(target.Object, "status", "available"); available {
+			return nil
+		}
+		if err := sleep(ctx); err != nil {
+			return fmt.Errorf("the Longhorn backup target %s isn't available: %w", targetURL, err)
+		}
+	}
+}
+
+// RestoreVolumes recreates every Longhorn volume from its latest completed backup, keeping the
+// volume names so the persistent volumes of workloads bind to the restored data. Volumes without
+// a backup are left alone and returned.
+func RestoreVolumes(ctx context.Context, client dynamic.Interface, logFn func(string)) (restored, missing []string, err error) {
+	volumes, err := client.Resource(VolumeResource).Namespace(Namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list Longhorn volumes: %w", err)
+	}
+	backups, err := client.Resource(BackupResource).Namespace(Namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list Longhorn backups: %w", err)
+	}
+	latest := latestBackups(backups.Items)
+
+	for _, volume := range volumes.Items {
+		url, ok := latest[volume.GetName()]
+		if !ok {
+			logFn(fmt.Sprintf("No backup of volume %s was found, it can't be restored", volume.GetName()))
+			missing = append(missing, volume.GetName())
+			continue
+		}
+
+		logFn(fmt.Sprintf("Restoring volume %s from %s...", volume.GetName(), url))
+		if err := restoreVolume(ctx, client, &volume, url); err != nil {
+			return restored, missing, fmt.Errorf("failed to restore volume %s: %w", volume.GetName(), err)
+		}
+		restored = append(restored, volume.GetName())
+	}
+	return restored, missing, nil
+}
+
+// latestBackups returns the URL of the newest completed backup of each volume
+func latestBackups(backups []unstructured.Unstructured) map[string]string {
+	urls := map[string]string{}
+	created := map[string]string{}
+	for _, backup := range backups {
+		volume := backup.GetLabels()[backupVolumeLabel]
+		state, _, _ := unstructured.NestedString(backup.Object, "status", "state")
+		url, _, _ := unstructured.NestedString(backup.Object, "status", "url")
+		if volume == "" || state != "Completed" || url == "" {
+			continue
+		}
+		// RFC 3339 timestamps in UTC sort as strings
+		createdAt, _, _ := unstructured.NestedString(backup.Object, "status", "backupCreatedAt")
+		if _, ok := urls[volume]; !ok || createdAt > created[volume] {
+			urls[volume] = url
+			created[volume] = createdAt
+		}
+	}
+	return urls
+}
+
+// restoreVolume deletes the volume, whose replicas were on the lost server, and creates it again
+// from the backup
+func restoreVolume(ctx context.Context, client dynamic.Interface, volume *unstructured.Unstructured, backupURL string) error {
+	volumes := client.Resource(VolumeResource).Namespace(Namespace)
+
+	spec := map[string]interface{}{"fromBackup": backupURL}
+	if existing, ok := volume.Object["spec"].(map[string]interface{}); ok {
+		for _, field := range volumeSpecFields {
+			if value, ok := existing[field]; ok {
+				spec[field] = value
+			}
+		}
+	}
+	replacement := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": volume.GetAPIVersion(),
+		"kind":       volume.GetKind(),
+		"metadata": map[string]interface{}{
+			"name":      volume.GetName(),
+			"namespace": Namespace,
+		},
+		"spec": spec,
+	}}
+	replacement.SetLabels(volume.GetLabels())
+
+	if err := volumes.Delete(ctx, volume.GetName(), metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+	for {
+		_, err := volumes.Get(ctx, volume.GetName(), metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if err := sleep(ctx); err != nil {
+			return fmt.Errorf("the old volume wasn't deleted: %w", err)
+		}
+	}
+
+	_, err := volumes.Create(ctx, replacement, metav1.CreateOptions{})
+	return err
+}
+
+func sleep(ctx context.Context) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(pollInterval):
+		return nil
+	}
+}
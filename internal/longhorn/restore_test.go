@@ -0,0 +1,110 @@
+package longhorn
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+)
+
+func longhornObject(kind, name string, fields map[string]interface{}) *unstructured.Unstructured {
+	object := &unstructured.Unstructured{Object: fields}
+	object.SetAPIVersion("longhorn.io/v1beta2")
+	object.SetKind(kind)
+	object.SetName(name)
+	object.SetNamespace(Namespace)
+	return object
+}
+
+func testBackup(name, volume, state, createdAt string) *unstructured.Unstructured {
+	backup := longhornObject("Backup", name, map[string]interface{}{
+		"status": map[string]interface{}{
+			"state":           state,
+			"url":             "s3://backups@us-east-1/?backup=" + name + "&volume=" + volume,
+			"backupCreatedAt": createdAt,
+		},
+	})
+	backup.SetLabels(map[string]string{backupVolumeLabel: volume})
+	return backup
+}
+
+func newFakeClient(objects ...runtime.Object) dynamic.Interface {
+	return dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{
+			VolumeResource:           "VolumeList",
+			BackupResource:           "BackupList",
+			BackupTargetResource:     "BackupTargetList",
+			SnapshotResource:         "SnapshotList",
+			VolumeAttachmentResource: "VolumeAttachmentList",
+		}, objects...)
+}
+
+func TestRestoreVolumes(t *testing.T) {
+	client := newFakeClient(
+		longhornObject("Volume", "pvc-data", map[string]interface{}{
+			"spec": map[string]interface{}{
+				"size":             "10737418240",
+				"numberOfReplicas": int64(1),
+				"accessMode":       "rwo",
+				"nodeID":           "old-server",
+			},
+		}),
+		longhornObject("Volume", "pvc-cache", map[string]interface{}{"spec": map[string]interface{}{"size": "1073741824"}}),
+		testBackup("backup-old", "pvc-data", "Completed", "2025-06-01T02:00:00Z"),
+		testBackup("backup-new", "pvc-data", "Completed", "2025-06-02T02:00:00Z"),
+		testBackup("backup-partial", "pvc-data", "Error", "2025-06-03T02:00:00Z"),
+	)
+	ctx := context.Background()
+
+	restored, missing, err := RestoreVolumes(ctx, client, func(string) {})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"pvc-data"}, restored)
+	assert.Equal(t, []string{"pvc-cache"}, missing)
+
+	volume, err := client.Resource(VolumeResource).Namespace(Namespace).Get(ctx, "pvc-data", metav1.GetOptions{})
+	require.NoError(t, err)
+	spec := volume.Object["spec"].(map[string]interface{})
+	assert.Equal(t, "s3://backups@us-east-1/?backup=backup-new&volume=pvc-data", spec["fromBackup"])
+	assert.Equal(t, "10737418240", spec["size"])
+	assert.Equal(t, int64(1), spec["numberOfReplicas"])
+	assert.NotContains(t, spec, "nodeID", "the lost server can't be attached to")
+
+	untouched, err := client.Resource(VolumeResource).Namespace(Namespace).Get(ctx, "pvc-cache", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.NotContains(t, untouched.Object["spec"], "fromBackup")
+}
+
+func TestReconnectBackupTarget(t *testing.T) {
+	original := pollInterval
+	t.Cleanup(func() { pollInterval = original })
+	pollInterval = time.Millisecond
+
+	client := newFakeClient(longhornObject("BackupTarget", "default", map[string]interface{}{
+		"spec":   map[string]interface{}{"pollInterval": "5m0s"},
+		"status": map[string]interface{}{"available": true},
+	}))
+	ctx := context.Background()
+
+	require.NoError(t, ReconnectBackupTarget(ctx, client, "s3://backups@us-east-1/", CredentialSecretName, func(string) {}))
+	target, err := client.Resource(BackupTargetResource).Namespace(Namespace).Get(ctx, "default", metav1.GetOptions{})
+	require.NoError(t, err)
+	spec := target.Object["spec"].(map[string]interface{})
+	assert.Equal(t, "s3://backups@us-east-1/", spec["backupTargetURL"])
+	assert.Equal(t, CredentialSecretName, spec["credentialSecret"])
+	assert.Equal(t, "5m0s", spec["pollInterval"])
+	assert.NotEmpty(t, spec["syncRequestedAt"])
+
+	// Gives up when Longhorn never reports the target available
+	client = newFakeClient(longhornObject("BackupTarget", "default", map[string]interface{}{}))
+	ctx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	assert.ErrorContains(t, ReconnectBackupTarget(ctx, client, "nfs://nfs.example.com:/longhorn", "", func(string) {}), "isn't available")
+}
@@ -0,0 +1,36 @@
+package network
+
+import (
+	"fmt"
+	"time"
+)
+
+// ValidateDomain checks whether the domain resolves to the expected IP and whether it is
+// behind Cloudflare. If allowCloudflare is false and the domain *is* behind Cloudflare,
+// the domain is considered invalid.
+func ValidateDomain(domain, expectedIP string, allowCloudflare bool, logFn func(string)) (dnsValid, behindCF bool) {
+	logFn(fmt.Sprintf("Checking %s…", domain))
+
+	behindCF = CheckCloudflareProxy(domain, logFn)
+	if behindCF && !allowCloudflare {
+		return false, true
+	}
+
+	dnsValid = ValidateDNS(domain, expectedIP, logFn)
+	return dnsValid, behindCF
+}
+
+// DetectWildcard probes an arbitrary sub‑domain to infer wildcard DNS configuration.
+// If the probe domain is behind Cloudflare the presence of wildcard is assumed true.
+func DetectWildcard(base, expectedIP string, logFn func(string)) (dnsValid, behindCF bool) {
+	probe := fmt.Sprintf("test%d.%s", time.Now().Unix(), base)
+	logFn(fmt.Sprintf("Checking for wildcard domain with %s…", probe))
+
+	behindCF = CheckCloudflareProxy(probe, logFn)
+	if behindCF {
+		return true, true // wildcard via Cloudflare
+	}
+
+	dnsValid = ValidateDNS(probe, expectedIP, logFn)
+	return dnsValid, behindCF
+}
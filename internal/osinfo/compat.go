@@ -0,0 +1,66 @@
+package osinfo
+
+import (
+	"slices"
+	"strings"
+)
+
+// Derivative maps a distribution to the supported distribution it installs packages like
+type Derivative struct {
+	// Family is the supported distribution whose packages and version rules apply
+	Family string
+	// Versions maps the derivative's major versions to family versions, nil when they match
+	Versions map[string]string
+	// Rolling releases have no versions, any release is supported
+	Rolling bool
+}
+
+// Derivatives lists the distributions supported through their family, keyed by os-release ID
+var Derivatives = map[string]Derivative{
+	"opensuse-leap":       {Family: "opensuse"},
+	"opensuse-tumbleweed": {Family: "opensuse", Rolling: true},
+	"sles":                {Family: "opensuse"},
+	"rhel":                {Family: "rocky"},
+	"ol":                  {Family: "rocky"},
+	// Amazon Linux 2023 is built from Fedora but tracks EL9, Amazon Linux 2 still uses yum
+	"amzn":      {Family: "rocky", Versions: map[string]string{"2023": "9"}},
+	"linuxmint": {Family: "ubuntu", Versions: map[string]string{"21": "22.04", "22": "24.04"}},
+	"pop":       {Family: "ubuntu"},
+}
+
+// resolveFamily sets Distribution to the supported family of the detected distribution. The ID
+// wins over ID_LIKE, so derivatives listed in Derivatives get their own version rules and others
+// fall back to the first family they claim to be like.
+func resolveFamily(info *OSInfo) {
+	for _, id := range append([]string{info.ID}, info.IDLike...) {
+		if slices.Contains(AllSupportedDistros, id) {
+			info.Distribution = id
+			return
+		}
+		if derivative, ok := Derivatives[id]; ok {
+			info.Distribution = derivative.Family
+			return
+		}
+	}
+}
+
+// versionSupported checks the detected version against the rules of its family
+func versionSupported(info *OSInfo) bool {
+	derivative, ok := Derivatives[info.ID]
+	if !ok {
+		return IsVersionSupported(info.Distribution, info.VersionID)
+	}
+	if derivative.Rolling {
+		return true
+	}
+
+	version := info.VersionID
+	if derivative.Versions != nil {
+		mapped, found := derivative.Versions[strings.Split(version, ".")[0]]
+		if !found {
+			return false
+		}
+		version = mapped
+	}
+	return IsVersionSupported(derivative.Family, version)
+}
@@ -18,6 +18,14 @@ var AllSupportedDistros = []string{
 	"centos",
 	"rocky",
 	"almalinux",
+	"arch",
+	"manjaro",
+}
+
+// Rolling release distros have no versions, any release is supported
+var RollingReleaseDistros = []string{
+	"arch",
+	"manjaro",
 }
 
 var AllSupportedDistrosVersions = map[string][]string{
@@ -54,11 +62,37 @@ var AllSupportedDistrosVersions = map[string][]string{
 
 // OSInfo contains OS details
 type OSInfo struct {
+	// Distribution is the supported family, it differs from ID on derivatives
 	Distribution string
+	ID           string
+	IDLike       []string
 	Version      string
 	VersionID    string
 	PrettyName   string
 	Architecture string
+	// Virtualization is the hypervisor of a virtual machine, empty on bare metal and in containers
+	Virtualization string
+	// Container is what the installer runs in, such as docker, lxc or wsl2, empty on a full host
+	Container string
+	// Systemd is set when systemd is the init system
+	Systemd bool
+
+	KernelVersion string
+	// Memory sizes are in bytes, zero when /proc/meminfo can't be read
+	MemoryTotal     uint64
+	MemoryAvailable uint64
+	CPUCount        int
+	CPUModel        string
+	// CgroupVersion is 1 or 2
+	CgroupVersion int
+	// InitSystem is the name of PID 1, such as systemd or openrc-init
+	InitSystem string
+	// Filesystem types of / and /var/lib, where K3s and Longhorn keep their data
+	RootFilesystem   string
+	VarLibFilesystem string
+	// SELinux is enforcing, permissive or disabled
+	SELinux  string
+	AppArmor bool
 }
 
 // Allow mock
@@ -76,6 +110,10 @@ func getGoarch() string {
 
 // IsVersionSupported checks compatibility
 func IsVersionSupported(distribution, version string) bool {
+	if slices.Contains(RollingReleaseDistros, distribution) {
+		return true
+	}
+
 	versions, ok := AllSupportedDistrosVersions[distribution]
 	if !ok {
 		return false
@@ -116,6 +154,9 @@ func GetOSInfo() (*OSInfo, error) {
 	if err != nil {
 		return nil, err
 	}
+	// Checked by callers that install, see EnvironmentIssues
+	detectEnvironment(info)
+	detectHardware(info)
 
 	// Check if the detected distribution is supported
 	if !slices.Contains(AllSupportedDistros, info.Distribution) {
@@ -123,7 +164,7 @@ func GetOSInfo() (*OSInfo, error) {
 	}
 
 	// Check if the detected version is supported
-	if !IsVersionSupported(info.Distribution, info.VersionID) {
+	if !versionSupported(info) {
 		return nil, errdefs.NewCustomError(errdefs.ErrTypeUnsupportedVersion, fmt.Sprintf("Unsupported version: %s", info.VersionID))
 	}
 
@@ -148,10 +148,9 @@ func TestGetOSInfo_UnsupportedDistribution(t *testing.T) {
 	getArchFunc = func() string { return "amd64" }
 
 	// Create a mock OS release file with unsupported distribution
-	content := `ID=fedora
-VERSION_ID="38"
-VERSION="38 (Workstation Edition)"
-PRETTY_NAME="Fedora Linux 38 (Workstation Edition)"
+	content := `ID=gentoo
+VERSION_ID="2.17"
+PRETTY_NAME="Gentoo Linux"
 `
 	tmpFile := createMockOSReleaseFile(t, content)
 	defer os.Remove(tmpFile.Name())
@@ -174,7 +173,7 @@ PRETTY_NAME="Fedora Linux 38 (Workstation Edition)"
 	customErr, ok := err.(*errdefs.CustomError)
 	assert.True(t, ok, "Expected CustomError type")
 	assert.Equal(t, errdefs.ErrTypeUnsupportedDistribution, customErr.Type, "Wrong error type")
-	assert.Contains(t, err.Error(), "Unsupported distribution: fedora")
+	assert.Contains(t, err.Error(), "Unsupported distribution: gentoo")
 }
 
 func TestGetOSInfo_UnsupportedVersion(t *testing.T) {
@@ -295,3 +294,34 @@ PRETTY_NAME="Ubuntu 22.04 LTS"
 	assert.Nil(t, info, "Expected nil info")
 	assert.Error(t, err, "Expected error for empty distribution")
 }
+
+func TestGetOSInfo_RollingRelease(t *testing.T) {
+	origGetOoosFunc := getOoosFunc
+	origGetArchFunc := getArchFunc
+	origOsOpen := osOpen
+	defer func() {
+		getOoosFunc = origGetOoosFunc
+		getArchFunc = origGetArchFunc
+		osOpen = origOsOpen
+	}()
+
+	getOoosFunc = func() string { return "linux" }
+	getArchFunc = func() string { return "amd64" }
+
+	// Neither needs a VERSION_ID, or even a BUILD_ID
+	for distro, content := range map[string]string{
+		"arch":    "NAME=\"Arch Linux\"\nID=arch\nBUILD_ID=rolling\n",
+		"manjaro": "NAME=\"Manjaro Linux\"\nID=manjaro\nID_LIKE=arch\nPRETTY_NAME=\"Manjaro Linux\"\n",
+	} {
+		tmpFile := createMockOSReleaseFile(t, content)
+		defer os.Remove(tmpFile.Name())
+		osOpen = func(name string) (*os.File, error) {
+			return tmpFile, nil
+		}
+
+		info, err := GetOSInfo()
+		assert.NoError(t, err, distro)
+		require.NotNil(t, info, distro)
+		assert.Equal(t, distro, info.Distribution)
+	}
+}
@@ -0,0 +1,235 @@
+package osinfo
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// hostRoot is prefixed to the files environment detection reads, tests point it at fixtures
+var hostRoot = "/"
+
+// Containers that share the host's init and kernel too closely to run K3s, as named by
+// systemd-detect-virt
+var blockingContainers = map[string]bool{
+	"docker":     true,
+	"podman":     true,
+	"containerd": true,
+	"kubernetes": true,
+	"oci":        true,
+	"rkt":        true,
+	"openvz":     true,
+	"proot":      true,
+	"wsl1":       true,
+}
+
+// containerNames are the display names of containers
+var containerNames = map[string]string{
+	"docker":         "Docker container",
+	"podman":         "Podman container",
+	"containerd":     "containerd container",
+	"kubernetes":     "Kubernetes pod",
+	"lxc":            "LXC/LXD container",
+	"lxc-libvirt":    "libvirt LXC container",
+	"systemd-nspawn": "systemd-nspawn container",
+	"openvz":         "OpenVZ container",
+	"wsl1":           "WSL 1",
+	"wsl2":           "WSL 2",
+}
+
+// dmiVendors maps DMI vendor and product names to hypervisors, first match wins
+var dmiVendors = []struct {
+	prefix string
+	name   string
+}{
+	{"KVM", "kvm"},
+	{"OpenStack", "kvm"},
+	{"KubeVirt", "kvm"},
+	{"Amazon EC2", "amazon"},
+	{"QEMU", "qemu"},
+	{"VMware", "vmware"},
+	{"VMW", "vmware"},
+	{"innotek GmbH", "oracle"},
+	{"VirtualBox", "oracle"},
+	{"Xen", "xen"},
+	{"Bochs", "bochs"},
+	{"Parallels", "parallels"},
+	{"BHYVE", "bhyve"},
+	{"Hyper-V", "microsoft"},
+	{"Google Compute Engine", "google"},
+	{"DigitalOcean", "kvm"},
+	{"Hetzner", "kvm"},
+	{"Apple Virtualization", "apple"},
+}
+
+// EnvironmentIssue is a problem with the environment the installer runs in
+type EnvironmentIssue struct {
+	// Blocking issues keep the installation from succeeding, others are warnings
+	Blocking bool
+	Message  string
+}
+
+// detectEnvironment fills in the container, virtualization and init system of info, the way
+// systemd-detect-virt does
+func detectEnvironment(info *OSInfo) {
+	info.Container = detectContainer()
+	if info.Container == "" {
+		info.Virtualization = detectVirtualization()
+	}
+	info.Systemd = exists("run/systemd/system")
+}
+
+// detectContainer returns the container the installer runs in, empty on a full host
+func detectContainer() string {
+	// WSL kernels are named 5.15.153.1-microsoft-standard-WSL2, or 4.4.0-19041-Microsoft for WSL 1
+	kernel := readHostFile("proc/sys/kernel/osrelease")
+	if strings.Contains(strings.ToLower(kernel), "microsoft") {
+		if strings.Contains(kernel, "WSL2") || strings.Contains(kernel, "microsoft-standard") {
+			return "wsl2"
+		}
+		return "wsl1"
+	}
+
+	if exists("run/.containerenv") {
+		return "podman"
+	}
+	if exists(".dockerenv") {
+		return "docker"
+	}
+
+	// systemd and most container managers tell the container's init what it runs in
+	if container := readHostFile("run/systemd/container"); container != "" {
+		return container
+	}
+	for _, variable := range strings.Split(readHostFile("proc/1/environ"), "\x00") {
+		if container, ok := strings.CutPrefix(variable, "container="); ok && container != "" {
+			return container
+		}
+	}
+
+	cgroup := readHostFile("proc/1/cgroup")
+	switch {
+	case strings.Contains(cgroup, "kubepods"):
+		return "kubernetes"
+	case strings.Contains(cgroup, "/docker"), strings.Contains(cgroup, "docker-"):
+		return "docker"
+	case strings.Contains(cgroup, "libpod"):
+		return "podman"
+	case strings.Contains(cgroup, "/lxc"), strings.Contains(cgroup, "lxc.payload"):
+		return "lxc"
+	}
+
+	// OpenVZ exposes /proc/vz inside containers, the host also has /proc/bc
+	if exists("proc/vz") && !exists("proc/bc") {
+		return "openvz"
+	}
+	return ""
+}
+
+// detectVirtualization returns the hypervisor of a virtual machine, empty on bare metal
+func detectVirtualization() string {
+	for _, file := range []string{"sys_vendor", "product_name", "board_vendor", "bios_vendor"} {
+		value := readHostFile(filepath.Join("sys/class/dmi/id", file))
+		for _, vendor := range dmiVendors {
+			if value != "" && strings.HasPrefix(value, vendor.prefix) {
+				return vendor.name
+			}
+		}
+	}
+	if hypervisor := readHostFile("sys/hypervisor/type"); hypervisor != "" {
+		return hypervisor
+	}
+	// Hypervisors that aren't recognized still set the hypervisor CPU flag
+	for _, line := range strings.Split(readHostFile("proc/cpuinfo"), "\n") {
+		if strings.HasPrefix(line, "flags") && strings.Contains(line+" ", " hypervisor ") {
+			return "other"
+		}
+	}
+	return ""
+}
+
+// EnvironmentName describes where the installer runs, e.g. "LXC/LXD container" or "KVM virtual machine"
+func EnvironmentName(info *OSInfo) string {
+	if info.Container != "" {
+		if name, ok := containerNames[info.Container]; ok {
+			return name
+		}
+		return fmt.Sprintf("%s container", info.Container)
+	}
+	switch info.Virtualization {
+	case "":
+		return "bare metal"
+	case "other":
+		return "virtual machine"
+	case "kvm", "qemu", "xen", "bhyve":
+		return fmt.Sprintf("%s virtual machine", strings.ToUpper(info.Virtualization))
+	case "microsoft":
+		return "Hyper-V virtual machine"
+	case "oracle":
+		return "VirtualBox virtual machine"
+	default:
+		return fmt.Sprintf("%s%s virtual machine", strings.ToUpper(info.Virtualization[:1]), info.Virtualization[1:])
+	}
+}
+
+// EnvironmentIssues explains how the environment the installer runs in keeps the installation
+// from working, swap creation, Longhorn's iSCSI and the K3s systemd service being the usual suspects
+func EnvironmentIssues(info *OSInfo) []EnvironmentIssue {
+	var issues []EnvironmentIssue
+	switch {
+	case info.Container == "wsl1":
+		issues = append(issues, EnvironmentIssue{Blocking: true,
+			Message: "WSL 1 has no Linux kernel to run K3s, convert the distribution with wsl --set-version <distribution> 2"})
+	case info.Container == "openvz":
+		issues = append(issues, EnvironmentIssue{Blocking: true,
+			Message: "OpenVZ containers can't load the kernel modules K3s and Longhorn need, install on a KVM virtual machine or a dedicated server"})
+	case blockingContainers[info.Container]:
+		issues = append(issues, EnvironmentIssue{Blocking: true,
+			Message: fmt.Sprintf("Unbind can't be installed inside a %s, K3s, swap and Longhorn's iSCSI need a full server. Run the installer on the host or in a virtual machine.", EnvironmentName(info))})
+	case info.Container == "wsl2":
+		issues = append(issues, EnvironmentIssue{
+			Message: "WSL 2 is only suitable for trying Unbind out: swap is managed by Windows in .wslconfig, the default WSL kernel lacks the iSCSI modules Longhorn volumes need, and the server isn't reachable from other machines without port forwarding"})
+	case info.Container == "lxc", info.Container == "lxc-libvirt":
+		issues = append(issues, EnvironmentIssue{
+			Message: "K3s only runs in privileged LXC/LXD containers with nesting enabled, swap can't be created inside the container, and Longhorn needs the iscsi_tcp module loaded on the host"})
+	case info.Container != "":
+		issues = append(issues, EnvironmentIssue{
+			Message: fmt.Sprintf("Unbind isn't tested inside a %s, swap can't be created and Longhorn needs the iscsi_tcp module loaded on the host", EnvironmentName(info))})
+	}
+
+	// K3s installs itself as a systemd service
+	if !info.Systemd && !blockingContainers[info.Container] {
+		message := "systemd isn't running, K3s needs it to run as a service"
+		if info.Container == "wsl2" {
+			message += ", enable it with systemd=true in the [boot] section of /etc/wsl.conf and restart WSL with wsl --shutdown"
+		}
+		issues = append(issues, EnvironmentIssue{Blocking: true, Message: message})
+	}
+	return issues
+}
+
+// BlockingIssue returns the first issue that keeps the installation from succeeding, nil when
+// there is none
+func BlockingIssue(issues []EnvironmentIssue) *EnvironmentIssue {
+	for i := range issues {
+		if issues[i].Blocking {
+			return &issues[i]
+		}
+	}
+	return nil
+}
+
+// readHostFile returns the trimmed contents of a file, empty when it can't be read
+func readHostFile(path string) string {
+	data, err := os.ReadFile(filepath.Join(hostRoot, path))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(filepath.Join(hostRoot, path))
+	return err == nil
+}
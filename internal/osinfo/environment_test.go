@@ -0,0 +1,172 @@
+package osinfo
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeHost points environment detection at a root with the given files
+func fakeHost(t *testing.T, files map[string]string) {
+	root := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+
+	original := hostRoot
+	t.Cleanup(func() { hostRoot = original })
+	hostRoot = root
+}
+
+func TestDetectEnvironment(t *testing.T) {
+	systemd := map[string]string{"run/systemd/system/.keep": ""}
+	with := func(files map[string]string) map[string]string {
+		for name, content := range systemd {
+			files[name] = content
+		}
+		return files
+	}
+
+	tests := []struct {
+		name           string
+		files          map[string]string
+		container      string
+		virtualization string
+		systemd        bool
+		environment    string
+	}{
+		{
+			name:        "bare metal",
+			files:       with(map[string]string{"sys/class/dmi/id/sys_vendor": "Dell Inc.\n", "proc/cpuinfo": "flags\t\t: fpu vme de\n"}),
+			systemd:     true,
+			environment: "bare metal",
+		},
+		{
+			name:           "KVM virtual machine",
+			files:          with(map[string]string{"sys/class/dmi/id/sys_vendor": "QEMU\n", "sys/class/dmi/id/product_name": "Standard PC (Q35 + ICH9, 2009)\n"}),
+			virtualization: "qemu",
+			systemd:        true,
+			environment:    "QEMU virtual machine",
+		},
+		{
+			name:           "unknown hypervisor",
+			files:          with(map[string]string{"proc/cpuinfo": "processor\t: 0\nflags\t\t: fpu vme hypervisor lahf_lm\n"}),
+			virtualization: "other",
+			systemd:        true,
+			environment:    "virtual machine",
+		},
+		{
+			name:        "docker",
+			files:       map[string]string{".dockerenv": "", "sys/class/dmi/id/sys_vendor": "QEMU\n"},
+			container:   "docker",
+			environment: "Docker container",
+		},
+		{
+			name:        "podman",
+			files:       map[string]string{"run/.containerenv": ""},
+			container:   "podman",
+			environment: "Podman container",
+		},
+		{
+			name:        "kubernetes pod",
+			files:       map[string]string{"proc/1/cgroup": "0::/kubepods.slice/kubepods-burstable.slice/cri-containerd-0123.scope\n"},
+			container:   "kubernetes",
+			environment: "Kubernetes pod",
+		},
+		{
+			name:        "LXD container",
+			files:       with(map[string]string{"proc/1/environ": "container=lxc\x00HOME=/\x00"}),
+			container:   "lxc",
+			systemd:     true,
+			environment: "LXC/LXD container",
+		},
+		{
+			name:        "systemd-nspawn",
+			files:       with(map[string]string{"run/systemd/container": "systemd-nspawn\n"}),
+			container:   "systemd-nspawn",
+			systemd:     true,
+			environment: "systemd-nspawn container",
+		},
+		{
+			name:        "WSL 2",
+			files:       map[string]string{"proc/sys/kernel/osrelease": "5.15.153.1-microsoft-standard-WSL2\n"},
+			container:   "wsl2",
+			environment: "WSL 2",
+		},
+		{
+			name:        "WSL 1",
+			files:       map[string]string{"proc/sys/kernel/osrelease": "4.4.0-19041-Microsoft\n"},
+			container:   "wsl1",
+			environment: "WSL 1",
+		},
+		{
+			name:        "OpenVZ",
+			files:       with(map[string]string{"proc/vz/veinfo": ""}),
+			container:   "openvz",
+			systemd:     true,
+			environment: "OpenVZ container",
+		},
+		{
+			name:        "OpenVZ host",
+			files:       with(map[string]string{"proc/vz/veinfo": "", "proc/bc/0/resources": ""}),
+			systemd:     true,
+			environment: "bare metal",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fakeHost(t, tt.files)
+
+			info := &OSInfo{}
+			detectEnvironment(info)
+			assert.Equal(t, tt.container, info.Container)
+			assert.Equal(t, tt.virtualization, info.Virtualization)
+			assert.Equal(t, tt.systemd, info.Systemd)
+			assert.Equal(t, tt.environment, EnvironmentName(info))
+		})
+	}
+}
+
+func TestEnvironmentIssues(t *testing.T) {
+	tests := []struct {
+		name     string
+		info     OSInfo
+		blocking string
+		warnings int
+	}{
+		{name: "server", info: OSInfo{Virtualization: "kvm", Systemd: true}},
+		{name: "docker", info: OSInfo{Container: "docker"}, blocking: "can't be installed inside a Docker container"},
+		{name: "WSL 1", info: OSInfo{Container: "wsl1"}, blocking: "wsl --set-version"},
+		{name: "WSL 2 without systemd", info: OSInfo{Container: "wsl2"}, blocking: "systemd=true", warnings: 1},
+		{name: "WSL 2", info: OSInfo{Container: "wsl2", Systemd: true}, warnings: 1},
+		{name: "LXC", info: OSInfo{Container: "lxc", Systemd: true}, warnings: 1},
+		{name: "no systemd", info: OSInfo{}, blocking: "systemd isn't running"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			issues := EnvironmentIssues(&tt.info)
+			issue := BlockingIssue(issues)
+			if tt.blocking == "" {
+				assert.Nil(t, issue)
+			} else {
+				require.NotNil(t, issue)
+				assert.Contains(t, issue.Message, tt.blocking)
+			}
+
+			warnings := 0
+			for _, issue := range issues {
+				if !issue.Blocking {
+					warnings++
+				}
+			}
+			assert.Equal(t, tt.warnings, warnings)
+		})
+	}
+}
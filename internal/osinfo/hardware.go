@@ -0,0 +1,136 @@
+package osinfo
+
+import (
+	"bufio"
+	"fmt"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// detectHardware fills in the hardware and kernel facts of info
+func detectHardware(info *OSInfo) {
+	info.KernelVersion = readHostFile("proc/sys/kernel/osrelease")
+	info.MemoryTotal, info.MemoryAvailable = readMemory()
+	info.CPUCount, info.CPUModel = readCPU()
+
+	info.CgroupVersion = 1
+	if exists("sys/fs/cgroup/cgroup.controllers") {
+		info.CgroupVersion = 2
+	}
+
+	info.InitSystem = readHostFile("proc/1/comm")
+
+	mounts := readMounts()
+	info.RootFilesystem = filesystemOf(mounts, "/")
+	info.VarLibFilesystem = filesystemOf(mounts, "/var/lib")
+
+	switch readHostFile("sys/fs/selinux/enforce") {
+	case "1":
+		info.SELinux = "enforcing"
+	case "0":
+		info.SELinux = "permissive"
+	default:
+		info.SELinux = "disabled"
+	}
+	info.AppArmor = readHostFile("sys/module/apparmor/parameters/enabled") == "Y"
+}
+
+// readMemory returns MemTotal and MemAvailable from /proc/meminfo in bytes
+func readMemory() (total, available uint64) {
+	scanner := bufio.NewScanner(strings.NewReader(readHostFile("proc/meminfo")))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+		kb, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			continue
+		}
+		switch fields[0] {
+		case "MemTotal:":
+			total = kb * 1024
+		case "MemAvailable:":
+			available = kb * 1024
+		}
+	}
+	return total, available
+}
+
+// readCPU returns the number of processors and their model from /proc/cpuinfo
+func readCPU() (count int, model string) {
+	scanner := bufio.NewScanner(strings.NewReader(readHostFile("proc/cpuinfo")))
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), ":")
+		if !ok {
+			continue
+		}
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		switch key {
+		case "processor":
+			count++
+		// x86 uses model name, ARM boards such as the Raspberry Pi only have Model
+		case "model name", "Model":
+			if model == "" {
+				model = value
+			}
+		}
+	}
+	if count == 0 {
+		count = runtime.NumCPU()
+	}
+	return count, model
+}
+
+type mount struct {
+	target     string
+	filesystem string
+}
+
+func readMounts() []mount {
+	var mounts []mount
+	for _, line := range strings.Split(readHostFile("proc/self/mounts"), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 3 {
+			continue
+		}
+		mounts = append(mounts, mount{target: fields[1], filesystem: fields[2]})
+	}
+	return mounts
+}
+
+// filesystemOf returns the type of the filesystem dir lives on, the last mount wins when mounts
+// are stacked
+func filesystemOf(mounts []mount, dir string) string {
+	best, filesystem := -1, ""
+	for _, m := range mounts {
+		if dir != m.target && m.target != "/" && !strings.HasPrefix(dir, m.target+"/") {
+			continue
+		}
+		if len(m.target) >= best {
+			best, filesystem = len(m.target), m.filesystem
+		}
+	}
+	return filesystem
+}
+
+// FormatMemory renders a size in bytes as GB with one decimal
+func FormatMemory(bytes uint64) string {
+	return fmt.Sprintf("%.1f GB", float64(bytes)/(1<<30))
+}
+
+// HardwareSummary describes the CPUs and memory, e.g. "4 CPUs (AMD EPYC 7B13), 7.8 GB RAM"
+func HardwareSummary(info *OSInfo) string {
+	cpus := fmt.Sprintf("%d CPUs", info.CPUCount)
+	if info.CPUCount == 1 {
+		cpus = "1 CPU"
+	}
+	if info.CPUModel != "" {
+		cpus += fmt.Sprintf(" (%s)", info.CPUModel)
+	}
+	if info.MemoryTotal == 0 {
+		return cpus
+	}
+	return fmt.Sprintf("%s, %s RAM", cpus, FormatMemory(info.MemoryTotal))
+}
@@ -0,0 +1,52 @@
+package osinfo
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDetectHardware(t *testing.T) {
+	fakeHost(t, map[string]string{
+		"proc/sys/kernel/osrelease": "6.8.0-45-generic\n",
+		"proc/meminfo":              "MemTotal:        8039612 kB\nMemFree:          512000 kB\nMemAvailable:    6029824 kB\n",
+		"proc/cpuinfo": "processor\t: 0\nmodel name\t: AMD EPYC 7B13\nflags\t\t: fpu\n\n" +
+			"processor\t: 1\nmodel name\t: AMD EPYC 7B13\nflags\t\t: fpu\n",
+		"sys/fs/cgroup/cgroup.controllers":       "cpu memory\n",
+		"proc/1/comm":                            "systemd\n",
+		"proc/self/mounts":                       "/dev/sda1 / ext4 rw,relatime 0 0\nproc /proc proc rw 0 0\n/dev/sdb1 /var/lib/rancher xfs rw 0 0\n/dev/sdc1 /var/lib xfs rw 0 0\n",
+		"sys/fs/selinux/enforce":                 "1",
+		"sys/module/apparmor/parameters/enabled": "N\n",
+	})
+
+	info := &OSInfo{}
+	detectHardware(info)
+	assert.Equal(t, "6.8.0-45-generic", info.KernelVersion)
+	assert.Equal(t, uint64(8039612*1024), info.MemoryTotal)
+	assert.Equal(t, uint64(6029824*1024), info.MemoryAvailable)
+	assert.Equal(t, 2, info.CPUCount)
+	assert.Equal(t, "AMD EPYC 7B13", info.CPUModel)
+	assert.Equal(t, 2, info.CgroupVersion)
+	assert.Equal(t, "systemd", info.InitSystem)
+	assert.Equal(t, "ext4", info.RootFilesystem)
+	assert.Equal(t, "xfs", info.VarLibFilesystem)
+	assert.Equal(t, "enforcing", info.SELinux)
+	assert.False(t, info.AppArmor)
+	assert.Equal(t, "2 CPUs (AMD EPYC 7B13), 7.7 GB RAM", HardwareSummary(info))
+}
+
+func TestDetectHardware_Minimal(t *testing.T) {
+	fakeHost(t, map[string]string{
+		"proc/cpuinfo":                           "processor\t: 0\nModel\t\t: Raspberry Pi 4 Model B Rev 1.4\n",
+		"proc/self/mounts":                       "/dev/root / ext4 rw 0 0\n",
+		"sys/module/apparmor/parameters/enabled": "Y\n",
+	})
+
+	info := &OSInfo{}
+	detectHardware(info)
+	assert.Equal(t, 1, info.CgroupVersion)
+	assert.Equal(t, "ext4", info.VarLibFilesystem)
+	assert.Equal(t, "disabled", info.SELinux)
+	assert.True(t, info.AppArmor)
+	assert.Equal(t, "1 CPU (Raspberry Pi 4 Model B Rev 1.4)", HardwareSummary(info))
+}
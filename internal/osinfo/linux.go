@@ -29,6 +29,7 @@ func readOSRelease(info *OSInfo) error {
 	}
 	defer file.Close()
 
+	var buildID string
 	scanner := bufio.NewScanner(file)
 	for scanner.Scan() {
 		line := scanner.Text()
@@ -42,18 +43,27 @@ func readOSRelease(info *OSInfo) error {
 
 		switch key {
 		case "ID":
-			if value == "opensuse-leap" {
-				value = "opensuse"
-			}
-			info.Distribution = value
+			info.ID = value
+		case "ID_LIKE":
+			info.IDLike = strings.Fields(value)
 		case "VERSION_ID":
 			info.VersionID = value
 		case "VERSION":
 			info.Version = value
 		case "PRETTY_NAME":
 			info.PrettyName = value
+		case "BUILD_ID":
+			buildID = value
 		}
 	}
 
+	// Rolling releases such as Arch have no VERSION_ID, only BUILD_ID=rolling
+	if info.VersionID == "" {
+		info.VersionID = buildID
+	}
+
+	info.Distribution = info.ID
+	resolveFamily(info)
+
 	return scanner.Err()
 }
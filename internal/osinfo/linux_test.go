@@ -75,6 +75,18 @@ ANOTHER_KEY=value`,
 			expectedPretty: "Ubuntu 22.04 LTS",
 			shouldErr:      false,
 		},
+		{
+			name: "Arch Linux",
+			fileContent: `NAME="Arch Linux"
+PRETTY_NAME="Arch Linux"
+ID=arch
+BUILD_ID=rolling
+ANSI_COLOR="38;2;23;147;209"`,
+			expectedDistro: "arch",
+			expectedVerID:  "rolling",
+			expectedPretty: "Arch Linux",
+			shouldErr:      false,
+		},
 		{
 			name: "Malformed lines",
 			fileContent: `NAME="Ubuntu"
@@ -206,3 +218,188 @@ PRETTY_NAME="Ubuntu 22.04 LTS"`,
 		})
 	}
 }
+
+func TestReadOSRelease_Derivatives(t *testing.T) {
+	tests := []struct {
+		name             string
+		fileContent      string
+		expectedID       string
+		expectedFamily   string
+		versionSupported bool
+	}{
+		{
+			name: "RHEL 9",
+			fileContent: `NAME="Red Hat Enterprise Linux"
+VERSION="9.4 (Plow)"
+ID="rhel"
+ID_LIKE="fedora"
+VERSION_ID="9.4"
+PRETTY_NAME="Red Hat Enterprise Linux 9.4 (Plow)"`,
+			expectedID:       "rhel",
+			expectedFamily:   "rocky",
+			versionSupported: true,
+		},
+		{
+			name: "RHEL 8",
+			fileContent: `NAME="Red Hat Enterprise Linux"
+ID="rhel"
+ID_LIKE="fedora"
+VERSION_ID="8.10"`,
+			expectedID:       "rhel",
+			expectedFamily:   "rocky",
+			versionSupported: false,
+		},
+		{
+			name: "Oracle Linux 9",
+			fileContent: `NAME="Oracle Linux Server"
+VERSION="9.4"
+ID="ol"
+ID_LIKE="fedora"
+VERSION_ID="9.4"
+PRETTY_NAME="Oracle Linux Server 9.4"`,
+			expectedID:       "ol",
+			expectedFamily:   "rocky",
+			versionSupported: true,
+		},
+		{
+			name: "Amazon Linux 2023",
+			fileContent: `NAME="Amazon Linux"
+VERSION="2023"
+ID="amzn"
+ID_LIKE="fedora"
+VERSION_ID="2023"
+PRETTY_NAME="Amazon Linux 2023.5.20240805"`,
+			expectedID:       "amzn",
+			expectedFamily:   "rocky",
+			versionSupported: true,
+		},
+		{
+			name: "Amazon Linux 2",
+			fileContent: `NAME="Amazon Linux"
+VERSION="2"
+ID="amzn"
+ID_LIKE="centos rhel fedora"
+VERSION_ID="2"
+PRETTY_NAME="Amazon Linux 2"`,
+			expectedID:       "amzn",
+			expectedFamily:   "rocky",
+			versionSupported: false,
+		},
+		{
+			name: "Linux Mint 22",
+			fileContent: `NAME="Linux Mint"
+VERSION="22 (Wilma)"
+ID=linuxmint
+ID_LIKE="ubuntu debian"
+VERSION_ID="22"
+PRETTY_NAME="Linux Mint 22"
+UBUNTU_CODENAME=noble`,
+			expectedID:       "linuxmint",
+			expectedFamily:   "ubuntu",
+			versionSupported: true,
+		},
+		{
+			name: "Linux Mint 21.3",
+			fileContent: `NAME="Linux Mint"
+VERSION="21.3 (Virginia)"
+ID=linuxmint
+ID_LIKE="ubuntu debian"
+VERSION_ID="21.3"
+PRETTY_NAME="Linux Mint 21.3"
+UBUNTU_CODENAME=jammy`,
+			expectedID:       "linuxmint",
+			expectedFamily:   "ubuntu",
+			versionSupported: true,
+		},
+		{
+			name: "Pop!_OS 22.04",
+			fileContent: `NAME="Pop!_OS"
+VERSION="22.04 LTS"
+ID=pop
+ID_LIKE="ubuntu debian"
+VERSION_ID="22.04"
+PRETTY_NAME="Pop!_OS 22.04 LTS"`,
+			expectedID:       "pop",
+			expectedFamily:   "ubuntu",
+			versionSupported: true,
+		},
+		{
+			name: "openSUSE Leap 15.6",
+			fileContent: `NAME="openSUSE Leap"
+VERSION="15.6"
+ID="opensuse-leap"
+ID_LIKE="suse opensuse"
+VERSION_ID="15.6"
+PRETTY_NAME="openSUSE Leap 15.6"`,
+			expectedID:       "opensuse-leap",
+			expectedFamily:   "opensuse",
+			versionSupported: true,
+		},
+		{
+			name: "openSUSE Tumbleweed",
+			fileContent: `NAME="openSUSE Tumbleweed"
+ID="opensuse-tumbleweed"
+ID_LIKE="opensuse suse"
+VERSION_ID="20240801"
+PRETTY_NAME="openSUSE Tumbleweed"`,
+			expectedID:       "opensuse-tumbleweed",
+			expectedFamily:   "opensuse",
+			versionSupported: true,
+		},
+		{
+			name: "SUSE Linux Enterprise Server 15 SP6",
+			fileContent: `NAME="SLES"
+VERSION="15-SP6"
+VERSION_ID="15.6"
+PRETTY_NAME="SUSE Linux Enterprise Server 15 SP6"
+ID="sles"
+ID_LIKE="suse"`,
+			expectedID:       "sles",
+			expectedFamily:   "opensuse",
+			versionSupported: true,
+		},
+		{
+			name: "Unlisted derivative falls back to ID_LIKE",
+			fileContent: `NAME="Zorin OS"
+ID=zorin
+ID_LIKE="ubuntu debian"
+VERSION_ID="17"`,
+			expectedID:       "zorin",
+			expectedFamily:   "ubuntu",
+			versionSupported: false,
+		},
+		{
+			name: "Unrelated distribution",
+			fileContent: `NAME="Alpine Linux"
+ID=alpine
+VERSION_ID=3.20.2`,
+			expectedID:       "alpine",
+			expectedFamily:   "alpine",
+			versionSupported: false,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tmpFile, err := os.CreateTemp("", "os-release-test")
+			require.NoError(t, err)
+			defer os.Remove(tmpFile.Name())
+
+			_, err = tmpFile.WriteString(tc.fileContent)
+			require.NoError(t, err)
+			tmpFile.Close()
+
+			origOpen := osOpen
+			defer func() { osOpen = origOpen }()
+			osOpen = func(name string) (*os.File, error) {
+				return os.Open(tmpFile.Name())
+			}
+
+			info := &OSInfo{}
+			require.NoError(t, readOSRelease(info))
+			assert.Equal(t, tc.expectedID, info.ID)
+			assert.Equal(t, tc.expectedFamily, info.Distribution)
+			assert.Equal(t, tc.versionSupported, versionSupported(info))
+		})
+	}
+}
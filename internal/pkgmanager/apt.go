@@ -3,7 +3,9 @@ package pkgmanager
 import (
 	"context"
 	"fmt"
+	"os"
 	"os/exec"
+	"slices"
 	"time"
 )
 
@@ -11,6 +13,10 @@ import (
 type AptInstaller struct {
 	// Channel to send log messages
 	LogChan chan<- string
+	// How long to wait for another package manager to release its lock
+	LockTimeout time.Duration
+	// Installs from local packages instead of the configured repositories when set
+	Source *LocalSource
 }
 
 // NewAptInstaller creates apt package manager
@@ -31,66 +37,59 @@ func (self *AptInstaller) InstallPackages(ctx context.Context, packages []string
 		progressFunc("", 0.05, "Initializing package installation...", false)
 	}
 
+	options, targets, cleanup, err := self.sourceOptions(packages)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+	options = append([]string{"-o", "APT::Status-Fd=3"}, options...)
+	deadline := lockDeadline(self.LockTimeout)
+
 	// Update package lists and install packages with better progress distribution
 	steps := []struct {
 		progress    float64
 		endProgress float64
 		step        string
 		cmd         *exec.Cmd
+		parse       progressParser
 	}{
 		{
 			progress:    0.10,
 			endProgress: 0.25,
 			step:        "Updating package lists...",
-			cmd:         exec.CommandContext(ctx, "apt-get", "update", "-y"),
+			cmd:         exec.CommandContext(ctx, "apt-get", slices.Concat(options, []string{"update", "-y"})...),
+			parse:       newAptParser(1.0),
 		},
 		{
 			progress:    0.30,
 			endProgress: 0.85,
 			step:        fmt.Sprintf("Installing %d packages...", len(packages)),
-			cmd:         exec.CommandContext(ctx, "apt-get", append([]string{"install", "-y"}, packages...)...),
+			cmd:         exec.CommandContext(ctx, "apt-get", slices.Concat(options, []string{"install", "-y"}, targets)...),
+			parse:       newAptParser(0.5),
 		},
 	}
+	// Package files have nothing to update
+	if self.Source != nil && self.Source.URL == "" {
+		steps = steps[1:]
+	}
 
 	// Execute each step with progress updates
 	for _, step := range steps {
+		// Another package manager may hold the lock, e.g. unattended upgrades right after boot
+		if err := waitForLocks(ctx, aptLocks, "apt", self.LockTimeout, self.log, progressFunc, step.progress); err != nil {
+			return fmt.Errorf("failed during %s: %w", step.step, err)
+		}
+		// apt-daily and apt-daily-upgrade run back to back and can take the lock again right away
+		step.cmd.Args = slices.Insert(step.cmd.Args, 1, "-o", fmt.Sprintf("DPkg::Lock::Timeout=%d", remainingLockSeconds(deadline)))
+
 		// Report starting this step
 		if progressFunc != nil {
 			progressFunc("", step.progress, step.step, false)
 		}
 		self.log(step.step)
 
-		// Start a goroutine to update progress during long-running steps
-		progressDone := make(chan struct{})
-		go func(startProgress, endProgress float64, stepDesc string) {
-			ticker := time.NewTicker(100 * time.Millisecond) // Faster updates for smoother progress
-			defer ticker.Stop()
-
-			currentProgress := startProgress
-			// Calculate increment based on time - slower increments for more realistic progress
-			increment := (endProgress - startProgress) / 200 // Spread over ~20 seconds
-
-			for {
-				select {
-				case <-ticker.C:
-					// Gradually increment progress, but don't exceed end point
-					if currentProgress < endProgress-0.02 {
-						currentProgress += increment
-						if progressFunc != nil {
-							progressFunc("", currentProgress, stepDesc, false)
-						}
-					}
-				case <-progressDone:
-					return
-				case <-ctx.Done():
-					return
-				}
-			}
-		}(step.progress, step.endProgress, step.step)
-
-		// Execute the command
-		output, err := step.cmd.CombinedOutput()
-		close(progressDone) // Stop progress updates
+		// apt reports download and dpkg progress for each package on the status fd
+		output, err := runWithProgress(step.cmd, true, step.parse, step.progress, step.endProgress, progressFunc)
 
 		// Handle errors
 		if err != nil {
@@ -123,6 +122,66 @@ func (self *AptInstaller) InstallPackages(ctx context.Context, packages []string
 	return nil
 }
 
+// sourceOptions returns the apt options and install targets for the package source. Local sources
+// replace the configured sources so nothing is fetched from the network. The cleanup func removes
+// the temporary sources list of a file:// repository.
+func (self *AptInstaller) sourceOptions(packages []string) (options []string, targets []string, cleanup func(), err error) {
+	cleanup = func() {}
+	switch {
+	case self.Source == nil:
+		return nil, packages, cleanup, nil
+	case self.Source.URL != "":
+		// A flat repository, as dpkg-scanpackages writes, the lists of the configured sources are kept
+		list, err := os.CreateTemp("", "unbind-offline-*.list")
+		if err != nil {
+			return nil, nil, cleanup, err
+		}
+		cleanup = func() { os.Remove(list.Name()) }
+		_, err = fmt.Fprintf(list, "deb [trusted=yes] %s ./\n", self.Source.URL)
+		if closeErr := list.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return nil, nil, cleanup, err
+		}
+		return []string{"-o", "Dir::Etc::sourcelist=" + list.Name(), "-o", "Dir::Etc::sourceparts=-", "-o", "APT::Get::List-Cleanup=0"}, packages, cleanup, nil
+	default:
+		files, err := self.Source.packageFiles(packages, []string{".deb"}, debName)
+		if err != nil {
+			return nil, nil, cleanup, err
+		}
+		return []string{"-o", "Dir::Etc::sourcelist=/dev/null", "-o", "Dir::Etc::sourceparts=-"}, files, cleanup, nil
+	}
+}
+
+// InstalledPackages queries dpkg for the installed versions of packages
+func (self *AptInstaller) InstalledPackages(ctx context.Context, packages []string) (map[string]string, error) {
+	installed := map[string]string{}
+	if len(packages) == 0 {
+		return installed, nil
+	}
+
+	output, err := runQuery(ctx, "dpkg-query", append([]string{"-W", "-f=${Package}\t${db:Status-Status}\t${Version}\n"}, packages...)...)
+	if err != nil {
+		return nil, err
+	}
+	// Removed packages whose configuration files are left behind are still listed
+	for _, fields := range parseQuery(output, "\t", 3) {
+		if fields[1] == "installed" {
+			installed[fields[0]] = fields[2]
+		}
+	}
+	return installed, nil
+}
+
+// RemovePackages removes packages with apt, their configuration files are kept
+func (self *AptInstaller) RemovePackages(ctx context.Context, packages []string) error {
+	if len(packages) == 0 {
+		return nil
+	}
+	return removePackages(ctx, aptLocks, self.LockTimeout, self.log, "apt-get", append([]string{"remove", "-y"}, packages...)...)
+}
+
 // log writes to log channel
 func (self *AptInstaller) log(message string) {
 	if self.LogChan != nil {
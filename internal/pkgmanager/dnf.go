@@ -11,6 +11,10 @@ import (
 type DNFInstaller struct {
 	// Channel to send log messages
 	LogChan chan<- string
+	// How long to wait for another package manager to release its lock
+	LockTimeout time.Duration
+	// Installs from local packages instead of the configured repositories when set
+	Source *LocalSource
 }
 
 // NewDNFInstaller creates a new DNFInstaller
@@ -31,61 +35,46 @@ func (self *DNFInstaller) InstallPackages(ctx context.Context, packages []string
 		progressFunc("", 0.05, "Initializing DNF package installation...", false)
 	}
 
-	// Update package lists
-	if progressFunc != nil {
-		progressFunc("", 0.10, "Updating DNF package lists...", false)
+	// Another package manager may hold the lock, e.g. an automatic update
+	if err := waitForLocks(ctx, dnfLocks, "dnf", self.LockTimeout, self.log, progressFunc, 0.10); err != nil {
+		return err
 	}
-	self.log("Updating DNF package lists...")
 
-	updateCmd := exec.CommandContext(ctx, "dnf", "makecache", "--refresh", "-y")
-	if output, err := updateCmd.CombinedOutput(); err != nil {
-		self.log(fmt.Sprintf("Error updating dnf: %s", string(output)))
-		return fmt.Errorf("failed to update dnf: %w", err)
+	args, err := self.installArgs(packages)
+	if err != nil {
+		return err
 	}
 
-	if progressFunc != nil {
-		progressFunc("", 0.25, "Completed: Updating DNF package lists...", false)
+	// Update package lists, local sources are read when installing
+	if self.Source == nil {
+		if progressFunc != nil {
+			progressFunc("", 0.10, "Updating DNF package lists...", false)
+		}
+		self.log("Updating DNF package lists...")
+
+		updateCmd := exec.CommandContext(ctx, "dnf", "makecache", "--refresh", "-y")
+		if output, err := updateCmd.CombinedOutput(); err != nil {
+			self.log(fmt.Sprintf("Error updating dnf: %s", string(output)))
+			return fmt.Errorf("failed to update dnf: %w", err)
+		}
+
+		if progressFunc != nil {
+			progressFunc("", 0.25, "Completed: Updating DNF package lists...", false)
+		}
+	}
+
+	if err := waitForLocks(ctx, dnfLocks, "dnf", self.LockTimeout, self.log, progressFunc, 0.30); err != nil {
+		return err
 	}
 
-	// Install packages with gradual progress
+	// Install packages, following the progress dnf prints for each package
 	if progressFunc != nil {
 		progressFunc("", 0.30, fmt.Sprintf("Installing %d packages...", len(packages)), false)
 	}
 	self.log(fmt.Sprintf("Installing %d packages...", len(packages)))
 
-	// Start gradual progress updates
-	progressDone := make(chan struct{})
-	go func() {
-		currentProgress := 0.30
-		for currentProgress < 0.80 {
-			select {
-			case <-progressDone:
-				return
-			case <-ctx.Done():
-				return
-			default:
-				currentProgress += 0.03
-				if progressFunc != nil {
-					progressFunc("", currentProgress, fmt.Sprintf("Installing %d packages...", len(packages)), false)
-				}
-				// Sleep for gradual progress
-				select {
-				case <-progressDone:
-					return
-				case <-ctx.Done():
-					return
-				default:
-					time.Sleep(300 * time.Millisecond)
-				}
-			}
-		}
-	}()
-
-	args := append([]string{"install", "-y"}, packages...)
 	installCmd := exec.CommandContext(ctx, "dnf", args...)
-	output, err := installCmd.CombinedOutput()
-	close(progressDone)
-
+	output, err := runWithProgress(installCmd, false, parseDNF, 0.30, 0.85, progressFunc)
 	if err != nil {
 		self.log(fmt.Sprintf("Error installing packages: %s", string(output)))
 		return fmt.Errorf("failed to install packages: %w", err)
@@ -113,6 +102,36 @@ func (self *DNFInstaller) InstallPackages(ctx context.Context, packages []string
 	return nil
 }
 
+// installArgs returns the dnf arguments installing packages from the package source. Local sources
+// replace the configured repositories so nothing is fetched from the network.
+func (self *DNFInstaller) installArgs(packages []string) ([]string, error) {
+	switch {
+	case self.Source == nil:
+		return append([]string{"install", "-y"}, packages...), nil
+	case self.Source.URL != "":
+		return append([]string{"install", "-y", "--repofrompath=unbind-offline," + self.Source.URL, "--repo=unbind-offline"}, packages...), nil
+	default:
+		files, err := self.Source.packageFiles(packages, []string{".rpm"}, packageName)
+		if err != nil {
+			return nil, err
+		}
+		return append([]string{"install", "-y", "--disablerepo=*"}, files...), nil
+	}
+}
+
+// InstalledPackages queries rpm for the installed versions of packages
+func (self *DNFInstaller) InstalledPackages(ctx context.Context, packages []string) (map[string]string, error) {
+	return rpmInstalledPackages(ctx, packages)
+}
+
+// RemovePackages removes packages with dnf
+func (self *DNFInstaller) RemovePackages(ctx context.Context, packages []string) error {
+	if len(packages) == 0 {
+		return nil
+	}
+	return removePackages(ctx, dnfLocks, self.LockTimeout, self.log, "dnf", append([]string{"remove", "-y"}, packages...)...)
+}
+
 // log sends a message to the log channel if available
 func (self *DNFInstaller) log(message string) {
 	if self.LogChan != nil {
@@ -0,0 +1,57 @@
+package pkgmanager
+
+import (
+	"fmt"
+	"strings"
+)
+
+// downloadImages are the container images packages are downloaded in for each distribution.
+// {version} is replaced with the release and {major} with its major version.
+var downloadImages = map[string]string{
+	"ubuntu":    "ubuntu:{version}",
+	"debian":    "debian:{major}",
+	"fedora":    "fedora:{version}",
+	"centos":    "quay.io/centos/centos:stream{major}",
+	"rocky":     "rockylinux/rockylinux:{major}",
+	"almalinux": "almalinux:{major}",
+	"opensuse":  "opensuse/leap:{version}",
+	"arch":      "archlinux:latest",
+	"manjaro":   "manjarolinux/base:latest",
+}
+
+// DownloadImage returns the container image of a distribution release to download packages in
+func DownloadImage(distribution, version string) (string, error) {
+	image, ok := downloadImages[distribution]
+	if !ok {
+		return "", fmt.Errorf("unsupported distribution: %s", distribution)
+	}
+	return strings.NewReplacer(
+		"{version}", version,
+		"{major}", strings.Split(version, ".")[0],
+	).Replace(image), nil
+}
+
+// DownloadScript returns a shell script downloading packages and the dependencies that aren't
+// installed where it runs into dir, ready to be used as a LocalSource. Run in a fresh container
+// of the target release, that's what a minimal installation of it is missing. The packages
+// themselves are downloaded even when they are installed already.
+func DownloadScript(distribution, dir string, packages []string) (string, error) {
+	names := strings.Join(packages, " ")
+	dir = "'" + strings.ReplaceAll(dir, "'", `'\''`) + "'"
+	switch distribution {
+	case "ubuntu", "debian":
+		return fmt.Sprintf("set -e\nmkdir -p %[1]s/partial\napt-get update\n"+
+			"apt-get install -y --reinstall --download-only -o Dir::Cache::archives=%[1]s %[2]s\n"+
+			"rm -rf %[1]s/partial %[1]s/lock\n", dir, names), nil
+	case "fedora", "centos", "rocky", "almalinux":
+		// dnf 4 downloads with a plugin, dnf 5 has the command built in
+		return fmt.Sprintf("set -e\ndnf download --help >/dev/null 2>&1 || dnf install -y dnf-plugins-core\n"+
+			"dnf download --resolve --destdir %s %s\n", dir, names), nil
+	case "opensuse":
+		return fmt.Sprintf("set -e\nzypper --non-interactive --pkg-cache-dir %s install --download-only --force %s\n", dir, names), nil
+	case "arch", "manjaro":
+		return fmt.Sprintf("set -e\npacman -Syw --noconfirm --cachedir %s %s\n", dir, names), nil
+	default:
+		return "", fmt.Errorf("unsupported distribution: %s", distribution)
+	}
+}
@@ -3,6 +3,7 @@ package pkgmanager
 import (
 	"context"
 	"fmt"
+	"time"
 )
 
 // ProgressFunc callback for install progress updates
@@ -13,17 +14,38 @@ type PackageManager interface {
 	// InstallPackages installs the specified packages
 	// The operation can be cancelled using the provided context
 	InstallPackages(ctx context.Context, packages []string, progressFunc ProgressFunc) error
+	// InstalledPackages returns the installed version of each of the specified packages,
+	// packages that aren't installed are left out
+	InstalledPackages(ctx context.Context, packages []string) (map[string]string, error)
+	// RemovePackages removes the specified packages
+	RemovePackages(ctx context.Context, packages []string) error
 }
 
-// NewPackageManager factory based on distro type
-func NewPackageManager(distribution string, logChan chan<- string) (PackageManager, error) {
+// NewPackageManager factory based on distro type. Installs wait up to lockTimeout for another
+// package manager to finish, zero waits for DefaultLockTimeout. A non-nil source installs from
+// local packages instead of the configured repositories.
+func NewPackageManager(distribution string, logChan chan<- string, lockTimeout time.Duration, source *LocalSource) (PackageManager, error) {
 	switch distribution {
 	case "ubuntu", "debian":
-		return NewAptInstaller(logChan), nil
+		installer := NewAptInstaller(logChan)
+		installer.LockTimeout = lockTimeout
+		installer.Source = source
+		return installer, nil
 	case "fedora", "centos", "rocky", "almalinux":
-		return NewDNFInstaller(logChan), nil
+		installer := NewDNFInstaller(logChan)
+		installer.LockTimeout = lockTimeout
+		installer.Source = source
+		return installer, nil
 	case "opensuse":
-		return NewZypperInstaller(logChan), nil
+		installer := NewZypperInstaller(logChan)
+		installer.LockTimeout = lockTimeout
+		installer.Source = source
+		return installer, nil
+	case "arch", "manjaro":
+		installer := NewPacmanInstaller(logChan)
+		installer.LockTimeout = lockTimeout
+		installer.Source = source
+		return installer, nil
 	default:
 		return nil, fmt.Errorf("unsupported distribution: %s", distribution)
 	}
@@ -0,0 +1,208 @@
+package pkgmanager
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// DefaultLockTimeout is how long installs wait for another package manager to finish
+const DefaultLockTimeout = 10 * time.Minute
+
+// Lock kinds, package managers lock with fcntl, a pid file, or the mere existence of a file
+const (
+	lockFcntl = iota
+	lockPIDFile
+	lockExists
+)
+
+// lockFile is a file a package manager holds while it runs
+type lockFile struct {
+	path string
+	kind int
+}
+
+// Lock files of each package manager
+var (
+	aptLocks = []lockFile{
+		{path: "/var/lib/dpkg/lock-frontend", kind: lockFcntl},
+		{path: "/var/lib/dpkg/lock", kind: lockFcntl},
+		{path: "/var/lib/apt/lists/lock", kind: lockFcntl},
+		{path: "/var/cache/apt/archives/lock", kind: lockFcntl},
+	}
+	dnfLocks = []lockFile{
+		{path: "/var/lib/dnf/rpmdb_lock.pid", kind: lockPIDFile},
+		{path: "/var/cache/dnf/metadata_lock.pid", kind: lockPIDFile},
+		{path: "/var/lib/rpm/.rpm.lock", kind: lockFcntl},
+	}
+	zypperLocks = []lockFile{
+		{path: "/run/zypp.pid", kind: lockPIDFile},
+		{path: "/var/lib/rpm/.rpm.lock", kind: lockFcntl},
+	}
+	pacmanLocks = []lockFile{
+		{path: "/var/lib/pacman/db.lck", kind: lockExists},
+	}
+)
+
+// Mockable for tests
+var (
+	procDir          = "/proc"
+	lockPollInterval = 2 * time.Second
+)
+
+// LockHolder is a process holding a package manager lock
+type LockHolder struct {
+	PID  int // 0 when the lock doesn't say who holds it
+	Name string
+	Path string
+}
+
+func (self *LockHolder) String() string {
+	if self.PID == 0 {
+		return fmt.Sprintf("another %s to release %s", self.Name, self.Path)
+	}
+	return fmt.Sprintf("%s (pid %d) to release %s", self.Name, self.PID, self.Path)
+}
+
+// waitForLocks waits until none of locks is held, reporting who holds them. It gives up after
+// timeout, zero waits for DefaultLockTimeout.
+func waitForLocks(ctx context.Context, locks []lockFile, manager string, timeout time.Duration, logFn func(string), progressFunc ProgressFunc, progress float64) error {
+	if timeout <= 0 {
+		timeout = DefaultLockTimeout
+	}
+	deadline := time.Now().Add(timeout)
+
+	var reported *LockHolder
+	for {
+		holder := findLockHolder(locks, manager)
+		if holder == nil {
+			if reported != nil {
+				logFn(fmt.Sprintf("%s released the package manager lock", reported.Name))
+			}
+			return nil
+		}
+
+		if reported == nil || *reported != *holder {
+			logFn(fmt.Sprintf("Waiting for %s...", holder))
+			reported = holder
+		}
+		if progressFunc != nil {
+			remaining := time.Until(deadline).Round(time.Second)
+			progressFunc("", progress, fmt.Sprintf("Waiting for %s (%s left)...", holder, remaining), false)
+		}
+
+		if time.Now().After(deadline) {
+			if holder.PID == 0 {
+				return fmt.Errorf("timed out after %s waiting for %s, remove it if no %s is running", timeout, holder, manager)
+			}
+			return fmt.Errorf("timed out after %s waiting for %s", timeout, holder)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(lockPollInterval):
+		}
+	}
+}
+
+// lockDeadline is when an install starting now gives up waiting for locks, zero timeout waits
+// for DefaultLockTimeout
+func lockDeadline(timeout time.Duration) time.Time {
+	if timeout <= 0 {
+		timeout = DefaultLockTimeout
+	}
+	return time.Now().Add(timeout)
+}
+
+// remainingLockSeconds is what is left until deadline in whole seconds. Package managers that can
+// wait for their own lock get it, another one may take the lock between waitForLocks and the command.
+func remainingLockSeconds(deadline time.Time) int {
+	return max(int(time.Until(deadline).Seconds()), 0)
+}
+
+// findLockHolder returns the first held lock, nil when none is
+func findLockHolder(locks []lockFile, manager string) *LockHolder {
+	for _, lock := range locks {
+		switch lock.kind {
+		case lockFcntl:
+			if pid := fcntlLockHolder(lock.path); pid > 0 {
+				return &LockHolder{PID: pid, Name: processName(pid), Path: lock.path}
+			}
+		case lockPIDFile:
+			data, err := os.ReadFile(lock.path)
+			if err != nil {
+				continue
+			}
+			// Pid files outlive package managers that crashed
+			pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+			if err != nil || !processExists(pid) {
+				continue
+			}
+			return &LockHolder{PID: pid, Name: processName(pid), Path: lock.path}
+		case lockExists:
+			if _, err := os.Stat(lock.path); err == nil {
+				return &LockHolder{Name: manager, Path: lock.path}
+			}
+		}
+	}
+	return nil
+}
+
+// fcntlLockHolder returns the pid holding a lock on path according to /proc/locks, 0 when the
+// file isn't locked
+func fcntlLockHolder(path string) int {
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0
+	}
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0
+	}
+	id := lockFileID(uint64(stat.Dev), uint64(stat.Ino))
+
+	file, err := os.Open(filepath.Join(procDir, "locks"))
+	if err != nil {
+		return 0
+	}
+	defer file.Close()
+
+	// 1: POSIX  ADVISORY  WRITE 1234 08:01:393219 0 EOF, waiting processes are marked with ->
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 6 || fields[1] == "->" || fields[5] != id {
+			continue
+		}
+		if pid, err := strconv.Atoi(fields[4]); err == nil && pid > 0 {
+			return pid
+		}
+	}
+	return 0
+}
+
+// lockFileID identifies a file the way /proc/locks does, major:minor:inode with the device numbers
+// in hex
+func lockFileID(dev, ino uint64) string {
+	return fmt.Sprintf("%02x:%02x:%d", (dev>>8)&0xfff|(dev>>32)&^0xfff, dev&0xff|(dev>>12)&^0xff, ino)
+}
+
+func processExists(pid int) bool {
+	_, err := os.Stat(filepath.Join(procDir, strconv.Itoa(pid)))
+	return pid > 0 && err == nil
+}
+
+// processName returns the command name of a process, unknown when it already exited
+func processName(pid int) string {
+	data, err := os.ReadFile(filepath.Join(procDir, strconv.Itoa(pid), "comm"))
+	if err != nil {
+		return "unknown process"
+	}
+	return strings.TrimSpace(string(data))
+}
@@ -0,0 +1,109 @@
+package pkgmanager
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeProc points procDir at a directory with the given /proc/locks and running processes
+func fakeProc(t *testing.T, locks string, processes map[int]string) {
+	original := procDir
+	t.Cleanup(func() { procDir = original })
+
+	procDir = t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(procDir, "locks"), []byte(locks), 0644))
+	for pid, name := range processes {
+		dir := filepath.Join(procDir, fmt.Sprint(pid))
+		require.NoError(t, os.Mkdir(dir, 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "comm"), []byte(name+"\n"), 0644))
+	}
+}
+
+// lockedFile creates a file and returns it with its /proc/locks id
+func lockedFile(t *testing.T) (string, string) {
+	path := filepath.Join(t.TempDir(), "lock-frontend")
+	require.NoError(t, os.WriteFile(path, nil, 0640))
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	stat := info.Sys().(*syscall.Stat_t)
+	return path, lockFileID(uint64(stat.Dev), uint64(stat.Ino))
+}
+
+func TestLockFileID(t *testing.T) {
+	tests := []struct {
+		name string
+		dev  uint64
+		want string
+	}{
+		{name: "sda1", dev: 0x0801, want: "08:01:393219"},
+		{name: "nvme0n1p3", dev: 0x10303, want: "103:03:393219"},
+		{name: "large minor", dev: 0x10082c, want: "08:12c:393219"},
+		{name: "overlay", dev: 0x2c, want: "00:2c:393219"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, lockFileID(tt.dev, 393219))
+		})
+	}
+}
+
+func TestFcntlLockHolder(t *testing.T) {
+	path, id := lockedFile(t)
+	fakeProc(t, "1: POSIX  ADVISORY  WRITE 1200 08:01:1234 0 EOF\n"+
+		"2: POSIX  ADVISORY  WRITE 4321 "+id+" 0 EOF\n"+
+		"2: -> POSIX  ADVISORY  WRITE 5678 "+id+" 0 EOF\n", nil)
+	assert.Equal(t, 4321, fcntlLockHolder(path), "processes waiting for the lock don't hold it")
+
+	fakeProc(t, "1: POSIX  ADVISORY  WRITE 1200 08:01:1234 0 EOF\n", nil)
+	assert.Zero(t, fcntlLockHolder(path))
+	assert.Zero(t, fcntlLockHolder(filepath.Join(t.TempDir(), "missing")))
+}
+
+func TestFindLockHolder(t *testing.T) {
+	dir := t.TempDir()
+	fcntlPath, id := lockedFile(t)
+	pidFile := filepath.Join(dir, "rpmdb_lock.pid")
+	existsFile := filepath.Join(dir, "db.lck")
+	locks := []lockFile{
+		{path: fcntlPath, kind: lockFcntl},
+		{path: pidFile, kind: lockPIDFile},
+		{path: existsFile, kind: lockExists},
+	}
+
+	fakeProc(t, "", map[int]string{4321: "apt-get", 777: "dnf"})
+	assert.Nil(t, findLockHolder(locks, "dnf"), "no lock file exists")
+
+	// Pid files of package managers that crashed are stale
+	require.NoError(t, os.WriteFile(pidFile, []byte("999\n"), 0644))
+	assert.Nil(t, findLockHolder(locks, "dnf"))
+	require.NoError(t, os.WriteFile(pidFile, []byte("not a pid"), 0644))
+	assert.Nil(t, findLockHolder(locks, "dnf"))
+
+	require.NoError(t, os.WriteFile(pidFile, []byte("777\n"), 0644))
+	assert.Equal(t, &LockHolder{PID: 777, Name: "dnf", Path: pidFile}, findLockHolder(locks, "dnf"))
+
+	require.NoError(t, os.Remove(pidFile))
+	require.NoError(t, os.WriteFile(existsFile, nil, 0644))
+	holder := findLockHolder(locks, "pacman")
+	assert.Equal(t, &LockHolder{Name: "pacman", Path: existsFile}, holder)
+	assert.Equal(t, "another pacman to release "+existsFile, holder.String())
+
+	// Earlier locks win
+	fakeProc(t, "1: POSIX  ADVISORY  WRITE 4321 "+id+" 0 EOF\n", map[int]string{4321: "apt-get"})
+	holder = findLockHolder(locks, "apt")
+	assert.Equal(t, &LockHolder{PID: 4321, Name: "apt-get", Path: fcntlPath}, holder)
+	assert.Equal(t, "apt-get (pid 4321) to release "+fcntlPath, holder.String())
+}
+
+func TestRemainingLockSeconds(t *testing.T) {
+	assert.InDelta(t, 90, remainingLockSeconds(lockDeadline(90*time.Second)), 1)
+	assert.InDelta(t, DefaultLockTimeout.Seconds(), remainingLockSeconds(lockDeadline(0)), 1)
+	assert.Zero(t, remainingLockSeconds(time.Now().Add(-time.Minute)), "the lock isn't waited for once the timeout passed")
+}
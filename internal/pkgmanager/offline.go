@@ -0,0 +1,115 @@
+package pkgmanager
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// LocalSource is where packages are installed from on servers without internet access, either a
+// directory of package files or a file:// repository with its metadata. Dependencies are resolved
+// by the package manager, so a directory must hold the dependencies the server is missing too.
+type LocalSource struct {
+	Dir string // Directory of .deb, .rpm or pacman package files, searched recursively
+	URL string // file:// repository
+}
+
+// ParseLocalSource parses a directory or a file:// repository URL
+func ParseLocalSource(spec string) (*LocalSource, error) {
+	source := &LocalSource{}
+	path := spec
+	if strings.HasPrefix(spec, "file://") {
+		source.URL = spec
+		path = strings.TrimPrefix(spec, "file://")
+		if !filepath.IsAbs(path) {
+			return nil, fmt.Errorf("unsupported package source %q, file:// repositories need an absolute path", spec)
+		}
+	} else {
+		var err error
+		if path, err = filepath.Abs(spec); err != nil {
+			return nil, err
+		}
+		source.Dir = path
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("package source %s: %w", spec, err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("package source %s isn't a directory", spec)
+	}
+	return source, nil
+}
+
+func (self *LocalSource) String() string {
+	if self.URL != "" {
+		return self.URL
+	}
+	return self.Dir
+}
+
+// path returns the directory of the source, the repository's for file:// URLs
+func (self *LocalSource) path() string {
+	if self.URL != "" {
+		return strings.TrimPrefix(self.URL, "file://")
+	}
+	return self.Dir
+}
+
+// packageFiles returns every package file with one of the suffixes in the source directory. All
+// of them are handed to the package manager so it can pick the dependencies it needs, but each
+// package must have a file of its own. nameOf returns the package name a file holds.
+func (self *LocalSource) packageFiles(packages []string, suffixes []string, nameOf func(file string) string) ([]string, error) {
+	var files []string
+	found := map[string]bool{}
+	err := filepath.WalkDir(self.path(), func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+		for _, suffix := range suffixes {
+			if strings.HasSuffix(entry.Name(), suffix) {
+				files = append(files, path)
+				found[nameOf(strings.TrimSuffix(entry.Name(), suffix))] = true
+				break
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read package source %s: %w", self, err)
+	}
+
+	var missing []string
+	for _, pkg := range packages {
+		if !found[pkg] {
+			missing = append(missing, pkg)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("package source %s has no %s file for %s", self, strings.Join(suffixes, " or "), strings.Join(missing, ", "))
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// debName returns the package in a .deb file name, curl_8.5.0-2ubuntu10_amd64
+func debName(file string) string {
+	name, _, _ := strings.Cut(file, "_")
+	return name
+}
+
+// pacmanName returns the package in a pacman package file name, git-2.45.2-1-x86_64
+func pacmanName(file string) string {
+	parts := strings.Split(file, "-")
+	if len(parts) < 4 {
+		return file
+	}
+	return strings.Join(parts[:len(parts)-3], "-")
+}
+
+// pacmanSuffixes are the compressions pacman packages come in
+var pacmanSuffixes = []string{".pkg.tar.zst", ".pkg.tar.xz", ".pkg.tar.gz", ".pkg.tar"}
@@ -1,6 +1,10 @@
 package pkgmanager
 
-import "sort"
+import (
+	"sort"
+
+	"github.com/unbindapp/unbind-installer/internal/osinfo"
+)
 
 // PackageMapping defines the mapping of common package names to distribution-specific package names
 var PackageMapping = map[string]map[string]string{
@@ -12,6 +16,8 @@ var PackageMapping = map[string]map[string]string{
 		"opensuse":  "tar",
 		"rocky":     "tar",
 		"almalinux": "tar",
+		"arch":      "tar",
+		"manjaro":   "tar",
 	},
 	"iscsiadm": {
 		"ubuntu":    "open-iscsi",
@@ -21,6 +27,20 @@ var PackageMapping = map[string]map[string]string{
 		"opensuse":  "open-iscsi",
 		"rocky":     "iscsi-initiator-utils",
 		"almalinux": "iscsi-initiator-utils",
+		"arch":      "open-iscsi",
+		"manjaro":   "open-iscsi",
+	},
+	// Longhorn needs an NFSv4 client for NFS backup targets and RWX volumes
+	"mount.nfs": {
+		"ubuntu":    "nfs-common",
+		"debian":    "nfs-common",
+		"fedora":    "nfs-utils",
+		"centos":    "nfs-utils",
+		"opensuse":  "nfs-client",
+		"rocky":     "nfs-utils",
+		"almalinux": "nfs-utils",
+		"arch":      "nfs-utils",
+		"manjaro":   "nfs-utils",
 	},
 	"git": {
 		"ubuntu":    "git",
@@ -30,6 +50,8 @@ var PackageMapping = map[string]map[string]string{
 		"opensuse":  "git",
 		"rocky":     "git",
 		"almalinux": "git",
+		"arch":      "git",
+		"manjaro":   "git",
 	},
 	"curl": {
 		"ubuntu":    "curl",
@@ -39,6 +61,8 @@ var PackageMapping = map[string]map[string]string{
 		"opensuse":  "curl",
 		"rocky":     "curl",
 		"almalinux": "curl",
+		"arch":      "curl",
+		"manjaro":   "curl",
 	},
 	"wget": {
 		"ubuntu":    "wget",
@@ -48,6 +72,8 @@ var PackageMapping = map[string]map[string]string{
 		"opensuse":  "wget",
 		"rocky":     "wget",
 		"almalinux": "wget",
+		"arch":      "wget",
+		"manjaro":   "wget",
 	},
 	"ca-certificates": {
 		"ubuntu":    "ca-certificates",
@@ -57,6 +83,8 @@ var PackageMapping = map[string]map[string]string{
 		"opensuse":  "ca-certificates",
 		"rocky":     "ca-certificates",
 		"almalinux": "ca-certificates",
+		"arch":      "ca-certificates",
+		"manjaro":   "ca-certificates",
 	},
 	"apt-transport-https": {
 		"ubuntu":    "apt-transport-https",
@@ -66,6 +94,8 @@ var PackageMapping = map[string]map[string]string{
 		"opensuse":  "", // Not needed on OpenSUSE
 		"rocky":     "", // Not needed on Rocky
 		"almalinux": "", // Not needed on AlmaLinux
+		"arch":      "", // Not needed on Arch
+		"manjaro":   "", // Not needed on Manjaro
 	},
 	"apache2-utils": {
 		"ubuntu":    "apache2-utils",
@@ -75,9 +105,54 @@ var PackageMapping = map[string]map[string]string{
 		"opensuse":  "apache2-utils",
 		"rocky":     "httpd-tools",
 		"almalinux": "httpd-tools",
+		"arch":      "apache",
+		"manjaro":   "apache",
+	},
+}
+
+// SELinuxPackageMapping defines the policy packages K3s needs when SELinux is enabled, by common
+// package name and distribution
+var SELinuxPackageMapping = map[string]map[string]string{
+	"container-selinux": {
+		"fedora":    "container-selinux",
+		"centos":    "container-selinux",
+		"rocky":     "container-selinux",
+		"almalinux": "container-selinux",
+	},
+	"k3s-selinux": {
+		"fedora":    "k3s-selinux",
+		"centos":    "k3s-selinux",
+		"rocky":     "k3s-selinux",
+		"almalinux": "k3s-selinux",
 	},
 }
 
+// GetRequiredPackages returns the packages to install on this server, the SELinux policy packages
+// included when SELinux is enabled
+func GetRequiredPackages(info *osinfo.OSInfo) []string {
+	result := GetDistributionPackages(info.Distribution)
+	if info.SELinux == "" || info.SELinux == "disabled" {
+		return result
+	}
+	for name, packageMap := range SELinuxPackageMapping {
+		distPkg, ok := packageMap[info.Distribution]
+		if !ok || distPkg == "" {
+			continue
+		}
+		// No base repository ships k3s-selinux, skip it where there's no repository for it, the
+		// K3s installer then sets it up itself
+		if requirements, needsRepository := RepositoryRequirements[name]; needsRepository {
+			if _, found := forDistribution(requirements, info); !found {
+				continue
+			}
+		}
+		result = append(result, distPkg)
+	}
+
+	sort.Strings(result)
+	return result
+}
+
 // GetDistributionPackages returns all available packages for the specified distribution
 func GetDistributionPackages(distribution string) []string {
 	var result []string
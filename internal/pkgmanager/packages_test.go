@@ -0,0 +1,78 @@
+package pkgmanager
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/unbindapp/unbind-installer/internal/osinfo"
+)
+
+func TestSELinuxPackagesAndRepositories(t *testing.T) {
+	tests := []struct {
+		name    string
+		info    osinfo.OSInfo
+		selinux bool
+		baseURL string
+	}{
+		{
+			name:    "rocky",
+			info:    osinfo.OSInfo{Distribution: "rocky", ID: "rocky", IDLike: []string{"rhel", "centos", "fedora"}, VersionID: "9.5"},
+			selinux: true,
+			baseURL: "https://rpm.rancher.io/k3s/stable/common/centos/9/noarch",
+		},
+		{
+			name:    "rhel",
+			info:    osinfo.OSInfo{Distribution: "rocky", ID: "rhel", IDLike: []string{"fedora"}, VersionID: "8.10"},
+			selinux: true,
+			baseURL: "https://rpm.rancher.io/k3s/stable/common/centos/8/noarch",
+		},
+		{
+			name:    "amazon linux",
+			info:    osinfo.OSInfo{Distribution: "rocky", ID: "amzn", IDLike: []string{"fedora"}, VersionID: "2023"},
+			selinux: true,
+			baseURL: "https://rpm.rancher.io/k3s/stable/common/centos/9/noarch",
+		},
+		{
+			name:    "fedora",
+			info:    osinfo.OSInfo{Distribution: "fedora", ID: "fedora", VersionID: "41"},
+			selinux: true,
+			baseURL: "https://rpm.rancher.io/k3s/stable/common/centos/9/noarch",
+		},
+		{
+			name:    "unlisted derivative",
+			info:    osinfo.OSInfo{Distribution: "rocky", ID: "eurolinux", IDLike: []string{"rhel", "fedora"}, VersionID: "9.2"},
+			selinux: true,
+			baseURL: "https://rpm.rancher.io/k3s/stable/common/centos/9/noarch",
+		},
+		{
+			name: "no repository",
+			info: osinfo.OSInfo{Distribution: "rocky", ID: "mystery", VersionID: "9"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fakeRepositoryCommands(t)
+
+			tt.info.SELinux = "disabled"
+			assert.NotContains(t, GetRequiredPackages(&tt.info), "k3s-selinux")
+
+			tt.info.SELinux = "permissive"
+			packages := GetRequiredPackages(&tt.info)
+			assert.Contains(t, packages, "container-selinux")
+			if !tt.selinux {
+				assert.NotContains(t, packages, "k3s-selinux")
+				return
+			}
+			assert.Contains(t, packages, "k3s-selinux")
+
+			repos, err := RequiredRepositories(context.Background(), &tt.info, packages)
+			require.NoError(t, err)
+			require.Len(t, repos, 1)
+			assert.Equal(t, "rancher-k3s-common", repos[0].ID)
+			assert.Contains(t, repos[0].Enable, "'baseurl="+tt.baseURL+"'")
+		})
+	}
+}
@@ -0,0 +1,189 @@
+package pkgmanager
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// pacmanKernelModules are loaded before a system upgrade, an upgraded kernel package removes the
+// modules of the running kernel until the server reboots
+var pacmanKernelModules = []string{"overlay", "br_netfilter", "iscsi_tcp"}
+
+// UpgradesSystem reports whether installing packages upgrades the whole system, Arch doesn't
+// support partial upgrades so pacman upgrades everything along with the packages. Callers ask
+// before installing when it does.
+func UpgradesSystem(distribution string, source *LocalSource) bool {
+	return source == nil && (distribution == "arch" || distribution == "manjaro")
+}
+
+// PacmanInstaller for Arch-based systems
+type PacmanInstaller struct {
+	// Channel to send log messages
+	LogChan chan<- string
+	// How long to wait for another package manager to release its lock
+	LockTimeout time.Duration
+	// Installs from local packages instead of the configured repositories when set
+	Source *LocalSource
+}
+
+// NewPacmanInstaller creates pacman package manager
+func NewPacmanInstaller(logChan chan<- string) *PacmanInstaller {
+	return &PacmanInstaller{
+		LogChan: logChan,
+	}
+}
+
+// InstallPackages handles pacman package installation
+func (self *PacmanInstaller) InstallPackages(ctx context.Context, packages []string, progressFunc ProgressFunc) error {
+	if len(packages) == 0 {
+		return nil
+	}
+
+	// Start with proper progress reporting
+	if progressFunc != nil {
+		progressFunc("", 0.05, "Initializing package installation...", false)
+	}
+
+	// Arch doesn't support partial upgrades, so the system is upgraded along with the install
+	// rather than installing against freshly synced databases alone
+	steps := []struct {
+		progress    float64
+		endProgress float64
+		step        string
+		cmd         *exec.Cmd
+		parse       progressParser
+	}{
+		{
+			progress:    0.10,
+			endProgress: 0.25,
+			step:        "Synchronizing package databases...",
+			cmd:         exec.CommandContext(ctx, "pacman", "-Sy", "--noconfirm"),
+		},
+		{
+			progress:    0.30,
+			endProgress: 0.85,
+			step:        fmt.Sprintf("Upgrading the system and installing %d packages...", len(packages)),
+			cmd:         exec.CommandContext(ctx, "pacman", append([]string{"-Su", "--needed", "--noconfirm"}, packages...)...),
+			parse:       parsePacman,
+		},
+	}
+	// pacman repositories are directories of package files next to their database, so both kinds
+	// of local source are installed from the files without syncing anything
+	if self.Source != nil {
+		files, err := self.Source.packageFiles(packages, pacmanSuffixes, pacmanName)
+		if err != nil {
+			return err
+		}
+		steps = steps[1:]
+		steps[0].step = fmt.Sprintf("Installing %d packages from %s...", len(packages), self.Source)
+		steps[0].cmd = exec.CommandContext(ctx, "pacman", append([]string{"-U", "--needed", "--noconfirm"}, files...)...)
+	}
+
+	// K3s and Longhorn can't load modules after an upgrade removed the running kernel's modules
+	kernel := ""
+	if self.Source == nil {
+		kernel = self.loadKernelModules(ctx)
+	}
+
+	// Execute each step with progress updates
+	for _, step := range steps {
+		// Another package manager may hold the lock, e.g. unattended upgrades right after boot
+		if err := waitForLocks(ctx, pacmanLocks, "pacman", self.LockTimeout, self.log, progressFunc, step.progress); err != nil {
+			return fmt.Errorf("failed during %s: %w", step.step, err)
+		}
+
+		// Report starting this step
+		if progressFunc != nil {
+			progressFunc("", step.progress, step.step, false)
+		}
+		self.log(step.step)
+
+		// Execute the command, following the progress pacman prints for each package
+		output, err := runWithProgress(step.cmd, false, step.parse, step.progress, step.endProgress, progressFunc)
+
+		// Handle errors
+		if err != nil {
+			self.log(fmt.Sprintf("Error: %s\nOutput: %s", err, string(output)))
+			return fmt.Errorf("failed during %s: %w", step.step, err)
+		}
+
+		// Log successful completion and report completion progress
+		self.log(fmt.Sprintf("Completed: %s", step.step))
+		if progressFunc != nil {
+			progressFunc("", step.endProgress, fmt.Sprintf("Completed: %s", step.step), false)
+		}
+	}
+
+	// Final verification step with minimal progress
+	if progressFunc != nil {
+		progressFunc("", 0.90, "Verifying installation...", false)
+	}
+	self.log("Verifying installation...")
+	if kernel != "" {
+		if _, err := os.Stat(filepath.Join("/usr/lib/modules", kernel)); os.IsNotExist(err) {
+			self.log(fmt.Sprintf("Warning: The kernel was upgraded and the modules of the running kernel %s were removed, reboot the server once the installation finishes", kernel))
+		}
+	}
+
+	// Small delay to show verification step
+	time.Sleep(500 * time.Millisecond)
+
+	// Report completion
+	if progressFunc != nil {
+		progressFunc("", 1.0, "Installation complete", true)
+	}
+	self.log("Packages installed successfully")
+
+	return nil
+}
+
+// InstalledPackages queries the local pacman database for the installed versions of packages
+func (self *PacmanInstaller) InstalledPackages(ctx context.Context, packages []string) (map[string]string, error) {
+	installed := map[string]string{}
+	if len(packages) == 0 {
+		return installed, nil
+	}
+
+	output, err := runQuery(ctx, "pacman", append([]string{"-Q"}, packages...)...)
+	if err != nil {
+		return nil, err
+	}
+	for _, fields := range parseQuery(output, " ", 2) {
+		installed[fields[0]] = fields[1]
+	}
+	return installed, nil
+}
+
+// RemovePackages removes packages with pacman
+func (self *PacmanInstaller) RemovePackages(ctx context.Context, packages []string) error {
+	if len(packages) == 0 {
+		return nil
+	}
+	return removePackages(ctx, pacmanLocks, self.LockTimeout, self.log, "pacman", append([]string{"-R", "--noconfirm"}, packages...)...)
+}
+
+// loadKernelModules loads the modules K3s and Longhorn need and returns the running kernel release
+func (self *PacmanInstaller) loadKernelModules(ctx context.Context) string {
+	for _, module := range pacmanKernelModules {
+		if output, err := exec.CommandContext(ctx, "modprobe", module).CombinedOutput(); err != nil {
+			self.log(fmt.Sprintf("Warning: Could not load kernel module %s: %s", module, strings.TrimSpace(string(output))))
+		}
+	}
+	release, err := os.ReadFile("/proc/sys/kernel/osrelease")
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(release))
+}
+
+// log writes to log channel
+func (self *PacmanInstaller) log(message string) {
+	if self.LogChan != nil {
+		self.LogChan <- message
+	}
+}
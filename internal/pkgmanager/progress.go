@@ -0,0 +1,237 @@
+package pkgmanager
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// progressParser reads a line of package manager output. ok is false for lines that aren't about
+// progress, fraction is how far along the command is from 0 to 1, or 0 when the line doesn't say.
+type progressParser func(line string) (pkg string, fraction float64, step string, ok bool)
+
+// runWithProgress runs cmd, reporting the progress parsed from its output within [start, end] of
+// the whole install. With statusFd the lines the command writes to file descriptor 3 are parsed
+// too, for apt's APT::Status-Fd. It returns the combined output for error messages.
+func runWithProgress(cmd *exec.Cmd, statusFd bool, parse progressParser, start, end float64, progressFunc ProgressFunc) ([]byte, error) {
+	lines := make(chan string, 100)
+	var readers sync.WaitGroup
+	readLines := func(r io.Reader) {
+		defer readers.Done()
+		scanner := bufio.NewScanner(r)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+		// Keep draining after an overlong line so the command never blocks on a full pipe
+		io.Copy(io.Discard, r)
+	}
+
+	var output bytes.Buffer
+	outputReader, outputWriter := io.Pipe()
+	cmd.Stdout = io.MultiWriter(&output, outputWriter)
+	cmd.Stderr = cmd.Stdout
+	// Daemons restarted by maintainer scripts can inherit the output pipes and outlive the command
+	cmd.WaitDelay = time.Second
+	readers.Add(1)
+	go readLines(outputReader)
+
+	var statusReader, statusWriter *os.File
+	if statusFd {
+		var err error
+		if statusReader, statusWriter, err = os.Pipe(); err != nil {
+			outputWriter.Close()
+			return nil, err
+		}
+		defer statusReader.Close()
+		cmd.ExtraFiles = []*os.File{statusWriter}
+		readers.Add(1)
+		go readLines(statusReader)
+	}
+
+	// Both streams are parsed in one place so progress never goes backwards
+	parsed := make(chan struct{})
+	go func() {
+		defer close(parsed)
+		last := start
+		for line := range lines {
+			if parse == nil || progressFunc == nil {
+				continue
+			}
+			pkg, fraction, step, ok := parse(line)
+			if !ok {
+				continue
+			}
+			if progress := start + (end-start)*fraction; progress > last {
+				last = progress
+			}
+			progressFunc(pkg, last, step, false)
+		}
+	}()
+
+	err := cmd.Start()
+	if statusWriter != nil {
+		// The command has its own copy, ours would keep the pipe open
+		statusWriter.Close()
+	}
+	if err == nil {
+		err = cmd.Wait()
+	}
+	outputWriter.Close()
+	if statusReader != nil {
+		// Same for the status pipe, which exec doesn't track
+		statusReader.SetReadDeadline(time.Now().Add(time.Second))
+	}
+	readers.Wait()
+	close(lines)
+	<-parsed
+	return output.Bytes(), err
+}
+
+// aptGetLine names a package apt downloads: Get:3 http://archive.ubuntu.com/ubuntu noble/main amd64 curl amd64 8.5.0-2ubuntu10 [226 kB]
+var aptGetLine = regexp.MustCompile(`^Get:\d+ \S+ \S+ \S+ (\S+) \S+ \S+ \[(.+)\]$`)
+
+// newAptParser parses apt's status lines, dlstatus:<n>:<percent>:<description> while downloading
+// and pmstatus:<package>:<percent>:<description> while installing, along with the Get lines that
+// name the package being downloaded. Downloads take up downloadShare of the command's progress.
+func newAptParser(downloadShare float64) progressParser {
+	downloading := ""
+	return func(line string) (string, float64, string, bool) {
+		if match := aptGetLine.FindStringSubmatch(line); match != nil {
+			downloading = match[1]
+			return downloading, 0, fmt.Sprintf("Downloading %s (%s)", match[1], match[2]), true
+		}
+
+		kind, rest, _ := strings.Cut(line, ":")
+		if kind != "dlstatus" && kind != "pmstatus" {
+			return "", 0, "", false
+		}
+		// Package names can carry an architecture, pmstatus:libc6:amd64:12.5:Unpacking libc6 (amd64)
+		parts := strings.Split(rest, ":")
+		for i := 1; i < len(parts); i++ {
+			percent, err := strconv.ParseFloat(parts[i], 64)
+			if err != nil {
+				continue
+			}
+			description := strings.Join(parts[i+1:], ":")
+			if kind == "dlstatus" {
+				if downloading != "" {
+					description = fmt.Sprintf("%s (%s)", description, downloading)
+				}
+				return downloading, downloadShare * percent / 100, description, true
+			}
+			pkg, _, _ := strings.Cut(strings.Join(parts[:i], ":"), ":")
+			return pkg, downloadShare + (1-downloadShare)*percent/100, description, true
+		}
+		return "", 0, "", false
+	}
+}
+
+var (
+	// dnf 4: (1/5): git-2.43.5-1.el9_4.x86_64.rpm   1.2 MB/s |  50 kB   00:00
+	dnfDownloadLine = regexp.MustCompile(`^\((\d+)/(\d+)\): (\S+)\.rpm\s`)
+	// dnf 4:   Installing       : git-2.43.5-1.el9_4.x86_64     3/5
+	dnfTransactionLine = regexp.MustCompile(`^\s+(Installing|Upgrading|Verifying)\s*: (\S+)\s+(\d+)/(\d+)$`)
+	// dnf 5: [1/5] git-0:2.47.0-1.fc41.x86_64  100% | ... while downloading and
+	// [3/7] Installing git-0:2.47.0-1.fc41.x86_64  100% | ... in the transaction
+	dnf5Line = regexp.MustCompile(`^\[\s*(\d+)/(\d+)\] (?:(Installing|Upgrading) )?(\S+-\S+)`)
+)
+
+// parseDNF reads dnf 4 and dnf 5 output, downloads take up the first half of the progress
+func parseDNF(line string) (string, float64, string, bool) {
+	if match := dnfDownloadLine.FindStringSubmatch(line); match != nil {
+		done, total := atoi(match[1]), atoi(match[2])
+		return packageName(match[3]), 0.5 * ratio(done, total), fmt.Sprintf("Downloading %s (%d/%d)", packageName(match[3]), done, total), true
+	}
+	if match := dnfTransactionLine.FindStringSubmatch(line); match != nil {
+		done, total := atoi(match[3]), atoi(match[4])
+		if match[1] == "Verifying" {
+			return packageName(match[2]), 0.85 + 0.15*ratio(done, total), fmt.Sprintf("Verifying %s (%d/%d)", packageName(match[2]), done, total), true
+		}
+		return packageName(match[2]), 0.5 + 0.35*ratio(done, total), fmt.Sprintf("%s %s (%d/%d)", match[1], packageName(match[2]), done, total), true
+	}
+	if match := dnf5Line.FindStringSubmatch(line); match != nil {
+		done, total := atoi(match[1]), atoi(match[2])
+		if match[3] == "" {
+			return packageName(match[4]), 0.5 * ratio(done, total), fmt.Sprintf("Downloading %s (%d/%d)", packageName(match[4]), done, total), true
+		}
+		return packageName(match[4]), 0.5 + 0.5*ratio(done, total), fmt.Sprintf("%s %s (%d/%d)", match[3], packageName(match[4]), done, total), true
+	}
+	return "", 0, "", false
+}
+
+var (
+	// Retrieving: git-2.43.0-150600.3.3.1.x86_64 (Main Repository) (1/5), 2.3 MiB
+	// Retrieving package git-2.26.2-lp152.2.6.1.x86_64   (1/5),   4.8 MiB ( 27.4 MiB unpacked)
+	zypperDownloadLine = regexp.MustCompile(`^Retrieving(?: package)?:? (\S+)\s.*?\((\d+)/(\d+)\)`)
+	// (1/5) Installing: git-2.43.0-150600.3.3.1.x86_64 ...........[done]
+	zypperInstallLine = regexp.MustCompile(`^\((\d+)/(\d+)\) Installing: (\S+)`)
+)
+
+// parseZypper reads zypper output, downloads take up the first half of the progress
+func parseZypper(line string) (string, float64, string, bool) {
+	if match := zypperDownloadLine.FindStringSubmatch(line); match != nil {
+		done, total := atoi(match[2]), atoi(match[3])
+		return packageName(match[1]), 0.5 * ratio(done, total), fmt.Sprintf("Downloading %s (%d/%d)", packageName(match[1]), done, total), true
+	}
+	if match := zypperInstallLine.FindStringSubmatch(line); match != nil {
+		done, total := atoi(match[1]), atoi(match[2])
+		return packageName(match[3]), 0.5 + 0.5*ratio(done, total), fmt.Sprintf("Installing %s (%d/%d)", packageName(match[3]), done, total), true
+	}
+	return "", 0, "", false
+}
+
+var (
+	// git-2.45.2-1-x86_64 downloading...
+	pacmanDownloadLine = regexp.MustCompile(`^\s*(\S+)-[^-\s]+-[^-\s]+-[^-\s]+ downloading\.\.\.$`)
+	// (1/5) installing git
+	pacmanInstallLine = regexp.MustCompile(`^\(\s*(\d+)/(\d+)\) (installing|upgrading) (\S+)`)
+)
+
+// parsePacman reads pacman output, downloads take up the first half of the progress
+func parsePacman(line string) (string, float64, string, bool) {
+	if match := pacmanDownloadLine.FindStringSubmatch(line); match != nil {
+		return match[1], 0, fmt.Sprintf("Downloading %s", match[1]), true
+	}
+	if match := pacmanInstallLine.FindStringSubmatch(line); match != nil {
+		done, total := atoi(match[1]), atoi(match[2])
+		action := "Installing"
+		if match[3] == "upgrading" {
+			action = "Upgrading"
+		}
+		return match[4], 0.5 + 0.5*ratio(done, total), fmt.Sprintf("%s %s (%d/%d)", action, match[4], done, total), true
+	}
+	return "", 0, "", false
+}
+
+// packageName strips the version, release and architecture from an rpm name such as
+// git-core-0:2.47.0-1.fc41.x86_64
+func packageName(nevra string) string {
+	if i := strings.LastIndex(nevra, "."); i > 0 {
+		nevra = nevra[:i]
+	}
+	parts := strings.Split(nevra, "-")
+	if len(parts) < 3 {
+		return nevra
+	}
+	return strings.Join(parts[:len(parts)-2], "-")
+}
+
+func atoi(s string) int {
+	n, _ := strconv.Atoi(s)
+	return n
+}
+
+func ratio(done, total int) float64 {
+	if total <= 0 {
+		return 0
+	}
+	return float64(done) / float64(total)
+}
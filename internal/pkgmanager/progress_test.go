@@ -0,0 +1,112 @@
+package pkgmanager
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type parsedLine struct {
+	line     string
+	pkg      string
+	fraction float64
+	step     string
+	ok       bool
+}
+
+func assertParsed(t *testing.T, parse progressParser, lines []parsedLine) {
+	t.Helper()
+	for _, tt := range lines {
+		pkg, fraction, step, ok := parse(tt.line)
+		if assert.Equal(t, tt.ok, ok, tt.line) && ok {
+			assert.Equal(t, tt.pkg, pkg, tt.line)
+			assert.InDelta(t, tt.fraction, fraction, 0.0001, tt.line)
+			assert.Equal(t, tt.step, step, tt.line)
+		}
+	}
+}
+
+func TestAptParser(t *testing.T) {
+	// Lines in the order apt writes them, the parser remembers the package being downloaded
+	assertParsed(t, newAptParser(0.4), []parsedLine{
+		{line: "Reading package lists..."},
+		{line: "dlstatus:1:12.5:Retrieving file 1 of 2", fraction: 0.05, step: "Retrieving file 1 of 2", ok: true},
+		{
+			line: "Get:3 http://archive.ubuntu.com/ubuntu noble/main amd64 curl amd64 8.5.0-2ubuntu10 [226 kB]",
+			pkg:  "curl", step: "Downloading curl (226 kB)", ok: true,
+		},
+		{line: "dlstatus:1:50.0:Retrieving file 1 of 2", pkg: "curl", fraction: 0.2, step: "Retrieving file 1 of 2 (curl)", ok: true},
+		{line: "pmstatus:curl:50:Installing curl", pkg: "curl", fraction: 0.7, step: "Installing curl", ok: true},
+		{line: "pmstatus:libc6:amd64:12.5:Unpacking libc6 (amd64)", pkg: "libc6", fraction: 0.475, step: "Unpacking libc6 (amd64)", ok: true},
+		{line: "pmstatus:tzdata:all:100:Configuring tzdata: setting the zone", pkg: "tzdata", fraction: 1, step: "Configuring tzdata: setting the zone", ok: true},
+		{line: "pmstatus:curl:Installing curl"},
+		{line: "status:curl:installed"},
+	})
+}
+
+func TestParseDNF(t *testing.T) {
+	assertParsed(t, parseDNF, []parsedLine{
+		// dnf 4
+		{line: "(1/5): git-2.43.5-1.el9_4.x86_64.rpm   1.2 MB/s |  50 kB   00:00", pkg: "git", fraction: 0.1, step: "Downloading git (1/5)", ok: true},
+		{line: "  Installing       : git-2.43.5-1.el9_4.x86_64     3/5", pkg: "git", fraction: 0.71, step: "Installing git (3/5)", ok: true},
+		{line: "  Upgrading        : openssl-libs-1:3.2.2-6.el9_5.x86_64     4/5", pkg: "openssl-libs", fraction: 0.78, step: "Upgrading openssl-libs (4/5)", ok: true},
+		{line: "  Verifying        : git-2.43.5-1.el9_4.x86_64     5/5", pkg: "git", fraction: 1, step: "Verifying git (5/5)", ok: true},
+		// dnf 5
+		{
+			line: "[1/5] git-0:2.47.0-1.fc41.x86_64  100% |   1.2 MiB/s |  50.0 KiB |  00m00s",
+			pkg:  "git", fraction: 0.1, step: "Downloading git (1/5)", ok: true,
+		},
+		{
+			line: "[ 3/7] Installing git-core-0:2.47.0-1.fc41.x86_64  100% |  80.0 MiB/s |  22.4 MiB |  00m00s",
+			pkg:  "git-core", fraction: 0.5 + 0.5*3/7, step: "Installing git-core (3/7)", ok: true,
+		},
+		{line: "Last metadata expiration check: 0:01:02 ago on Mon 02 Jun 2025 03:00:00 AM UTC."},
+		{line: "Complete!"},
+	})
+}
+
+func TestParseZypper(t *testing.T) {
+	assertParsed(t, parseZypper, []parsedLine{
+		{
+			line: "Retrieving: git-2.43.0-150600.3.3.1.x86_64 (Main Repository) (1/5), 2.3 MiB",
+			pkg:  "git", fraction: 0.1, step: "Downloading git (1/5)", ok: true,
+		},
+		{
+			line: "Retrieving package git-2.26.2-lp152.2.6.1.x86_64   (1/5),   4.8 MiB ( 27.4 MiB unpacked)",
+			pkg:  "git", fraction: 0.1, step: "Downloading git (1/5)", ok: true,
+		},
+		{
+			line: "(2/5) Installing: git-2.43.0-150600.3.3.1.x86_64 ...........[done]",
+			pkg:  "git", fraction: 0.7, step: "Installing git (2/5)", ok: true,
+		},
+		{line: "Loading repository data..."},
+	})
+}
+
+func TestParsePacman(t *testing.T) {
+	assertParsed(t, parsePacman, []parsedLine{
+		{line: " git-2.45.2-1-x86_64 downloading...", pkg: "git", step: "Downloading git", ok: true},
+		{line: " perl-error-0.17029-5-any downloading...", pkg: "perl-error", step: "Downloading perl-error", ok: true},
+		{line: "(1/5) installing git", pkg: "git", fraction: 0.6, step: "Installing git (1/5)", ok: true},
+		{line: "( 2/10) upgrading openssl", pkg: "openssl", fraction: 0.6, step: "Upgrading openssl (2/10)", ok: true},
+		{line: ":: Proceed with installation? [Y/n]"},
+	})
+}
+
+func TestPackageName(t *testing.T) {
+	tests := map[string]string{
+		"git-core-0:2.47.0-1.fc41.x86_64":            "git-core",
+		"git-2.43.5-1.el9_4.x86_64":                  "git",
+		"perl-Error-0.17029-5.el9.noarch":            "perl-Error",
+		"git-2.43.0-150600.3.3.1.x86_64":             "git",
+		"container-selinux":                          "container-selinux",
+		"curl":                                       "curl",
+		"openssl-libs-1:3.2.2-6.el9_5.x86_64":        "openssl-libs",
+		"k3s-selinux-1.6-1.el9.noarch":               "k3s-selinux",
+		"python3-libselinux-3.6-1.el9.x86_64":        "python3-libselinux",
+		"iscsi-initiator-utils-6.2.1.9-1.el9.x86_64": "iscsi-initiator-utils",
+	}
+	for nevra, want := range tests {
+		assert.Equal(t, want, packageName(nevra), nevra)
+	}
+}
@@ -0,0 +1,73 @@
+package pkgmanager
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// runQuery runs a package query and returns its standard output. Query tools exit non-zero
+// when any of the packages isn't installed, which only means those are missing from the output.
+func runQuery(ctx context.Context, name string, args ...string) ([]byte, error) {
+	output, err := exec.CommandContext(ctx, name, args...).Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if ctx.Err() != nil || !errors.As(err, &exitErr) {
+			return nil, fmt.Errorf("failed to query installed packages with %s: %w", name, err)
+		}
+	}
+	return output, nil
+}
+
+// parseQuery reads one line of separated fields per package, lines with a different number of
+// fields are messages about packages that aren't installed
+func parseQuery(output []byte, separator string, fields int) [][]string {
+	var result [][]string
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for scanner.Scan() {
+		parts := strings.Split(strings.TrimSpace(scanner.Text()), separator)
+		if len(parts) == fields {
+			result = append(result, parts)
+		}
+	}
+	return result
+}
+
+// rpmInstalledPackages queries the rpm database, shared by dnf and zypper
+func rpmInstalledPackages(ctx context.Context, packages []string) (map[string]string, error) {
+	installed := map[string]string{}
+	if len(packages) == 0 {
+		return installed, nil
+	}
+
+	output, err := runQuery(ctx, "rpm", append([]string{"-q", "--queryformat", "%{NAME}\\t%{VERSION}-%{RELEASE}\\n"}, packages...)...)
+	if err != nil {
+		return nil, err
+	}
+	for _, fields := range parseQuery(output, "\t", 2) {
+		installed[fields[0]] = fields[1]
+	}
+	return installed, nil
+}
+
+// removePackages runs a package removal command once locks are released, logging its output when
+// it fails
+func removePackages(ctx context.Context, locks []lockFile, lockTimeout time.Duration, logFn func(string), name string, args ...string) error {
+	if err := waitForLocks(ctx, locks, name, lockTimeout, logFn, nil, 0); err != nil {
+		return err
+	}
+
+	logFn(fmt.Sprintf("Removing packages with %s...", name))
+	output, err := exec.CommandContext(ctx, name, args...).CombinedOutput()
+	if err != nil {
+		logFn(fmt.Sprintf("Error removing packages: %s", string(output)))
+		return fmt.Errorf("failed to remove packages: %w", err)
+	}
+	logFn("Packages removed successfully")
+	return nil
+}
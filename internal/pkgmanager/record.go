@@ -0,0 +1,133 @@
+package pkgmanager
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// DefaultRecordPath is where the packages the installer added are recorded. It is kept out of
+// /etc/unbind so a backup restored on another server doesn't bring this server's list along.
+const DefaultRecordPath = "/var/lib/unbind/installed-packages"
+
+// InstallMissing installs the packages that aren't installed yet and records them at recordPath,
+// so uninstall removes exactly what the installer added. The package lists aren't updated at all
+// when nothing is missing. It returns the packages it installed.
+func InstallMissing(ctx context.Context, manager PackageManager, packages []string, recordPath string, progressFunc ProgressFunc) ([]string, error) {
+	missing, err := MissingPackages(ctx, manager, packages)
+	if err != nil {
+		return nil, err
+	}
+	if len(missing) == 0 {
+		if progressFunc != nil {
+			progressFunc("", 1.0, "All packages are already installed", true)
+		}
+		return nil, nil
+	}
+
+	if err := manager.InstallPackages(ctx, missing, progressFunc); err != nil {
+		return nil, err
+	}
+	if err := AddToRecord(recordPath, missing); err != nil {
+		return missing, err
+	}
+	return missing, nil
+}
+
+// MissingPackages returns the packages that aren't installed
+func MissingPackages(ctx context.Context, manager PackageManager, packages []string) ([]string, error) {
+	installed, err := manager.InstalledPackages(ctx, packages)
+	if err != nil {
+		return nil, err
+	}
+
+	var missing []string
+	for _, pkg := range packages {
+		if _, ok := installed[pkg]; !ok {
+			missing = append(missing, pkg)
+		}
+	}
+	return missing, nil
+}
+
+// RemoveRecorded removes the recorded packages that are still installed, then the record itself.
+// It returns the packages it removed.
+func RemoveRecorded(ctx context.Context, manager PackageManager, recordPath string) ([]string, error) {
+	recorded, err := LoadRecord(recordPath)
+	if err != nil {
+		return nil, err
+	}
+
+	installed, err := manager.InstalledPackages(ctx, recorded)
+	if err != nil {
+		return nil, err
+	}
+	var remove []string
+	for _, pkg := range recorded {
+		if _, ok := installed[pkg]; ok {
+			remove = append(remove, pkg)
+		}
+	}
+
+	if err := manager.RemovePackages(ctx, remove); err != nil {
+		return nil, err
+	}
+	if err := os.Remove(recordPath); err != nil && !os.IsNotExist(err) {
+		return remove, fmt.Errorf("failed to remove %s: %w", recordPath, err)
+	}
+	return remove, nil
+}
+
+// LoadRecord returns the recorded packages, none when nothing was recorded
+func LoadRecord(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var packages []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if pkg := strings.TrimSpace(scanner.Text()); pkg != "" {
+			packages = append(packages, pkg)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return packages, nil
+}
+
+// AddToRecord adds packages to the record at path, one package per line
+func AddToRecord(path string, packages []string) error {
+	recorded, err := LoadRecord(path)
+	if err != nil {
+		return err
+	}
+
+	seen := map[string]bool{}
+	var all []string
+	for _, pkg := range append(recorded, packages...) {
+		if !seen[pkg] {
+			seen[pkg] = true
+			all = append(all, pkg)
+		}
+	}
+	sort.Strings(all)
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to record installed packages: %w", err)
+	}
+	if err := os.WriteFile(path, []byte(strings.Join(all, "\n")+"\n"), 0644); err != nil {
+		return fmt.Errorf("failed to record installed packages: %w", err)
+	}
+	return nil
+}
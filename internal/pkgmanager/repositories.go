@@ -0,0 +1,220 @@
+package pkgmanager
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/unbindapp/unbind-installer/internal/osinfo"
+)
+
+// DefaultRepositoryRecordPath is where the repositories the installer enabled are recorded
+const DefaultRepositoryRecordPath = "/var/lib/unbind/enabled-repositories"
+
+// Repository is an extra package repository that isn't enabled on every installation
+type Repository struct {
+	ID   string
+	Name string
+	// Shell commands run on this server
+	Check   string // Succeeds when the repository is already enabled
+	Enable  string
+	Disable string
+}
+
+// RepositoryRequirements declares the packages that need an extra repository, by common package
+// name and os-release ID. Derivatives that ship a package in their base repositories aren't listed,
+// unlisted IDs fall back to the IDs in ID_LIKE. The RHEL family ships every package but k3s-selinux
+// in BaseOS and AppStream, so it never needs EPEL or CRB.
+var RepositoryRequirements = map[string]map[string]string{
+	"apache2-utils": {
+		"sles": "sle-module-server-applications",
+	},
+	"git": {
+		"sles": "sle-module-development-tools",
+	},
+	"k3s-selinux": {
+		"fedora":    "rancher-k3s-common",
+		"rocky":     "rancher-k3s-common",
+		"almalinux": "rancher-k3s-common",
+		"centos":    "rancher-k3s-common",
+		"rhel":      "rancher-k3s-common",
+		"ol":        "rancher-k3s-common",
+		"amzn":      "rancher-k3s-common",
+	},
+}
+
+// rancherK3sCommon is the repository Rancher publishes k3s-selinux in, built for each Enterprise
+// Linux release
+func rancherK3sCommon(release string) Repository {
+	return Repository{
+		Name:  "Rancher K3s Common",
+		Check: "test -e /etc/yum.repos.d/rancher-k3s-common.repo",
+		Enable: "printf '%s\\n' '[rancher-k3s-common]' 'name=Rancher K3s Common' " +
+			"'baseurl=https://rpm.rancher.io/k3s/stable/common/centos/" + release + "/noarch' " +
+			"'enabled=1' 'gpgcheck=1' 'repo_gpgcheck=0' 'gpgkey=https://rpm.rancher.io/public.key' " +
+			"> /etc/yum.repos.d/rancher-k3s-common.repo",
+		Disable: "rm -f /etc/yum.repos.d/rancher-k3s-common.repo",
+	}
+}
+
+// repositories describes how each extra repository is managed, by repository ID and os-release ID.
+// {major} is replaced with the major release, {version} with the full release and {arch} with the
+// machine architecture.
+var repositories = map[string]map[string]Repository{
+	"rancher-k3s-common": {
+		"rocky":     rancherK3sCommon("{major}"),
+		"almalinux": rancherK3sCommon("{major}"),
+		"centos":    rancherK3sCommon("{major}"),
+		"rhel":      rancherK3sCommon("{major}"),
+		"ol":        rancherK3sCommon("{major}"),
+		// Amazon Linux 2023 tracks Enterprise Linux 9 but is versioned by year
+		"amzn": rancherK3sCommon("9"),
+		// There are no Fedora builds, the Enterprise Linux 9 policy matches its container-selinux
+		"fedora": rancherK3sCommon("9"),
+	},
+	"sle-module-server-applications": {
+		"sles": {
+			Name:    "SUSE Server Applications Module",
+			Check:   "test -e /etc/products.d/sle-module-server-applications.prod",
+			Enable:  "SUSEConnect -p sle-module-server-applications/{version}/{arch}",
+			Disable: "SUSEConnect -d -p sle-module-server-applications/{version}/{arch}",
+		},
+	},
+	"sle-module-development-tools": {
+		"sles": {
+			Name:    "SUSE Development Tools Module",
+			Check:   "test -e /etc/products.d/sle-module-development-tools.prod",
+			Enable:  "SUSEConnect -p sle-module-development-tools/{version}/{arch}",
+			Disable: "SUSEConnect -d -p sle-module-development-tools/{version}/{arch}",
+		},
+	},
+}
+
+// RequiredRepositories returns the extra repositories that packages need on this distribution and
+// that aren't enabled yet, packages are distribution package names
+func RequiredRepositories(ctx context.Context, info *osinfo.OSInfo, packages []string) ([]Repository, error) {
+	seen := map[string]bool{}
+	var required []Repository
+	for _, pkg := range packages {
+		repoID, _ := forDistribution(RepositoryRequirements[commonName(info.Distribution, pkg)], info)
+		if repoID == "" || seen[repoID] {
+			continue
+		}
+		seen[repoID] = true
+
+		repo, err := lookupRepository(info, repoID)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := runRepositoryCommand(ctx, repo.Check); err == nil {
+			continue
+		}
+		required = append(required, repo)
+	}
+	return required, nil
+}
+
+// EnableRepositories enables repos, recording each one at recordPath so uninstall can disable it
+func EnableRepositories(ctx context.Context, repos []Repository, recordPath string, logFn func(string)) error {
+	for _, repo := range repos {
+		logFn(fmt.Sprintf("Enabling the %s repository...", repo.Name))
+		if output, err := runRepositoryCommand(ctx, repo.Enable); err != nil {
+			logFn(fmt.Sprintf("Error enabling the %s repository: %s", repo.Name, string(output)))
+			return fmt.Errorf("failed to enable the %s repository: %w", repo.Name, err)
+		}
+		if err := AddToRecord(recordPath, []string{repo.ID}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DisableRecordedRepositories disables the repositories recorded at recordPath, then removes the
+// record. It returns the repositories it disabled.
+func DisableRecordedRepositories(ctx context.Context, info *osinfo.OSInfo, recordPath string, logFn func(string)) ([]string, error) {
+	recorded, err := LoadRecord(recordPath)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, repoID := range recorded {
+		repo, err := lookupRepository(info, repoID)
+		if err != nil {
+			return nil, err
+		}
+		logFn(fmt.Sprintf("Disabling the %s repository...", repo.Name))
+		if output, err := runRepositoryCommand(ctx, repo.Disable); err != nil {
+			logFn(fmt.Sprintf("Error disabling the %s repository: %s", repo.Name, string(output)))
+			return nil, fmt.Errorf("failed to disable the %s repository: %w", repo.Name, err)
+		}
+	}
+	if err := os.Remove(recordPath); err != nil && !os.IsNotExist(err) {
+		return recorded, fmt.Errorf("failed to remove %s: %w", recordPath, err)
+	}
+	return recorded, nil
+}
+
+// RepositoryNames lists the names of repos for prompts and messages
+func RepositoryNames(repos []Repository) string {
+	names := make([]string, len(repos))
+	for i, repo := range repos {
+		names[i] = repo.Name
+	}
+	return strings.Join(names, ", ")
+}
+
+// lookupRepository returns how a repository is managed on this distribution
+func lookupRepository(info *osinfo.OSInfo, repoID string) (Repository, error) {
+	repo, ok := forDistribution(repositories[repoID], info)
+	if !ok {
+		return Repository{}, fmt.Errorf("the %s repository isn't supported on %s", repoID, info.ID)
+	}
+
+	arch := info.Architecture
+	switch arch {
+	case "amd64":
+		arch = "x86_64"
+	case "arm64":
+		arch = "aarch64"
+	}
+	replacer := strings.NewReplacer(
+		"{major}", strings.Split(info.VersionID, ".")[0],
+		"{version}", info.VersionID,
+		"{arch}", arch,
+	)
+
+	repo.ID = repoID
+	repo.Check = replacer.Replace(repo.Check)
+	repo.Enable = replacer.Replace(repo.Enable)
+	repo.Disable = replacer.Replace(repo.Disable)
+	return repo, nil
+}
+
+// forDistribution returns the entry for the os-release ID, or for the first ID in ID_LIKE that has
+// one so derivatives that aren't listed get the entry of their parent
+func forDistribution[T any](entries map[string]T, info *osinfo.OSInfo) (T, bool) {
+	for _, id := range append([]string{info.ID}, info.IDLike...) {
+		if entry, ok := entries[id]; ok {
+			return entry, true
+		}
+	}
+	var zero T
+	return zero, false
+}
+
+// commonName returns the common name of a distribution package, the package itself if unmapped
+func commonName(distribution, pkg string) string {
+	for name, packageMap := range PackageMapping {
+		if packageMap[distribution] == pkg {
+			return name
+		}
+	}
+	return pkg
+}
+
+// runRepositoryCommand runs a repository command with the shell, mockable for tests
+var runRepositoryCommand = func(ctx context.Context, command string) ([]byte, error) {
+	return exec.CommandContext(ctx, "sh", "-c", command).CombinedOutput()
+}
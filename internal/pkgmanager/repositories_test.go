@@ -0,0 +1,104 @@
+package pkgmanager
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/unbindapp/unbind-installer/internal/osinfo"
+)
+
+// fakeRepositoryCommands records repository commands, checks succeed for the enabled repositories
+func fakeRepositoryCommands(t *testing.T, enabled ...string) *[]string {
+	original := runRepositoryCommand
+	t.Cleanup(func() { runRepositoryCommand = original })
+
+	var commands []string
+	runRepositoryCommand = func(ctx context.Context, command string) ([]byte, error) {
+		commands = append(commands, command)
+		for _, check := range enabled {
+			if command == check {
+				return nil, nil
+			}
+		}
+		return []byte("not enabled"), errors.New("exit status 1")
+	}
+	return &commands
+}
+
+func TestLookupRepository(t *testing.T) {
+	sles := &osinfo.OSInfo{Distribution: "opensuse", ID: "sles", VersionID: "15.6", Architecture: "arm64"}
+	repo, err := lookupRepository(sles, "sle-module-development-tools")
+	require.NoError(t, err)
+	assert.Equal(t, "sle-module-development-tools", repo.ID)
+	assert.Equal(t, "SUSEConnect -p sle-module-development-tools/15.6/aarch64", repo.Enable)
+	assert.Equal(t, "SUSEConnect -d -p sle-module-development-tools/15.6/aarch64", repo.Disable)
+
+	sles.Architecture = "amd64"
+	repo, err = lookupRepository(sles, "sle-module-server-applications")
+	require.NoError(t, err)
+	assert.Equal(t, "SUSEConnect -p sle-module-server-applications/15.6/x86_64", repo.Enable)
+
+	_, err = lookupRepository(&osinfo.OSInfo{ID: "opensuse-leap"}, "sle-module-development-tools")
+	assert.ErrorContains(t, err, "the sle-module-development-tools repository isn't supported on opensuse-leap")
+}
+
+func TestRequiredRepositories(t *testing.T) {
+	sles := &osinfo.OSInfo{Distribution: "opensuse", ID: "sles", VersionID: "15.6", Architecture: "amd64"}
+	commands := fakeRepositoryCommands(t, "test -e /etc/products.d/sle-module-development-tools.prod")
+
+	repos, err := RequiredRepositories(context.Background(), sles, []string{"git", "apache2-utils", "curl"})
+	require.NoError(t, err)
+	require.Len(t, repos, 1, "the development tools module is already enabled")
+	assert.Equal(t, "sle-module-server-applications", repos[0].ID)
+	assert.Len(t, *commands, 2, "only repositories that packages need are checked")
+
+	// openSUSE Leap ships every package in its base repositories
+	leap := &osinfo.OSInfo{Distribution: "opensuse", ID: "opensuse-leap", VersionID: "15.6", Architecture: "amd64"}
+	repos, err = RequiredRepositories(context.Background(), leap, []string{"git", "apache2-utils"})
+	require.NoError(t, err)
+	assert.Empty(t, repos)
+}
+
+func TestRequiredRepositories_RHELFamily(t *testing.T) {
+	commands := fakeRepositoryCommands(t)
+	for _, info := range []*osinfo.OSInfo{
+		{Distribution: "rocky", ID: "rocky", VersionID: "9.5"},
+		{Distribution: "almalinux", ID: "almalinux", VersionID: "8.10"},
+		{Distribution: "centos", ID: "centos", VersionID: "9"},
+		{Distribution: "rocky", ID: "rhel", IDLike: []string{"fedora"}, VersionID: "9.4"},
+	} {
+		repos, err := RequiredRepositories(context.Background(), info, GetRequiredPackages(info))
+		require.NoError(t, err)
+		assert.Empty(t, repos, "%s ships every package in BaseOS and AppStream", info.ID)
+	}
+	assert.Empty(t, *commands)
+}
+
+func TestEnableAndDisableRepositories(t *testing.T) {
+	sles := &osinfo.OSInfo{Distribution: "opensuse", ID: "sles", VersionID: "15.6", Architecture: "amd64"}
+	commands := fakeRepositoryCommands(t)
+	record := filepath.Join(t.TempDir(), "enabled-repositories")
+
+	repos, err := RequiredRepositories(context.Background(), sles, []string{"apache2-utils"})
+	require.NoError(t, err)
+
+	// Every command fails in the fake, so enabling needs a fake that succeeds
+	runRepositoryCommand = func(ctx context.Context, command string) ([]byte, error) {
+		*commands = append(*commands, command)
+		return nil, nil
+	}
+	require.NoError(t, EnableRepositories(context.Background(), repos, record, func(string) {}))
+	disabled, err := DisableRecordedRepositories(context.Background(), sles, record, func(string) {})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"sle-module-server-applications"}, disabled)
+	assert.Equal(t, []string{
+		"test -e /etc/products.d/sle-module-server-applications.prod",
+		"SUSEConnect -p sle-module-server-applications/15.6/x86_64",
+		"SUSEConnect -d -p sle-module-server-applications/15.6/x86_64",
+	}, *commands)
+	assert.NoFileExists(t, record)
+}
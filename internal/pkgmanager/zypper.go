@@ -3,6 +3,7 @@ package pkgmanager
 import (
 	"context"
 	"fmt"
+	"os"
 	"os/exec"
 	"time"
 )
@@ -11,6 +12,10 @@ import (
 type ZypperInstaller struct {
 	// Channel to send log messages
 	LogChan chan<- string
+	// How long to wait for another package manager to release its lock
+	LockTimeout time.Duration
+	// Installs from local packages instead of the configured repositories when set
+	Source *LocalSource
 }
 
 // NewZypperInstaller creates a new ZypperInstaller
@@ -31,61 +36,49 @@ func (self *ZypperInstaller) InstallPackages(ctx context.Context, packages []str
 		progressFunc("", 0.05, "Initializing Zypper package installation...", false)
 	}
 
-	// Refresh repositories
-	if progressFunc != nil {
-		progressFunc("", 0.10, "Refreshing zypper repositories...", false)
+	// Another package manager may hold the lock, e.g. an automatic update
+	deadline := lockDeadline(self.LockTimeout)
+	if err := waitForLocks(ctx, zypperLocks, "zypper", self.LockTimeout, self.log, progressFunc, 0.10); err != nil {
+		return err
 	}
-	self.log("Refreshing zypper repositories...")
 
-	refreshCmd := exec.CommandContext(ctx, "zypper", "--non-interactive", "--no-gpg-checks", "refresh")
-	if output, err := refreshCmd.CombinedOutput(); err != nil {
-		self.log(fmt.Sprintf("Error refreshing zypper: %s", string(output)))
-		return fmt.Errorf("failed to refresh zypper: %w", err)
+	args, err := self.installArgs(packages)
+	if err != nil {
+		return err
 	}
 
-	if progressFunc != nil {
-		progressFunc("", 0.25, "Completed: Refreshing zypper repositories...", false)
+	// Refresh repositories, local sources are read when installing
+	if self.Source == nil {
+		if progressFunc != nil {
+			progressFunc("", 0.10, "Refreshing zypper repositories...", false)
+		}
+		self.log("Refreshing zypper repositories...")
+
+		refreshCmd := exec.CommandContext(ctx, "zypper", "--non-interactive", "--no-gpg-checks", "refresh")
+		refreshCmd.Env = zypperLockEnv(deadline)
+		if output, err := refreshCmd.CombinedOutput(); err != nil {
+			self.log(fmt.Sprintf("Error refreshing zypper: %s", string(output)))
+			return fmt.Errorf("failed to refresh zypper: %w", err)
+		}
+
+		if progressFunc != nil {
+			progressFunc("", 0.25, "Completed: Refreshing zypper repositories...", false)
+		}
+	}
+
+	if err := waitForLocks(ctx, zypperLocks, "zypper", self.LockTimeout, self.log, progressFunc, 0.30); err != nil {
+		return err
 	}
 
-	// Install packages with gradual progress
+	// Install packages, following the progress zypper prints for each package
 	if progressFunc != nil {
 		progressFunc("", 0.30, fmt.Sprintf("Installing %d packages...", len(packages)), false)
 	}
 	self.log(fmt.Sprintf("Installing %d packages...", len(packages)))
 
-	// Start gradual progress updates
-	progressDone := make(chan struct{})
-	go func() {
-		currentProgress := 0.30
-		for currentProgress < 0.80 {
-			select {
-			case <-progressDone:
-				return
-			case <-ctx.Done():
-				return
-			default:
-				currentProgress += 0.03
-				if progressFunc != nil {
-					progressFunc("", currentProgress, fmt.Sprintf("Installing %d packages...", len(packages)), false)
-				}
-				// Sleep for gradual progress
-				select {
-				case <-progressDone:
-					return
-				case <-ctx.Done():
-					return
-				default:
-					time.Sleep(300 * time.Millisecond)
-				}
-			}
-		}
-	}()
-
-	args := append([]string{"--non-interactive", "--no-gpg-checks", "install"}, packages...)
 	installCmd := exec.CommandContext(ctx, "zypper", args...)
-	output, err := installCmd.CombinedOutput()
-	close(progressDone)
-
+	installCmd.Env = zypperLockEnv(deadline)
+	output, err := runWithProgress(installCmd, false, parseZypper, 0.30, 0.85, progressFunc)
 	if err != nil {
 		self.log(fmt.Sprintf("Error installing packages: %s", string(output)))
 		return fmt.Errorf("failed to install packages: %w", err)
@@ -113,9 +106,44 @@ func (self *ZypperInstaller) InstallPackages(ctx context.Context, packages []str
 	return nil
 }
 
+// installArgs returns the zypper arguments installing packages from the package source. The
+// configured repositories aren't refreshed for local sources so nothing is fetched from the network.
+func (self *ZypperInstaller) installArgs(packages []string) ([]string, error) {
+	switch {
+	case self.Source == nil:
+		return append([]string{"--non-interactive", "--no-gpg-checks", "install"}, packages...), nil
+	case self.Source.URL != "":
+		return append([]string{"--non-interactive", "--no-gpg-checks", "--no-refresh", "--plus-repo", self.Source.URL, "install"}, packages...), nil
+	default:
+		files, err := self.Source.packageFiles(packages, []string{".rpm"}, packageName)
+		if err != nil {
+			return nil, err
+		}
+		return append([]string{"--non-interactive", "--no-gpg-checks", "--no-refresh", "install"}, files...), nil
+	}
+}
+
+// InstalledPackages queries rpm for the installed versions of packages
+func (self *ZypperInstaller) InstalledPackages(ctx context.Context, packages []string) (map[string]string, error) {
+	return rpmInstalledPackages(ctx, packages)
+}
+
+// RemovePackages removes packages with zypper
+func (self *ZypperInstaller) RemovePackages(ctx context.Context, packages []string) error {
+	if len(packages) == 0 {
+		return nil
+	}
+	return removePackages(ctx, zypperLocks, self.LockTimeout, self.log, "zypper", append([]string{"--non-interactive", "remove"}, packages...)...)
+}
+
 // log sends a message to the log channel if available
 func (self *ZypperInstaller) log(message string) {
 	if self.LogChan != nil {
 		self.LogChan <- message
 	}
 }
+
+// zypperLockEnv makes zypper wait for a lock taken after waitForLocks until deadline
+func zypperLockEnv(deadline time.Time) []string {
+	return append(os.Environ(), fmt.Sprintf("ZYPP_LOCK_TIMEOUT=%d", remainingLockSeconds(deadline)))
+}
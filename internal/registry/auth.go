@@ -0,0 +1,396 @@
+package registry
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/unbindapp/unbind-installer/internal/errdefs"
+)
+
+// CheckRepositoryName is the repository we request push access to when validating credentials.
+// Nothing is ever pushed to it, upload sessions opened during the check are cancelled right away.
+const CheckRepositoryName = "unbind-credential-check"
+
+// Credentials identifies an account on a container registry.
+// Host may carry a namespace path, e.g. us-docker.pkg.dev/my-project/my-repo.
+type Credentials struct {
+	Provider Provider
+	Host     string
+	Username string
+	Password string
+}
+
+// Checker validates registry credentials using the Docker registry v2 auth handshake
+type Checker struct {
+	client  *http.Client
+	logFunc func(string)
+}
+
+// NewChecker creates a credential checker that reports progress through logFunc
+func NewChecker(logFunc func(string)) *Checker {
+	return &Checker{
+		client: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+		logFunc: logFunc,
+	}
+}
+
+// challenge is a parsed Www-Authenticate header
+type challenge struct {
+	scheme string
+	params map[string]string
+}
+
+// tokenResponse is the body returned by a registry token server
+type tokenResponse struct {
+	Token       string `json:"token"`
+	AccessToken string `json:"access_token"`
+}
+
+// tokenClaims holds the parts of a registry JWT we care about
+type tokenClaims struct {
+	Access []struct {
+		Type    string   `json:"type"`
+		Name    string   `json:"name"`
+		Actions []string `json:"actions"`
+	} `json:"access"`
+}
+
+// Validate runs the registry auth handshake and confirms the credentials can push to the registry:
+//  1. Ping /v2/ to discover how the registry wants us to authenticate
+//  2. For Bearer registries, request a push+pull token for a test repository using basic auth
+//  3. Confirm push scope from the token claims, or by opening (and cancelling) a blob upload
+func (self *Checker) Validate(ctx context.Context, creds Credentials) error {
+	if creds.Provider == ProviderECR {
+		return self.validateECR(ctx, creds)
+	}
+
+	apiHost := APIHost(creds.Host)
+	repository := TestRepository(creds)
+
+	self.log(fmt.Sprintf("Pinging https://%s/v2/...", apiHost))
+	ch, err := self.ping(ctx, apiHost)
+	if err != nil {
+		return err
+	}
+
+	switch ch.scheme {
+	case "bearer":
+		self.log("Registry uses token authentication, requesting a push token...")
+		token, err := self.fetchToken(ctx, ch, repository, creds)
+		if err != nil {
+			return err
+		}
+
+		// Registries that issue JWTs tell us exactly what we were granted
+		if granted, ok := grantedActions(token, repository); ok {
+			if !slices.Contains(granted, "push") {
+				return errdefs.NewCustomError(errdefs.ErrTypeRegistryPushDenied,
+					fmt.Sprintf("%s does not grant push access to %s", creds.Host, repository))
+			}
+			self.log(fmt.Sprintf("Token grants push access to %s", repository))
+			return nil
+		}
+
+		return self.probePush(ctx, apiHost, repository, "Bearer "+token)
+	case "basic":
+		self.log("Registry uses basic authentication, verifying credentials...")
+		authHeader := "Basic " + basicAuth(creds.Username, creds.Password)
+		if err := self.checkBasicAuth(ctx, apiHost, authHeader); err != nil {
+			return err
+		}
+		return self.probePush(ctx, apiHost, repository, authHeader)
+	default:
+		// The registry accepted an anonymous ping, the push probe tells us if the credentials work
+		self.log("Registry does not require authentication for /v2/, checking push access...")
+		return self.probePush(ctx, apiHost, repository, "Basic "+basicAuth(creds.Username, creds.Password))
+	}
+}
+
+// ping calls /v2/ and returns the authentication challenge, if any
+func (self *Checker) ping(ctx context.Context, apiHost string) (*challenge, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("https://%s/v2/", apiHost), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create ping request: %w", err)
+	}
+
+	resp, err := self.client.Do(req)
+	if err != nil {
+		return nil, errdefs.NewCustomError(errdefs.ErrTypeRegistryUnreachable,
+			fmt.Sprintf("could not connect to %s: %v", apiHost, err))
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return &challenge{}, nil
+	case http.StatusUnauthorized:
+		ch := parseChallenge(resp.Header.Get("Www-Authenticate"))
+		if ch == nil {
+			return nil, errdefs.NewCustomError(errdefs.ErrTypeRegistryUnreachable,
+				fmt.Sprintf("%s returned 401 without an authentication challenge", apiHost))
+		}
+		return ch, nil
+	default:
+		return nil, errdefs.NewCustomError(errdefs.ErrTypeRegistryUnreachable,
+			fmt.Sprintf("%s does not look like a registry (GET /v2/ returned %d)", apiHost, resp.StatusCode))
+	}
+}
+
+// fetchToken requests a push+pull token for the repository from the challenge realm
+func (self *Checker) fetchToken(ctx context.Context, ch *challenge, repository string, creds Credentials) (string, error) {
+	realm := ch.params["realm"]
+	if realm == "" {
+		return "", errdefs.NewCustomError(errdefs.ErrTypeRegistryUnreachable, "registry challenge is missing a token realm")
+	}
+
+	tokenURL, err := url.Parse(realm)
+	if err != nil {
+		return "", errdefs.NewCustomError(errdefs.ErrTypeRegistryUnreachable, fmt.Sprintf("invalid token realm %q: %v", realm, err))
+	}
+
+	query := tokenURL.Query()
+	if service := ch.params["service"]; service != "" {
+		query.Set("service", service)
+	}
+	query.Set("scope", fmt.Sprintf("repository:%s:pull,push", repository))
+	query.Set("account", creds.Username)
+	tokenURL.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, tokenURL.String(), nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create token request: %w", err)
+	}
+	req.SetBasicAuth(creds.Username, creds.Password)
+
+	self.log(fmt.Sprintf("Requesting token from %s://%s%s...", tokenURL.Scheme, tokenURL.Host, tokenURL.Path))
+	resp, err := self.client.Do(req)
+	if err != nil {
+		return "", errdefs.NewCustomError(errdefs.ErrTypeRegistryUnreachable,
+			fmt.Sprintf("could not connect to token server %s: %v", tokenURL.Host, err))
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return "", errdefs.NewCustomError(errdefs.ErrTypeRegistryInvalidCredentials,
+			fmt.Sprintf("the token server rejected the username or password (status %d)", resp.StatusCode))
+	case resp.StatusCode != http.StatusOK:
+		return "", errdefs.NewCustomError(errdefs.ErrTypeRegistryUnreachable,
+			fmt.Sprintf("token server returned unexpected status %d", resp.StatusCode))
+	}
+
+	var body tokenResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("failed to decode token response: %w", err)
+	}
+
+	token := body.Token
+	if token == "" {
+		token = body.AccessToken
+	}
+	if token == "" {
+		return "", fmt.Errorf("token server response did not contain a token")
+	}
+
+	return token, nil
+}
+
+// checkBasicAuth confirms a basic auth registry accepts the credentials
+func (self *Checker) checkBasicAuth(ctx context.Context, apiHost, authHeader string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("https://%s/v2/", apiHost), nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", authHeader)
+
+	resp, err := self.client.Do(req)
+	if err != nil {
+		return errdefs.NewCustomError(errdefs.ErrTypeRegistryUnreachable, fmt.Sprintf("could not connect to %s: %v", apiHost, err))
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return nil
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return errdefs.NewCustomError(errdefs.ErrTypeRegistryInvalidCredentials,
+			fmt.Sprintf("%s rejected the username or password (status %d)", apiHost, resp.StatusCode))
+	default:
+		return errdefs.NewCustomError(errdefs.ErrTypeRegistryUnreachable,
+			fmt.Sprintf("%s returned unexpected status %d", apiHost, resp.StatusCode))
+	}
+}
+
+// probePush opens a blob upload session to prove push access, then cancels it
+func (self *Checker) probePush(ctx context.Context, apiHost, repository, authHeader string) error {
+	self.log(fmt.Sprintf("Checking push access to %s...", repository))
+
+	uploadURL := fmt.Sprintf("https://%s/v2/%s/blobs/uploads/", apiHost, repository)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, uploadURL, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create upload request: %w", err)
+	}
+	req.Header.Set("Authorization", authHeader)
+
+	resp, err := self.client.Do(req)
+	if err != nil {
+		return errdefs.NewCustomError(errdefs.ErrTypeRegistryUnreachable, fmt.Sprintf("could not connect to %s: %v", apiHost, err))
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	switch resp.StatusCode {
+	case http.StatusAccepted, http.StatusCreated:
+		self.cancelUpload(ctx, apiHost, resp.Header.Get("Location"), authHeader)
+		self.log(fmt.Sprintf("Push access to %s confirmed", repository))
+		return nil
+	case http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound:
+		return errdefs.NewCustomError(errdefs.ErrTypeRegistryPushDenied,
+			fmt.Sprintf("%s denied push access to %s (status %d)", apiHost, repository, resp.StatusCode))
+	default:
+		return errdefs.NewCustomError(errdefs.ErrTypeRegistryUnreachable,
+			fmt.Sprintf("%s returned unexpected status %d when starting an upload", apiHost, resp.StatusCode))
+	}
+}
+
+// cancelUpload deletes an upload session opened by probePush, failures are only logged
+func (self *Checker) cancelUpload(ctx context.Context, apiHost, location, authHeader string) {
+	if location == "" {
+		return
+	}
+
+	uploadURL, err := url.Parse(location)
+	if err != nil {
+		self.log(fmt.Sprintf("Warning: could not parse upload location %q: %v", location, err))
+		return
+	}
+	if !uploadURL.IsAbs() {
+		uploadURL = &url.URL{Scheme: "https", Host: apiHost, Path: uploadURL.Path, RawQuery: uploadURL.RawQuery}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, uploadURL.String(), nil)
+	if err != nil {
+		return
+	}
+	req.Header.Set("Authorization", authHeader)
+
+	resp, err := self.client.Do(req)
+	if err != nil {
+		self.log(fmt.Sprintf("Warning: could not cancel test upload: %v", err))
+		return
+	}
+	resp.Body.Close()
+}
+
+func (self *Checker) log(message string) {
+	if self.logFunc != nil {
+		self.logFunc(message)
+	}
+}
+
+// APIHost maps a registry name to the host serving its v2 API
+func APIHost(host string) string {
+	host, _, _ = strings.Cut(host, "/")
+	switch host {
+	case "docker.io", "index.docker.io", "registry.hub.docker.com":
+		return "registry-1.docker.io"
+	default:
+		return host
+	}
+}
+
+// TestRepository returns the repository used to check push access for an account.
+// A namespace in the host wins, otherwise the username is used as the namespace.
+// Robot accounts such as quay's "org+robot" push into their organization namespace.
+func TestRepository(creds Credentials) string {
+	if _, namespace, ok := strings.Cut(creds.Host, "/"); ok && strings.Trim(namespace, "/") != "" {
+		return fmt.Sprintf("%s/%s", strings.Trim(namespace, "/"), CheckRepositoryName)
+	}
+
+	namespace := strings.ToLower(creds.Username)
+	if idx := strings.Index(namespace, "+"); idx > 0 {
+		namespace = namespace[:idx]
+	}
+	return fmt.Sprintf("%s/%s", namespace, CheckRepositoryName)
+}
+
+// parseChallenge parses a Www-Authenticate header such as
+// Bearer realm="https://auth.docker.io/token",service="registry.docker.io"
+func parseChallenge(header string) *challenge {
+	header = strings.TrimSpace(header)
+	if header == "" {
+		return nil
+	}
+
+	scheme, rest, _ := strings.Cut(header, " ")
+	ch := &challenge{
+		scheme: strings.ToLower(scheme),
+		params: map[string]string{},
+	}
+
+	for rest != "" {
+		rest = strings.TrimLeft(rest, " ,")
+		key, value, found := strings.Cut(rest, "=")
+		if !found {
+			break
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+
+		if strings.HasPrefix(value, "\"") {
+			// Quoted value, may contain commas
+			end := strings.Index(value[1:], "\"")
+			if end < 0 {
+				ch.params[key] = value[1:]
+				break
+			}
+			ch.params[key] = value[1 : end+1]
+			rest = value[end+2:]
+		} else {
+			value, rest, _ = strings.Cut(value, ",")
+			ch.params[key] = strings.TrimSpace(value)
+		}
+	}
+
+	return ch
+}
+
+// grantedActions reads the access claim of a JWT token, ok is false if the token is opaque
+func grantedActions(token, repository string) (actions []string, ok bool) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, false
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return nil, false
+	}
+
+	var claims tokenClaims
+	if err := json.Unmarshal(payload, &claims); err != nil || claims.Access == nil {
+		return nil, false
+	}
+
+	for _, access := range claims.Access {
+		if access.Type == "repository" && access.Name == repository {
+			return access.Actions, true
+		}
+	}
+	return []string{}, true
+}
+
+func basicAuth(username, password string) string {
+	return base64.StdEncoding.EncodeToString([]byte(username + ":" + password))
+}
@@ -0,0 +1,260 @@
+package registry
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/unbindapp/unbind-installer/internal/errdefs"
+)
+
+// fakeRegistry is a minimal stand-in for a registry and its token server
+type fakeRegistry struct {
+	scheme       string // "bearer", "basic" or "" for anonymous
+	username     string
+	password     string
+	jwtTokens    bool     // issue JWTs with an access claim instead of opaque tokens
+	grantActions []string // actions granted to a valid account
+	uploadStatus int      // status returned when starting an upload
+	cancelled    bool     // whether the test upload was cancelled
+}
+
+func (self *fakeRegistry) handler(serverURL func() string) http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/v2/", func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/v2/":
+			self.handlePing(w, r, serverURL())
+		case strings.HasSuffix(r.URL.Path, "/blobs/uploads/") && r.Method == http.MethodPost:
+			if !self.authorized(r) {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			status := self.uploadStatus
+			if status == 0 {
+				status = http.StatusAccepted
+			}
+			if status == http.StatusAccepted {
+				w.Header().Set("Location", r.URL.Path+"session-1")
+			}
+			w.WriteHeader(status)
+		case strings.HasSuffix(r.URL.Path, "/blobs/uploads/session-1") && r.Method == http.MethodDelete:
+			self.cancelled = true
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		username, password, ok := r.BasicAuth()
+		if !ok || username != self.username || password != self.password {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		scope := r.URL.Query().Get("scope")
+		repository := strings.Split(scope, ":")[1]
+
+		token := "opaque-token"
+		if self.jwtTokens {
+			token = makeJWT(repository, self.grantActions)
+		}
+		json.NewEncoder(w).Encode(map[string]string{"token": token})
+	})
+
+	return mux
+}
+
+func (self *fakeRegistry) handlePing(w http.ResponseWriter, r *http.Request, serverURL string) {
+	switch self.scheme {
+	case "bearer":
+		if strings.HasPrefix(r.Header.Get("Authorization"), "Bearer ") {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		w.Header().Set("Www-Authenticate", fmt.Sprintf(`Bearer realm="%s/token",service="fake-registry"`, serverURL))
+		w.WriteHeader(http.StatusUnauthorized)
+	case "basic":
+		if !self.authorized(r) {
+			w.Header().Set("Www-Authenticate", `Basic realm="fake-registry"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	default:
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+// authorized checks the Authorization header of registry API calls
+func (self *fakeRegistry) authorized(r *http.Request) bool {
+	auth := r.Header.Get("Authorization")
+	if self.scheme == "bearer" {
+		return auth == "Bearer opaque-token" && slices.Contains(self.grantActions, "push")
+	}
+	username, password, ok := r.BasicAuth()
+	return ok && username == self.username && password == self.password
+}
+
+func makeJWT(repository string, actions []string) string {
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none"}`))
+	claims, _ := json.Marshal(map[string]interface{}{
+		"access": []map[string]interface{}{
+			{"type": "repository", "name": repository, "actions": actions},
+		},
+	})
+	return header + "." + base64.RawURLEncoding.EncodeToString(claims) + ".signature"
+}
+
+// startFakeRegistry runs the fake registry behind TLS and returns a checker trusting it
+func startFakeRegistry(t *testing.T, registry *fakeRegistry) (*Checker, string) {
+	var server *httptest.Server
+	server = httptest.NewTLSServer(registry.handler(func() string { return server.URL }))
+	t.Cleanup(server.Close)
+
+	checker := NewChecker(nil)
+	checker.client = server.Client()
+
+	return checker, strings.TrimPrefix(server.URL, "https://")
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name        string
+		registry    *fakeRegistry
+		password    string
+		expectedErr error
+		cancelled   bool
+	}{
+		{
+			name:     "Bearer JWT with push",
+			registry: &fakeRegistry{scheme: "bearer", jwtTokens: true, grantActions: []string{"pull", "push"}},
+			password: "secret",
+		},
+		{
+			name:        "Bearer JWT without push",
+			registry:    &fakeRegistry{scheme: "bearer", jwtTokens: true, grantActions: []string{"pull"}},
+			password:    "secret",
+			expectedErr: errdefs.ErrRegistryPushDenied,
+		},
+		{
+			name:        "Bearer wrong password",
+			registry:    &fakeRegistry{scheme: "bearer", jwtTokens: true, grantActions: []string{"pull", "push"}},
+			password:    "wrong",
+			expectedErr: errdefs.ErrRegistryInvalidCredentials,
+		},
+		{
+			name:      "Bearer opaque token with push",
+			registry:  &fakeRegistry{scheme: "bearer", grantActions: []string{"pull", "push"}},
+			password:  "secret",
+			cancelled: true,
+		},
+		{
+			name:        "Bearer opaque token without push",
+			registry:    &fakeRegistry{scheme: "bearer", grantActions: []string{"pull"}},
+			password:    "secret",
+			expectedErr: errdefs.ErrRegistryPushDenied,
+		},
+		{
+			name:      "Basic auth with push",
+			registry:  &fakeRegistry{scheme: "basic"},
+			password:  "secret",
+			cancelled: true,
+		},
+		{
+			name:        "Basic auth wrong password",
+			registry:    &fakeRegistry{scheme: "basic"},
+			password:    "wrong",
+			expectedErr: errdefs.ErrRegistryInvalidCredentials,
+		},
+		{
+			name:        "Basic auth read-only account",
+			registry:    &fakeRegistry{scheme: "basic", uploadStatus: http.StatusForbidden},
+			password:    "secret",
+			expectedErr: errdefs.ErrRegistryPushDenied,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.registry.username = "unbind"
+			tc.registry.password = "secret"
+			checker, host := startFakeRegistry(t, tc.registry)
+
+			err := checker.Validate(context.Background(), Credentials{
+				Host:     host,
+				Username: "unbind",
+				Password: tc.password,
+			})
+
+			if tc.expectedErr != nil {
+				require.Error(t, err)
+				assert.ErrorIs(t, err, tc.expectedErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tc.cancelled, tc.registry.cancelled)
+		})
+	}
+}
+
+func TestValidate_Unreachable(t *testing.T) {
+	server := httptest.NewTLSServer(http.NotFoundHandler())
+	host := strings.TrimPrefix(server.URL, "https://")
+	client := server.Client()
+	server.Close()
+
+	checker := NewChecker(nil)
+	checker.client = client
+
+	err := checker.Validate(context.Background(), Credentials{Host: host, Username: "unbind", Password: "secret"})
+	require.Error(t, err)
+	assert.ErrorIs(t, err, errdefs.ErrRegistryUnreachable)
+}
+
+func TestValidate_NotARegistry(t *testing.T) {
+	// Anything other than 200 or 401 from /v2/ means this is not a registry
+	server := httptest.NewTLSServer(http.NotFoundHandler())
+	t.Cleanup(server.Close)
+
+	checker := NewChecker(nil)
+	checker.client = server.Client()
+	host := strings.TrimPrefix(server.URL, "https://")
+
+	err := checker.Validate(context.Background(), Credentials{Host: host, Username: "unbind", Password: "secret"})
+	require.Error(t, err)
+	assert.ErrorIs(t, err, errdefs.ErrRegistryUnreachable)
+}
+
+func TestParseChallenge(t *testing.T) {
+	ch := parseChallenge(`Bearer realm="https://auth.docker.io/token",service="registry.docker.io",scope="repository:a/b:pull,push"`)
+	require.NotNil(t, ch)
+	assert.Equal(t, "bearer", ch.scheme)
+	assert.Equal(t, "https://auth.docker.io/token", ch.params["realm"])
+	assert.Equal(t, "registry.docker.io", ch.params["service"])
+	assert.Equal(t, "repository:a/b:pull,push", ch.params["scope"])
+
+	ch = parseChallenge(`Basic realm="Registry Realm"`)
+	require.NotNil(t, ch)
+	assert.Equal(t, "basic", ch.scheme)
+	assert.Equal(t, "Registry Realm", ch.params["realm"])
+
+	assert.Nil(t, parseChallenge(""))
+}
+
+func TestTestRepository(t *testing.T) {
+	assert.Equal(t, "octocat/unbind-credential-check", TestRepository(Credentials{Username: "OctoCat"}))
+	assert.Equal(t, "myorg/unbind-credential-check", TestRepository(Credentials{Username: "myorg+builder"}))
+	assert.Equal(t, "registry-1.docker.io", APIHost("docker.io"))
+	assert.Equal(t, "ghcr.io", APIHost("ghcr.io"))
+}
@@ -0,0 +1,175 @@
+package registry
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"strings"
+)
+
+// dockerHubConfigKey is the key docker login uses for Docker Hub
+const dockerHubConfigKey = "https://index.docker.io/v1/"
+
+// DockerConfig is the subset of ~/.docker/config.json holding registry credentials
+type DockerConfig struct {
+	Auths map[string]struct {
+		Auth          string `json:"auth"`
+		Username      string `json:"username"`
+		Password      string `json:"password"`
+		IdentityToken string `json:"identitytoken"`
+	} `json:"auths"`
+	CredsStore  string            `json:"credsStore"`
+	CredHelpers map[string]string `json:"credHelpers"`
+
+	path string
+}
+
+// ImportedCredentials are credentials found in a docker config, with where they came from
+type ImportedCredentials struct {
+	Username string
+	Password string
+	Source   string // e.g. "~/.docker/config.json" or "docker-credential-pass"
+}
+
+// runCredentialHelper runs docker-credential-<helper> get, mockable for tests
+var runCredentialHelper = func(helper, serverURL string) ([]byte, error) {
+	cmd := exec.Command("docker-credential-"+helper, "get")
+	cmd.Stdin = strings.NewReader(serverURL)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("docker-credential-%s failed: %w %s", helper, err, strings.TrimSpace(stderr.String()))
+	}
+	return output, nil
+}
+
+// DefaultDockerConfigPath returns the docker config of the user running the installer
+func DefaultDockerConfigPath() string {
+	if dir := os.Getenv("DOCKER_CONFIG"); dir != "" {
+		return filepath.Join(dir, "config.json")
+	}
+	return filepath.Join(invokingUserHome(), ".docker", "config.json")
+}
+
+// ExpandHome expands a leading ~ in a path, as a shell would for --flag=~/path
+func ExpandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	return filepath.Join(invokingUserHome(), strings.TrimPrefix(path, "~"))
+}
+
+// invokingUserHome returns the home directory of the user, under sudo the user who ran sudo
+func invokingUserHome() string {
+	if sudoUser := os.Getenv("SUDO_USER"); sudoUser != "" && sudoUser != "root" {
+		if u, err := user.Lookup(sudoUser); err == nil {
+			return u.HomeDir
+		}
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "/root"
+	}
+	return home
+}
+
+// LoadDockerConfig reads a docker config.json
+func LoadDockerConfig(path string) (*DockerConfig, error) {
+	data, err := os.ReadFile(ExpandHome(path))
+	if err != nil {
+		return nil, err
+	}
+
+	var cfg DockerConfig
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	cfg.path = path
+	return &cfg, nil
+}
+
+// Lookup finds credentials for a registry host. Credential helpers configured for the
+// host win over the global credential store, which wins over inline auths, matching docker.
+// Returns nil without error when the config has nothing for the host.
+func (self *DockerConfig) Lookup(host string) (*ImportedCredentials, error) {
+	keys := configKeys(host)
+
+	for _, key := range keys {
+		if helper, ok := self.CredHelpers[key]; ok && helper != "" {
+			return self.fromHelper(helper, key)
+		}
+	}
+
+	if self.CredsStore != "" {
+		for _, key := range keys {
+			creds, err := self.fromHelper(self.CredsStore, key)
+			if err == nil && creds != nil {
+				return creds, nil
+			}
+		}
+	}
+
+	for _, key := range keys {
+		entry, ok := self.Auths[key]
+		if !ok {
+			continue
+		}
+
+		username, password := entry.Username, entry.Password
+		if entry.Auth != "" {
+			decoded, err := base64.StdEncoding.DecodeString(entry.Auth)
+			if err != nil {
+				return nil, fmt.Errorf("invalid auth for %s in %s: %w", key, self.path, err)
+			}
+			var ok bool
+			if username, password, ok = strings.Cut(string(decoded), ":"); !ok {
+				return nil, fmt.Errorf("invalid auth for %s in %s", key, self.path)
+			}
+		}
+		if username == "" || password == "" {
+			// Identity tokens only work with the registry's OAuth flow, not basic auth
+			continue
+		}
+
+		return &ImportedCredentials{Username: username, Password: password, Source: self.path}, nil
+	}
+
+	return nil, nil
+}
+
+// fromHelper asks a docker credential helper for a server's credentials
+func (self *DockerConfig) fromHelper(helper, serverURL string) (*ImportedCredentials, error) {
+	output, err := runCredentialHelper(helper, serverURL)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp struct {
+		Username string `json:"Username"`
+		Secret   string `json:"Secret"`
+	}
+	if err := json.Unmarshal(output, &resp); err != nil {
+		return nil, fmt.Errorf("docker-credential-%s returned invalid output: %w", helper, err)
+	}
+	if resp.Username == "" || resp.Secret == "" || resp.Username == "<token>" {
+		return nil, nil
+	}
+
+	return &ImportedCredentials{Username: resp.Username, Password: resp.Secret, Source: "docker-credential-" + helper}, nil
+}
+
+// configKeys returns the keys docker may have stored a host's credentials under
+func configKeys(host string) []string {
+	apiHost := APIHost(host)
+	if apiHost == "registry-1.docker.io" {
+		return []string{dockerHubConfigKey, "index.docker.io", "docker.io", "registry-1.docker.io"}
+	}
+	return []string{apiHost, "https://" + apiHost, "http://" + apiHost, "https://" + apiHost + "/v1/", "https://" + apiHost + "/v2/"}
+}
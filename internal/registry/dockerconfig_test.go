@@ -0,0 +1,97 @@
+package registry
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeDockerConfig(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "config.json")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0600))
+	return path
+}
+
+func mockCredentialHelper(t *testing.T, secrets map[string]string) {
+	original := runCredentialHelper
+	runCredentialHelper = func(helper, serverURL string) ([]byte, error) {
+		if secret, ok := secrets[helper+"|"+serverURL]; ok {
+			return []byte(fmt.Sprintf(`{"ServerURL":%q,"Username":"helper-user","Secret":%q}`, serverURL, secret)), nil
+		}
+		return nil, fmt.Errorf("credentials not found in native keychain")
+	}
+	t.Cleanup(func() { runCredentialHelper = original })
+}
+
+func TestDockerConfigLookup_InlineAuth(t *testing.T) {
+	auth := base64.StdEncoding.EncodeToString([]byte("octocat:ghp_token"))
+	path := writeDockerConfig(t, `{"auths":{
+		"ghcr.io":{"auth":"`+auth+`"},
+		"https://index.docker.io/v1/":{"username":"hubuser","password":"hubpass"},
+		"quay.io":{"identitytoken":"abc"}
+	}}`)
+
+	cfg, err := LoadDockerConfig(path)
+	require.NoError(t, err)
+
+	creds, err := cfg.Lookup("ghcr.io")
+	require.NoError(t, err)
+	require.NotNil(t, creds)
+	assert.Equal(t, "octocat", creds.Username)
+	assert.Equal(t, "ghp_token", creds.Password)
+	assert.Equal(t, path, creds.Source)
+
+	creds, err = cfg.Lookup("docker.io")
+	require.NoError(t, err)
+	require.NotNil(t, creds)
+	assert.Equal(t, "hubuser", creds.Username)
+
+	// Identity tokens can't be used as a password
+	creds, err = cfg.Lookup("quay.io")
+	require.NoError(t, err)
+	assert.Nil(t, creds)
+
+	creds, err = cfg.Lookup("registry.example.com")
+	require.NoError(t, err)
+	assert.Nil(t, creds)
+}
+
+func TestDockerConfigLookup_CredentialHelpers(t *testing.T) {
+	mockCredentialHelper(t, map[string]string{
+		"ecr-login|123456789012.dkr.ecr.us-east-1.amazonaws.com": "from-ecr-helper",
+		"pass|https://index.docker.io/v1/":                       "from-store",
+	})
+
+	path := writeDockerConfig(t, `{
+		"credsStore":"pass",
+		"credHelpers":{"123456789012.dkr.ecr.us-east-1.amazonaws.com":"ecr-login"},
+		"auths":{"https://index.docker.io/v1/":{}}
+	}`)
+
+	cfg, err := LoadDockerConfig(path)
+	require.NoError(t, err)
+
+	creds, err := cfg.Lookup("123456789012.dkr.ecr.us-east-1.amazonaws.com")
+	require.NoError(t, err)
+	require.NotNil(t, creds)
+	assert.Equal(t, "from-ecr-helper", creds.Password)
+	assert.Equal(t, "docker-credential-ecr-login", creds.Source)
+
+	creds, err = cfg.Lookup("docker.io")
+	require.NoError(t, err)
+	require.NotNil(t, creds)
+	assert.Equal(t, "from-store", creds.Password)
+	assert.Equal(t, "docker-credential-pass", creds.Source)
+}
+
+func TestExpandHome(t *testing.T) {
+	t.Setenv("SUDO_USER", "")
+	t.Setenv("HOME", "/home/admin")
+	assert.Equal(t, "/home/admin/.docker/config.json", ExpandHome("~/.docker/config.json"))
+	assert.Equal(t, "/etc/docker/config.json", ExpandHome("/etc/docker/config.json"))
+}
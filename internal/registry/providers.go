@@ -0,0 +1,191 @@
+package registry
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/unbindapp/unbind-installer/internal/awsauth"
+	"github.com/unbindapp/unbind-installer/internal/errdefs"
+)
+
+// Provider identifies registries that need more than a static username and password
+type Provider string
+
+const (
+	ProviderGeneric Provider = ""    // Docker Hub, GHCR, Quay or any other v2 registry
+	ProviderECR     Provider = "ecr" // AWS Elastic Container Registry, access key pair
+	ProviderGAR     Provider = "gar" // Google Artifact Registry / Container Registry, service account key
+	ProviderACR     Provider = "acr" // Azure Container Registry, service principal
+)
+
+// ECRUsername is the username docker uses with ECR authorization tokens
+const ECRUsername = "AWS"
+
+// GARUsername tells Google registries the password is a service account JSON key
+const GARUsername = "_json_key"
+
+// ECR authorization tokens are valid for 12 hours
+const ECRTokenLifetime = 12 * time.Hour
+
+var ecrHostPattern = regexp.MustCompile(`^(\d{12})\.dkr\.ecr(?:-fips)?\.([a-z0-9-]+)\.amazonaws\.com(\.cn)?$`)
+
+// ecrEndpoint returns the ECR API endpoint for a region, mockable for tests
+var ecrEndpoint = func(region string, china bool) string {
+	if china {
+		return fmt.Sprintf("https://api.ecr.%s.amazonaws.com.cn/", region)
+	}
+	return fmt.Sprintf("https://api.ecr.%s.amazonaws.com/", region)
+}
+
+// ECRRegistry is a parsed ECR registry host
+type ECRRegistry struct {
+	AccountID string
+	Region    string
+	China     bool
+}
+
+// ParseECRHost parses <account>.dkr.ecr.<region>.amazonaws.com
+func ParseECRHost(host string) (*ECRRegistry, error) {
+	match := ecrHostPattern.FindStringSubmatch(strings.ToLower(APIHost(host)))
+	if match == nil {
+		return nil, fmt.Errorf("%s is not an ECR registry, expected <account>.dkr.ecr.<region>.amazonaws.com", host)
+	}
+	return &ECRRegistry{
+		AccountID: match[1],
+		Region:    match[2],
+		China:     match[3] != "",
+	}, nil
+}
+
+// ecrAuthorizationResponse is the GetAuthorizationToken response body
+type ecrAuthorizationResponse struct {
+	AuthorizationData []struct {
+		AuthorizationToken string  `json:"authorizationToken"`
+		ExpiresAt          float64 `json:"expiresAt"`
+		ProxyEndpoint      string  `json:"proxyEndpoint"`
+	} `json:"authorizationData"`
+}
+
+// ECRLogin exchanges an access key pair (username and password of creds) for a docker login
+// that is valid for ECRTokenLifetime
+func (self *Checker) ECRLogin(ctx context.Context, creds Credentials) (Credentials, time.Time, error) {
+	ecr, err := ParseECRHost(creds.Host)
+	if err != nil {
+		return Credentials{}, time.Time{}, err
+	}
+
+	self.log(fmt.Sprintf("Requesting an ECR authorization token in %s...", ecr.Region))
+
+	body := []byte(`{"registryIds":["` + ecr.AccountID + `"]}`)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, ecrEndpoint(ecr.Region, ecr.China), bytes.NewReader(body))
+	if err != nil {
+		return Credentials{}, time.Time{}, fmt.Errorf("failed to create ECR request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "AmazonEC2ContainerRegistry_V20150921.GetAuthorizationToken")
+
+	awsauth.NewSigner(awsauth.Credentials{
+		AccessKeyID:     creds.Username,
+		SecretAccessKey: creds.Password,
+	}, ecr.Region, "ecr").Sign(req, body)
+
+	resp, err := self.client.Do(req)
+	if err != nil {
+		return Credentials{}, time.Time{}, errdefs.NewCustomError(errdefs.ErrTypeRegistryUnreachable,
+			fmt.Sprintf("could not connect to the ECR API: %v", err))
+	}
+	defer resp.Body.Close()
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+
+	switch {
+	case resp.StatusCode == http.StatusOK:
+	case resp.StatusCode == http.StatusBadRequest || resp.StatusCode == http.StatusForbidden:
+		// AWS reports bad keys (UnrecognizedClientException) and missing IAM permissions as 400/403
+		return Credentials{}, time.Time{}, errdefs.NewCustomError(errdefs.ErrTypeRegistryInvalidCredentials,
+			fmt.Sprintf("AWS rejected the access key: %s", strings.TrimSpace(string(respBody))))
+	default:
+		return Credentials{}, time.Time{}, errdefs.NewCustomError(errdefs.ErrTypeRegistryUnreachable,
+			fmt.Sprintf("ECR API returned unexpected status %d", resp.StatusCode))
+	}
+
+	var authResp ecrAuthorizationResponse
+	if err := json.Unmarshal(respBody, &authResp); err != nil || len(authResp.AuthorizationData) == 0 {
+		return Credentials{}, time.Time{}, errdefs.NewCustomError(errdefs.ErrTypeRegistryUnreachable,
+			"ECR API returned no authorization data")
+	}
+
+	data := authResp.AuthorizationData[0]
+	decoded, err := base64.StdEncoding.DecodeString(data.AuthorizationToken)
+	if err != nil {
+		return Credentials{}, time.Time{}, fmt.Errorf("failed to decode ECR authorization token: %w", err)
+	}
+	username, password, ok := strings.Cut(string(decoded), ":")
+	if !ok {
+		return Credentials{}, time.Time{}, fmt.Errorf("ECR authorization token is malformed")
+	}
+
+	expiresAt := time.Now().Add(ECRTokenLifetime)
+	if data.ExpiresAt > 0 {
+		expiresAt = time.Unix(int64(data.ExpiresAt), 0)
+	}
+
+	return Credentials{
+		Provider: ProviderECR,
+		Host:     creds.Host,
+		Username: username,
+		Password: password,
+	}, expiresAt, nil
+}
+
+// validateECR exchanges the access key for a token and logs in with it.
+// ECR repositories must exist before anything can be pushed and push rights come from IAM policy,
+// so the push probe used for other registries can't tell us anything here.
+func (self *Checker) validateECR(ctx context.Context, creds Credentials) error {
+	login, expiresAt, err := self.ECRLogin(ctx, creds)
+	if err != nil {
+		return err
+	}
+	self.log(fmt.Sprintf("Received ECR token valid until %s", expiresAt.Format(time.RFC3339)))
+
+	apiHost := APIHost(creds.Host)
+	if err := self.checkBasicAuth(ctx, apiHost, "Basic "+basicAuth(login.Username, login.Password)); err != nil {
+		return err
+	}
+
+	self.log("ECR login succeeded, make sure the IAM user is allowed to push (ecr:InitiateLayerUpload, ecr:PutImage)")
+	return nil
+}
+
+// ServiceAccountKey holds the fields of a Google service account JSON key we check
+type ServiceAccountKey struct {
+	Type        string `json:"type"`
+	ProjectID   string `json:"project_id"`
+	ClientEmail string `json:"client_email"`
+	PrivateKey  string `json:"private_key"`
+}
+
+// ParseServiceAccountKey checks data is a Google service account JSON key.
+// Keys are sent as the password with GARUsername and don't expire, so no refresher is needed.
+func ParseServiceAccountKey(data []byte) (*ServiceAccountKey, error) {
+	var key ServiceAccountKey
+	if err := json.Unmarshal(data, &key); err != nil {
+		return nil, fmt.Errorf("not a valid JSON key file: %w", err)
+	}
+	if key.Type != "service_account" || key.ClientEmail == "" || key.PrivateKey == "" {
+		return nil, fmt.Errorf("not a service account key, expected type \"service_account\" with client_email and private_key")
+	}
+	return &key, nil
+}
+
+// NeedsCredentialRefresher reports whether the provider issues tokens that expire
+func NeedsCredentialRefresher(provider Provider) bool {
+	return provider == ProviderECR
+}
@@ -0,0 +1,155 @@
+package system
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LonghornPolicyModule lets iscsid open the device files Longhorn creates, see
+// https://github.com/longhorn/longhorn/blob/master/deploy/prerequisite/longhorn-iscsi-selinux-workaround.yaml
+const LonghornPolicyModule = "longhorn-iscsi-selinux-workaround"
+
+const longhornPolicy = "(allow iscsid_t self (capability (dac_override)))\n"
+
+var auditLogPath = "/var/log/audit/audit.log"
+
+// AVCDenial is an access SELinux denied, or would have denied in permissive mode
+type AVCDenial struct {
+	Time       time.Time
+	Command    string
+	Permission string
+	Source     string
+	Target     string
+	Class      string
+}
+
+func (self AVCDenial) String() string {
+	return fmt.Sprintf("%s denied { %s } on %s for %s (%s)", self.Source, self.Permission, self.Class, self.Command, self.Target)
+}
+
+// InstallLonghornPolicy installs the SELinux module Longhorn's iSCSI volumes need, unless it's
+// already installed
+func InstallLonghornPolicy(logChan chan<- string) error {
+	installed, err := longhornPolicyInstalled()
+	if err != nil {
+		return err
+	}
+	if installed {
+		logChan <- "The Longhorn SELinux policy is already installed."
+		return nil
+	}
+
+	dir, err := os.MkdirTemp("", "unbind-selinux")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	// semodule names CIL modules after their file
+	path := filepath.Join(dir, LonghornPolicyModule+".cil")
+	if err := os.WriteFile(path, []byte(longhornPolicy), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if _, err := runCommand(logChan, "semodule", "-i", path); err != nil {
+		return fmt.Errorf("failed to install the Longhorn SELinux policy: %w", err)
+	}
+	return nil
+}
+
+// RemoveLonghornPolicy removes the SELinux module InstallLonghornPolicy installed, if any
+func RemoveLonghornPolicy(logChan chan<- string) {
+	if _, err := exec.LookPath("semodule"); err != nil {
+		return
+	}
+	if installed, err := longhornPolicyInstalled(); err != nil || !installed {
+		return
+	}
+	if _, err := runCommand(logChan, "semodule", "-r", LonghornPolicyModule); err != nil {
+		logChan <- "Warning: Failed to remove the Longhorn SELinux policy, continuing anyway"
+	}
+}
+
+func longhornPolicyInstalled() (bool, error) {
+	modules, err := runCommand(nil, "semodule", "-l")
+	if err != nil {
+		return false, fmt.Errorf("failed to list SELinux modules: %w", err)
+	}
+	for _, module := range strings.Fields(modules) {
+		if module == LonghornPolicyModule {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+var (
+	auditTimestamp = regexp.MustCompile(`msg=audit\((\d+)\.(\d+):`)
+	avcDenied      = regexp.MustCompile(`avc:\s+denied\s+\{ ([^}]+) \}`)
+)
+
+// AVCDenials returns the denials in the audit log since the given time whose source context
+// contains one of sources, such as container_t
+func AVCDenials(since time.Time, sources ...string) ([]AVCDenial, error) {
+	file, err := os.Open(auditLogPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open the audit log: %w", err)
+	}
+	defer file.Close()
+
+	var denials []AVCDenial
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "type=AVC ") && !strings.HasPrefix(line, "type=USER_AVC ") {
+			continue
+		}
+		permission := avcDenied.FindStringSubmatch(line)
+		timestamp := auditTimestamp.FindStringSubmatch(line)
+		if permission == nil || timestamp == nil {
+			continue
+		}
+		seconds, _ := strconv.ParseInt(timestamp[1], 10, 64)
+		millis, _ := strconv.ParseInt(timestamp[2], 10, 64)
+		at := time.Unix(seconds, millis*int64(time.Millisecond))
+		if at.Before(since) {
+			continue
+		}
+
+		denial := AVCDenial{
+			Time:       at,
+			Command:    strings.Trim(auditField(line, "comm"), `"`),
+			Permission: strings.TrimSpace(permission[1]),
+			Source:     auditField(line, "scontext"),
+			Target:     auditField(line, "tcontext"),
+			Class:      auditField(line, "tclass"),
+		}
+		for _, source := range sources {
+			if strings.Contains(denial.Source, source) {
+				denials = append(denials, denial)
+				break
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read the audit log: %w", err)
+	}
+	return denials, nil
+}
+
+// auditField returns the value of a key=value field of an audit record
+func auditField(line, key string) string {
+	for _, field := range strings.Fields(line) {
+		if value, ok := strings.CutPrefix(field, key+"="); ok {
+			return value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,93 @@
+package system
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAVCDenials(t *testing.T) {
+	lines := map[string]string{
+		"container": `type=AVC msg=audit(1748833205.123:812): avc:  denied  { write } for  pid=4211 comm="longhorn-manage" ` +
+			`name="sys" dev="sysfs" ino=1 scontext=system_u:system_r:container_t:s0:c12,c345 ` +
+			`tcontext=system_u:object_r:sysfs_t:s0 tclass=dir permissive=0`,
+		"user avc": `type=USER_AVC msg=audit(1748833206.500:813): pid=1 uid=0 auid=4294967295 ses=4294967295 ` +
+			`subj=system_u:system_r:init_t:s0 msg='avc:  denied  { status } for auid=0 uid=0 gid=0 ` +
+			`path="/etc/systemd/system/k3s.service" cmdline="/usr/bin/systemctl is-active k3s" ` +
+			`scontext=system_u:system_r:k3s_t:s0 tcontext=system_u:object_r:systemd_unit_file_t:s0 ` +
+			`tclass=service exe="/usr/lib/systemd/systemd" sauid=0 hostname=? addr=? terminal=?'`,
+		"before since": `type=AVC msg=audit(1748833100.000:700): avc:  denied  { read } for  pid=3100 comm="iscsid" ` +
+			`scontext=system_u:system_r:iscsid_t:s0 tcontext=system_u:object_r:var_lib_t:s0 tclass=file permissive=0`,
+		"other source": `type=AVC msg=audit(1748833207.000:814): avc:  denied  { getattr } for  pid=990 comm="sshd" ` +
+			`scontext=system_u:system_r:sshd_t:s0-s0:c0.c1023 tcontext=system_u:object_r:user_home_t:s0 tclass=file permissive=0`,
+		"granted": `type=AVC msg=audit(1748833208.000:815): avc:  granted  { setsecparam } for  pid=4300 comm="load_policy" ` +
+			`scontext=system_u:system_r:container_t:s0 tcontext=system_u:object_r:security_t:s0 tclass=security`,
+		"syscall": `type=SYSCALL msg=audit(1748833205.123:812): arch=c000003e syscall=257 success=no exit=-13 comm="longhorn-manage" ` +
+			`subj=system_u:system_r:container_t:s0:c12,c345 key=(null)`,
+	}
+	log := filepath.Join(t.TempDir(), "audit.log")
+	original := auditLogPath
+	t.Cleanup(func() { auditLogPath = original })
+	auditLogPath = log
+
+	since := time.Unix(1748833200, 0)
+	sources := []string{"container_", "k3s_", "iscsid_t", "spc_t"}
+	tests := []struct {
+		line string
+		want []AVCDenial
+	}{
+		{line: "container", want: []AVCDenial{{
+			Time:       time.Unix(1748833205, 123*int64(time.Millisecond)),
+			Command:    "longhorn-manage",
+			Permission: "write",
+			Source:     "system_u:system_r:container_t:s0:c12,c345",
+			Target:     "system_u:object_r:sysfs_t:s0",
+			Class:      "dir",
+		}}},
+		{line: "user avc", want: []AVCDenial{{
+			Time:       time.Unix(1748833206, 500*int64(time.Millisecond)),
+			Permission: "status",
+			Source:     "system_u:system_r:k3s_t:s0",
+			Target:     "system_u:object_r:systemd_unit_file_t:s0",
+			Class:      "service",
+		}}},
+		{line: "before since"},
+		{line: "other source"},
+		{line: "granted"},
+		{line: "syscall"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			require.NoError(t, os.WriteFile(log, []byte(lines[tt.line]+"\n"), 0600))
+			denials, err := AVCDenials(since, sources...)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, denials)
+		})
+	}
+
+	// A whole log keeps the denials in order
+	all := []string{}
+	for _, name := range []string{"before since", "container", "syscall", "other source", "user avc", "granted"} {
+		all = append(all, lines[name])
+	}
+	require.NoError(t, os.WriteFile(log, []byte(strings.Join(all, "\n")+"\n"), 0600))
+	denials, err := AVCDenials(since, sources...)
+	require.NoError(t, err)
+	require.Len(t, denials, 2)
+	assert.Equal(t, "system_u:system_r:container_t:s0:c12,c345 denied { write } on dir for longhorn-manage (system_u:object_r:sysfs_t:s0)", denials[0].String())
+	assert.Equal(t, "status", denials[1].Permission)
+}
+
+func TestAVCDenials_MissingLog(t *testing.T) {
+	original := auditLogPath
+	t.Cleanup(func() { auditLogPath = original })
+	auditLogPath = filepath.Join(t.TempDir(), "audit.log")
+
+	_, err := AVCDenials(time.Now(), "container_")
+	assert.ErrorContains(t, err, "failed to open the audit log")
+}
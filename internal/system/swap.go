@@ -3,6 +3,7 @@ package system
 import (
 	"bufio"
 	"bytes"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
@@ -47,7 +48,7 @@ func runCommand(logChan chan<- string, name string, args ...string) (string, err
 		if logChan != nil {
 			logChan <- fmt.Sprintf("Error executing '%s': %v", cmdStr, err)
 		}
-		return stdoutStr, fmt.Errorf(errMsg)
+		return stdoutStr, errors.New(errMsg)
 	}
 
 	if logChan != nil {
@@ -285,8 +286,8 @@ func applySysctlSettings(logChan chan<- string, settings map[string]string, conf
 	return nil
 }
 
-// CreateSwapFile sets up and enables a system swap file
-func CreateSwapFile(sizeGB int, logChan chan<- string) error {
+// CreateSwapFile sets up and enables a system swap file, memoryTotal in bytes tunes min_free_kbytes
+func CreateSwapFile(sizeGB int, memoryTotal uint64, logChan chan<- string) error {
 	if os.Geteuid() != 0 {
 		return errdefs.ErrNotRoot // Ensure we run as root
 	}
@@ -402,7 +403,7 @@ func CreateSwapFile(sizeGB int, logChan chan<- string) error {
 	}
 
 	// Calculate min_free_kbytes
-	minFreeKbytes, err := calculateMinFreeKbytes(logChan)
+	minFreeKbytes, err := calculateMinFreeKbytes(memoryTotal, logChan)
 	if err != nil {
 		logChan <- fmt.Sprintf("Warning: Failed to calculate min_free_kbytes: %v", err)
 	}
@@ -431,33 +432,10 @@ func CreateSwapFile(sizeGB int, logChan chan<- string) error {
 }
 
 // Calculate swap min_free_kbytes as 1-2% of total RAM
-func calculateMinFreeKbytes(logChan chan<- string) (string, error) {
-	// Read total memory from /proc/meminfo
-	file, err := os.Open("/proc/meminfo")
-	if err != nil {
-		return "", fmt.Errorf("failed to open /proc/meminfo: %w", err)
-	}
-	defer file.Close()
-
-	scanner := bufio.NewScanner(file)
-	var totalMemKB uint64
-
-	for scanner.Scan() {
-		line := scanner.Text()
-		if strings.HasPrefix(line, "MemTotal:") {
-			fields := strings.Fields(line)
-			if len(fields) >= 2 {
-				totalMemKB, err = strconv.ParseUint(fields[1], 10, 64)
-				if err != nil {
-					return "", fmt.Errorf("failed to parse MemTotal: %w", err)
-				}
-				break
-			}
-		}
-	}
-
+func calculateMinFreeKbytes(memoryTotal uint64, logChan chan<- string) (string, error) {
+	totalMemKB := memoryTotal / 1024
 	if totalMemKB == 0 {
-		return "", fmt.Errorf("could not find MemTotal in /proc/meminfo")
+		return "", fmt.Errorf("total memory is unknown")
 	}
 
 	var percentage float64
@@ -486,3 +464,20 @@ func calculateMinFreeKbytes(logChan chan<- string) (string, error) {
 
 	return strconv.FormatUint(minFreeKB, 10), nil
 }
+
+// RecommendedSwapGB suggests a swap size for a server with memoryTotal bytes of RAM: as much as
+// RAM on small servers, half of it above 8 GB, and never more than a quarter of the free disk
+func RecommendedSwapGB(memoryTotal uint64, availableDiskGB float64) int {
+	ramGB := int((memoryTotal + 1<<29) >> 30) // Rounded to the nearest GB
+	size := ramGB
+	switch {
+	case ramGB < 2:
+		size = 2
+	case ramGB > 8:
+		size = min(max(ramGB/2, 8), 16)
+	}
+	if availableDiskGB > 0 {
+		size = min(size, int(availableDiskGB/4))
+	}
+	return max(size, 1)
+}
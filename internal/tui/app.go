@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/charmbracelet/bubbles/spinner"
 	"github.com/charmbracelet/bubbles/textinput"
@@ -11,6 +12,9 @@ import (
 	"github.com/unbindapp/unbind-installer/internal/installer"
 	"github.com/unbindapp/unbind-installer/internal/k3s"
 	"github.com/unbindapp/unbind-installer/internal/osinfo"
+	"github.com/unbindapp/unbind-installer/internal/pkgmanager"
+	"github.com/unbindapp/unbind-installer/internal/registry"
+	"github.com/unbindapp/unbind-installer/internal/utils"
 	"k8s.io/client-go/dynamic"
 )
 
@@ -41,6 +45,7 @@ type Model struct {
 	// Logging
 	logMessages []string
 	logChan     chan string
+	redactor    *utils.Redactor // Shared by all copies of the model, hides credentials in logs
 
 	// Educational facts
 	factChan    chan string
@@ -50,10 +55,18 @@ type Model struct {
 	dnsInfo           *dnsInfo
 	domainInput       textinput.Model
 	registryInput     textinput.Model
+	registrySettings  registrySettingsInputs
 	usernameInput     textinput.Model
 	passwordInput     textinput.Model
 	registryHostInput textinput.Model
-	selectedRegistry  int // 0=docker.io, 1=ghcr.io, 2=quay.io, 3=custom
+	selectedRegistry  int // Index into externalRegistryProviders
+	backupTarget      backupTargetInputs
+
+	// Docker config credentials that can be imported on the external registry screen
+	registryAuthFrom       string // Docker config.json to look in
+	autoImportRegistryAuth bool   // Import found credentials without asking, set by --registry-auth-from
+	importableRegistryAuth *registry.ImportedCredentials
+	importableRegistryHost string
 
 	// Kube client
 	kubeConfig      string
@@ -71,6 +84,17 @@ type Model struct {
 	// Package install progress
 	packageProgressChan chan packageInstallProgressMsg
 	packageProgress     packageInstallProgressMsg
+	packageLockTimeout  time.Duration           // How long to wait for another package manager, zero for the default
+	packageSource       *pkgmanager.LocalSource // Local packages to install from on servers without internet access
+
+	// Warnings about the environment the installer runs in, confirmed before installing
+	environmentWarnings []osinfo.EnvironmentIssue
+
+	// Extra package repositories waiting for consent, enabled once it's given
+	requiredRepositories []pkgmanager.Repository
+	repositoryConsent    bool
+	// Set once the user agrees to the full system upgrade pacman runs along with the install
+	systemUpgradeConsent bool
 }
 
 // NewModel initializes a new Model
@@ -95,6 +119,7 @@ func NewModel(version string) Model {
 
 	// Initialize registry input
 	registryInput := initializeRegistryInput()
+	registrySettings := initializeRegistrySettingsInputs()
 
 	// Initialize username and password inputs
 	usernameInput := initializeUsernameInput()
@@ -133,6 +158,7 @@ func NewModel(version string) Model {
 		styles:             styles,
 		logMessages:        []string{},
 		logChan:            logChan,
+		redactor:           utils.NewRedactor(),
 		unbindProgressChan: progressChan,
 		k3sProgressChan:    k3sProgressChan,
 		k3sProgress: k3s.K3SUpdateMessage{
@@ -153,10 +179,13 @@ func NewModel(version string) Model {
 		},
 		domainInput:         domainInput,
 		registryInput:       registryInput,
+		registrySettings:    registrySettings,
 		usernameInput:       usernameInput,
 		passwordInput:       passwordInput,
 		registryHostInput:   registryHostInput,
+		backupTarget:        initializeBackupTargetInputs(),
 		selectedRegistry:    0, // Default to Docker Hub
+		registryAuthFrom:    registry.DefaultDockerConfigPath(),
 		swapSizeInput:       swapInput,
 		packageProgressChan: packageProgressChan,
 		factChan:            make(chan string, 10),
@@ -165,6 +194,27 @@ func NewModel(version string) Model {
 	return model
 }
 
+// WithRegistryAuthSource imports external registry credentials from a docker config.json
+// (and any credential helpers it names) instead of asking for them
+func (self Model) WithRegistryAuthSource(path string) Model {
+	self.registryAuthFrom = registry.ExpandHome(path)
+	self.autoImportRegistryAuth = true
+	return self
+}
+
+// WithPackageLockTimeout sets how long package installs wait for another package manager to finish
+func (self Model) WithPackageLockTimeout(timeout time.Duration) Model {
+	self.packageLockTimeout = timeout
+	return self
+}
+
+// WithPackageSource installs the required packages from local packages instead of the configured
+// repositories
+func (self Model) WithPackageSource(source *pkgmanager.LocalSource) Model {
+	self.packageSource = source
+	return self
+}
+
 // Init is the Bubble Tea initialization function
 func (self Model) Init() tea.Cmd {
 	// Create a batch of initial commands
@@ -185,7 +235,7 @@ func (self Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		case "ctrl+c":
 			return self, tea.Quit
 		case "ctrl+d":
-			if self.state != StateDNSConfig && self.state != StateExternalRegistryInput && self.state != StateRegistryDomainInput {
+			if self.state != StateDNSConfig && self.state != StateExternalRegistryInput && self.state != StateRegistryDomainInput && self.state != StateBackupTargetInput {
 				// Toggle debug logs view
 				self.showDebugLogs = !self.showDebugLogs
 				return self, nil
@@ -261,6 +311,10 @@ func (self Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		model, cmd = self.updateCreatingSwapState(msg)
 	case StateSwapCreated:
 		model, cmd = self.updateSwapCreatedState(msg)
+	case StateConfirmRepositories:
+		model, cmd = self.updateConfirmRepositoriesState(msg)
+	case StateConfirmSystemUpgrade:
+		model, cmd = self.updateConfirmSystemUpgradeState(msg)
 	case StateInstallingPackages:
 		model, cmd = self.updateInstallingPackagesState(msg)
 	case StateInstallComplete:
@@ -285,6 +339,12 @@ func (self Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		model, cmd = self.updateExternalRegistryInputState(msg)
 	case StateExternalRegistryValidation:
 		model, cmd = self.updateExternalRegistryValidationState(msg)
+	case StateBackupTargetSelection:
+		model, cmd = self.updateBackupTargetSelectionState(msg)
+	case StateBackupTargetInput:
+		model, cmd = self.updateBackupTargetInputState(msg)
+	case StateBackupTargetValidation:
+		model, cmd = self.updateBackupTargetValidationState(msg)
 	case StateError:
 		model, cmd = self.updateErrorState(msg)
 	case StateInstallingK3S:
@@ -366,6 +426,10 @@ func (self Model) View() string {
 			content = viewCreatingSwap(self)
 		case StateSwapCreated:
 			content = viewSwapCreated(self)
+		case StateConfirmRepositories:
+			content = viewConfirmRepositories(self)
+		case StateConfirmSystemUpgrade:
+			content = viewConfirmSystemUpgrade(self)
 		case StateInstallingPackages:
 			content = viewInstallingPackages(self)
 		case StateInstallComplete:
@@ -396,6 +460,12 @@ func (self Model) View() string {
 			content = viewExternalRegistryInput(self)
 		case StateExternalRegistryValidation:
 			content = viewExternalRegistryValidation(self)
+		case StateBackupTargetSelection:
+			content = viewBackupTargetSelection(self)
+		case StateBackupTargetInput:
+			content = viewBackupTargetInput(self)
+		case StateBackupTargetValidation:
+			content = viewBackupTargetValidation(self)
 		default:
 			content = viewWelcome(self)
 		}
@@ -482,7 +552,7 @@ func (self Model) listenForLogs() tea.Cmd {
 				// Channel closed
 				return nil
 			}
-			return logMsg{message: msg}
+			return logMsg{message: self.redactor.Redact(msg)}
 		default:
 			// Don't block if no message is available
 			return nil
@@ -3,7 +3,6 @@ package tui
 import (
 	"context"
 	"fmt"
-	"net/http"
 	"os"
 	"strings"
 	"time"
@@ -15,6 +14,7 @@ import (
 	"github.com/unbindapp/unbind-installer/internal/network"
 	"github.com/unbindapp/unbind-installer/internal/osinfo"
 	"github.com/unbindapp/unbind-installer/internal/pkgmanager"
+	"github.com/unbindapp/unbind-installer/internal/registry"
 	"github.com/unbindapp/unbind-installer/internal/system"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/tools/clientcmd"
@@ -49,6 +49,10 @@ func detectOSInfo() tea.Msg {
 	if err != nil {
 		return errMsg{err}
 	}
+	// Containers and WSL fail in confusing ways later on, stop before anything changes
+	if issue := osinfo.BlockingIssue(osinfo.EnvironmentIssues(info)); issue != nil {
+		return errMsg{errdefs.NewCustomError(errdefs.ErrTypeUnsupportedEnvironment, issue.Message)}
+	}
 	return osInfoMsg{info}
 }
 
@@ -71,11 +75,24 @@ func (self Model) getDiskSpaceCommand() tea.Cmd {
 // createSwapCommand creates the swap file.
 func (self Model) createSwapCommand(sizeGB int) tea.Cmd {
 	return func() tea.Msg {
-		err := system.CreateSwapFile(sizeGB, self.logChan)
+		var memoryTotal uint64
+		if self.osInfo != nil {
+			memoryTotal = self.osInfo.MemoryTotal
+		}
+		err := system.CreateSwapFile(sizeGB, memoryTotal, self.logChan)
 		return swapCreateResultMsg{err: err}
 	}
 }
 
+// requiredPackages returns the packages to install. Local package sources don't hold the SELinux
+// policy, the K3s installer adds it itself then.
+func (self Model) requiredPackages() []string {
+	if self.packageSource != nil {
+		return pkgmanager.GetDistributionPackages(self.osInfo.Distribution)
+	}
+	return pkgmanager.GetRequiredPackages(self.osInfo)
+}
+
 // installRequiredPackages is a command that installs the required packages
 func (self Model) installRequiredPackages() tea.Cmd {
 	return func() tea.Msg {
@@ -84,10 +101,10 @@ func (self Model) installRequiredPackages() tea.Cmd {
 		defer cancel() // Ensure resources are cleaned up
 
 		// Get distribution-specific package names
-		packages := pkgmanager.GetDistributionPackages(self.osInfo.Distribution)
+		packages := self.requiredPackages()
 
 		// Create a new package manager
-		installer, err := pkgmanager.NewPackageManager(self.osInfo.Distribution, self.logChan)
+		installer, err := pkgmanager.NewPackageManager(self.osInfo.Distribution, self.logChan, self.packageLockTimeout, self.packageSource)
 		if err != nil {
 			return errMsg{err}
 		}
@@ -117,19 +134,49 @@ func (self Model) installRequiredPackages() tea.Cmd {
 				case self.packageProgressChan <- msg:
 					// Message sent successfully
 				default:
-					// Channel is full, log rather than block
-					if self.logChan != nil {
+					// Channel is full, package managers report progress for every package so
+					// dropping one update is harmless unless it's the last
+					if isComplete && self.logChan != nil {
 						self.logChan <- fmt.Sprintf("Warning: Package progress channel is full (progress: %.1f%%)", progress*100)
 					}
 				}
 			}
 		}
 
-		// Install the packages with context
-		err = installer.InstallPackages(ctx, packages, progressFunc)
+		// Some packages come from repositories that aren't enabled by default, ask before enabling
+		// them. Local package sources have to hold these packages themselves.
+		if self.packageSource == nil {
+			missing, err := pkgmanager.MissingPackages(ctx, installer, packages)
+			if err != nil {
+				return errMsg{err}
+			}
+			repos, err := pkgmanager.RequiredRepositories(ctx, self.osInfo, missing)
+			if err != nil {
+				return errMsg{err}
+			}
+			if len(repos) > 0 {
+				if !self.repositoryConsent {
+					return repositoryConsentMsg{repos: repos}
+				}
+				if err := pkgmanager.EnableRepositories(ctx, repos, pkgmanager.DefaultRepositoryRecordPath, self.log); err != nil {
+					return errMsg{err}
+				}
+			}
+			if len(missing) > 0 && pkgmanager.UpgradesSystem(self.osInfo.Distribution, self.packageSource) && !self.systemUpgradeConsent {
+				return systemUpgradeConsentMsg{}
+			}
+		}
+
+		// Install only what's missing, recording it so uninstall can remove it again
+		installed, err := pkgmanager.InstallMissing(ctx, installer, packages, pkgmanager.DefaultRecordPath, progressFunc)
 		if err != nil {
 			return errMsg{err}
 		}
+		if len(installed) == 0 {
+			self.log("All required packages are already installed")
+		} else {
+			self.log(fmt.Sprintf("Installed %s", strings.Join(installed, ", ")))
+		}
 
 		return installCompleteMsg{}
 	}
@@ -254,34 +301,14 @@ func (self Model) startRegistryDNSValidation() tea.Cmd {
 	}
 }
 
-// validateDomain checks whether the domain resolves to the expected IP and whether it is
-// behind Cloudflare. If allowCloudflare is false and the domain *is* behind Cloudflare,
-// the domain is considered invalid.
+// validateDomain checks the domain against the server's external IP, see network.ValidateDomain
 func (self Model) validateDomain(domain string, allowCloudflare bool) (dnsValid, behindCF bool) {
-	self.log(fmt.Sprintf("Checking %s…", domain))
-
-	behindCF = network.CheckCloudflareProxy(domain, self.log)
-	if behindCF && !allowCloudflare {
-		return false, true
-	}
-
-	dnsValid = network.ValidateDNS(domain, self.dnsInfo.ExternalIP, self.log)
-	return dnsValid, behindCF
+	return network.ValidateDomain(domain, self.dnsInfo.ExternalIP, allowCloudflare, self.log)
 }
 
-// detectWildcard probes an arbitrary sub‑domain to infer wildcard DNS configuration.
-// If the probe domain is behind Cloudflare the presence of wildcard is assumed true.
+// detectWildcard infers wildcard DNS configuration, see network.DetectWildcard
 func (self Model) detectWildcard(base string) (dnsValid, behindCF bool) {
-	probe := fmt.Sprintf("test%d.%s", time.Now().Unix(), base)
-	self.log(fmt.Sprintf("Checking for wildcard domain with %s…", probe))
-
-	behindCF = network.CheckCloudflareProxy(probe, self.log)
-	if behindCF {
-		return true, true // wildcard via Cloudflare
-	}
-
-	dnsValid = network.ValidateDNS(probe, self.dnsInfo.ExternalIP, self.log)
-	return dnsValid, behindCF
+	return network.DetectWildcard(base, self.dnsInfo.ExternalIP, self.log)
 }
 
 // installK3S is a command that installs K3S
@@ -289,6 +316,11 @@ func (self Model) installK3S() tea.Cmd {
 	return func() tea.Msg {
 		// Create a new K3S installer
 		installer := k3s.NewInstaller(self.logChan, self.k3sProgressChan, self.factChan)
+		if self.osInfo != nil {
+			installer.MemoryTotal = self.osInfo.MemoryTotal
+			installer.CPUCount = self.osInfo.CPUCount
+			installer.SELinux = self.osInfo.SELinux
+		}
 
 		// Create a context with timeout
 		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
@@ -348,6 +380,18 @@ func (self Model) installUnbind() tea.Cmd {
 			opts.UnbindRegistryDomain = self.dnsInfo.RegistryDomain
 			opts.DisableRegistry = false
 			self.log("Using self-hosted registry at: " + self.dnsInfo.RegistryDomain)
+
+			if self.dnsInfo.RegistryStorageSizeGB > 0 {
+				opts.RegistrySettings = &unbindInstaller.RegistrySettings{
+					StorageSizeGB: self.dnsInfo.RegistryStorageSizeGB,
+					StorageClass:  self.dnsInfo.RegistryStorageClass,
+					RetainTags:    self.dnsInfo.RegistryRetainTags,
+					GCSchedule:    self.dnsInfo.RegistryGCSchedule,
+				}
+				self.log(fmt.Sprintf("Registry storage: %d GB on %s, keeping %d tags per image, GC at '%s'",
+					self.dnsInfo.RegistryStorageSizeGB, self.dnsInfo.RegistryStorageClass,
+					self.dnsInfo.RegistryRetainTags, self.dnsInfo.RegistryGCSchedule))
+			}
 		} else {
 			// External registry
 			opts.RegistryUsername = self.dnsInfo.RegistryUsername
@@ -357,6 +401,32 @@ func (self Model) installUnbind() tea.Cmd {
 			self.log(fmt.Sprintf("Using external registry %s with account: %s",
 				self.dnsInfo.RegistryHost,
 				self.dnsInfo.RegistryUsername))
+
+			// Short-lived tokens are kept fresh by a refresher in the cluster
+			if registry.NeedsCredentialRefresher(self.dnsInfo.RegistryProvider) {
+				refresher, err := self.ecrRefresherOptions(ctx)
+				if err != nil {
+					self.log(fmt.Sprintf("Unbind installation failed: %s", err.Error()))
+					return errMsg{err: errdefs.NewCustomError(errdefs.ErrTypeUnbindInstallFailed, fmt.Sprintf("Unbind installation failed: %s", err.Error()))}
+				}
+				opts.ECRRefresher = refresher
+				opts.RegistryHost = registry.APIHost(self.dnsInfo.RegistryHost)
+				opts.RegistryUsername = registry.ECRUsername
+				opts.RegistryPassword = ""
+				opts.RegistryExistingSecret = unbindInstaller.RegistryCredentialsSecretName
+			}
+		}
+
+		if self.dnsInfo.LonghornBackup != nil {
+			opts.LonghornBackup = &unbindInstaller.LonghornBackupOptions{
+				Target:      self.dnsInfo.LonghornBackup.URL(),
+				Credentials: self.dnsInfo.LonghornBackup.CredentialSecretData(),
+				HasSecret:   self.dnsInfo.LonghornBackup.CredentialSecretData() != nil,
+				Schedule:    self.dnsInfo.LonghornBackupSchedule,
+				Retain:      self.dnsInfo.LonghornBackupRetain,
+			}
+			self.log(fmt.Sprintf("Longhorn backups to %s at '%s', keeping %d per volume",
+				opts.LonghornBackup.Target, opts.LonghornBackup.Schedule, opts.LonghornBackup.Retain))
 		}
 
 		// Set base domain if using wildcard
@@ -374,87 +444,93 @@ func (self Model) installUnbind() tea.Cmd {
 	}
 }
 
-func (self Model) log(msg string) {
-	self.logChan <- msg
+// validateBackupTarget checks the Longhorn backup target is reachable and writable
+func (self Model) validateBackupTarget(settings *backupTargetSettings) tea.Cmd {
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+
+		err := settings.CheckWritable(ctx, self.log)
+		return backupTargetValidationCompleteMsg{settings: settings, err: err}
+	}
 }
 
-// validateRegistryCredentials checks if the provided Docker registry credentials are valid
-func (self Model) validateRegistryCredentials() tea.Cmd {
+// detectDockerCredentials looks for credentials for a registry host in the docker config
+func (self Model) detectDockerCredentials(host string) tea.Cmd {
+	path := self.registryAuthFrom
 	return func() tea.Msg {
-		if self.dnsInfo == nil || self.dnsInfo.RegistryUsername == "" || self.dnsInfo.RegistryPassword == "" {
-			return errMsg{err: nil}
+		cfg, err := registry.LoadDockerConfig(path)
+		if err != nil {
+			if os.IsNotExist(err) {
+				return dockerCredentialsMsg{host: host}
+			}
+			return dockerCredentialsMsg{host: host, err: err}
 		}
 
-		username := self.dnsInfo.RegistryUsername
-		password := self.dnsInfo.RegistryPassword
-		host := self.dnsInfo.RegistryHost
-
-		self.log(fmt.Sprintf("Validating registry credentials for %s on %s...", username, host))
-
-		// Registry-specific authentication method
-		var authURL string
-		var client *http.Client
-		var req *http.Request
-		var err error
+		creds, err := cfg.Lookup(host)
+		return dockerCredentialsMsg{host: host, creds: creds, err: err}
+	}
+}
 
-		// Default is Docker Hub
-		if host == "docker.io" {
-			// Docker Hub authentication URL
-			authURL = "https://auth.docker.io/token?service=registry.docker.io&scope=repository:library/alpine:pull"
+// ecrRefresherOptions logs in to ECR with the access key so the refresher can be seeded with a valid token
+func (self Model) ecrRefresherOptions(ctx context.Context) (*unbindInstaller.ECRRefresherOptions, error) {
+	creds := registry.Credentials{
+		Provider: self.dnsInfo.RegistryProvider,
+		Host:     self.dnsInfo.RegistryHost,
+		Username: self.dnsInfo.RegistryUsername,
+		Password: self.dnsInfo.RegistryPassword,
+	}
 
-			// Create HTTP client
-			client = &http.Client{
-				Timeout: 10 * time.Second,
-			}
+	ecr, err := registry.ParseECRHost(creds.Host)
+	if err != nil {
+		return nil, err
+	}
 
-			// Create the request
-			req, err = http.NewRequest("GET", authURL, nil)
-			if err != nil {
-				self.log(fmt.Sprintf("Error creating request: %s", err.Error()))
-				return registryValidationCompleteMsg{success: false}
-			}
+	login, _, err := registry.NewChecker(self.log).ECRLogin(ctx, creds)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get an ECR token: %w", err)
+	}
+	self.redactor.Add(login.Password)
+
+	return &unbindInstaller.ECRRefresherOptions{
+		RegistryHost:    registry.APIHost(creds.Host),
+		Region:          ecr.Region,
+		AccessKeyID:     creds.Username,
+		SecretAccessKey: creds.Password,
+		InitialUsername: login.Username,
+		InitialPassword: login.Password,
+	}, nil
+}
 
-			// Add basic auth header
-			req.SetBasicAuth(username, password)
-		} else {
-			// Generic registry API check
-			self.log(fmt.Sprintf("Using generic authentication for %s", host))
+func (self Model) log(msg string) {
+	self.logChan <- msg
+}
 
-			// Use the catalog endpoint as a generic check
-			authURL = fmt.Sprintf("https://%s/v2/_catalog", host)
+// validateRegistryCredentials checks that the provided registry credentials are valid and allowed to push
+func (self Model) validateRegistryCredentials() tea.Cmd {
+	return func() tea.Msg {
+		if self.dnsInfo == nil || self.dnsInfo.RegistryUsername == "" || self.dnsInfo.RegistryPassword == "" {
+			return errMsg{err: nil}
+		}
 
-			// Create HTTP client
-			client = &http.Client{
-				Timeout: 10 * time.Second,
-			}
+		creds := registry.Credentials{
+			Provider: self.dnsInfo.RegistryProvider,
+			Host:     self.dnsInfo.RegistryHost,
+			Username: self.dnsInfo.RegistryUsername,
+			Password: self.dnsInfo.RegistryPassword,
+		}
 
-			// Create the request
-			req, err = http.NewRequest("GET", authURL, nil)
-			if err != nil {
-				self.log(fmt.Sprintf("Error creating request: %s", err.Error()))
-				return registryValidationCompleteMsg{success: false}
-			}
+		self.log(fmt.Sprintf("Validating registry credentials for %s on %s...", creds.Username, creds.Host))
 
-			// Add basic auth header
-			req.SetBasicAuth(username, password)
-		}
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
 
-		// Make the request
-		self.log(fmt.Sprintf("Connecting to %s...", host))
-		resp, err := client.Do(req)
-		if err != nil {
-			self.log(fmt.Sprintf("Connection error: %s", err.Error()))
-			return registryValidationCompleteMsg{success: false}
+		if err := registry.NewChecker(self.log).Validate(ctx, creds); err != nil {
+			self.log(fmt.Sprintf("Registry validation failed: %s", err.Error()))
+			return registryValidationCompleteMsg{success: false, err: err}
 		}
-		defer resp.Body.Close()
 
-		// Check response status
-		if resp.StatusCode == 200 || resp.StatusCode == 401 && resp.Header.Get("Www-Authenticate") != "" {
-			self.log("Authentication successful!")
-			return registryValidationCompleteMsg{success: true}
-		} else {
-			self.log(fmt.Sprintf("Authentication failed with status: %d", resp.StatusCode))
-			return registryValidationCompleteMsg{success: false}
-		}
+		self.log("Authentication successful, push access confirmed!")
+		return registryValidationCompleteMsg{success: true}
 	}
 }
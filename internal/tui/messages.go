@@ -7,6 +7,8 @@ import (
 	"github.com/unbindapp/unbind-installer/internal/k3s"
 	"github.com/unbindapp/unbind-installer/internal/network"
 	"github.com/unbindapp/unbind-installer/internal/osinfo"
+	"github.com/unbindapp/unbind-installer/internal/pkgmanager"
+	"github.com/unbindapp/unbind-installer/internal/registry"
 	"k8s.io/client-go/dynamic"
 )
 
@@ -44,6 +46,14 @@ type installPackagesMsg struct{}
 
 type installCompleteMsg struct{}
 
+// repositoryConsentMsg asks to enable the extra repositories some packages come from
+type repositoryConsentMsg struct {
+	repos []pkgmanager.Repository
+}
+
+// systemUpgradeConsentMsg asks before the package manager upgrades the whole system
+type systemUpgradeConsentMsg struct{}
+
 type packageInstallProgressMsg struct {
 	packageName string
 	progress    float64
@@ -99,6 +109,20 @@ type unbindInstallCompleteMsg struct{}
 // registryValidationCompleteMsg for credential check
 type registryValidationCompleteMsg struct {
 	success bool
+	err     error
+}
+
+// backupTargetValidationCompleteMsg for the backup target check
+type backupTargetValidationCompleteMsg struct {
+	settings *backupTargetSettings
+	err      error
+}
+
+// dockerCredentialsMsg carries credentials found in the docker config for a registry host
+type dockerCredentialsMsg struct {
+	host  string
+	creds *registry.ImportedCredentials
+	err   error
 }
 
 // Educational fact message
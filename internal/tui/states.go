@@ -1,6 +1,13 @@
 package tui
 
-import "time"
+import (
+	"time"
+
+	"github.com/unbindapp/unbind-installer/internal/config"
+	"github.com/unbindapp/unbind-installer/internal/k3s"
+	"github.com/unbindapp/unbind-installer/internal/longhorn"
+	"github.com/unbindapp/unbind-installer/internal/registry"
+)
 
 // ApplicationState represents the current state of the application
 type ApplicationState int
@@ -19,6 +26,8 @@ const (
 	StateEnterSwapSize
 	StateCreatingSwap
 	StateSwapCreated
+	StateConfirmRepositories
+	StateConfirmSystemUpgrade
 	StateInstallingPackages
 	StateInstallComplete
 	StateError
@@ -32,6 +41,9 @@ const (
 	StateRegistryDNSValidation
 	StateExternalRegistryInput
 	StateExternalRegistryValidation
+	StateBackupTargetSelection
+	StateBackupTargetInput
+	StateBackupTargetValidation
 	StateInstallingK3S
 	StateInstallingUnbind
 	StateInstallationComplete
@@ -62,9 +74,59 @@ type dnsInfo struct {
 	ValidationDuration time.Duration
 
 	// Registry configuration
-	RegistryType         RegistryType
-	RegistryUsername     string
-	RegistryPassword     string
-	RegistryHost         string
-	DisableLocalRegistry bool
+	RegistryType     RegistryType
+	RegistryProvider registry.Provider // Set for registries with short-lived or key-based credentials
+
+	// Self-hosted registry storage and cleanup
+	RegistryStorageSizeGB int
+	RegistryStorageClass  string
+	RegistryRetainTags    int    // Tags kept per repository, older ones are deleted
+	RegistryGCSchedule    string // Cron schedule for garbage collection
+	RegistryUsername      string
+	RegistryPassword      string
+	RegistryHost          string
+	DisableLocalRegistry  bool
+	RegistryValidationErr error // Why the last credential check failed, if it did
+
+	// Longhorn volume backups, none when LonghornBackup is nil
+	LonghornBackup         *longhorn.BackupTarget
+	LonghornBackupSchedule string
+	LonghornBackupRetain   int
+}
+
+// clusterConfig returns the settings saved to /etc/unbind/config so the unbind CLI can start from them
+func (self *dnsInfo) clusterConfig() *config.Config {
+	cfg := &config.Config{
+		ClusterIP:    self.InternalIP,
+		K3sVersion:   k3s.K3S_VERSION,
+		UnbindDomain: self.UnbindDomain,
+	}
+	if self.IsWildcard {
+		cfg.BaseDomain = self.Domain
+	}
+
+	switch self.RegistryType {
+	case RegistrySelfHosted:
+		cfg.RegistryType = config.RegistrySelfHosted
+		cfg.RegistryDomain = self.RegistryDomain
+		if self.RegistryStorageSizeGB > 0 {
+			cfg.RegistryStorageSizeGB = self.RegistryStorageSizeGB
+			cfg.RegistryStorageClass = self.RegistryStorageClass
+			cfg.RegistryRetainTags = self.RegistryRetainTags
+			cfg.RegistryGCSchedule = self.RegistryGCSchedule
+		}
+	case RegistryExternal:
+		cfg.RegistryType = config.RegistryExternal
+		cfg.RegistryHost = self.RegistryHost
+		cfg.RegistryUsername = self.RegistryUsername
+		if self.RegistryProvider != registry.ProviderGeneric {
+			cfg.RegistryProvider = string(self.RegistryProvider)
+		}
+	}
+	if self.LonghornBackup != nil {
+		cfg.LonghornBackupTarget = self.LonghornBackup.URL()
+		cfg.LonghornBackupSchedule = self.LonghornBackupSchedule
+		cfg.LonghornBackupRetain = self.LonghornBackupRetain
+	}
+	return cfg
 }
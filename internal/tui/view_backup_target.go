@@ -0,0 +1,417 @@
+package tui
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/unbindapp/unbind-installer/internal/longhorn"
+	"github.com/unbindapp/unbind-installer/internal/utils"
+)
+
+// viewBackupTargetSelection asks whether Longhorn should back up volumes off the node
+func viewBackupTargetSelection(m Model) string {
+	s := strings.Builder{}
+
+	// Banner
+	s.WriteString(getResponsiveBanner(m))
+	s.WriteString("\n\n")
+
+	maxWidth := getUsableWidth(m.width)
+
+	s.WriteString(m.styles.Bold.Render("Configure Volume Backups (Optional)"))
+	s.WriteString("\n\n")
+
+	instructionText := "Longhorn stores volume data on the servers in your cluster. A backup target keeps a copy somewhere else, so data survives the loss of a server. You can:"
+	for _, line := range wrapText(instructionText, maxWidth) {
+		s.WriteString(m.styles.Normal.Render(line))
+		s.WriteString("\n")
+	}
+	s.WriteString("\n")
+
+	options := []struct {
+		title string
+		text  string
+		note  string
+	}{
+		{"1. S3 Compatible Storage", "   Back up to AWS S3, MinIO, Backblaze B2, Cloudflare R2, or another S3 compatible service", "   - Requires a bucket and an access key with write access"},
+		{"2. NFS Share", "   Back up to an NFS export reachable from your servers", "   - Requires an NFSv4 export writable by root"},
+		{"3. Skip", "   Don't back up volumes, you can set a backup target in Longhorn later", ""},
+	}
+	for _, option := range options {
+		s.WriteString(m.styles.Bold.Render(option.title))
+		s.WriteString("\n")
+		for _, line := range wrapText(option.text, maxWidth) {
+			s.WriteString(m.styles.Normal.Render(line))
+			s.WriteString("\n")
+		}
+		if option.note != "" {
+			for _, line := range wrapText(option.note, maxWidth) {
+				s.WriteString(m.styles.Subtle.Render(line))
+				s.WriteString("\n")
+			}
+		}
+		s.WriteString("\n")
+	}
+
+	// Navigation hints
+	s.WriteString(m.styles.Bold.Render("Navigation:"))
+	s.WriteString("\n")
+	for _, hint := range []struct{ key, text string }{
+		{"1", " for S3 Compatible Storage"},
+		{"2", " for NFS Share"},
+		{"3", " to skip and continue with the installation"},
+	} {
+		s.WriteString(m.styles.Normal.Render("• Press "))
+		s.WriteString(m.styles.Key.Render(hint.key))
+		s.WriteString(m.styles.Normal.Render(hint.text))
+		s.WriteString("\n")
+	}
+	s.WriteString("\n")
+
+	// Status bar at the bottom
+	s.WriteString(m.styles.StatusBar.Render("Press Ctrl+c to quit"))
+
+	return renderWithLayout(m, s.String())
+}
+
+// updateBackupTargetSelectionState handles the choice of backup target type
+func (m Model) updateBackupTargetSelectionState(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if keyMsg, ok := msg.(tea.KeyMsg); ok {
+		switch keyMsg.String() {
+		case "1", "2":
+			m.backupTarget.kind = longhorn.BackupTargetS3
+			if keyMsg.String() == "2" {
+				m.backupTarget.kind = longhorn.BackupTargetNFS
+			}
+			m.backupTarget.err = nil
+			m.state = StateBackupTargetInput
+			fields := m.backupTargetFields()
+			for _, field := range fields {
+				field.Blur()
+			}
+			cmd := fields[0].Focus()
+			return m, tea.Batch(cmd, m.listenForLogs())
+
+		case "3", "s":
+			m.dnsInfo.LonghornBackup = nil
+			m.logChan <- "Skipping volume backups"
+			return m.startK3SInstall()
+		}
+	}
+
+	return m, m.listenForLogs()
+}
+
+// viewBackupTargetInput shows the backup target settings for the chosen type
+func viewBackupTargetInput(m Model) string {
+	s := strings.Builder{}
+
+	// Banner
+	s.WriteString(getResponsiveBanner(m))
+	s.WriteString("\n\n")
+
+	maxWidth := getUsableWidth(m.width)
+
+	title := "Configure S3 Backup Target"
+	description := "Longhorn writes volume backups to this bucket. For AWS S3, leave the endpoint empty."
+	if m.backupTarget.kind == longhorn.BackupTargetNFS {
+		title = "Configure NFS Backup Target"
+		description = "Longhorn mounts this export on every server to write volume backups."
+	}
+	s.WriteString(m.styles.Bold.Render(title))
+	s.WriteString("\n\n")
+	for _, line := range wrapText(description, maxWidth) {
+		s.WriteString(m.styles.Normal.Render(line))
+		s.WriteString("\n")
+	}
+	s.WriteString("\n")
+
+	inputWidth := maxWidth - 8 // Account for border and padding
+	if inputWidth < 20 {
+		inputWidth = 20
+	}
+
+	labels := m.backupTargetLabels()
+	for i, field := range m.backupTargetFields() {
+		s.WriteString(createStyledBox(
+			fmt.Sprintf("%s: %s", labels[i], field.View()),
+			lipgloss.NewStyle().
+				Border(lipgloss.RoundedBorder()).
+				BorderForeground(lipgloss.Color("#009900")).
+				Padding(0, 1),
+			inputWidth,
+		))
+		s.WriteString("\n")
+	}
+
+	scheduleText := "Every volume is backed up on the schedule above, older backups beyond the retention count are deleted."
+	for _, line := range wrapText(scheduleText, maxWidth) {
+		s.WriteString(m.styles.Subtle.Render(line))
+		s.WriteString("\n")
+	}
+	s.WriteString("\n")
+
+	if m.backupTarget.err != nil {
+		for _, line := range wrapText(m.backupTarget.err.Error(), maxWidth) {
+			s.WriteString(m.styles.Error.Render(line))
+			s.WriteString("\n")
+		}
+		s.WriteString("\n")
+	}
+
+	// Navigation hints
+	s.WriteString(m.styles.Bold.Render("Navigation:"))
+	s.WriteString("\n")
+
+	navHints := []string{
+		"• Press Tab to switch between fields",
+		"• Press Enter to check the backup target",
+		"• Press Ctrl+b to go back to backup target selection",
+	}
+
+	for _, hint := range navHints {
+		for _, line := range wrapText(hint, maxWidth) {
+			s.WriteString(m.styles.Normal.Render(line))
+			s.WriteString("\n")
+		}
+	}
+	s.WriteString("\n")
+
+	// Status bar at the bottom
+	s.WriteString(m.styles.StatusBar.Render("Press Ctrl+c to quit"))
+
+	return renderWithLayout(m, s.String())
+}
+
+// updateBackupTargetInputState handles updates in the backup target input state
+func (m Model) updateBackupTargetInputState(msg tea.Msg) (tea.Model, tea.Cmd) {
+	var cmd tea.Cmd
+
+	if keyMsg, ok := msg.(tea.KeyMsg); ok {
+		switch keyMsg.String() {
+		case "ctrl+b":
+			m.state = StateBackupTargetSelection
+			return m, m.listenForLogs()
+
+		case "tab":
+			fields := m.backupTargetFields()
+			for i, field := range fields {
+				if field.Focused() {
+					field.Blur()
+					cmd = fields[(i+1)%len(fields)].Focus()
+					break
+				}
+			}
+			return m, cmd
+
+		case "enter":
+			settings, err := m.backupTarget.settings()
+			if err != nil {
+				m.backupTarget.err = err
+				return m, m.listenForLogs()
+			}
+			m.backupTarget.err = nil
+			m.redactor.Add(settings.SecretAccessKey)
+
+			m.state = StateBackupTargetValidation
+			m.isLoading = true
+			return m, tea.Batch(
+				m.spinner.Tick,
+				m.validateBackupTarget(settings),
+				m.listenForLogs(),
+			)
+		}
+	}
+
+	for _, field := range m.backupTargetFields() {
+		if field.Focused() {
+			*field, cmd = field.Update(msg)
+		}
+	}
+
+	return m, tea.Batch(cmd, m.listenForLogs())
+}
+
+// viewBackupTargetValidation shows progress while the backup target is checked
+func viewBackupTargetValidation(m Model) string {
+	s := strings.Builder{}
+
+	// Banner
+	s.WriteString(getResponsiveBanner(m))
+	s.WriteString("\n\n")
+
+	maxWidth := getUsableWidth(m.width)
+
+	s.WriteString(m.spinner.View())
+	s.WriteString(" ")
+	s.WriteString(m.styles.Bold.Render("Checking Backup Target..."))
+	s.WriteString("\n\n")
+
+	checkText := "Making sure the backup target is reachable and writable before installing."
+	for _, line := range wrapText(checkText, maxWidth) {
+		s.WriteString(m.styles.Normal.Render(line))
+		s.WriteString("\n")
+	}
+	s.WriteString("\n")
+
+	// Process logs
+	if len(m.logMessages) > 0 {
+		s.WriteString(m.styles.Bold.Render("Validation Logs:"))
+		s.WriteString("\n")
+
+		startIdx := 0
+		if len(m.logMessages) > 8 {
+			startIdx = len(m.logMessages) - 8
+		}
+
+		for _, msg := range m.logMessages[startIdx:] {
+			for _, line := range wrapText(msg, maxWidth-1) {
+				s.WriteString(" ")
+				s.WriteString(m.styles.Subtle.Render(line))
+				s.WriteString("\n")
+			}
+		}
+	}
+
+	// Status bar at the bottom
+	s.WriteString("\n")
+	s.WriteString(m.styles.StatusBar.Render("Press Ctrl+c to quit"))
+
+	return renderWithLayout(m, s.String())
+}
+
+// updateBackupTargetValidationState waits for the backup target check
+func (m Model) updateBackupTargetValidationState(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if msg, ok := msg.(backupTargetValidationCompleteMsg); ok {
+		m.isLoading = false
+		if msg.err != nil {
+			// Back to the settings, saying why
+			m.state = StateBackupTargetInput
+			m.backupTarget.err = fmt.Errorf("backup target check failed: %w", msg.err)
+			m.logChan <- "Backup target check failed. Please check the settings and try again."
+			return m, m.listenForLogs()
+		}
+
+		m.dnsInfo.LonghornBackup = msg.settings.BackupTarget
+		m.dnsInfo.LonghornBackupSchedule = msg.settings.schedule
+		m.dnsInfo.LonghornBackupRetain = msg.settings.retain
+		m.logChan <- fmt.Sprintf("Backup target %s is writable", msg.settings.URL())
+		return m.startK3SInstall()
+	}
+
+	return m, m.listenForLogs()
+}
+
+// startK3SInstall leaves the configuration screens and installs K3s
+func (m Model) startK3SInstall() (tea.Model, tea.Cmd) {
+	m.state = StateInstallingK3S
+	m.isLoading = true
+	return m, tea.Batch(
+		m.spinner.Tick,
+		m.installK3S(),
+		m.listenForLogs(),
+	)
+}
+
+// backupTargetInputs holds the Longhorn backup target fields
+type backupTargetInputs struct {
+	kind longhorn.BackupTargetType
+
+	bucket          textinput.Model
+	region          textinput.Model
+	endpoint        textinput.Model
+	accessKeyID     textinput.Model
+	secretAccessKey textinput.Model
+	nfsExport       textinput.Model
+	schedule        textinput.Model
+	retain          textinput.Model
+	err             error
+}
+
+// initializeBackupTargetInputs creates the backup target fields with defaults filled in
+func initializeBackupTargetInputs() backupTargetInputs {
+	newInput := func(placeholder, value string) textinput.Model {
+		ti := textinput.New()
+		ti.Placeholder = placeholder
+		ti.SetValue(value)
+		ti.Width = 30
+		ti.Prompt = ""
+		return ti
+	}
+
+	secretAccessKey := newInput("secret access key", "")
+	secretAccessKey.EchoMode = textinput.EchoPassword
+
+	return backupTargetInputs{
+		bucket:          newInput("my-bucket/longhorn", ""),
+		region:          newInput("us-east-1", "us-east-1"),
+		endpoint:        newInput("https://minio.example.com:9000", ""),
+		accessKeyID:     newInput("access key ID", ""),
+		secretAccessKey: secretAccessKey,
+		nfsExport:       newInput("nfs.example.com:/exports/longhorn", ""),
+		schedule:        newInput(longhorn.DefaultBackupSchedule, longhorn.DefaultBackupSchedule),
+		retain:          newInput(strconv.Itoa(longhorn.DefaultBackupRetain), strconv.Itoa(longhorn.DefaultBackupRetain)),
+	}
+}
+
+// backupTargetFields returns the inputs for the chosen target type in tab order
+func (m *Model) backupTargetFields() []*textinput.Model {
+	inputs := &m.backupTarget
+	if inputs.kind == longhorn.BackupTargetNFS {
+		return []*textinput.Model{&inputs.nfsExport, &inputs.schedule, &inputs.retain}
+	}
+	return []*textinput.Model{
+		&inputs.bucket, &inputs.region, &inputs.endpoint, &inputs.accessKeyID, &inputs.secretAccessKey,
+		&inputs.schedule, &inputs.retain,
+	}
+}
+
+// backupTargetLabels returns the labels of backupTargetFields
+func (m Model) backupTargetLabels() []string {
+	if m.backupTarget.kind == longhorn.BackupTargetNFS {
+		return []string{"NFS Export", "Backup Schedule (cron)", "Backups Kept Per Volume"}
+	}
+	return []string{
+		"Bucket", "Region", "Endpoint (optional)", "Access Key ID", "Secret Access Key",
+		"Backup Schedule (cron)", "Backups Kept Per Volume",
+	}
+}
+
+// settings validates the fields, returning the target, schedule and retention
+func (self backupTargetInputs) settings() (*backupTargetSettings, error) {
+	target := &longhorn.BackupTarget{Type: self.kind}
+	if self.kind == longhorn.BackupTargetNFS {
+		target.NFSExport = strings.TrimSpace(self.nfsExport.Value())
+	} else {
+		target.Bucket, target.Prefix = longhorn.ParseBucket(self.bucket.Value())
+		target.Region = strings.TrimSpace(self.region.Value())
+		target.Endpoint = strings.TrimRight(strings.TrimSpace(self.endpoint.Value()), "/")
+		target.AccessKeyID = strings.TrimSpace(self.accessKeyID.Value())
+		target.SecretAccessKey = strings.TrimSpace(self.secretAccessKey.Value())
+	}
+	if err := target.Validate(); err != nil {
+		return nil, err
+	}
+
+	schedule := strings.Join(strings.Fields(self.schedule.Value()), " ")
+	if !utils.IsCronSchedule(schedule) {
+		return nil, fmt.Errorf("invalid backup schedule: '%s' is not a 5 field cron expression", schedule)
+	}
+	retain, err := strconv.Atoi(strings.TrimSpace(self.retain.Value()))
+	if err != nil || retain < 1 {
+		return nil, fmt.Errorf("invalid retention: '%s' must keep at least 1 backup", self.retain.Value())
+	}
+
+	return &backupTargetSettings{BackupTarget: target, schedule: schedule, retain: retain}, nil
+}
+
+// backupTargetSettings is a validated backup target with its recurring job settings
+type backupTargetSettings struct {
+	*longhorn.BackupTarget
+	schedule string
+	retain   int
+}
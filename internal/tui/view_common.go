@@ -172,6 +172,12 @@ func (m Model) updateLoadingState(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.state = StateOSInfo
 		m.isLoading = false
 
+		// Warnings wait for the user to continue
+		m.environmentWarnings = osinfo.EnvironmentIssues(msg.info)
+		if len(m.environmentWarnings) > 0 {
+			return m, m.listenForLogs()
+		}
+
 		// Schedule automatic advancement after 1 second
 		return m, tea.Batch(
 			m.listenForLogs(),
@@ -235,6 +241,16 @@ func viewError(m Model) string {
 				s.WriteString("\n")
 			}
 		}
+	} else if errors.Is(m.err, errdefs.ErrUnsupportedEnvironment) {
+		s.WriteString(m.styles.Error.Render("Sorry, Unbind can't be installed in this environment!"))
+		s.WriteString("\n")
+		var customErr *errdefs.CustomError
+		if errors.As(m.err, &customErr) {
+			for _, line := range wrapText(customErr.Message, maxWidth) {
+				s.WriteString(m.styles.Subtle.Render(line))
+				s.WriteString("\n")
+			}
+		}
 	} else if errors.Is(m.err, errdefs.ErrDistributionDetectionFailed) {
 		s.WriteString(m.styles.Error.Render("Sorry, I couldn't detect your Linux distribution!"))
 	} else if errors.Is(m.err, errdefs.ErrNotRoot) {
@@ -264,7 +280,7 @@ func viewError(m Model) string {
 			s.WriteString("\n")
 		}
 	} else if m.err != nil {
-		errorMsg := fmt.Sprintf("An error occurred: %v", m.err)
+		errorMsg := m.redactor.Redact(fmt.Sprintf("An error occurred: %v", m.err))
 		for _, line := range wrapText(errorMsg, maxWidth) {
 			s.WriteString(m.styles.Error.Render(line))
 			s.WriteString("\n")
@@ -301,6 +317,8 @@ func viewOSInfo(m Model) string {
 	s.WriteString(getResponsiveBanner(m))
 	s.WriteString("\n\n")
 
+	maxWidth := getUsableWidth(m.width)
+
 	// OS Pretty Name (if available)
 	if m.osInfo.PrettyName != "" {
 		s.WriteString(m.styles.Bold.Render("OS: "))
@@ -311,7 +329,12 @@ func viewOSInfo(m Model) string {
 	// Distribution and Version
 	if m.osInfo.Distribution != "" {
 		s.WriteString(m.styles.Bold.Render("Distribution: "))
-		s.WriteString(m.styles.Normal.Render(m.osInfo.Distribution))
+		if m.osInfo.ID != "" && m.osInfo.ID != m.osInfo.Distribution {
+			s.WriteString(m.styles.Normal.Render(m.osInfo.ID))
+			s.WriteString(m.styles.Subtle.Render(fmt.Sprintf(" (%s compatible)", m.osInfo.Distribution)))
+		} else {
+			s.WriteString(m.styles.Normal.Render(m.osInfo.Distribution))
+		}
 		s.WriteString("\n")
 	}
 
@@ -328,9 +351,61 @@ func viewOSInfo(m Model) string {
 		s.WriteString("\n")
 	}
 
+	s.WriteString(m.styles.Bold.Render("Environment: "))
+	s.WriteString(m.styles.Normal.Render(osinfo.EnvironmentName(m.osInfo)))
+	s.WriteString("\n")
+
+	if m.osInfo.KernelVersion != "" {
+		s.WriteString(m.styles.Bold.Render("Kernel: "))
+		s.WriteString(m.styles.Normal.Render(m.osInfo.KernelVersion))
+		s.WriteString("\n")
+	}
+
+	s.WriteString(m.styles.Bold.Render("Hardware: "))
+	s.WriteString(m.styles.Normal.Render(osinfo.HardwareSummary(m.osInfo)))
+	s.WriteString("\n")
+
+	if m.osInfo.RootFilesystem != "" {
+		s.WriteString(m.styles.Bold.Render("Filesystem: "))
+		s.WriteString(m.styles.Normal.Render(m.osInfo.RootFilesystem))
+		if m.osInfo.VarLibFilesystem != m.osInfo.RootFilesystem {
+			s.WriteString(m.styles.Subtle.Render(fmt.Sprintf(" (%s on /var/lib)", m.osInfo.VarLibFilesystem)))
+		}
+		s.WriteString("\n")
+	}
+
+	security := fmt.Sprintf("SELinux %s", m.osInfo.SELinux)
+	if m.osInfo.AppArmor {
+		security += ", AppArmor enabled"
+	}
+	s.WriteString(m.styles.Bold.Render("Security: "))
+	s.WriteString(m.styles.Normal.Render(security))
+	s.WriteString("\n")
+
+	s.WriteString("\n")
+	if len(m.environmentWarnings) == 0 {
+		s.WriteString(m.styles.Success.Render("✓ Your system is compatible with Unbind!"))
+		s.WriteString("\n\n")
+		return renderWithLayout(m, s.String())
+	}
+
+	s.WriteString(m.styles.Warning.Render(fmt.Sprintf("! Unbind may not work fully in a %s:", osinfo.EnvironmentName(m.osInfo))))
 	s.WriteString("\n")
-	s.WriteString(m.styles.Success.Render("✓ Your system is compatible with Unbind!"))
+	for _, warning := range m.environmentWarnings {
+		for i, line := range wrapText(warning.Message, maxWidth-4) {
+			if i == 0 {
+				s.WriteString("  • ")
+			} else {
+				s.WriteString("    ")
+			}
+			s.WriteString(m.styles.Subtle.Render(line))
+			s.WriteString("\n")
+		}
+	}
+	s.WriteString("\n")
+	s.WriteString(m.styles.HighlightButton.Render(" Press Enter to continue anyway "))
 	s.WriteString("\n\n")
+	s.WriteString(m.styles.Subtle.Render("Press 'Ctrl+c' to quit"))
 
 	return renderWithLayout(m, s.String())
 }
@@ -338,6 +413,11 @@ func viewOSInfo(m Model) string {
 // updateOSInfoState handles updates in the OS info state
 func (m Model) updateOSInfoState(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		if msg.String() == "enter" && len(m.environmentWarnings) > 0 {
+			return m.updateOSInfoState(installPackagesMsg{})
+		}
+
 	case installPackagesMsg:
 		m.state = StateCheckingSwap
 		m.isLoading = true
@@ -292,24 +292,14 @@ func (m Model) updateDNSSuccessState(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
 		if msg.String() == "enter" {
-			// Start K3S installation
-			m.state = StateInstallingK3S
-			m.isLoading = true
-			return m, tea.Batch(
-				m.spinner.Tick,
-				m.installK3S(),
-				m.listenForLogs(),
-			)
+			// Continue with the optional backup target
+			m.state = StateBackupTargetSelection
+			return m, m.listenForLogs()
 		}
 	case autoAdvanceMsg:
-		// Auto-advance to K3S installation
-		m.state = StateInstallingK3S
-		m.isLoading = true
-		return m, tea.Batch(
-			m.spinner.Tick,
-			m.installK3S(),
-			m.listenForLogs(),
-		)
+		// Auto-advance to the optional backup target
+		m.state = StateBackupTargetSelection
+		return m, m.listenForLogs()
 	case tea.WindowSizeMsg:
 		m.width = msg.Width
 		m.height = msg.Height
@@ -97,17 +97,23 @@ func viewInstallingPackages(m Model) string {
 	s.WriteString(m.styles.Bold.Render("Installing:"))
 	s.WriteString("\n")
 
-	for _, pkg := range pkgmanager.GetDistributionPackages(m.osInfo.Distribution) {
+	for _, pkg := range m.requiredPackages() {
+		// Highlight the package the package manager is downloading or installing right now
 		bullet := m.styles.Key.Render("•")
+		style := m.styles.Normal
+		if !m.packageProgress.isComplete && pkg == m.packageProgress.packageName {
+			bullet = m.styles.Key.Render("→")
+			style = m.styles.Bold
+		}
 		pkgLine := fmt.Sprintf("%s %s", bullet, pkg)
 		pkgLines := wrapText(pkgLine, maxWidth-2)
 		for j, line := range pkgLines {
 			if j == 0 {
 				s.WriteString("  ")
-				s.WriteString(m.styles.Normal.Render(line))
+				s.WriteString(style.Render(line))
 			} else {
 				s.WriteString("    ") // Extra indent for continuation
-				s.WriteString(m.styles.Normal.Render(line))
+				s.WriteString(style.Render(line))
 			}
 			s.WriteString("\n")
 		}
@@ -169,6 +175,17 @@ func (m Model) updateInstallingPackagesState(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 		return m.processStateUpdate(nil)
 
+	case repositoryConsentMsg:
+		m.requiredRepositories = msg.repos
+		m.state = StateConfirmRepositories
+		m.isLoading = false
+		return m, m.listenForLogs()
+
+	case systemUpgradeConsentMsg:
+		m.state = StateConfirmSystemUpgrade
+		m.isLoading = false
+		return m, m.listenForLogs()
+
 	case installCompleteMsg:
 		m.state = StateInstallComplete
 		m.isLoading = false
@@ -197,6 +214,155 @@ func (m Model) updateInstallingPackagesState(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m.processStateUpdate(nil)
 }
 
+// viewConfirmRepositories asks before enabling the extra repositories some packages come from
+func viewConfirmRepositories(m Model) string {
+	s := strings.Builder{}
+	s.WriteString(getResponsiveBanner(m))
+	s.WriteString("\n\n")
+
+	maxWidth := getUsableWidth(m.width)
+
+	s.WriteString(m.styles.Bold.Render("Extra Package Repositories"))
+	s.WriteString("\n\n")
+
+	descText := "Some of the required packages aren't in the repositories enabled on this server. The installer can enable these repositories:"
+	for _, line := range wrapText(descText, maxWidth) {
+		s.WriteString(m.styles.Normal.Render(line))
+		s.WriteString("\n")
+	}
+	s.WriteString("\n")
+
+	for _, repo := range m.requiredRepositories {
+		s.WriteString(m.styles.Normal.Render("  • " + repo.Name))
+		s.WriteString("\n")
+	}
+	s.WriteString("\n")
+
+	noteText := "They are disabled again when Unbind is uninstalled."
+	for _, line := range wrapText(noteText, maxWidth) {
+		s.WriteString(m.styles.Subtle.Render(line))
+		s.WriteString("\n")
+	}
+	s.WriteString("\n")
+
+	questionText := "Do you want to enable them now?"
+	for _, line := range wrapText(questionText, maxWidth) {
+		s.WriteString(m.styles.Bold.Render(line))
+		s.WriteString("\n")
+	}
+	s.WriteString("\n")
+
+	yesButton := m.styles.HighlightButton.Render(" Yes (y) ")
+	noButton := m.styles.Subtle.Render(" No (n - Quit) ")
+
+	// Center buttons if we have enough width
+	buttonText := yesButton + "  " + noButton
+	if maxWidth > len(" Yes (y)   No (n - Quit) ") {
+		padding := (maxWidth - len(" Yes (y)   No (n - Quit) ")) / 2
+		if padding > 0 {
+			s.WriteString(strings.Repeat(" ", padding))
+		}
+	}
+	s.WriteString(buttonText)
+	s.WriteString("\n\n")
+
+	instructionText := "Press 'y' to enable the repositories, 'n' to stop the installation, or 'Ctrl+c' to quit."
+	for _, line := range wrapText(instructionText, maxWidth) {
+		s.WriteString(m.styles.Subtle.Render(line))
+		s.WriteString("\n")
+	}
+
+	return renderWithLayout(m, s.String())
+}
+
+func (m Model) updateConfirmRepositoriesState(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if keyMsg, ok := msg.(tea.KeyMsg); ok {
+		switch strings.ToLower(keyMsg.String()) {
+		case "y":
+			m.repositoryConsent = true
+			return m.transition(StateInstallingPackages, true, m.installRequiredPackages())
+		case "n":
+			return m.handleError(fmt.Errorf("the %s repositories are needed to install the required packages", pkgmanager.RepositoryNames(m.requiredRepositories)),
+				"Package installation stopped")
+		case "q":
+			return m, tea.Quit
+		}
+	}
+	return m, m.listenForLogs()
+}
+
+// viewConfirmSystemUpgrade asks before pacman upgrades the whole system, Arch doesn't support
+// installing packages without it
+func viewConfirmSystemUpgrade(m Model) string {
+	s := strings.Builder{}
+	s.WriteString(getResponsiveBanner(m))
+	s.WriteString("\n\n")
+
+	maxWidth := getUsableWidth(m.width)
+
+	s.WriteString(m.styles.Bold.Render("System Upgrade"))
+	s.WriteString("\n\n")
+
+	descText := "Arch Linux doesn't support installing packages without upgrading the rest of the system, so pacman will upgrade every installed package along with the ones Unbind needs."
+	for _, line := range wrapText(descText, maxWidth) {
+		s.WriteString(m.styles.Normal.Render(line))
+		s.WriteString("\n")
+	}
+	s.WriteString("\n")
+
+	noteText := "If the kernel is upgraded, the modules of the running kernel are removed. The installer loads the ones K3s and Longhorn need first, but reboot the server once the installation finishes."
+	for _, line := range wrapText(noteText, maxWidth) {
+		s.WriteString(m.styles.Warning.Render(line))
+		s.WriteString("\n")
+	}
+	s.WriteString("\n")
+
+	questionText := "Do you want to upgrade the system now?"
+	for _, line := range wrapText(questionText, maxWidth) {
+		s.WriteString(m.styles.Bold.Render(line))
+		s.WriteString("\n")
+	}
+	s.WriteString("\n")
+
+	yesButton := m.styles.HighlightButton.Render(" Yes (y) ")
+	noButton := m.styles.Subtle.Render(" No (n - Quit) ")
+
+	// Center buttons if we have enough width
+	buttonText := yesButton + "  " + noButton
+	if maxWidth > len(" Yes (y)   No (n - Quit) ") {
+		padding := (maxWidth - len(" Yes (y)   No (n - Quit) ")) / 2
+		if padding > 0 {
+			s.WriteString(strings.Repeat(" ", padding))
+		}
+	}
+	s.WriteString(buttonText)
+	s.WriteString("\n\n")
+
+	instructionText := "Press 'y' to upgrade and install, 'n' to stop the installation, or 'Ctrl+c' to quit."
+	for _, line := range wrapText(instructionText, maxWidth) {
+		s.WriteString(m.styles.Subtle.Render(line))
+		s.WriteString("\n")
+	}
+
+	return renderWithLayout(m, s.String())
+}
+
+func (m Model) updateConfirmSystemUpgradeState(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if keyMsg, ok := msg.(tea.KeyMsg); ok {
+		switch strings.ToLower(keyMsg.String()) {
+		case "y":
+			m.systemUpgradeConsent = true
+			return m.transition(StateInstallingPackages, true, m.installRequiredPackages())
+		case "n":
+			return m.handleError(fmt.Errorf("installing the required packages on %s needs a full system upgrade", m.osInfo.Distribution),
+				"Package installation stopped")
+		case "q":
+			return m, tea.Quit
+		}
+	}
+	return m, m.listenForLogs()
+}
+
 // viewInstallComplete shows the installation complete screen
 func viewInstallComplete(m Model) string {
 	s := strings.Builder{}
@@ -211,7 +377,7 @@ func viewInstallComplete(m Model) string {
 	s.WriteString(m.styles.Bold.Render("Installed Packages:"))
 	s.WriteString("\n")
 
-	for _, pkg := range pkgmanager.GetDistributionPackages(m.osInfo.Distribution) {
+	for _, pkg := range m.requiredPackages() {
 		checkmark := m.styles.Success.Render("✓")
 		pkgLine := fmt.Sprintf("%s %s", checkmark, pkg)
 		pkgLines := wrapText(pkgLine, maxWidth-2)
@@ -291,7 +457,7 @@ func viewInstallationComplete(m Model) string {
 	s.WriteString(m.styles.Bold.Render("Management Options:"))
 	s.WriteString("\n")
 
-	mgmtText := "A management script has been installed at /usr/local/bin/unbind"
+	mgmtText := "The unbind management CLI has been installed at /usr/local/bin/unbind"
 	for _, line := range wrapText(mgmtText, maxWidth) {
 		s.WriteString(m.styles.Normal.Render(line))
 		s.WriteString("\n")
@@ -304,6 +470,14 @@ func viewInstallationComplete(m Model) string {
 	commands := []string{
 		"• unbind uninstall - Uninstall Unbind (WARNING: This will permanently delete all data)",
 		"• unbind add-node - Show instructions for adding a new node",
+		"• unbind remove-node - Drain a node and remove it from the cluster",
+		"• unbind status - Show the health of the cluster and the platform",
+		"• unbind logs - Show logs of platform components, K3s and Longhorn",
+		"• unbind reconfigure-domain - Move Unbind to a new domain",
+		"• unbind backup - Back up the cluster datastore to local disk or S3",
+		"• unbind restore - Restore the cluster datastore from a backup",
+		"• unbind migrate - Move Unbind to a new server",
+		"• unbind --help - Show all commands",
 	}
 
 	for _, cmd := range commands {
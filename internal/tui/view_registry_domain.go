@@ -2,12 +2,15 @@ package tui
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/charmbracelet/bubbles/spinner"
+	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/unbindapp/unbind-installer/internal/utils"
 )
 
 // viewRegistryDomainInput shows the registry domain configuration screen
@@ -104,11 +107,58 @@ func viewRegistryDomainInput(m Model) string {
 	}
 	s.WriteString("\n")
 
+	// Storage and cleanup settings
+	s.WriteString(m.styles.Bold.Render("Registry Storage:"))
+	s.WriteString("\n")
+	if m.availableDiskSpaceGB > 0 {
+		diskText := fmt.Sprintf("Available disk space: %.2f GB", m.availableDiskSpaceGB)
+		for _, line := range wrapText(diskText, maxWidth) {
+			s.WriteString(m.styles.Subtle.Render(line))
+			s.WriteString("\n")
+		}
+	}
+
+	for _, field := range []struct {
+		label string
+		input textinput.Model
+	}{
+		{"Volume Size (GB)", m.registrySettings.size},
+		{"Storage Class", m.registrySettings.storageClass},
+		{"Tags Kept Per Image", m.registrySettings.retainTags},
+		{"GC Schedule (cron)", m.registrySettings.gcSchedule},
+	} {
+		s.WriteString(createStyledBox(
+			fmt.Sprintf("%s: %s", field.label, field.input.View()),
+			lipgloss.NewStyle().
+				Border(lipgloss.RoundedBorder()).
+				BorderForeground(lipgloss.Color("#009900")).
+				Padding(0, 1),
+			inputWidth,
+		))
+		s.WriteString("\n")
+	}
+
+	settingsText := "Older tags beyond the retention count are deleted, and garbage collection frees their disk space on the schedule above."
+	for _, line := range wrapText(settingsText, maxWidth) {
+		s.WriteString(m.styles.Subtle.Render(line))
+		s.WriteString("\n")
+	}
+	s.WriteString("\n")
+
+	if m.registrySettings.err != nil {
+		for _, line := range wrapText(m.registrySettings.err.Error(), maxWidth) {
+			s.WriteString(m.styles.Error.Render(line))
+			s.WriteString("\n")
+		}
+		s.WriteString("\n")
+	}
+
 	// Navigation hints
 	s.WriteString(m.styles.Bold.Render("Navigation:"))
 	s.WriteString("\n")
 
 	navHints := []string{
+		"• Press Tab to switch between fields",
 		"• Press Enter to validate domain",
 		"• Press Ctrl+b to go back to registry type selection",
 	}
@@ -138,8 +188,37 @@ func (m Model) updateRegistryDomainInputState(msg tea.Msg) (tea.Model, tea.Cmd)
 		return m, m.listenForLogs()
 	}
 
+	switch msg := msg.(type) {
+	case diskSpaceResultMsg:
+		if msg.err != nil {
+			m.logChan <- fmt.Sprintf("Warning: Could not get available disk space: %v", msg.err)
+			m.availableDiskSpaceGB = -1
+		} else {
+			m.availableDiskSpaceGB = msg.availableGB
+		}
+		return m, m.listenForLogs()
+
+	case tea.KeyMsg:
+		// Cycle focus between the domain and the storage settings
+		if msg.String() == "tab" {
+			fields := m.registryDomainFields()
+			for i, field := range fields {
+				if field.Focused() {
+					field.Blur()
+					fields[(i+1)%len(fields)].Focus()
+					break
+				}
+			}
+			return m, nil
+		}
+	}
+
 	// Handle text input updates
-	m.registryInput, cmd = m.registryInput.Update(msg)
+	for _, field := range m.registryDomainFields() {
+		if field.Focused() {
+			*field, cmd = field.Update(msg)
+		}
+	}
 
 	// Store the registry domain value
 	if m.dnsInfo == nil {
@@ -147,8 +226,14 @@ func (m Model) updateRegistryDomainInputState(msg tea.Msg) (tea.Model, tea.Cmd)
 	}
 	m.dnsInfo.RegistryDomain = m.registryInput.Value()
 
-	// If Enter was pressed with a valid domain, start validation
+	// If Enter was pressed with a valid domain and settings, start validation
 	if keyMsg, ok := msg.(tea.KeyMsg); ok && keyMsg.String() == "enter" {
+		if err := m.applyRegistrySettings(); err != nil {
+			m.registrySettings.err = err
+			return m, tea.Batch(cmd, m.listenForLogs())
+		}
+		m.registrySettings.err = nil
+
 		if m.dnsInfo.RegistryDomain != "" {
 			m.state = StateRegistryDNSValidation
 			m.isLoading = true
@@ -173,6 +258,87 @@ func (m Model) updateRegistryDomainInputState(msg tea.Msg) (tea.Model, tea.Cmd)
 	return m, tea.Batch(cmd, m.listenForLogs())
 }
 
+// registrySettingsInputs holds the self-hosted registry storage and cleanup fields
+type registrySettingsInputs struct {
+	size         textinput.Model
+	storageClass textinput.Model
+	retainTags   textinput.Model
+	gcSchedule   textinput.Model
+	err          error
+}
+
+// Defaults sized for a small VPS
+const (
+	defaultRegistryStorageSizeGB = 20
+	defaultRegistryStorageClass  = "longhorn"
+	defaultRegistryRetainTags    = 10
+	defaultRegistryGCSchedule    = "0 3 * * *"
+)
+
+// initializeRegistrySettingsInputs creates the storage settings fields with their defaults filled in
+func initializeRegistrySettingsInputs() registrySettingsInputs {
+	newInput := func(value string) textinput.Model {
+		ti := textinput.New()
+		ti.Placeholder = value
+		ti.SetValue(value)
+		ti.Width = 20
+		ti.Prompt = ""
+		return ti
+	}
+
+	return registrySettingsInputs{
+		size:         newInput(strconv.Itoa(defaultRegistryStorageSizeGB)),
+		storageClass: newInput(defaultRegistryStorageClass),
+		retainTags:   newInput(strconv.Itoa(defaultRegistryRetainTags)),
+		gcSchedule:   newInput(defaultRegistryGCSchedule),
+	}
+}
+
+// registryDomainFields returns the registry domain screen inputs in tab order
+func (m *Model) registryDomainFields() []*textinput.Model {
+	return []*textinput.Model{
+		&m.registryInput,
+		&m.registrySettings.size,
+		&m.registrySettings.storageClass,
+		&m.registrySettings.retainTags,
+		&m.registrySettings.gcSchedule,
+	}
+}
+
+// applyRegistrySettings validates the storage settings and stores them in dnsInfo
+func (m *Model) applyRegistrySettings() error {
+	sizeGB, err := strconv.Atoi(strings.TrimSpace(m.registrySettings.size.Value()))
+	if err != nil || sizeGB <= 0 {
+		return fmt.Errorf("invalid volume size: '%s' must be a whole number of GB greater than 0", m.registrySettings.size.Value())
+	}
+	// Leave room for the OS, k3s and container images on the same disk
+	if m.availableDiskSpaceGB > 0 && float64(sizeGB) > m.availableDiskSpaceGB*0.8 {
+		return fmt.Errorf("invalid volume size: %d GB leaves too little free disk space (%.2f GB available, at most %.0f GB can go to the registry)",
+			sizeGB, m.availableDiskSpaceGB, m.availableDiskSpaceGB*0.8)
+	}
+
+	storageClass := strings.TrimSpace(m.registrySettings.storageClass.Value())
+	if storageClass == "" || !utils.IsDNSName(storageClass) {
+		return fmt.Errorf("invalid storage class: '%s'", storageClass)
+	}
+
+	retainTags, err := strconv.Atoi(strings.TrimSpace(m.registrySettings.retainTags.Value()))
+	if err != nil || retainTags < 1 {
+		return fmt.Errorf("invalid retention: '%s' must keep at least 1 tag", m.registrySettings.retainTags.Value())
+	}
+
+	gcSchedule := strings.Join(strings.Fields(m.registrySettings.gcSchedule.Value()), " ")
+	if !utils.IsCronSchedule(gcSchedule) {
+		return fmt.Errorf("invalid GC schedule: '%s' is not a 5 field cron expression", gcSchedule)
+	}
+
+	m.dnsInfo.RegistryStorageSizeGB = sizeGB
+	m.dnsInfo.RegistryStorageClass = storageClass
+	m.dnsInfo.RegistryRetainTags = retainTags
+	m.dnsInfo.RegistryGCSchedule = gcSchedule
+	return nil
+}
+
 // viewRegistryDNSValidation shows the registry DNS validation screen
 func viewRegistryDNSValidation(m Model) string {
 	s := strings.Builder{}
@@ -284,6 +450,9 @@ func (m Model) updateRegistryDNSValidationState(msg tea.Msg) (tea.Model, tea.Cmd
 			// Clear registry domain to force re-entry
 			m.dnsInfo.RegistryDomain = ""
 			m.registryInput.SetValue("")
+			for _, field := range m.registryDomainFields() {
+				field.Blur()
+			}
 			m.registryInput.Focus()
 
 			return m, m.listenForLogs()
@@ -298,6 +467,9 @@ func (m Model) updateRegistryDNSValidationState(msg tea.Msg) (tea.Model, tea.Cmd
 		// Clear registry domain to force re-entry
 		m.dnsInfo.RegistryDomain = ""
 		m.registryInput.SetValue("")
+		for _, field := range m.registryDomainFields() {
+			field.Blur()
+		}
 		m.registryInput.Focus()
 
 		return m, m.listenForLogs()
@@ -1,7 +1,9 @@
 package tui
 
 import (
+	"errors"
 	"fmt"
+	"os"
 	"strings"
 	"time"
 
@@ -9,6 +11,8 @@ import (
 	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/unbindapp/unbind-installer/internal/errdefs"
+	"github.com/unbindapp/unbind-installer/internal/registry"
 	"github.com/unbindapp/unbind-installer/internal/utils"
 )
 
@@ -51,7 +55,7 @@ func viewRegistryTypeSelection(m Model) string {
 	// Option 2: External registry
 	s.WriteString(m.styles.Bold.Render("2. External Registry"))
 	s.WriteString("\n")
-	option2Text := "   Use Docker Hub, GHCR, Quay, or another registry service"
+	option2Text := "   Use Docker Hub, GHCR, Quay, AWS ECR, Google Artifact Registry, Azure ACR, or another registry service"
 	for _, line := range wrapText(option2Text, maxWidth) {
 		s.WriteString(m.styles.Normal.Render(line))
 		s.WriteString("\n")
@@ -98,7 +102,9 @@ func (m Model) updateRegistryTypeSelectionState(msg tea.Msg) (tea.Model, tea.Cmd
 			m.dnsInfo.DisableLocalRegistry = false
 			m.state = StateRegistryDomainInput
 			m.registryInput.Focus()
-			return m, m.listenForLogs()
+			m.registrySettings.err = nil
+			// Disk space bounds the registry volume size
+			return m, tea.Batch(m.getDiskSpaceCommand(), m.listenForLogs())
 
 		case "2":
 			// External registry selected
@@ -108,8 +114,8 @@ func (m Model) updateRegistryTypeSelectionState(msg tea.Msg) (tea.Model, tea.Cmd
 			m.dnsInfo.RegistryType = RegistryExternal
 			m.dnsInfo.DisableLocalRegistry = true
 			m.state = StateExternalRegistryInput
-			m.usernameInput.Focus()
-			return m, m.listenForLogs()
+			cmd := m.selectExternalRegistry(m.selectedRegistry)
+			return m, tea.Batch(cmd, m.listenForLogs())
 
 		case "ctrl+b":
 			// Go back to DNS configuration
@@ -127,6 +133,132 @@ func (m Model) updateRegistryTypeSelectionState(msg tea.Msg) (tea.Model, tea.Cmd
 	return m, m.listenForLogs()
 }
 
+// externalRegistryProvider describes one of the registries offered on the external registry screen
+type externalRegistryProvider struct {
+	key             string // Function key that selects it
+	name            string
+	host            string // Fixed host, empty when the user enters one
+	hostPlaceholder string
+	provider        registry.Provider
+	usernameLabel   string
+	passwordLabel   string
+	fixedUsername   string // Used instead of a username field, e.g. _json_key
+	passwordIsFile  bool   // The password field takes a path to a key file
+	hint            string
+}
+
+var externalRegistryProviders = []externalRegistryProvider{
+	{key: "f1", name: "Docker Hub (docker.io)", host: "docker.io", usernameLabel: "Username", passwordLabel: "Password"},
+	{key: "f2", name: "GitHub Container Registry (ghcr.io)", host: "ghcr.io", usernameLabel: "Username", passwordLabel: "Password"},
+	{key: "f3", name: "Red Hat Quay (quay.io)", host: "quay.io", usernameLabel: "Username", passwordLabel: "Password"},
+	{key: "f4", name: "Custom Registry", hostPlaceholder: "registry.example.com", usernameLabel: "Username", passwordLabel: "Password"},
+	{
+		key:             "f5",
+		name:            "AWS Elastic Container Registry",
+		hostPlaceholder: "123456789012.dkr.ecr.us-east-1.amazonaws.com",
+		provider:        registry.ProviderECR,
+		usernameLabel:   "Access Key ID",
+		passwordLabel:   "Secret Access Key",
+		hint:            "ECR tokens expire after 12 hours, Unbind installs a CronJob that refreshes them with this access key",
+	},
+	{
+		key:             "f6",
+		name:            "Google Artifact Registry",
+		hostPlaceholder: "us-docker.pkg.dev/my-project/my-repository",
+		provider:        registry.ProviderGAR,
+		passwordLabel:   "Key File",
+		fixedUsername:   registry.GARUsername,
+		passwordIsFile:  true,
+		hint:            "Path to a service account JSON key with the Artifact Registry Writer role",
+	},
+	{
+		key:             "f7",
+		name:            "Azure Container Registry",
+		hostPlaceholder: "myregistry.azurecr.io",
+		provider:        registry.ProviderACR,
+		usernameLabel:   "Client ID",
+		passwordLabel:   "Client Secret",
+		hint:            "Service principal with the AcrPush role on the registry",
+	},
+}
+
+// supportsImport reports whether docker config credentials can be used as-is.
+// Docker stores short-lived ECR tokens and GAR keys inline, neither fits the fields here.
+func (self externalRegistryProvider) supportsImport() bool {
+	return self.provider != registry.ProviderECR && !self.passwordIsFile
+}
+
+// selectedExternalRegistry returns the provider currently selected
+func (m Model) selectedExternalRegistry() externalRegistryProvider {
+	if m.selectedRegistry < 0 || m.selectedRegistry >= len(externalRegistryProviders) {
+		return externalRegistryProviders[0]
+	}
+	return externalRegistryProviders[m.selectedRegistry]
+}
+
+// externalRegistryFields returns the inputs shown for the selected provider, in tab order
+func (m *Model) externalRegistryFields() []*textinput.Model {
+	provider := m.selectedExternalRegistry()
+
+	var fields []*textinput.Model
+	if provider.host == "" {
+		fields = append(fields, &m.registryHostInput)
+	}
+	if provider.fixedUsername == "" {
+		fields = append(fields, &m.usernameInput)
+	}
+	return append(fields, &m.passwordInput)
+}
+
+// selectExternalRegistry switches provider and resets the inputs for its fields,
+// the returned command looks for importable credentials for the provider's host
+func (m *Model) selectExternalRegistry(index int) tea.Cmd {
+	m.selectedRegistry = index
+	provider := m.selectedExternalRegistry()
+
+	m.registryHostInput.Placeholder = provider.hostPlaceholder
+	if provider.passwordIsFile {
+		m.passwordInput.EchoMode = textinput.EchoNormal
+		m.passwordInput.Placeholder = "/path/to/key.json"
+	} else {
+		m.passwordInput.EchoMode = textinput.EchoPassword
+		m.passwordInput.Placeholder = "password"
+	}
+
+	m.registryHostInput.Blur()
+	m.usernameInput.Blur()
+	m.passwordInput.Blur()
+	m.externalRegistryFields()[0].Focus()
+
+	return m.detectRegistryCredentials()
+}
+
+// detectRegistryCredentials starts a docker config lookup for the selected registry host
+func (m *Model) detectRegistryCredentials() tea.Cmd {
+	provider := m.selectedExternalRegistry()
+	host := provider.host
+	if host == "" {
+		host = strings.TrimSpace(m.registryHostInput.Value())
+	}
+
+	m.importableRegistryAuth = nil
+	m.importableRegistryHost = host
+	if host == "" || !provider.supportsImport() {
+		return nil
+	}
+	return m.detectDockerCredentials(host)
+}
+
+// importRegistryCredentials fills the username and password from the docker config
+func (m *Model) importRegistryCredentials() {
+	if m.importableRegistryAuth == nil {
+		return
+	}
+	m.usernameInput.SetValue(m.importableRegistryAuth.Username)
+	m.passwordInput.SetValue(m.importableRegistryAuth.Password)
+	m.logChan <- fmt.Sprintf("Imported credentials for %s from %s", m.importableRegistryHost, m.importableRegistryAuth.Source)
+}
+
 // viewExternalRegistryInput shows the input screen for external registry credentials
 func viewExternalRegistryInput(m Model) string {
 	s := strings.Builder{}
@@ -136,6 +268,7 @@ func viewExternalRegistryInput(m Model) string {
 	s.WriteString("\n\n")
 
 	maxWidth := getUsableWidth(m.width)
+	provider := m.selectedExternalRegistry()
 
 	// Instructions
 	s.WriteString(m.styles.Bold.Render("Enter External Registry Credentials"))
@@ -152,109 +285,84 @@ func viewExternalRegistryInput(m Model) string {
 	s.WriteString(m.styles.Bold.Render("Select Registry:"))
 	s.WriteString("\n")
 
-	// Docker Hub
-	if m.selectedRegistry == 0 {
-		s.WriteString(m.styles.SelectedOption.Render("→ [F1] Docker Hub (docker.io)"))
-	} else {
-		s.WriteString(m.styles.Normal.Render("  [F1] Docker Hub (docker.io)"))
-	}
-	s.WriteString("\n")
-
-	// GitHub Container Registry
-	if m.selectedRegistry == 1 {
-		s.WriteString(m.styles.SelectedOption.Render("→ [F2] GitHub Container Registry (ghcr.io)"))
-	} else {
-		s.WriteString(m.styles.Normal.Render("  [F2] GitHub Container Registry (ghcr.io)"))
-	}
-	s.WriteString("\n")
-
-	// Red Hat Quay
-	if m.selectedRegistry == 2 {
-		s.WriteString(m.styles.SelectedOption.Render("→ [F3] Red Hat Quay (quay.io)"))
-	} else {
-		s.WriteString(m.styles.Normal.Render("  [F3] Red Hat Quay (quay.io)"))
+	for i, option := range externalRegistryProviders {
+		label := fmt.Sprintf("[%s] %s", strings.ToUpper(option.key), option.name)
+		if m.selectedRegistry == i {
+			s.WriteString(m.styles.SelectedOption.Render("→ " + label))
+		} else {
+			s.WriteString(m.styles.Normal.Render("  " + label))
+		}
+		s.WriteString("\n")
 	}
 	s.WriteString("\n")
 
-	// Custom Registry
-	if m.selectedRegistry == 3 {
-		s.WriteString(m.styles.SelectedOption.Render("→ [F4] Custom Registry"))
-	} else {
-		s.WriteString(m.styles.Normal.Render("  [F4] Custom Registry"))
+	inputWidth := maxWidth - 8 // Account for border and padding
+	if inputWidth < 20 {
+		inputWidth = 20
 	}
-	s.WriteString("\n\n")
-
-	// Custom registry field if selected
-	if m.selectedRegistry == 3 {
-		inputWidth := maxWidth - 8 // Account for border and padding
-		if inputWidth < 20 {
-			inputWidth = 20
-		}
-
-		customRegistryInput := createStyledBox(
-			fmt.Sprintf("Registry Host: %s", m.registryHostInput.View()),
-			lipgloss.NewStyle().
-				Border(lipgloss.RoundedBorder()).
-				BorderForeground(lipgloss.Color("#009900")).
-				Padding(0, 1),
-			inputWidth,
-		)
-
-		s.WriteString(customRegistryInput)
+	inputStyle := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(lipgloss.Color("#009900")).
+		Padding(0, 1)
+
+	// Registry host field when the provider doesn't have a fixed one
+	if provider.host == "" {
+		s.WriteString(createStyledBox(fmt.Sprintf("Registry Host: %s", m.registryHostInput.View()), inputStyle, inputWidth))
 		s.WriteString("\n\n")
 	}
 
 	// Show current registry
 	s.WriteString(m.styles.Bold.Render("Registry: "))
-	var registryHost string
-	switch m.selectedRegistry {
-	case 0:
-		registryHost = "docker.io"
-	case 1:
-		registryHost = "ghcr.io"
-	case 2:
-		registryHost = "quay.io"
-	case 3:
+	registryHost := provider.host
+	if registryHost == "" {
 		registryHost = m.registryHostInput.Value()
 		if registryHost == "" {
-			registryHost = "registry.example.com"
+			registryHost = provider.hostPlaceholder
 		}
 	}
 	s.WriteString(m.styles.Normal.Render(getRegistryDisplayName(registryHost)))
 	s.WriteString("\n\n")
 
-	// Username input field
-	inputWidth := maxWidth - 8 // Account for border and padding
-	if inputWidth < 20 {
-		inputWidth = 20
+	// Username input field, unless the provider uses a fixed username
+	if provider.fixedUsername == "" {
+		s.WriteString(createStyledBox(fmt.Sprintf("%s: %s", provider.usernameLabel, m.usernameInput.View()), inputStyle, inputWidth))
+		s.WriteString("\n\n")
 	}
 
-	usernameInput := createStyledBox(
-		fmt.Sprintf("Username: %s", m.usernameInput.View()),
-		lipgloss.NewStyle().
-			Border(lipgloss.RoundedBorder()).
-			BorderForeground(lipgloss.Color("#009900")).
-			Padding(0, 1),
-		inputWidth,
-	)
-
-	s.WriteString(usernameInput)
-	s.WriteString("\n\n")
-
 	// Password input field
-	passwordInput := createStyledBox(
-		fmt.Sprintf("Password: %s", m.passwordInput.View()),
-		lipgloss.NewStyle().
-			Border(lipgloss.RoundedBorder()).
-			BorderForeground(lipgloss.Color("#009900")).
-			Padding(0, 1),
-		inputWidth,
-	)
-
-	s.WriteString(passwordInput)
+	s.WriteString(createStyledBox(fmt.Sprintf("%s: %s", provider.passwordLabel, m.passwordInput.View()), inputStyle, inputWidth))
 	s.WriteString("\n\n")
 
-	validationText := "We'll validate these credentials before proceeding"
+	// Offer credentials found in the docker config
+	if m.importableRegistryAuth != nil && m.usernameInput.Value() != m.importableRegistryAuth.Username {
+		importText := fmt.Sprintf("Found credentials for %s in %s. Press Ctrl+o to import them.",
+			m.importableRegistryAuth.Username, m.importableRegistryAuth.Source)
+		for _, line := range wrapText(importText, maxWidth) {
+			s.WriteString(m.styles.Key.Render(line))
+			s.WriteString("\n")
+		}
+		s.WriteString("\n")
+	}
+
+	// Provider specific notes
+	if provider.hint != "" {
+		for _, line := range wrapText(provider.hint, maxWidth) {
+			s.WriteString(m.styles.Subtle.Render(line))
+			s.WriteString("\n")
+		}
+		s.WriteString("\n")
+	}
+
+	// Show why the last validation attempt failed
+	if m.dnsInfo != nil && m.dnsInfo.RegistryValidationErr != nil {
+		for _, line := range wrapText(registryValidationErrorText(m.dnsInfo.RegistryValidationErr), maxWidth) {
+			s.WriteString(m.styles.Error.Render(line))
+			s.WriteString("\n")
+		}
+		s.WriteString("\n")
+	}
+
+	validationText := "We'll validate these credentials and check they can push images before proceeding"
 	for _, line := range wrapText(validationText, maxWidth) {
 		s.WriteString(m.styles.Subtle.Render(line))
 		s.WriteString("\n")
@@ -267,8 +375,9 @@ func viewExternalRegistryInput(m Model) string {
 
 	navHints := []string{
 		"• Press Tab to switch between fields",
-		"• Press F1 through F4 to select registry type",
+		fmt.Sprintf("• Press F1 through F%d to select registry type", len(externalRegistryProviders)),
 		"• Press Enter to validate credentials",
+		"• Press Ctrl+o to import credentials found in your docker config",
 		"• Press Ctrl+b to go back to registry type selection",
 	}
 
@@ -290,136 +399,109 @@ func viewExternalRegistryInput(m Model) string {
 func (m Model) updateExternalRegistryInputState(msg tea.Msg) (tea.Model, tea.Cmd) {
 	var cmd tea.Cmd
 
+	keyMsg, isKey := msg.(tea.KeyMsg)
+
 	// Check if back button was pressed
-	if keyMsg, ok := msg.(tea.KeyMsg); ok && keyMsg.String() == "ctrl+b" {
+	if isKey && keyMsg.String() == "ctrl+b" {
 		// Go back to registry type selection
 		m.state = StateRegistryTypeSelection
 		return m, m.listenForLogs()
 	}
 
-	// Check for registry selection keys
-	if keyMsg, ok := msg.(tea.KeyMsg); ok {
-		switch keyMsg.String() {
-		case "f1":
-			// Docker Hub
-			m.selectedRegistry = 0
-		case "f2":
-			// GitHub Container Registry
-			m.selectedRegistry = 1
-		case "f3":
-			// Red Hat Quay
-			m.selectedRegistry = 2
-		case "f4":
-			// Custom Registry
-			m.selectedRegistry = 3
+	// Credentials found in the docker config, ignore lookups for a host that's no longer selected
+	if credsMsg, ok := msg.(dockerCredentialsMsg); ok {
+		if credsMsg.host == m.importableRegistryHost {
+			if credsMsg.err != nil {
+				m.logChan <- fmt.Sprintf("Could not read docker credentials for %s: %v", credsMsg.host, credsMsg.err)
+			}
+			m.importableRegistryAuth = credsMsg.creds
+			if m.autoImportRegistryAuth && m.usernameInput.Value() == "" && m.passwordInput.Value() == "" {
+				m.importRegistryCredentials()
+			}
 		}
+		return m.storeExternalRegistryValues(), m.listenForLogs()
 	}
 
-	// Check if tab was pressed
-	if keyMsg, ok := msg.(tea.KeyMsg); ok && keyMsg.String() == "tab" {
-		// Toggle focus between inputs
-		if m.selectedRegistry == 3 {
-			// Custom registry has 3 fields
-			if m.registryHostInput.Focused() {
-				m.registryHostInput.Blur()
-				m.usernameInput.Focus()
-			} else if m.usernameInput.Focused() {
-				m.usernameInput.Blur()
-				m.passwordInput.Focus()
-			} else {
-				m.passwordInput.Blur()
-				m.registryHostInput.Focus()
-			}
-		} else {
-			// Other registries have 2 fields
-			if m.usernameInput.Focused() {
-				m.usernameInput.Blur()
-				m.passwordInput.Focus()
-			} else {
-				m.passwordInput.Blur()
-				m.usernameInput.Focus()
+	// Check for registry selection keys
+	if isKey {
+		for i, option := range externalRegistryProviders {
+			if keyMsg.String() == option.key {
+				cmd = m.selectExternalRegistry(i)
+				return m, tea.Batch(cmd, m.listenForLogs())
 			}
 		}
-		return m, nil
+
+		if keyMsg.String() == "ctrl+o" {
+			m.importRegistryCredentials()
+			return m.storeExternalRegistryValues(), m.listenForLogs()
+		}
 	}
 
-	// Update the focused input
-	if m.registryHostInput.Focused() {
-		m.registryHostInput, cmd = m.registryHostInput.Update(msg)
-	} else if m.usernameInput.Focused() {
-		m.usernameInput, cmd = m.usernameInput.Update(msg)
-	} else {
-		m.passwordInput, cmd = m.passwordInput.Update(msg)
+	fields := m.externalRegistryFields()
+	focused := 0
+	for i, field := range fields {
+		if field.Focused() {
+			focused = i
+		}
 	}
 
-	// Store the values
-	if m.dnsInfo == nil {
-		m.dnsInfo = &dnsInfo{}
+	// Check if tab was pressed, cycle focus between the provider's fields
+	if isKey && keyMsg.String() == "tab" {
+		fields[focused].Blur()
+		fields[(focused+1)%len(fields)].Focus()
+		if fields[focused] == &m.registryHostInput {
+			cmd = m.detectRegistryCredentials()
+		}
+		return m, cmd
 	}
-	m.dnsInfo.RegistryUsername = m.usernameInput.Value()
-	m.dnsInfo.RegistryPassword = m.passwordInput.Value()
 
-	// Set registry host based on selection
-	switch m.selectedRegistry {
-	case 0:
-		m.dnsInfo.RegistryHost = "docker.io"
-	case 1:
-		m.dnsInfo.RegistryHost = "ghcr.io"
-	case 2:
-		m.dnsInfo.RegistryHost = "quay.io"
-	case 3:
-		m.dnsInfo.RegistryHost = m.registryHostInput.Value()
-	}
-
-	// If Enter was pressed, handle tabbing or submission
-	if keyMsg, ok := msg.(tea.KeyMsg); ok && keyMsg.String() == "enter" {
-		if m.selectedRegistry == 3 {
-			// Custom registry: 3 fields
-			if m.registryHostInput.Focused() {
-				// Move to username
-				m.registryHostInput.Blur()
-				m.usernameInput.Focus()
-				return m, nil
-			} else if m.usernameInput.Focused() {
-				// Move to password
-				m.usernameInput.Blur()
-				m.passwordInput.Focus()
-				return m, nil
-			} else if m.passwordInput.Focused() {
-				// Only submit if all fields are filled
-				if m.dnsInfo.RegistryUsername != "" && m.dnsInfo.RegistryPassword != "" && m.dnsInfo.RegistryHost != "" {
-					m.state = StateExternalRegistryValidation
-					m.isLoading = true
-					m.dnsInfo.TestingStartTime = time.Now()
-					return m, tea.Batch(
-						m.spinner.Tick,
-						m.validateRegistryCredentials(),
-						m.listenForLogs(),
-					)
-				}
-				return m, m.listenForLogs()
+	// Update the focused input
+	*fields[focused], cmd = fields[focused].Update(msg)
+
+	// Store the values
+	m = m.storeExternalRegistryValues()
+	provider := m.selectedExternalRegistry()
+
+	// If Enter was pressed, move to the next field or submit from the last one
+	if isKey && keyMsg.String() == "enter" {
+		if focused < len(fields)-1 {
+			fields[focused].Blur()
+			fields[focused+1].Focus()
+			if fields[focused] == &m.registryHostInput {
+				cmd = m.detectRegistryCredentials()
 			}
-		} else {
-			// Other registries: 2 fields
-			if m.usernameInput.Focused() {
-				// Move to password
-				m.usernameInput.Blur()
-				m.passwordInput.Focus()
-				return m, nil
-			} else if m.passwordInput.Focused() {
-				if m.dnsInfo.RegistryUsername != "" && m.dnsInfo.RegistryPassword != "" {
-					m.state = StateExternalRegistryValidation
-					m.isLoading = true
-					m.dnsInfo.TestingStartTime = time.Now()
-					return m, tea.Batch(
-						m.spinner.Tick,
-						m.validateRegistryCredentials(),
-						m.listenForLogs(),
-					)
-				}
+			return m, cmd
+		}
+
+		// Only submit if all fields are filled
+		if m.dnsInfo.RegistryUsername == "" || m.dnsInfo.RegistryPassword == "" || m.dnsInfo.RegistryHost == "" {
+			return m, m.listenForLogs()
+		}
+
+		// Key file providers send the file contents as the password
+		if provider.passwordIsFile {
+			keyData, err := os.ReadFile(strings.TrimSpace(m.dnsInfo.RegistryPassword))
+			if err == nil {
+				_, err = registry.ParseServiceAccountKey(keyData)
+			}
+			if err != nil {
+				m.dnsInfo.RegistryValidationErr = fmt.Errorf("could not use key file: %w", err)
 				return m, m.listenForLogs()
 			}
+			m.dnsInfo.RegistryPassword = string(keyData)
 		}
+
+		m.redactor.Add(m.dnsInfo.RegistryPassword)
+
+		m.state = StateExternalRegistryValidation
+		m.isLoading = true
+		m.dnsInfo.TestingStartTime = time.Now()
+		m.dnsInfo.RegistryValidationErr = nil
+		return m, tea.Batch(
+			m.spinner.Tick,
+			m.validateRegistryCredentials(),
+			m.listenForLogs(),
+		)
 	}
 
 	if _, ok := msg.(tea.WindowSizeMsg); ok {
@@ -431,6 +513,25 @@ func (m Model) updateExternalRegistryInputState(msg tea.Msg) (tea.Model, tea.Cmd
 	return m, tea.Batch(cmd, m.listenForLogs())
 }
 
+// storeExternalRegistryValues copies the inputs for the selected provider into dnsInfo
+func (m Model) storeExternalRegistryValues() Model {
+	if m.dnsInfo == nil {
+		m.dnsInfo = &dnsInfo{}
+	}
+	provider := m.selectedExternalRegistry()
+	m.dnsInfo.RegistryProvider = provider.provider
+	m.dnsInfo.RegistryHost = provider.host
+	if provider.host == "" {
+		m.dnsInfo.RegistryHost = strings.TrimSpace(m.registryHostInput.Value())
+	}
+	m.dnsInfo.RegistryUsername = provider.fixedUsername
+	if provider.fixedUsername == "" {
+		m.dnsInfo.RegistryUsername = strings.TrimSpace(m.usernameInput.Value())
+	}
+	m.dnsInfo.RegistryPassword = m.passwordInput.Value()
+	return m
+}
+
 // viewExternalRegistryValidation shows validation of external registry credentials
 func viewExternalRegistryValidation(m Model) string {
 	s := strings.Builder{}
@@ -507,15 +608,16 @@ func (m Model) updateExternalRegistryValidationState(msg tea.Msg) (tea.Model, te
 				}),
 			)
 		} else {
-			// If validation fails, go back to registry input
+			// If validation fails, go back to registry input and say why
 			m.state = StateExternalRegistryInput
 			m.isLoading = false
+			m.dnsInfo.RegistryValidationErr = msg.err
 			m.logChan <- "Registry credentials validation failed. Please try again."
 
-			// Focus username field
-			m.usernameInput.Focus()
+			// Focus the first field again
+			cmd := m.selectExternalRegistry(m.selectedRegistry)
 
-			return m, m.listenForLogs()
+			return m, tea.Batch(cmd, m.listenForLogs())
 		}
 
 	case tea.WindowSizeMsg:
@@ -527,6 +629,20 @@ func (m Model) updateExternalRegistryValidationState(msg tea.Msg) (tea.Model, te
 	return m, m.listenForLogs()
 }
 
+// registryValidationErrorText explains a failed credential check to the user
+func registryValidationErrorText(err error) string {
+	switch {
+	case errors.Is(err, errdefs.ErrRegistryInvalidCredentials):
+		return "The registry rejected these credentials. Check the username and password or access token."
+	case errors.Is(err, errdefs.ErrRegistryPushDenied):
+		return "These credentials can log in but are not allowed to push images. Use a token with write access."
+	case errors.Is(err, errdefs.ErrRegistryUnreachable):
+		return "Could not reach the registry. Check the registry host and your network connection."
+	default:
+		return fmt.Sprintf("Registry validation failed: %s", err.Error())
+	}
+}
+
 // getRegistryDisplayName returns a user-friendly display name for registry hosts
 func getRegistryDisplayName(host string) string {
 	switch host {
@@ -536,6 +652,16 @@ func getRegistryDisplayName(host string) string {
 		return "GitHub Container Registry"
 	case "quay.io":
 		return "Red Hat Quay"
+	}
+
+	apiHost := registry.APIHost(host)
+	switch {
+	case strings.Contains(apiHost, ".dkr.ecr."):
+		return "AWS ECR (" + host + ")"
+	case strings.HasSuffix(apiHost, "-docker.pkg.dev") || strings.HasSuffix(apiHost, "gcr.io"):
+		return "Google Artifact Registry (" + host + ")"
+	case strings.HasSuffix(apiHost, ".azurecr.io"):
+		return "Azure Container Registry (" + host + ")"
 	default:
 		return host
 	}
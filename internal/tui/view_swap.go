@@ -10,6 +10,8 @@ import (
 	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/unbindapp/unbind-installer/internal/osinfo"
+	"github.com/unbindapp/unbind-installer/internal/system"
 )
 
 // viewCheckingSwap shows progress while checking swap status.
@@ -139,6 +141,15 @@ func viewEnterSwapSize(m Model) string {
 		s.WriteString("\n")
 	}
 
+	if recommended := m.recommendedSwapGB(); recommended > 0 {
+		recommendedText := fmt.Sprintf("Recommended for %s of RAM: %d GB", osinfo.FormatMemory(m.osInfo.MemoryTotal), recommended)
+		for _, line := range wrapText(recommendedText, maxWidth) {
+			s.WriteString(m.styles.Subtle.Render(line))
+			s.WriteString("\n")
+		}
+		s.WriteString("\n")
+	}
+
 	// Create styled input box
 	inputWidth := maxWidth - 8 // Account for border and padding
 	if inputWidth < 20 {
@@ -261,7 +272,12 @@ func (m Model) updateCheckingSwapState(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m, m.listenForLogs()
 		}
 
-		if msg.isEnabled {
+		// Containers get their swap from the host, a swap file can't be enabled inside them
+		if !msg.isEnabled && m.osInfo != nil && m.osInfo.Container != "" {
+			m.logChan <- fmt.Sprintf("Swap can't be created inside a %s, skipping swap creation", osinfo.EnvironmentName(m.osInfo))
+		}
+
+		if msg.isEnabled || (m.osInfo != nil && m.osInfo.Container != "") {
 			// Swap exists, skip creation flow and go to installing packages
 			m.state = StateInstallingPackages
 			m.isLoading = true
@@ -316,6 +332,9 @@ func (m Model) updateConfirmCreateSwapState(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.isLoading = false
 			m.swapSizeInput.Focus()
 			m.swapSizeInput.SetValue("")
+			if recommended := m.recommendedSwapGB(); recommended > 0 {
+				m.swapSizeInput.SetValue(strconv.Itoa(recommended))
+			}
 			m.swapSizeInputErr = nil
 			return m, textinput.Blink
 		} else if strings.ToLower(msg.String()) == "n" {
@@ -336,6 +355,14 @@ func (m Model) updateConfirmCreateSwapState(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, m.listenForLogs()
 }
 
+// recommendedSwapGB is the suggested swap size, zero when the amount of RAM is unknown
+func (m Model) recommendedSwapGB() int {
+	if m.osInfo == nil || m.osInfo.MemoryTotal == 0 {
+		return 0
+	}
+	return system.RecommendedSwapGB(m.osInfo.MemoryTotal, m.availableDiskSpaceGB)
+}
+
 func (m Model) updateEnterSwapSizeState(msg tea.Msg) (tea.Model, tea.Cmd) {
 	var cmd tea.Cmd
 	var cmds []tea.Cmd
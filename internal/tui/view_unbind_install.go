@@ -205,9 +205,9 @@ func (m Model) updateInstallingUnbindState(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m.processStateUpdate(nil)
 
 	case unbindInstallCompleteMsg:
-		// Install management script with cluster IP
-		if err := installer.InstallManagementScript(m.dnsInfo.InternalIP); err != nil {
-			m.logMessages = append(m.logMessages, fmt.Sprintf("Warning: Failed to install management script: %v", err))
+		// Install the management CLI with the cluster IP and the chosen settings
+		if err := installer.InstallManagementCLI(m.dnsInfo.clusterConfig()); err != nil {
+			m.logMessages = append(m.logMessages, fmt.Sprintf("Warning: Failed to install management CLI: %v", err))
 		}
 
 		// Move to installation complete state
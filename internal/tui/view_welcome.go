@@ -1,6 +1,8 @@
 package tui
 
 import (
+	"os"
+	"path/filepath"
 	"strings"
 
 	tea "github.com/charmbracelet/bubbletea"
@@ -31,6 +33,14 @@ func viewWelcome(m Model) string {
 	}
 	s.WriteString("\n")
 
+	// Rebuilding a lost server from a backup is a separate command
+	restoreHint := "Rebuilding a lost server from a backup? Quit and run: " + filepath.Base(os.Args[0]) + " restore-server <backup>"
+	for _, line := range wrapText(restoreHint, maxWidth) {
+		s.WriteString(m.styles.Subtle.Render(line))
+		s.WriteString("\n")
+	}
+	s.WriteString("\n")
+
 	// Requirements section
 	reqTitle := m.styles.Bold.Render("Requirements:")
 	s.WriteString(reqTitle)
@@ -0,0 +1,104 @@
+package utils
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+// RedactedPlaceholder replaces secret values in redacted text
+const RedactedPlaceholder = "[REDACTED]"
+
+// minSecretLength is the shortest secret that is redacted, shorter values would replace common
+// words and numbers all over the logs
+const minSecretLength = 6
+
+// minSecretLineLength is the shortest line of a multi-line secret that is redacted on its own
+const minSecretLineLength = 16
+
+// Redactor replaces known secret values in text before it is logged or displayed
+type Redactor struct {
+	mu      sync.RWMutex
+	secrets []string
+}
+
+// NewRedactor creates an empty redactor
+func NewRedactor() *Redactor {
+	return &Redactor{}
+}
+
+// Add registers secret values, those shorter than six characters are ignored. Lines of multi-line secrets such as key files are
+// registered too, since tools often echo them one line at a time.
+func (self *Redactor) Add(secrets ...string) {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+
+	for _, secret := range secrets {
+		self.add(secret)
+		if strings.Contains(secret, "\n") {
+			for _, line := range strings.Split(secret, "\n") {
+				if line = strings.TrimSpace(line); len(line) >= minSecretLineLength {
+					self.add(line)
+				}
+			}
+		}
+	}
+
+	// Longest first so a secret containing another is replaced whole
+	sort.Slice(self.secrets, func(i, j int) bool {
+		return len(self.secrets[i]) > len(self.secrets[j])
+	})
+}
+
+func (self *Redactor) add(secret string) {
+	if len(secret) < minSecretLength {
+		return
+	}
+	for _, existing := range self.secrets {
+		if existing == secret {
+			return
+		}
+	}
+	self.secrets = append(self.secrets, secret)
+}
+
+// Redact replaces every registered secret in text
+func (self *Redactor) Redact(text string) string {
+	if self == nil {
+		return text
+	}
+
+	self.mu.RLock()
+	defer self.mu.RUnlock()
+
+	for _, secret := range self.secrets {
+		text = strings.ReplaceAll(text, secret, RedactedPlaceholder)
+	}
+	return text
+}
+
+// RedactError replaces every registered secret in the message of err, the original error can still
+// be unwrapped
+func (self *Redactor) RedactError(err error) error {
+	if err == nil {
+		return nil
+	}
+	message := self.Redact(err.Error())
+	if message == err.Error() {
+		return err
+	}
+	return &redactedError{message: message, err: err}
+}
+
+type redactedError struct {
+	message string
+	err     error
+}
+
+func (self *redactedError) Error() string {
+	return self.message
+}
+
+func (self *redactedError) Unwrap() error {
+	return self.err
+}
@@ -0,0 +1,83 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedactor(t *testing.T) {
+	tests := []struct {
+		name    string
+		secrets []string
+		text    string
+		want    string
+	}{
+		{
+			name:    "secret",
+			secrets: []string{"hunter22"},
+			text:    "login with hunter22 failed, hunter22 is wrong",
+			want:    "login with [REDACTED] failed, [REDACTED] is wrong",
+		},
+		{
+			name:    "overlapping secrets",
+			secrets: []string{"secret", "secret-key-123"},
+			text:    "key secret-key-123 and secret",
+			want:    "key [REDACTED] and [REDACTED]",
+		},
+		{
+			name:    "short secrets",
+			secrets: []string{"a", "ab", "12345"},
+			text:    "a bad 12345",
+			want:    "a bad 12345",
+		},
+		{
+			name:    "empty secret",
+			secrets: []string{""},
+			text:    "nothing to hide",
+			want:    "nothing to hide",
+		},
+		{
+			name:    "empty text",
+			secrets: []string{"hunter22"},
+			text:    "",
+			want:    "",
+		},
+		{
+			name:    "key file lines",
+			secrets: []string{"-----BEGIN KEY-----\nMIIEvQIBADANBgkqhkiG9w0BAQEF\nend\n-----END KEY-----"},
+			text:    "line MIIEvQIBADANBgkqhkiG9w0BAQEF and end",
+			want:    "line [REDACTED] and end",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			redactor := NewRedactor()
+			redactor.Add(tt.secrets...)
+			assert.Equal(t, tt.want, redactor.Redact(tt.text))
+		})
+	}
+}
+
+func TestRedactor_Nil(t *testing.T) {
+	var redactor *Redactor
+	assert.Equal(t, "hunter22", redactor.Redact("hunter22"))
+	assert.NoError(t, redactor.RedactError(nil))
+}
+
+func TestRedactor_RedactError(t *testing.T) {
+	redactor := NewRedactor()
+	redactor.Add("hunter22")
+
+	cause := errors.New("unauthorized")
+	err := redactor.RedactError(fmt.Errorf("login with hunter22: %w", cause))
+	assert.EqualError(t, err, "login with [REDACTED]: unauthorized")
+	assert.ErrorIs(t, err, cause)
+
+	unchanged := errors.New("timeout")
+	assert.Same(t, unchanged, redactor.RedactError(unchanged))
+	assert.NoError(t, redactor.RedactError(nil))
+}
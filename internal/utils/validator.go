@@ -21,3 +21,19 @@ func IsDNSName(str string) bool {
 func IsIP(str string) bool {
 	return net.ParseIP(str) != nil
 }
+
+var rxCronField = regexp.MustCompile(`^(\*|\d+(-\d+)?)(/\d+)?(,(\*|\d+(-\d+)?)(/\d+)?)*$`)
+
+// IsCronSchedule checks a standard 5 field cron expression (minute hour day month weekday)
+func IsCronSchedule(str string) bool {
+	fields := strings.Fields(str)
+	if len(fields) != 5 {
+		return false
+	}
+	for _, field := range fields {
+		if !rxCronField.MatchString(field) {
+			return false
+		}
+	}
+	return true
+}
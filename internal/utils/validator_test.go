@@ -0,0 +1,32 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsCronSchedule(t *testing.T) {
+	tests := []struct {
+		schedule string
+		want     bool
+	}{
+		{schedule: "0 3 * * *", want: true},
+		{schedule: "*/15 * * * *", want: true},
+		{schedule: "0 1,13 * * 1-5", want: true},
+		{schedule: "0-30/10 2 1 */2 0", want: true},
+		{schedule: "  0  3 * *  * ", want: true},
+		{schedule: "", want: false},
+		{schedule: "0 3 * *", want: false},
+		{schedule: "0 0 3 * * *", want: false},
+		{schedule: "@daily", want: false},
+		{schedule: "0 3 * * MON", want: false},
+		{schedule: "0 3 * * 1,", want: false},
+		{schedule: "0 3 * * -1", want: false},
+		{schedule: "0 3 ? * *", want: false},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, IsCronSchedule(tt.schedule), tt.schedule)
+	}
+}